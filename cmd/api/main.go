@@ -1,22 +1,56 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"fmt"
 	"log/slog"
+	"net"
 	"os"
+	"strings"
+	"time"
 
 	"tyr/config"
 
 	"tyr/internal/api/root"
+	"tyr/internal/api/v1/admin/activitylog"
+	adminapikey "tyr/internal/api/v1/admin/apikey"
+	admindocument "tyr/internal/api/v1/admin/document"
+	adminimpersonation "tyr/internal/api/v1/admin/impersonation"
+	"tyr/internal/api/v1/admin/lockout"
+	"tyr/internal/api/v1/admin/policy"
+	adminsession "tyr/internal/api/v1/admin/session"
+	adminuser "tyr/internal/api/v1/admin/user"
+	appapikey "tyr/internal/api/v1/app/apikey"
 	"tyr/internal/api/v1/app/document"
+	"tyr/internal/api/v1/app/expensereport"
+	"tyr/internal/api/v1/app/me"
+	"tyr/internal/api/v1/app/organization"
+	"tyr/internal/api/v1/app/session"
 	"tyr/internal/api/v1/auth"
+	"tyr/internal/api/wellknown"
+	"tyr/internal/apikey"
 	"tyr/internal/db"
+	"tyr/internal/denylist"
+	"tyr/internal/expense"
+	"tyr/internal/impersonation"
+	"tyr/internal/jwtkeys"
+	"tyr/internal/loginguard"
+	"tyr/internal/mfa"
+	"tyr/internal/oidc"
+	"tyr/internal/otp"
+	"tyr/internal/passwordpolicy"
+	"tyr/internal/privacy"
 	"tyr/internal/rbac"
+	"tyr/internal/reextract"
 	"tyr/internal/repo"
+	"tyr/internal/usertoken"
 	"tyr/third_party/azure"
+	"tyr/third_party/blob"
+	"tyr/third_party/mailer"
+	"tyr/third_party/sms"
 
 	"github.com/M15t/gram/pkg/server"
-	"github.com/M15t/gram/pkg/server/middleware/jwt"
 	"github.com/M15t/gram/pkg/server/middleware/secure"
 	"github.com/M15t/gram/pkg/server/middleware/slogger"
 	"github.com/M15t/gram/pkg/util/crypter"
@@ -56,6 +90,9 @@ func main() {
 		AllowOrigins:      cfg.Server.AllowOrigins,
 		Debug:             cfg.General.Debug,
 	})
+	// The client IP keys the login lockouts, it must not be spoofable with X-Forwarded-For
+	e.IPExtractor, err = ipExtractor(cfg.Server.TrustedProxies)
+	checkErr(err)
 
 	// Create a slog logger, which:
 	//   - Logs to stdout.
@@ -85,34 +122,117 @@ func main() {
 	// Initialize core services
 	crypterSvc := crypter.New()
 	repoSvc := repo.New(db)
-	rbacSvc := rbac.New(cfg.General.Debug)
-	jwtSvc := jwt.New(cfg.JWT.Algorithm, cfg.JWT.Secret, cfg.JWT.DurationAccessToken, cfg.JWT.DurationRefreshToken)
+	rbacSvc, err := rbac.New(repoSvc.RBAC, cfg.General.Debug)
+	checkErr(err)
+	// the policies changed on the other instances are picked up by polling
+	go rbacSvc.Watch(context.Background(), time.Duration(cfg.RBAC.ReloadInterval)*time.Second, func(err error) {
+		e.Logger.Errorf("error reloading rbac policies: %+v", err)
+	})
+	jwtSvc, err := jwtkeys.New(cfg.JWT)
+	checkErr(err)
+	denylistSvc := denylist.New(repoSvc.RevokedToken, time.Duration(cfg.JWT.DurationAccessToken)*time.Second, time.Duration(cfg.JWT.RevocationCacheTTL)*time.Second)
+
+	mailerSvc, err := mailer.New(cfg.Mailer)
+	checkErr(err)
+	userTokenSvc := usertoken.New(repoSvc.UserToken, cfg.Account.TokenSecret)
+	smsSvc, err := sms.New(cfg.SMS)
+	checkErr(err)
+	otpSvc, err := otp.New(repoSvc.User, smsSvc, cfg.OTP, cfg.Account.TokenSecret)
+	checkErr(err)
+	mfaSvc, err := mfa.New(repoSvc.User, repoSvc.MFARecoveryCode, cfg.MFA)
+	checkErr(err)
+	oidcSvc, err := oidc.New(cfg.OIDC)
+	checkErr(err)
+	loginGuardStore, err := loginguard.NewStore(cfg.LoginGuard, repoSvc.LoginGuard)
+	checkErr(err)
+	loginGuardSvc := loginguard.New(loginGuardStore, cfg.LoginGuard)
+	passwordPolicySvc, err := passwordpolicy.New(repoSvc.PasswordHistory, crypterSvc, cfg.PasswordPolicy)
+	checkErr(err)
+
+	blobSvc, err := blob.New(cfg.Blob)
+	checkErr(err)
+	privacySvc := privacy.New(repoSvc, blobSvc, denylistSvc, cfg.Privacy)
 
 	azureSvc := azure.New(cfg.Azure, repoSvc)
+	reextractSvc := reextract.New(repoSvc)
+	expenseSvc := expense.New(repoSvc, cfg.Expense)
+	apiKeySvc := apikey.New(repoSvc)
 
 	// Initialize services
-	authSvc := auth.New(repoSvc, jwtSvc, crypterSvc)
-	// sessionSvc := session.New(repoSvc, rbacSvc)
-	// userSvc := user.New(repoSvc, rbacSvc, crypterSvc)
-
-	documentSvc := document.New(repoSvc, rbacSvc, crypterSvc, azureSvc)
+	authSvc := auth.New(repoSvc, jwtSvc, crypterSvc, denylistSvc, mailerSvc, userTokenSvc, otpSvc, mfaSvc, oidcSvc, loginGuardSvc, passwordPolicySvc, rbacSvc, cfg.Session, cfg.Account)
+	adminSessionSvc := adminsession.New(repoSvc, rbacSvc, denylistSvc)
+	adminUserSvc := adminuser.New(repoSvc, rbacSvc, crypterSvc, passwordPolicySvc, denylistSvc)
+	activityLogSvc := activitylog.New(repoSvc, rbacSvc)
+	adminDocumentSvc := admindocument.New(repoSvc, rbacSvc, reextractSvc)
+	lockoutSvc := lockout.New(repoSvc, rbacSvc, loginGuardSvc)
+	policySvc := policy.New(repoSvc, rbacSvc)
+	adminAPIKeySvc := adminapikey.New(repoSvc, rbacSvc)
+
+	documentSvc := document.New(repoSvc, rbacSvc, crypterSvc, azureSvc, privacySvc)
+	appSessionSvc := session.New(repoSvc, rbacSvc, denylistSvc)
+	meSvc := me.New(repoSvc, rbacSvc, crypterSvc, passwordPolicySvc, denylistSvc, privacySvc)
+	organizationSvc := organization.New(repoSvc, rbacSvc, denylistSvc, mailerSvc, cfg.Account)
+	expenseReportSvc := expensereport.New(repoSvc, rbacSvc, expenseSvc)
+	appAPIKeySvc := appapikey.New(repoSvc, rbacSvc, cfg.APIKey)
+	impersonationSvc := impersonation.New(repoSvc.AuditEvent)
+	adminImpersonationSvc := adminimpersonation.New(repoSvc, rbacSvc, jwtSvc, denylistSvc, cfg.Impersonation)
 
 	// Initialize root API
 	root.NewHTTP(e)
+	wellknown.NewHTTP(jwtSvc, e.Group("/.well-known"))
 
 	v1router := e.Group("/v1")
 
-	auth.NewHTTP(authSvc, v1router.Group("/auth"))
+	// access tokens are verified then checked against the denylist, the impersonation tokens are read-only and audited
+	authMW := []echo.MiddlewareFunc{jwtSvc.MWFunc(), denylistSvc.MWFunc(), contextutil.MWContext(), impersonationSvc.MWFunc()}
 
-	// Initialize admin APIs
-	// v1adminRouter := v1router.Group("/admin")
-	v1appRouter := v1router.Group("/app")
-	// v1adminRouter.Use(jwtSvc.MWFunc(), contextutil.MWContext())
-	// session.NewHTTP(sessionSvc, v1adminRouter.Group("/sessions"))
-	// user.NewHTTP(userSvc, v1adminRouter.Group("/users"))
+	auth.NewHTTP(authSvc, v1router.Group("/auth"), authMW...)
+
+	// the admin and app APIs accept the API keys of the integrations as well, restricted to their scopes by RBAC
+	apiMW := []echo.MiddlewareFunc{apiKeySvc.MWFunc(jwtSvc.MWFunc()), denylistSvc.MWFunc(), contextutil.MWContext(), impersonationSvc.MWFunc()}
+
+	// Initialize admin APIs, only for the portal roles, each route is enforced by RBAC on its object as well
+	v1adminRouter := v1router.Group("/admin")
+	v1adminRouter.Use(apiMW...)
+	v1adminRouter.Use(rbac.MWRolesFunc(rbacSvc.IsPortalRole))
+	adminsession.NewHTTP(adminSessionSvc, v1adminRouter.Group("/sessions", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectSession, rbac.AllActions)))
+	adminuser.NewHTTP(adminUserSvc, v1adminRouter.Group("/users", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectUser, rbac.AllActions)))
+	activitylog.NewHTTP(activityLogSvc, v1adminRouter.Group("/activity-logs", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectActivityLog, rbac.AllActions)))
+	admindocument.NewHTTP(adminDocumentSvc, v1adminRouter.Group("/documents", rbac.MWEnforce(rbacSvc, rbac.ObjectDocument, rbac.ActionUpdateAll)))
+	lockout.NewHTTP(lockoutSvc, v1adminRouter.Group("/lockouts", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectLockout, rbac.AllActions)))
+	policy.NewHTTP(policySvc, v1adminRouter.Group("/rbac", rbac.MWRoles(rbac.RoleSuperAdmin), rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectRBAC, rbac.AllActions)))
+	adminapikey.NewHTTP(adminAPIKeySvc, v1adminRouter.Group("/api-keys", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectAPIKey, rbac.AllActions)))
+	adminimpersonation.NewHTTP(adminImpersonationSvc, v1adminRouter.Group("/impersonations", rbac.MWRoles(rbac.RoleSuperAdmin), rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectImpersonation, rbac.AllActions)))
 
-	v1appRouter.Use(jwtSvc.MWFunc(), contextutil.MWContext())
-	document.NewHTTP(documentSvc, v1appRouter.Group("/documents"))
+	// Initialize app APIs, each route is enforced by RBAC on the own records of its object
+	v1appRouter := v1router.Group("/app")
+	v1appRouter.Use(apiMW...)
+	me.NewHTTP(meSvc, v1appRouter.Group("/me", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectUser, rbac.OwnActions)))
+	me.NewExportHTTP(meSvc, v1appRouter.Group("/me/export", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectDataExport, rbac.OwnActions)))
+	document.NewHTTP(documentSvc, v1appRouter.Group("/documents", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectDocument, rbac.OwnActions)))
+	session.NewHTTP(appSessionSvc, v1appRouter.Group("/sessions", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectSession, rbac.OwnActions)))
+	organization.NewHTTP(organizationSvc, v1appRouter.Group("/organizations", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectOrganization, rbac.OwnActions)))
+	organization.NewInvitationHTTP(organizationSvc, v1appRouter.Group("/invitations", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectOrganization, rbac.OwnActions)))
+	expensereport.NewHTTP(expenseReportSvc, v1appRouter.Group("/expense-reports", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectExpenseReport, rbac.OwnActions)))
+	appapikey.NewHTTP(appAPIKeySvc, v1appRouter.Group("/api-keys", rbac.MWEnforceByMethod(rbacSvc, rbac.ObjectAPIKey, rbac.OwnActions)))
 
 	server.Start(e, config.IsLambda())
 }
+
+// ipExtractor returns the direct peer address as the client IP,
+// unless the trusted proxies are given then X-Forwarded-For is read up to the first untrusted hop
+func ipExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	opts := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
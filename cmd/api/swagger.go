@@ -10,6 +10,12 @@
 // Authorization: Bearer ${access_token}
 // ```
 //
+// The integrations may use an API key created by `/v1/app/api-keys` instead, as `Authorization: Bearer ${token}`.
+// The requests are restricted to the scopes of the key, eg: `documents:read`.
+//
+// The access tokens issued by `/v1/admin/impersonations` to the superadmins are read-only,
+// every request made with them is recorded in the audit events of the impersonated user.
+//
 // Terms Of Service: N/A
 //
 // Version: %{VERSION}
@@ -7,9 +7,25 @@ type (
 		Server
 		DB
 		JWT
+		Session
+		RBAC
 		App
 		Azure
 		Plaid
+		Blob
+		Mailer
+		Account
+		SMS
+		OTP
+		MFA
+		OIDC
+		LoginGuard
+		PasswordPolicy
+		Privacy
+		ActivityLog
+		Expense
+		APIKey
+		Impersonation
 	}
 
 	// General holds general configurations
@@ -29,6 +45,9 @@ type (
 		WriteTimeout int `env:"SERVER_WRITE_TIMEOUT" envDefault:"60"`
 		// CORS settings
 		AllowOrigins []string `env:"SERVER_ALLOW_ORIGINS" envDefault:"*"`
+		// The IP ranges (CIDR) of the proxies trusted to set X-Forwarded-For,
+		// the client IP is the direct peer address when empty
+		TrustedProxies []string `env:"SERVER_TRUSTED_PROXIES"`
 	}
 
 	// DB holds DB configurations
@@ -44,10 +63,27 @@ type (
 
 	// JWT holds JWT configurations
 	JWT struct {
-		Secret               string `env:"JWT_SECRET,notEmpty"`
-		Algorithm            string `env:"JWT_ALGORITHM" envDefault:"HS256"`
+		// Shared secret for HS256, only used to verify the tokens issued before once JWT_KEYS is set
+		Secret    string `env:"JWT_SECRET"`
+		Algorithm string `env:"JWT_ALGORITHM" envDefault:"HS256"` // HS256 || RS256 || ES256 || EdDSA
+		// JSON array of asymmetric signing keys, see jwtkeys.KeyConfig
+		Keys                 string `env:"JWT_KEYS"`
 		DurationAccessToken  int    `env:"JWT_DURATION_ACCESS_TOKEN" envDefault:"3600"`   // 1 hour in second
 		DurationRefreshToken int    `env:"JWT_DURATION_REFRESH_TOKEN" envDefault:"86400"` // 1 day in second
+		// How long an access token which is not revoked is trusted before checking the denylist again
+		RevocationCacheTTL int `env:"JWT_REVOCATION_CACHE_TTL" envDefault:"30"` // in second
+	}
+
+	// Session holds login session configurations
+	Session struct {
+		// Maximum number of active sessions per user, the oldest ones are revoked on login. 0 means unlimited
+		MaxPerUser int `env:"SESSION_MAX_PER_USER" envDefault:"5"`
+	}
+
+	// RBAC holds role based access control configurations
+	RBAC struct {
+		// How often the roles and the policies are checked for the changes made by the other instances
+		ReloadInterval int `env:"RBAC_RELOAD_INTERVAL" envDefault:"10"` // in second
 	}
 
 	// App holds app specific configurations
@@ -66,6 +102,148 @@ type (
 		ClientID string `env:"PLAID_CLIENT_ID"`
 		Secret   string `env:"PLAID_SECRET"`
 	}
+
+	// Blob holds blob storage configurations
+	Blob struct {
+		Driver    string `env:"BLOB_DRIVER" envDefault:"local"` // s3 || local
+		Bucket    string `env:"BLOB_BUCKET"`
+		Region    string `env:"BLOB_REGION" envDefault:"ap-southeast-1"`
+		LocalPath string `env:"BLOB_LOCAL_PATH" envDefault:"./tmp/blob"`
+	}
+
+	// Mailer holds email configurations
+	Mailer struct {
+		Driver       string `env:"MAIL_DRIVER" envDefault:"file"` // smtp || file || memory
+		From         string `env:"MAIL_FROM" envDefault:"Tyr <no-reply@tyr.io>"`
+		SMTPHost     string `env:"MAIL_SMTP_HOST"`
+		SMTPPort     int    `env:"MAIL_SMTP_PORT" envDefault:"587"`
+		SMTPUsername string `env:"MAIL_SMTP_USERNAME"`
+		SMTPPassword string `env:"MAIL_SMTP_PASSWORD"`
+		FilePath     string `env:"MAIL_FILE_PATH" envDefault:"./tmp/mail"`
+	}
+
+	// Account holds account verification and recovery configurations
+	Account struct {
+		// Secret to sign the email verification and password reset tokens
+		TokenSecret string `env:"ACCOUNT_TOKEN_SECRET"`
+		// Base URL of the web app where the links in emails point to
+		WebURL           string `env:"ACCOUNT_WEB_URL" envDefault:"http://localhost:3000"`
+		VerifyEmailTTL   int    `env:"ACCOUNT_VERIFY_EMAIL_TTL" envDefault:"86400"`  // 1 day in second
+		ResetPasswordTTL int    `env:"ACCOUNT_RESET_PASSWORD_TTL" envDefault:"3600"` // 1 hour in second
+		UnlockTTL        int    `env:"ACCOUNT_UNLOCK_TTL" envDefault:"3600"`         // 1 hour in second
+		InvitationTTL    int    `env:"ACCOUNT_INVITATION_TTL" envDefault:"604800"`   // 7 days in second, of the organization invitations
+	}
+
+	// SMS holds text message configurations
+	SMS struct {
+		Driver string `env:"SMS_DRIVER" envDefault:"console"` // console || memory
+	}
+
+	// OTP holds one-time password configurations
+	OTP struct {
+		Length         int `env:"OTP_LENGTH" envDefault:"6"`
+		TTL            int `env:"OTP_TTL" envDefault:"300"`            // 5 minutes in second
+		ResendCooldown int `env:"OTP_RESEND_COOLDOWN" envDefault:"60"` // in second
+		MaxAttempts    int `env:"OTP_MAX_ATTEMPTS" envDefault:"5"`
+	}
+
+	// MFA holds TOTP two-factor authentication configurations
+	MFA struct {
+		// Secret to encrypt the TOTP secrets at rest
+		EncryptionKey string `env:"MFA_ENCRYPTION_KEY"`
+		Issuer        string `env:"MFA_ISSUER" envDefault:"Tyr"`
+		// Roles which must login with two-factor authentication, eg: admin,superadmin
+		RequiredRoles []string `env:"MFA_REQUIRED_ROLES" envSeparator:","`
+		ChallengeTTL  int      `env:"MFA_CHALLENGE_TTL" envDefault:"300"` // 5 minutes in second
+		MaxAttempts   int      `env:"MFA_MAX_ATTEMPTS" envDefault:"5"`
+		RecoveryCodes int      `env:"MFA_RECOVERY_CODES" envDefault:"10"`
+	}
+
+	// LoginGuard holds login brute-force protection configurations
+	LoginGuard struct {
+		Store string `env:"LOGIN_GUARD_STORE" envDefault:"postgres"` // postgres || memory
+		// Sliding window to count the failed attempts in
+		Window             int `env:"LOGIN_GUARD_WINDOW" envDefault:"900"` // 15 minutes in second
+		AccountMaxFailures int `env:"LOGIN_GUARD_ACCOUNT_MAX_FAILURES" envDefault:"5"`
+		IPMaxFailures      int `env:"LOGIN_GUARD_IP_MAX_FAILURES" envDefault:"20"`
+		LockoutDuration    int `env:"LOGIN_GUARD_LOCKOUT_DURATION" envDefault:"900"` // 15 minutes in second
+		// The attempts after this number of failures are delayed progressively, doubling from the base delay
+		DelayAfter  int `env:"LOGIN_GUARD_DELAY_AFTER" envDefault:"2"`
+		DelayBaseMS int `env:"LOGIN_GUARD_DELAY_BASE_MS" envDefault:"250"`
+		DelayMaxMS  int `env:"LOGIN_GUARD_DELAY_MAX_MS" envDefault:"4000"`
+	}
+
+	// PasswordPolicy holds password policy configurations
+	PasswordPolicy struct {
+		MinLength int `env:"PASSWORD_MIN_LENGTH" envDefault:"10"`
+		MaxLength int `env:"PASSWORD_MAX_LENGTH" envDefault:"128"`
+		// Minimum number of character classes out of lowercase, uppercase, digit and symbol
+		MinCharClasses int `env:"PASSWORD_MIN_CHAR_CLASSES" envDefault:"3"`
+		// Rejects the passwords containing the name, email or phone of the user
+		CheckUserInfo bool `env:"PASSWORD_CHECK_USER_INFO" envDefault:"true"`
+		// Number of the latest passwords which cannot be reused. 0 disables the check
+		HistorySize int `env:"PASSWORD_HISTORY_SIZE" envDefault:"5"`
+		// Days after which the password expires and must be reset. 0 means never
+		MaxAgeDays int `env:"PASSWORD_MAX_AGE_DAYS" envDefault:"0"`
+		// Rejects the passwords found in the breached password hash prefix file
+		CheckBreached bool `env:"PASSWORD_CHECK_BREACHED" envDefault:"true"`
+		// Path to a breached password hash prefix file, see passwordpolicy.LoadPrefixFile. The bundled one is used if empty
+		BreachedFile string `env:"PASSWORD_BREACHED_FILE"`
+		// Password replacing the well-known ones of the users seeded by the migration, required outside local development
+		SeedPassword string `env:"PASSWORD_SEED"`
+	}
+
+	// OIDC holds social login configurations
+	OIDC struct {
+		// JSON array of the identity providers, see oidc.ProviderConfig
+		Providers    string `env:"OIDC_PROVIDERS"`
+		JWKSCacheTTL int    `env:"OIDC_JWKS_CACHE_TTL" envDefault:"3600"` // 1 hour in second
+	}
+
+	// Privacy holds account data export and erasure configurations
+	Privacy struct {
+		// Blob key prefix of the receipt files, they are stored under <prefix>/<user id>/
+		ReceiptPrefix string `env:"PRIVACY_RECEIPT_PREFIX" envDefault:"receipts"`
+		// Blob key prefix of the data export archives
+		ExportPrefix string `env:"PRIVACY_EXPORT_PREFIX" envDefault:"exports"`
+		ExportTTL    int    `env:"PRIVACY_EXPORT_TTL" envDefault:"604800"`     // 7 days in second
+		ErasureGrace int    `env:"PRIVACY_ERASURE_GRACE" envDefault:"2592000"` // 30 days in second
+		// The pending data exports are failed after this time, so that the users can request another one
+		ExportTimeout int `env:"PRIVACY_EXPORT_TIMEOUT" envDefault:"3600"` // 1 hour in second
+	}
+
+	// ActivityLog holds activity log retention configurations
+	ActivityLog struct {
+		RetentionDays    int    `env:"ACTIVITY_LOG_RETENTION_DAYS" envDefault:"90"`
+		ArchivePrefix    string `env:"ACTIVITY_LOG_ARCHIVE_PREFIX" envDefault:"archives/activity-logs"`
+		ArchiveBatchSize int    `env:"ACTIVITY_LOG_ARCHIVE_BATCH_SIZE" envDefault:"1000"`
+		// Number of upcoming monthly partitions to keep created ahead of time
+		PartitionsAhead int `env:"ACTIVITY_LOG_PARTITIONS_AHEAD" envDefault:"3"`
+	}
+
+	// Expense holds expense report policy configurations
+	Expense struct {
+		// The documents over this total must have a receipt file
+		ReceiptThreshold float64 `env:"EXPENSE_RECEIPT_THRESHOLD" envDefault:"75"`
+		// Whether the spend on saturdays and sundays is flagged
+		FlagWeekend bool `env:"EXPENSE_FLAG_WEEKEND" envDefault:"true"`
+		// Whether the policy violations block the submission, otherwise they are only flagged for the approver
+		BlockViolations bool `env:"EXPENSE_BLOCK_VIOLATIONS" envDefault:"false"`
+	}
+
+	// APIKey holds API key configurations
+	APIKey struct {
+		// Maximum number of active API keys per user. 0 means unlimited
+		MaxPerUser int `env:"APIKEY_MAX_PER_USER" envDefault:"10"`
+		DefaultTTL int `env:"APIKEY_DEFAULT_TTL" envDefault:"7776000"` // 90 days in second, when no expiry is requested
+		MaxTTL     int `env:"APIKEY_MAX_TTL" envDefault:"31536000"`    // 1 year in second
+	}
+
+	// Impersonation holds superadmin impersonation configurations
+	Impersonation struct {
+		// Lifetime of the impersonation tokens, capped to the one of the access tokens
+		TTL int `env:"IMPERSONATION_TTL" envDefault:"900"` // 15 minutes in second
+	}
 )
 
 // LoadAll returns all configurations for the app
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
 	"tyr/config"
 	"tyr/internal/db"
+	"tyr/internal/passwordpolicy"
 	"tyr/internal/types"
 
 	"github.com/M15t/gram/pkg/util/crypter"
@@ -17,6 +19,7 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // EnablePostgreSQL: remove this and all tx.Set() functions bellow
@@ -78,6 +81,11 @@ func Run() (respErr error) {
 		return err
 	}
 
+	seedPassword, err := seedUserPassword(cfg.PasswordPolicy)
+	if err != nil {
+		return err
+	}
+
 	migration.Run(db, []*gormigrate.Migration{
 		// create initial table(s)
 		{
@@ -194,7 +202,602 @@ func Run() (respErr error) {
 				return tx.Migrator().DropTable("profiles")
 			},
 		},
+		// partition "activity_logs" table by month of created_at
+		{
+			ID: "202610190900",
+			Migrate: func(tx *gorm.DB) error {
+				// the table is renamed, copied then dropped, all or nothing
+				return tx.Transaction(func(tx *gorm.DB) error {
+					if err := migration.ExecMultiple(tx, `
+						ALTER TABLE activity_logs RENAME TO activity_logs_old;
+						CREATE TABLE activity_logs (LIKE activity_logs_old INCLUDING DEFAULTS) PARTITION BY RANGE (created_at);
+						CREATE TABLE activity_logs_default PARTITION OF activity_logs DEFAULT;
+					`); err != nil {
+						return err
+					}
+
+					// create monthly partitions from the oldest record until a few months ahead
+					from := time.Now()
+					var oldest *time.Time
+					if err := tx.Raw(`SELECT MIN(created_at) FROM activity_logs_old`).Scan(&oldest).Error; err != nil {
+						return err
+					}
+					if oldest != nil {
+						from = *oldest
+					}
+					from = time.Date(from.UTC().Year(), from.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+					for m := from; m.Before(time.Now().AddDate(0, 3, 0)); m = m.AddDate(0, 1, 0) {
+						if err := tx.Exec(fmt.Sprintf(`CREATE TABLE activity_logs_p%s PARTITION OF activity_logs FOR VALUES FROM ('%s') TO ('%s')`,
+							m.Format("200601"), m.Format(time.DateOnly), m.AddDate(0, 1, 0).Format(time.DateOnly))).Error; err != nil {
+							return err
+						}
+					}
+
+					return migration.ExecMultiple(tx, `
+						INSERT INTO activity_logs SELECT * FROM activity_logs_old;
+						DROP TABLE activity_logs_old;
+						ALTER TABLE activity_logs ADD PRIMARY KEY (id, created_at);
+						CREATE INDEX idx_activity_logs_deleted_at ON activity_logs (deleted_at);
+						CREATE INDEX idx_activity_logs_created_at ON activity_logs (created_at);
+						CREATE INDEX idx_activity_logs_apim_request_id ON activity_logs (apim_request_id);
+					`)
+				})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				// the table is renamed, copied then dropped, all or nothing
+				return tx.Transaction(func(tx *gorm.DB) error {
+					return migration.ExecMultiple(tx, `
+						ALTER TABLE activity_logs RENAME TO activity_logs_partitioned;
+						CREATE TABLE activity_logs (LIKE activity_logs_partitioned INCLUDING DEFAULTS);
+						INSERT INTO activity_logs SELECT * FROM activity_logs_partitioned;
+						DROP TABLE activity_logs_partitioned CASCADE;
+						ALTER TABLE activity_logs ADD PRIMARY KEY (id);
+						CREATE INDEX idx_activity_logs_deleted_at ON activity_logs (deleted_at);
+					`)
+				})
+			},
+		},
+		// create "document_analyses" table, backfill from the stored analyze results in "activity_logs"
+		{
+			ID: "202610191000",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.DocumentAnalysis{}); err != nil {
+					return err
+				}
+
+				analyses := []*types.DocumentAnalysis{}
+				if err := tx.Raw(`SELECT DISTINCT ON (d.apim_request_id)
+						d.id AS document_id,
+						d.apim_request_id,
+						l.response_body->'analyzeResult'->>'modelId' AS model_id,
+						l.response_body->'analyzeResult'->>'apiVersion' AS api_version,
+						l.response_body->>'status' AS status,
+						l.created_at AS analyzed_at,
+						COALESCE(jsonb_array_length(l.response_body->'analyzeResult'->'pages'), 0) AS total_page,
+						COALESCE(l.response_body->'analyzeResult'->>'content', '') AS content,
+						l.response_body->'analyzeResult'->'pages' AS pages,
+						l.response_body AS raw_result
+					FROM documents d
+					JOIN activity_logs l ON l.apim_request_id = d.apim_request_id
+					WHERE d.apim_request_id <> '' AND l.response_code = 200 AND l.response_body->>'status' = 'succeeded'
+					ORDER BY d.apim_request_id, l.created_at DESC`).Scan(&analyses).Error; err != nil {
+					return err
+				}
+				if len(analyses) == 0 {
+					return nil
+				}
+
+				return tx.CreateInBatches(analyses, 100).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("document_analyses")
+			},
+		},
+		// full-text search on "documents" over merchant, line item descriptions and OCR content
+		{
+			ID: "202610191100",
+			Migrate: func(tx *gorm.DB) error {
+				// statements contain function bodies, so they can't be split by semicolon
+				for _, sql := range []string{
+					`ALTER TABLE documents ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+					`CREATE OR REPLACE FUNCTION document_search_vector(doc_id text, merchant_name text, merchant_address text) RETURNS tsvector AS $$
+						SELECT setweight(to_tsvector('simple', COALESCE(merchant_name, '')), 'A')
+							|| setweight(to_tsvector('simple', COALESCE(merchant_address, '')), 'B')
+							|| setweight(to_tsvector('simple', COALESCE((
+								SELECT string_agg(item->>'description', ' ')
+								FROM document_items di, jsonb_array_elements(CASE WHEN jsonb_typeof(di.data) = 'array' THEN di.data ELSE '[]'::jsonb END) item
+								WHERE di.document_id = doc_id AND di.deleted_at IS NULL
+							), '')), 'B')
+							|| setweight(to_tsvector('simple', COALESCE((
+								SELECT a.content FROM document_analyses a
+								WHERE a.document_id = doc_id AND a.deleted_at IS NULL
+								ORDER BY a.analyzed_at DESC LIMIT 1
+							), '')), 'C')
+					$$ LANGUAGE sql STABLE`,
+					`CREATE OR REPLACE FUNCTION documents_search_vector_trigger() RETURNS trigger AS $$
+					BEGIN
+						NEW.search_vector := document_search_vector(NEW.id, NEW.merchant_name, NEW.merchant_address);
+						RETURN NEW;
+					END
+					$$ LANGUAGE plpgsql`,
+					`CREATE OR REPLACE FUNCTION document_children_search_vector_trigger() RETURNS trigger AS $$
+					BEGIN
+						UPDATE documents d SET search_vector = document_search_vector(d.id, d.merchant_name, d.merchant_address)
+						WHERE d.id = NEW.document_id;
+						RETURN NULL;
+					END
+					$$ LANGUAGE plpgsql`,
+					`DROP TRIGGER IF EXISTS trg_documents_search_vector ON documents`,
+					`CREATE TRIGGER trg_documents_search_vector BEFORE INSERT OR UPDATE OF merchant_name, merchant_address ON documents
+						FOR EACH ROW EXECUTE FUNCTION documents_search_vector_trigger()`,
+					`DROP TRIGGER IF EXISTS trg_document_items_search_vector ON document_items`,
+					`CREATE TRIGGER trg_document_items_search_vector AFTER INSERT OR UPDATE OF data ON document_items
+						FOR EACH ROW EXECUTE FUNCTION document_children_search_vector_trigger()`,
+					`DROP TRIGGER IF EXISTS trg_document_analyses_search_vector ON document_analyses`,
+					`CREATE TRIGGER trg_document_analyses_search_vector AFTER INSERT OR UPDATE OF content ON document_analyses
+						FOR EACH ROW EXECUTE FUNCTION document_children_search_vector_trigger()`,
+					`UPDATE documents SET search_vector = document_search_vector(id, merchant_name, merchant_address)`,
+					`CREATE INDEX IF NOT EXISTS idx_documents_search_vector ON documents USING GIN (search_vector)`,
+				} {
+					if err := tx.Exec(sql).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, sql := range []string{
+					`DROP TRIGGER IF EXISTS trg_document_analyses_search_vector ON document_analyses`,
+					`DROP TRIGGER IF EXISTS trg_document_items_search_vector ON document_items`,
+					`DROP TRIGGER IF EXISTS trg_documents_search_vector ON documents`,
+					`DROP FUNCTION IF EXISTS document_children_search_vector_trigger()`,
+					`DROP FUNCTION IF EXISTS documents_search_vector_trigger()`,
+					`DROP FUNCTION IF EXISTS document_search_vector(text, text, text)`,
+					`ALTER TABLE documents DROP COLUMN IF EXISTS search_vector`,
+				} {
+					if err := tx.Exec(sql).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		// add device metadata to "sessions" table to support multiple sessions per user
+		{
+			ID: "202610191200",
+			Migrate: func(tx *gorm.DB) error {
+				// the columns already exist on a fresh database, the table is created from the current struct
+				return migration.ExecMultiple(tx, `
+					ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_name varchar(100) NOT NULL DEFAULT '';
+					ALTER TABLE sessions ADD COLUMN IF NOT EXISTS platform varchar(20) NOT NULL DEFAULT '';
+					ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
+					ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_ip_address varchar(45) NOT NULL DEFAULT '';
+					ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_user_agent text NOT NULL DEFAULT '';
+					CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id, created_at);
+				`)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return migration.ExecMultiple(tx, `
+					DROP INDEX IF EXISTS idx_sessions_user_id;
+					ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_user_agent;
+					ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_ip_address;
+					ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_at;
+					ALTER TABLE sessions DROP COLUMN IF EXISTS platform;
+					ALTER TABLE sessions DROP COLUMN IF EXISTS device_name;
+				`)
+			},
+		},
+		// store only the hash of refresh tokens and create "audit_events" table
+		{
+			ID: "202610191300",
+			Migrate: func(tx *gorm.DB) error {
+				// a fresh database has the hash column already, the table is created from the current struct
+				if tx.Migrator().HasColumn("sessions", "refresh_token") {
+					if err := migration.ExecMultiple(tx, `
+						ALTER TABLE sessions RENAME COLUMN refresh_token TO refresh_token_hash;
+						ALTER INDEX IF EXISTS uix_users_refresh_token RENAME TO uix_sessions_refresh_token_hash;
+						UPDATE sessions SET refresh_token_hash = encode(sha256(refresh_token_hash::bytea), 'hex') WHERE refresh_token_hash IS NOT NULL;
+						ALTER TABLE sessions ALTER COLUMN refresh_token_hash TYPE varchar(64);
+					`); err != nil {
+						return err
+					}
+				}
+				return tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.AuditEvent{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable("audit_events"); err != nil {
+					return err
+				}
+				// the hashed tokens cannot be restored, all sessions need to login again
+				return migration.ExecMultiple(tx, `
+					ALTER TABLE sessions ALTER COLUMN refresh_token_hash TYPE text;
+					UPDATE sessions SET refresh_token_hash = NULL, is_blocked = true;
+					ALTER INDEX uix_sessions_refresh_token_hash RENAME TO uix_users_refresh_token;
+					ALTER TABLE sessions RENAME COLUMN refresh_token_hash TO refresh_token;
+				`)
+			},
+		},
+		// create "revoked_tokens" table as the access token denylist
+		{
+			ID: "202610191400",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.RevokedToken{}); err != nil {
+					return err
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("revoked_tokens")
+			},
+		},
+		// create "user_tokens" table for email verification and password reset
+		{
+			ID: "202610191500",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.UserToken{}); err != nil {
+					return err
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("user_tokens")
+			},
+		},
+		// store only the hash of one-time passwords and count the wrong attempts
+		{
+			ID: "202610191600",
+			Migrate: func(tx *gorm.DB) error {
+				return migration.ExecMultiple(tx, `
+					UPDATE users SET otp = NULL, otp_sent_at = NULL;
+					ALTER TABLE users ALTER COLUMN otp TYPE varchar(64);
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS otp_attempts integer NOT NULL DEFAULT 0;
+				`)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return migration.ExecMultiple(tx, `
+					ALTER TABLE users DROP COLUMN IF EXISTS otp_attempts;
+					UPDATE users SET otp = NULL, otp_sent_at = NULL;
+					ALTER TABLE users ALTER COLUMN otp TYPE varchar(10);
+				`)
+			},
+		},
+		// add TOTP two-factor authentication to "users" table and create "mfa_recovery_codes" table
+		{
+			ID: "202610191700",
+			Migrate: func(tx *gorm.DB) error {
+				if err := migration.ExecMultiple(tx, `
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_secret text;
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled_at timestamptz;
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_step bigint NOT NULL DEFAULT 0;
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_failed_attempts integer NOT NULL DEFAULT 0;
+				`); err != nil {
+					return err
+				}
+				return tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.MFARecoveryCode{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable("mfa_recovery_codes"); err != nil {
+					return err
+				}
+				return migration.ExecMultiple(tx, `
+					ALTER TABLE users DROP COLUMN IF EXISTS mfa_failed_attempts;
+					ALTER TABLE users DROP COLUMN IF EXISTS mfa_last_step;
+					ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled_at;
+					ALTER TABLE users DROP COLUMN IF EXISTS mfa_secret;
+				`)
+			},
+		},
+		// create "user_identities" table for social login, the users signed up by social login may have no phone
+		{
+			ID: "202610191800",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.UserIdentity{}); err != nil {
+					return err
+				}
+				return migration.ExecMultiple(tx, `
+					DROP INDEX IF EXISTS uix_users_phone;
+					CREATE UNIQUE INDEX uix_users_phone ON users (phone) WHERE phone <> '';
+				`)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := migration.ExecMultiple(tx, `
+					DROP INDEX IF EXISTS uix_users_phone;
+					CREATE UNIQUE INDEX uix_users_phone ON users (phone);
+				`); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("user_identities")
+			},
+		},
+		// create "login_failures" and "login_lockouts" tables for login brute-force protection
+		{
+			ID: "202610191900",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.LoginFailure{}, &types.LoginLockout{}); err != nil {
+					return err
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("login_lockouts", "login_failures")
+			},
+		},
+		// create "password_histories" table, add password_changed_at column to "users" table
+		{
+			ID: "202610192000",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.PasswordHistory{}); err != nil {
+					return err
+				}
+
+				// the current passwords are considered set when the users were created
+				return migration.ExecMultiple(tx, `
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at timestamptz;
+					UPDATE users SET password_changed_at = created_at WHERE password_changed_at IS NULL;
+				`)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable("password_histories"); err != nil {
+					return err
+				}
+				return tx.Exec(`ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at`).Error
+			},
+		},
+		// replace the well-known passwords of the seeded users with the configured seed password
+		{
+			ID: "202610192050",
+			Migrate: func(tx *gorm.DB) error {
+				if seedPassword == "" {
+					return nil
+				}
+
+				users := []*types.User{}
+				if err := tx.Where(`email IN ?`, []string{"nido@tyr.io", "roht@tyr.io", "collector@tyr.io"}).Find(&users).Error; err != nil {
+					return err
+				}
+				now := time.Now()
+				for _, usr := range users {
+					// the passwords already changed are kept
+					if !crypter.CompareHashAndPassword(usr.Password, usr.Role+"123!@#") {
+						continue
+					}
+					if err := tx.Model(&types.User{}).Where(`id = ?`, usr.ID).Updates(map[string]interface{}{
+						"password":            crypter.HashPassword(seedPassword),
+						"password_changed_at": now,
+					}).Error; err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return nil
+			},
+		},
+		// restrict "users" status to the valid ones, the soft-deleted users are in the deleted status
+		{
+			ID: "202610192100",
+			Migrate: func(tx *gorm.DB) error {
+				// the users without status were active before the column, the other unknown statuses are blocked rather than activated
+				return migration.ExecMultiple(tx, `
+					UPDATE users SET status = 'active' WHERE (status IS NULL OR status = '') AND deleted_at IS NULL;
+					UPDATE users SET status = 'deleted' WHERE deleted_at IS NOT NULL;
+					UPDATE users SET status = 'blocked' WHERE status NOT IN ('active', 'blocked', 'deleted');
+					ALTER TABLE users ALTER COLUMN status SET NOT NULL;
+					ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_status;
+					ALTER TABLE users ADD CONSTRAINT chk_users_status CHECK (status IN ('active', 'blocked', 'deleted'));
+				`)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return migration.ExecMultiple(tx, `
+					ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_status;
+					ALTER TABLE users ALTER COLUMN status DROP NOT NULL;
+				`)
+			},
+		},
+		// create "data_exports" table, add erasure_scheduled_at column to "users" table
+		{
+			ID: "202610192200",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.DataExport{}); err != nil {
+					return err
+				}
+				return migration.ExecMultiple(tx, `
+					CREATE UNIQUE INDEX IF NOT EXISTS uix_data_exports_user_id_pending ON data_exports (user_id) WHERE status = 'pending' AND deleted_at IS NULL;
+					ALTER TABLE users ADD COLUMN IF NOT EXISTS erasure_scheduled_at timestamptz;
+					CREATE INDEX IF NOT EXISTS idx_users_erasure_scheduled_at ON users (erasure_scheduled_at) WHERE erasure_scheduled_at IS NOT NULL;
+				`)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable("data_exports"); err != nil {
+					return err
+				}
+				return tx.Exec(`ALTER TABLE users DROP COLUMN IF EXISTS erasure_scheduled_at`).Error
+			},
+		},
+		// create the RBAC tables with the default roles and policies, which were hardcoded before
+		{
+			ID: "202610192300",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.RBACRole{}, &types.RBACRoleParent{}, &types.RBACPolicy{}, &types.RBACRevision{}); err != nil {
+					return err
+				}
+
+				// the roles and the policies as of this migration, the later ones are added by their own migrations
+				roles := []*types.RBACRole{
+					{Name: "user", Description: "App user, manages the own records", System: true},
+					{Name: "admin", Description: "Portal administrator", System: true},
+					{Name: "superadmin", Description: "Portal super administrator, manages the roles and the policies", System: true},
+				}
+				parents := []*types.RBACRoleParent{
+					{Role: "admin", Parent: "user"},
+					{Role: "superadmin", Parent: "admin"},
+				}
+				policies := []*types.RBACPolicy{
+					{Role: "user", Object: "user", Action: "read"},
+					{Role: "user", Object: "user", Action: "update"},
+					{Role: "user", Object: "user", Action: "delete"},
+					{Role: "user", Object: "document", Action: "create"},
+					{Role: "user", Object: "document", Action: "read"},
+					{Role: "user", Object: "document", Action: "update"},
+					{Role: "user", Object: "document", Action: "delete"},
+					{Role: "user", Object: "plaid", Action: "create"},
+					{Role: "user", Object: "session", Action: "read"},
+					{Role: "user", Object: "session", Action: "delete"},
+					{Role: "user", Object: "data_export", Action: "create"},
+					{Role: "user", Object: "data_export", Action: "read"},
+					{Role: "admin", Object: "user", Action: "*"},
+					{Role: "admin", Object: "session", Action: "*"},
+					{Role: "admin", Object: "document", Action: "*"},
+					{Role: "admin", Object: "activity_log", Action: "read_all"},
+					{Role: "admin", Object: "lockout", Action: "*"},
+					{Role: "superadmin", Object: "*", Action: "*"},
+				}
+				for _, recs := range []interface{}{roles, parents, policies, &types.RBACRevision{ID: 1, Revision: 1}} {
+					if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(recs).Error; err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("rbac_revisions", "rbac_policies", "rbac_role_parents", "rbac_roles")
+			},
+		},
+		// create the organization tables, share documents in organizations, keep the active organization of sessions
+		{
+			ID: "202610192310",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.Organization{}, &types.OrganizationMember{}, &types.OrganizationInvitation{}); err != nil {
+					return err
+				}
+				if err := migration.ExecMultiple(tx, `
+					CREATE UNIQUE INDEX IF NOT EXISTS uix_organization_invitations_pending ON organization_invitations (organization_id, email) WHERE status = 'pending' AND deleted_at IS NULL;
+					ALTER TABLE documents ADD COLUMN IF NOT EXISTS organization_id varchar(26);
+					CREATE INDEX IF NOT EXISTS idx_documents_organization_id ON documents (organization_id);
+					ALTER TABLE sessions ADD COLUMN IF NOT EXISTS active_organization_id varchar(26);
+				`); err != nil {
+					return err
+				}
+
+				// the policies are loaded from the database since the RBAC tables were created
+				for _, action := range []string{rbac.ActionCreate, rbac.ActionRead, rbac.ActionUpdate, rbac.ActionDelete} {
+					if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.RBACPolicy{Role: rbac.RoleUser, Object: rbac.ObjectOrganization, Action: action}).Error; err != nil {
+						return err
+					}
+				}
+				return tx.Exec(`UPDATE rbac_revisions SET revision = revision + 1, updated_at = now()`).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Unscoped().Where(`object = ?`, rbac.ObjectOrganization).Delete(&types.RBACPolicy{}).Error; err != nil {
+					return err
+				}
+				if err := migration.ExecMultiple(tx, `
+					UPDATE rbac_revisions SET revision = revision + 1, updated_at = now();
+					ALTER TABLE sessions DROP COLUMN IF EXISTS active_organization_id;
+					ALTER TABLE documents DROP COLUMN IF EXISTS organization_id;
+				`); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("organization_invitations", "organization_members", "organizations")
+			},
+		},
+		// create the expense report tables, claim documents in reports through expense_report_documents
+		{
+			ID: "202610192320",
+			Migrate: func(tx *gorm.DB) error {
+				// the join table expense_report_documents is created along with the reports
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.ExpenseReport{}, &types.ExpenseReportComment{}, &types.ExpenseReportEvent{}); err != nil {
+					return err
+				}
+				// a document is claimed in one report only
+				if err := migration.ExecMultiple(tx, `
+					CREATE UNIQUE INDEX IF NOT EXISTS uix_expense_report_documents_document ON expense_report_documents (document_id);
+				`); err != nil {
+					return err
+				}
+
+				for _, action := range []string{rbac.ActionCreate, rbac.ActionRead, rbac.ActionUpdate, rbac.ActionDelete} {
+					if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.RBACPolicy{Role: rbac.RoleUser, Object: rbac.ObjectExpenseReport, Action: action}).Error; err != nil {
+						return err
+					}
+				}
+				return tx.Exec(`UPDATE rbac_revisions SET revision = revision + 1, updated_at = now()`).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Unscoped().Where(`object = ?`, rbac.ObjectExpenseReport).Delete(&types.RBACPolicy{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE rbac_revisions SET revision = revision + 1, updated_at = now()`).Error; err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("expense_report_events", "expense_report_comments", "expense_report_documents", "expense_reports")
+			},
+		},
+		// create the API keys table for the integrations
+		{
+			ID: "202610192330",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.APIKey{}); err != nil {
+					return err
+				}
+
+				policies := []*types.RBACPolicy{
+					{Role: rbac.RoleUser, Object: rbac.ObjectAPIKey, Action: rbac.ActionCreate},
+					{Role: rbac.RoleUser, Object: rbac.ObjectAPIKey, Action: rbac.ActionRead},
+					{Role: rbac.RoleUser, Object: rbac.ObjectAPIKey, Action: rbac.ActionDelete},
+					{Role: rbac.RoleAdmin, Object: rbac.ObjectAPIKey, Action: rbac.ActionReadAll},
+					{Role: rbac.RoleAdmin, Object: rbac.ObjectAPIKey, Action: rbac.ActionDeleteAll},
+				}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&policies).Error; err != nil {
+					return err
+				}
+				return tx.Exec(`UPDATE rbac_revisions SET revision = revision + 1, updated_at = now()`).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Unscoped().Where(`object = ?`, rbac.ObjectAPIKey).Delete(&types.RBACPolicy{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE rbac_revisions SET revision = revision + 1, updated_at = now()`).Error; err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("api_keys")
+			},
+		},
+		// create the impersonations table of the superadmins, only the superadmins are allowed by their wildcard policy
+		{
+			ID: "202610192340",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Set("gorm:table_options", defaultTableOpts).AutoMigrate(&types.Impersonation{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("impersonations")
+			},
+		},
 	})
 
 	return nil
 }
+
+// seedUserPassword returns the configured password of the seeded users which must satisfy the password policy.
+// It is required outside local development, where the seeded users keep their well-known passwords
+func seedUserPassword(cfg config.PasswordPolicy) (string, error) {
+	if cfg.SeedPassword == "" {
+		if config.IsLambda() {
+			return "", fmt.Errorf("PASSWORD_SEED is required to replace the passwords of the seeded users")
+		}
+		return "", nil
+	}
+
+	policy, err := passwordpolicy.New(nil, nil, cfg)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.Validate(context.Background(), cfg.SeedPassword, nil); err != nil {
+		return "", fmt.Errorf("invalid seed password: %w", err)
+	}
+
+	return cfg.SeedPassword, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/db"
+	"tyr/internal/denylist"
+	"tyr/internal/privacy"
+	"tyr/internal/repo"
+	"tyr/third_party/blob"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	if config.IsLambda() {
+		// start lambda request handler
+		lambda.Start(handler)
+		return
+	}
+
+	// start the function directly
+	if err := Run(context.Background()); err != nil {
+		log.Println(err)
+	}
+}
+
+func handler(ctx context.Context) (string, error) {
+	if err := Run(ctx); err != nil {
+		return "Account data export and erasure failed!", err
+	}
+	return "Account data export and erasure completed!", nil
+}
+
+// Run builds the archives of the pending data exports, erases all data of the users whose scheduled erasure is due,
+// and purges the expired data export archives.
+func Run(ctx context.Context) error {
+	cfg, err := config.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	gdb, sqldb, err := db.New(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer sqldb.Close()
+
+	blobSvc, err := blob.New(cfg.Blob)
+	if err != nil {
+		return err
+	}
+
+	repoSvc := repo.New(gdb)
+	denylistSvc := denylist.New(repoSvc.RevokedToken, time.Duration(cfg.JWT.DurationAccessToken)*time.Second, time.Duration(cfg.JWT.RevocationCacheTTL)*time.Second)
+	privacySvc := privacy.New(repoSvc, blobSvc, denylistSvc, cfg.Privacy)
+
+	built, timedOut, buildErr := privacySvc.BuildPending(ctx)
+	log.Printf("built %d data exports, %d timed out", built, timedOut)
+
+	erased, purged, err := privacySvc.RunDue(ctx)
+	log.Printf("erased %d users, purged %d data exports", erased, purged)
+	return errors.Join(buildErr, err)
+}
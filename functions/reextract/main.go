@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"tyr/config"
+	"tyr/internal/db"
+	"tyr/internal/reextract"
+	"tyr/internal/repo"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Event represents the lambda invocation payload
+type Event struct {
+	DocumentID string `json:"document_id"`
+	DryRun     bool   `json:"dry_run"`
+}
+
+var (
+	documentID = flag.String("id", "", "Re-extract a single document by ID, leave empty to re-extract all analyzed documents")
+	dryRun     = flag.Bool("dry-run", true, "Only print the diff report without updating any document")
+)
+
+func main() {
+	if config.IsLambda() {
+		// start lambda request handler
+		lambda.Start(handler)
+		return
+	}
+
+	// start the function directly
+	flag.Parse()
+	report, err := Run(context.Background(), Event{DocumentID: *documentID, DryRun: *dryRun})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+}
+
+func handler(ctx context.Context, evt Event) (*reextract.Report, error) {
+	return Run(ctx, evt)
+}
+
+// Run re-extracts documents from the stored Azure results
+func Run(ctx context.Context, evt Event) (*reextract.Report, error) {
+	cfg, err := config.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	gdb, sqldb, err := db.New(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	defer sqldb.Close()
+
+	return reextract.New(repo.New(gdb)).Run(ctx, reextract.Input{
+		DocumentID: evt.DocumentID,
+		DryRun:     evt.DryRun,
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/db"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	"tyr/third_party/blob"
+
+	"github.com/M15t/gram/pkg/util/ulidutil"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	if config.IsLambda() {
+		// start lambda request handler
+		lambda.Start(handler)
+		return
+	}
+
+	// start the function directly
+	if err := Run(context.Background()); err != nil {
+		log.Println(err)
+	}
+}
+
+func handler(ctx context.Context) (string, error) {
+	if err := Run(ctx); err != nil {
+		return "Activity log retention failed!", err
+	}
+	return "Activity log retention completed!", nil
+}
+
+// Run archives activity logs older than the retention period to blob storage then deletes them.
+// It also keeps upcoming monthly partitions created and drops the empty expired ones.
+func Run(ctx context.Context) error {
+	cfg, err := config.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	gdb, sqldb, err := db.New(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer sqldb.Close()
+
+	blobSvc, err := blob.New(cfg.Blob)
+	if err != nil {
+		return err
+	}
+
+	repoSvc := repo.New(gdb)
+	now := time.Now().UTC()
+	cutoff := now.AddDate(0, 0, -cfg.ActivityLog.RetentionDays)
+
+	if err := repoSvc.ActivityLog.EnsurePartitions(ctx, now, now.AddDate(0, cfg.ActivityLog.PartitionsAhead, 0)); err != nil {
+		return fmt.Errorf("error creating partitions: %w", err)
+	}
+
+	key := fmt.Sprintf("%s/%s/%s.ndjson.gz", cfg.ActivityLog.ArchivePrefix, cutoff.Format("2006/01/02"), ulidutil.NewString())
+	ids, err := archive(ctx, repoSvc.ActivityLog, blobSvc, key, cutoff, cfg.ActivityLog.ArchiveBatchSize)
+	if err != nil {
+		return fmt.Errorf("error archiving activity logs: %w", err)
+	}
+	log.Printf("archived %d activity logs before %s to %s", len(ids), cutoff.Format(time.RFC3339), key)
+
+	// delete archived records only after the archive has been stored successfully
+	for len(ids) > 0 {
+		n := min(len(ids), cfg.ActivityLog.ArchiveBatchSize)
+		if err := repoSvc.ActivityLog.HardDeleteBefore(ctx, cutoff, ids[:n]); err != nil {
+			return fmt.Errorf("error deleting archived activity logs: %w", err)
+		}
+		ids = ids[n:]
+	}
+
+	dropped, err := repoSvc.ActivityLog.DropEmptyPartitionsBefore(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("error dropping partitions: %w", err)
+	}
+	log.Printf("dropped %d expired partitions: %v", len(dropped), dropped)
+
+	return nil
+}
+
+// archive streams all records created before cutoff into a gzipped NDJSON object, returns the archived IDs
+func archive(ctx context.Context, r *repo.ActivityLog, blobSvc blob.Service, key string, cutoff time.Time, batchSize int) ([]string, error) {
+	// nothing to archive, skip uploading an empty file
+	first, err := r.ListBefore(ctx, cutoff, nil, 1)
+	if err != nil || len(first) == 0 {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	ids := []string{}
+	go func() {
+		pw.CloseWithError(writeNDJSON(ctx, r, pw, cutoff, batchSize, &ids))
+	}()
+
+	if err := blobSvc.Put(ctx, key, pr, "application/x-ndjson"); err != nil {
+		pr.CloseWithError(err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func writeNDJSON(ctx context.Context, r *repo.ActivityLog, w io.Writer, cutoff time.Time, batchSize int, ids *[]string) error {
+	gw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(gw)
+	enc := json.NewEncoder(bw)
+
+	var after *types.ActivityLog
+	for {
+		recs, err := r.ListBefore(ctx, cutoff, after, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+			*ids = append(*ids, rec.ID)
+		}
+		if len(recs) < batchSize {
+			break
+		}
+		after = recs[len(recs)-1]
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
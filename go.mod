@@ -9,6 +9,7 @@ require (
 	github.com/aws/aws-sdk-go v1.52.3
 	github.com/caarlos0/env/v9 v9.0.0
 	github.com/go-gormigrate/gormigrate/v2 v2.1.2
+	github.com/go-playground/validator/v10 v10.16.0
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/iancoleman/strcase v0.3.0
 	github.com/imdatngo/gowhere v1.1.3
@@ -27,7 +28,6 @@ require (
 	github.com/gabriel-vasile/mimetype v1.4.2 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.16.0 // indirect
 	github.com/go-sql-driver/mysql v1.7.0 // indirect
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
 	github.com/imdatngo/mergo v0.3.12 // indirect
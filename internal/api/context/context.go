@@ -36,8 +36,19 @@ func (h *HTTPContext) SetAuthUser() {
 		Name:  h.getValue("name"),
 		Email: h.getValue("email"),
 		Role:  h.getValue("role"),
+
+		SessionID: h.getValue("sid"),
+
+		OrganizationID: h.getValue("oid"),
+		OrgRole:        h.getValue("org_role"),
+
+		APIKeyID: h.getValue("api_key_id"),
+		ActorID:  h.getValue("actor_id"),
 		// Add more fields if needed
 	}
+	if scopes, ok := h.Context.Get("scopes").([]string); ok {
+		h.au.Scopes = scopes
+	}
 }
 
 // getValue safely retrieves a string value from the context
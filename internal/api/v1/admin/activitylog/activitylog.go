@@ -0,0 +1,93 @@
+package activitylog
+
+import (
+	"errors"
+
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+
+	contextutil "tyr/internal/api/context"
+)
+
+// Read returns single activity log by id
+func (s *ActivityLog) Read(c contextutil.Context, id string) (*types.ActivityLog, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	rec := &types.ActivityLog{}
+	if err := s.repo.ActivityLog.ReadByID(c.GetContext(), rec, id); err != nil {
+		return nil, ErrActivityLogNotFound.SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// List returns the list of activity logs
+func (s *ActivityLog) List(c contextutil.Context, req ListActivityLogReq) (*ListActivityLogsResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	if req.MaxDurationMS > 0 && req.MinDurationMS > req.MaxDurationMS {
+		return nil, ErrInvalidDuration
+	}
+
+	if req.IsCursor() {
+		return s.listByCursor(c, req)
+	}
+
+	lc, err := req.ToListCond()
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64 = 0
+	data := []*types.ActivityLog{}
+	if err := s.repo.ActivityLog.List(c.GetContext(), &data, &count, lc); err != nil {
+		return nil, server.NewHTTPInternalError("Error listing activity log").SetInternal(err)
+	}
+
+	return &ListActivityLogsResp{
+		Data:       data,
+		TotalCount: count,
+	}, nil
+}
+
+// listByCursor returns a page of activity logs using keyset pagination
+func (s *ActivityLog) listByCursor(c contextutil.Context, req ListActivityLogReq) (*ListActivityLogsResp, error) {
+	var count int64 = 0
+	data := []*types.ActivityLog{}
+	page, err := s.repo.ActivityLog.ListByCursor(c.GetContext(), &data, &count, req.ToCursorCond())
+	switch {
+	case errors.Is(err, repo.ErrInvalidCursor):
+		return nil, ErrInvalidCursor
+	case errors.Is(err, repo.ErrUnsupportedCursorSort):
+		return nil, ErrUnsupportedCursorSort
+	case err != nil:
+		return nil, server.NewHTTPInternalError("Error listing activity log").SetInternal(err)
+	}
+
+	return &ListActivityLogsResp{
+		Data:       data,
+		TotalCount: count,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}, nil
+}
+
+// enforce checks activity log permission to perform the action
+func (s *ActivityLog) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectActivityLog, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package activitylog
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrActivityLogNotFound   = server.NewHTTPError(http.StatusBadRequest, "ACTIVITY_LOG_NOTFOUND", "Activity log not found")
+	ErrInvalidTimeRange      = server.NewHTTPValidationError("Invalid time range, `from` must be before `to`")
+	ErrInvalidCursor         = server.NewHTTPValidationError("Invalid cursor, it must be taken from the previous page with the same sorting")
+	ErrUnsupportedCursorSort = server.NewHTTPValidationError("Invalid sort, cursor pagination only supports sorting by `created_at`")
+	ErrInvalidDuration       = server.NewHTTPValidationError("Invalid duration range, `min_duration_ms` must not exceed `max_duration_ms`")
+)
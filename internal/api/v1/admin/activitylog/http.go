@@ -0,0 +1,89 @@
+package activitylog
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents activity log http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents activity log application interface
+type Service interface {
+	Read(contextutil.Context, string) (*types.ActivityLog, error)
+	List(contextutil.Context, ListActivityLogReq) (*ListActivityLogsResp, error)
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/admin/activity-logs/{id} admin-activity-logs activityLogsRead
+	// ---
+	// summary: Returns a single activity log
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of activity log
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The activity log
+	//     schema:
+	//       "$ref": "#/definitions/ActivityLog"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 404, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id", h.read)
+
+	// swagger:operation GET /v1/admin/activity-logs admin-activity-logs activityLogsList
+	// ---
+	// summary: Returns list of activity logs, filtered by url, method, status, time range and duration
+	// responses:
+	//   "200":
+	//     description: List of activity logs
+	//     schema:
+	//       "$ref": "#/definitions/ListActivityLogsResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+}
+
+func (h *HTTP) read(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.Read(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	req := ListActivityLogReq{}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	resp, err := h.svc.List(contextutil.NewContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,18 @@
+package activitylog
+
+import (
+	"tyr/internal/repo"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new activity log application service
+func New(repo *repo.Service, rbacSvc rbac.Intf) *ActivityLog {
+	return &ActivityLog{repo: repo, rbac: rbacSvc}
+}
+
+// ActivityLog represents activity log application service
+type ActivityLog struct {
+	repo *repo.Service
+	rbac rbac.Intf
+}
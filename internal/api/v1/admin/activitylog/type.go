@@ -0,0 +1,105 @@
+package activitylog
+
+import (
+	"time"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
+
+	"github.com/M15t/gram/pkg/server"
+	requestutil "github.com/M15t/gram/pkg/util/request"
+)
+
+// ListActivityLogReq contains request data to get list of activity logs
+// swagger:parameters activityLogsList
+type ListActivityLogReq struct {
+	requestutil.ListQueryRequest
+	// Pagination mode, either `offset` (default) using `p` or `cursor` using `cursor`.
+	// In cursor mode `pp` is the page size and `s` accepts a single key among `created_at`
+	// in: query
+	Pagination string `json:"pagination,omitempty" query:"pagination" validate:"omitempty,oneof=offset cursor"`
+	// Opaque cursor of the next page returned by the previous page, implies cursor pagination
+	// in: query
+	Cursor string `json:"cursor,omitempty" query:"cursor"`
+	// Whether to count the total records, default to true in offset mode and false in cursor mode
+	// in: query
+	Count *bool `json:"count,omitempty" query:"count"`
+	// Part of the request URL
+	// in: query
+	URL string `json:"url,omitempty" query:"url"`
+	// HTTP method of the request, eg: GET
+	// in: query
+	Method string `json:"method,omitempty" query:"method"`
+	// HTTP status code of the response, eg: 200
+	// in: query
+	Status int `json:"status,omitempty" query:"status"`
+	// in: query
+	APIMRequestID string `json:"apim_request_id,omitempty" query:"apim_request_id"`
+	// Lower bound (inclusive) of created time, in RFC3339 format
+	// in: query
+	From *time.Time `json:"from,omitempty" query:"from"`
+	// Upper bound (exclusive) of created time, in RFC3339 format
+	// in: query
+	To *time.Time `json:"to,omitempty" query:"to"`
+	// Minimum duration in milliseconds
+	// in: query
+	MinDurationMS int64 `json:"min_duration_ms,omitempty" query:"min_duration_ms" validate:"gte=0"`
+	// Maximum duration in milliseconds
+	// in: query
+	MaxDurationMS int64 `json:"max_duration_ms,omitempty" query:"max_duration_ms" validate:"gte=0"`
+}
+
+// ToListCond transforms the service request to repo conditions
+func (lq *ListActivityLogReq) ToListCond() (*requestutil.ListCondition[repo.ActivityLogsFilter], error) {
+	if err := filterutil.ValidateSort(lq.Sort, repo.ActivityLogFilterSchema); err != nil {
+		return nil, server.NewHTTPValidationError("Invalid sort, " + err.Error())
+	}
+
+	return &requestutil.ListCondition[repo.ActivityLogsFilter]{
+		Page:    lq.Page,
+		PerPage: lq.PerPage,
+		Sort:    lq.Sort,
+		Count:   lq.Count == nil || *lq.Count,
+		Filter:  lq.toFilter(),
+	}, nil
+}
+
+// IsCursor returns whether the listing is requested with cursor pagination
+func (lq *ListActivityLogReq) IsCursor() bool {
+	return lq.Cursor != "" || lq.Pagination == "cursor"
+}
+
+// ToCursorCond transforms the service request to repo keyset pagination conditions
+func (lq *ListActivityLogReq) ToCursorCond() *repo.CursorCondition[repo.ActivityLogsFilter] {
+	return &repo.CursorCondition[repo.ActivityLogsFilter]{
+		Cursor: lq.Cursor,
+		Limit:  lq.PerPage,
+		Sort:   lq.Sort,
+		Count:  lq.Count != nil && *lq.Count,
+		Filter: lq.toFilter(),
+	}
+}
+
+func (lq *ListActivityLogReq) toFilter() repo.ActivityLogsFilter {
+	return repo.ActivityLogsFilter{
+		URL:           lq.URL,
+		Method:        lq.Method,
+		ResponseCode:  lq.Status,
+		APIMRequestID: lq.APIMRequestID,
+		From:          lq.From,
+		To:            lq.To,
+		MinDurationMS: lq.MinDurationMS,
+		MaxDurationMS: lq.MaxDurationMS,
+	}
+}
+
+// ListActivityLogsResp contains list of paginated activity logs and total numbers after filtered
+// swagger:model
+type ListActivityLogsResp struct {
+	Data       []*types.ActivityLog `json:"data"`
+	TotalCount int64                `json:"total_count"`
+	// Cursor of the next page, only in cursor mode
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more,omitempty"`
+}
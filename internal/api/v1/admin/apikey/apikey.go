@@ -0,0 +1,96 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/gorm"
+)
+
+// Read returns single API key by id
+func (s *APIKey) Read(c contextutil.Context, id string) (*types.APIKey, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	rec := &types.APIKey{}
+	if err := s.repo.APIKey.ReadByID(c.GetContext(), rec, id); err != nil {
+		return nil, ErrAPIKeyNotFound.SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// List returns the list of API keys of all users
+func (s *APIKey) List(c contextutil.Context, req ListAPIKeysReq) (*ListAPIKeysResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	lc, err := req.ToListCond()
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64 = 0
+	data := []*types.APIKey{}
+	if err := s.repo.APIKey.List(c.GetContext(), &data, &count, lc); err != nil {
+		return nil, server.NewHTTPInternalError("error listing API keys").SetInternal(err)
+	}
+
+	return &ListAPIKeysResp{
+		Data:       data,
+		TotalCount: count,
+	}, nil
+}
+
+// Revoke revokes an API key of any user, eg: a leaked one, the requests with its token are rejected right away
+func (s *APIKey) Revoke(c contextutil.Context, id string, data RevokeAPIKeyReq) error {
+	rec, err := s.Read(c, id)
+	if err != nil {
+		return err
+	}
+	if err := s.enforce(c, rbac.ActionDeleteAll); err != nil {
+		return err
+	}
+
+	ctx := c.GetContext()
+	if err := s.repo.APIKey.Revoke(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPIKeyNotFound.SetInternal(err)
+		}
+		return server.NewHTTPInternalError("error revoking API key").SetInternal(err)
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"name": rec.Name, "prefix": rec.Prefix})
+	if err := s.repo.AuditEvent.Create(ctx, &types.AuditEvent{
+		UserID:     rec.UserID,
+		ActorID:    c.AuthUser().ID,
+		Action:     types.AuditActionAPIKeyRevoked,
+		ObjectType: "api_key",
+		ObjectID:   rec.ID,
+		Reason:     data.Reason,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   meta,
+	}); err != nil {
+		return server.NewHTTPInternalError("error recording audit event").SetInternal(err)
+	}
+
+	return nil
+}
+
+// enforce checks user permission to perform the action
+func (s *APIKey) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectAPIKey, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package apikey
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrAPIKeyNotFound = server.NewHTTPError(http.StatusBadRequest, "API_KEY_NOTFOUND", "API key not found")
+)
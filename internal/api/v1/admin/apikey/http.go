@@ -0,0 +1,129 @@
+package apikey
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents API key http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents API key administration interface
+type Service interface {
+	Read(contextutil.Context, string) (*types.APIKey, error)
+	List(contextutil.Context, ListAPIKeysReq) (*ListAPIKeysResp, error)
+	Revoke(contextutil.Context, string, RevokeAPIKeyReq) error
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/admin/api-keys/{id} admin-api-keys apiKeysRead
+	// ---
+	// summary: Returns a single API key
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of API key
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The API key
+	//     schema:
+	//       "$ref": "#/definitions/APIKey"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id", h.read)
+
+	// swagger:operation GET /v1/admin/api-keys admin-api-keys apiKeysList
+	// ---
+	// summary: Returns list of API keys of all users
+	// responses:
+	//   "200":
+	//     description: List of API keys
+	//     schema:
+	//       "$ref": "#/definitions/ListAPIKeysResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation DELETE /v1/admin/api-keys/{id} admin-api-keys apiKeysRevoke
+	// ---
+	// summary: Revokes an API key of any user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of API key
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   schema:
+	//     "$ref": "#/definitions/RevokeAPIKeyReq"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id", h.revoke)
+}
+
+func (h *HTTP) read(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.Read(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	req := ListAPIKeysReq{}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	resp, err := h.svc.List(contextutil.NewContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) revoke(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	req := RevokeAPIKeyReq{}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := h.svc.Revoke(contextutil.NewContext(c), id, req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,18 @@
+package apikey
+
+import (
+	"tyr/internal/repo"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new API key administration service
+func New(repo *repo.Service, rbacSvc rbac.Intf) *APIKey {
+	return &APIKey{repo: repo, rbac: rbacSvc}
+}
+
+// APIKey represents API key administration service
+type APIKey struct {
+	repo *repo.Service
+	rbac rbac.Intf
+}
@@ -0,0 +1,56 @@
+package apikey
+
+import (
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
+
+	requestutil "github.com/M15t/gram/pkg/util/request"
+)
+
+// RevokeAPIKeyReq contains request data to revoke an API key
+// swagger:model
+type RevokeAPIKeyReq struct {
+	// Recorded in the audit events of the user
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// ListAPIKeysReq contains request data to get list of API keys
+// swagger:parameters apiKeysList
+type ListAPIKeysReq struct {
+	requestutil.ListQueryRequest
+	// Filter expression, conditions are separated by comma and combined by AND.
+	// Supported operators: =, !=, >, >=, <, <=, ~ (contains), !~ (not contains), between (from..to), in (a|b|c).
+	// Values containing commas must be double-quoted, eg: `user_id=01HX...,last_used_at<2026-01-01T00:00:00Z`
+	// in: query
+	Filter string `json:"filter,omitempty" query:"filter"`
+	// Only the revoked keys if true, only the ones not revoked if false
+	// in: query
+	Revoked *bool `json:"revoked,omitempty" query:"revoked"`
+}
+
+// ToListCond transforms the service request to repo conditions
+func (lq *ListAPIKeysReq) ToListCond() (*requestutil.ListCondition[repo.APIKeysFilter], error) {
+	query, err := filterutil.ParseList(lq.Filter, lq.Sort, repo.APIKeyFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requestutil.ListCondition[repo.APIKeysFilter]{
+		Page:    lq.Page,
+		PerPage: lq.PerPage,
+		Sort:    lq.Sort,
+		Count:   true,
+		Filter: repo.APIKeysFilter{
+			Revoked: lq.Revoked,
+			Query:   query,
+		},
+	}, nil
+}
+
+// ListAPIKeysResp contains list of paginated API keys and total numbers after filtered
+// swagger:model
+type ListAPIKeysResp struct {
+	Data       []*types.APIKey `json:"data"`
+	TotalCount int64           `json:"total_count"`
+}
@@ -0,0 +1,12 @@
+package document
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrDocumentNotFound = server.NewHTTPError(http.StatusBadRequest, "DOCUMENT_NOTFOUND", "Document not found")
+)
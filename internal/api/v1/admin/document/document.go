@@ -0,0 +1,49 @@
+package document
+
+import (
+	"errors"
+
+	"tyr/internal/rbac"
+	"tyr/internal/reextract"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/gorm"
+
+	contextutil "tyr/internal/api/context"
+)
+
+// reextractPageSize is the maximum number of documents re-extracted per request
+const reextractPageSize = 200
+
+// Reextract reprocesses one or all documents from the stored raw Azure responses through the current mapping code,
+// so mapping improvements can be applied retroactively without calling Azure again.
+func (s *Document) Reextract(c contextutil.Context, req ReextractReq) (*reextract.Report, error) {
+	if err := s.enforce(c, rbac.ActionUpdateAll); err != nil {
+		return nil, err
+	}
+
+	// the analyzed documents are re-extracted page by page within the request, the functions/reextract job runs them all at once
+	report, err := s.reextract.Run(c.GetContext(), reextract.Input{
+		DocumentID: req.DocumentID,
+		DryRun:     req.DryRun == nil || *req.DryRun,
+		Limit:      reextractPageSize,
+		AfterID:    req.AfterID,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotFound.SetInternal(err)
+		}
+		return nil, server.NewHTTPInternalError("error re-extracting document").SetInternal(err)
+	}
+
+	return report, nil
+}
+
+// enforce checks document permission to perform the action
+func (s *Document) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectDocument, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
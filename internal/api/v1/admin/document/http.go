@@ -0,0 +1,63 @@
+package document
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/reextract"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents admin document http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents admin document application interface
+type Service interface {
+	Reextract(contextutil.Context, ReextractReq) (*reextract.Report, error)
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation POST /v1/admin/documents/reextract admin-documents adminDocumentsReextract
+	// ---
+	// summary: Re-extracts one or all documents from the stored Azure results using the current mapping code
+	// description: |
+	//   It is a dry run unless `dry_run` is set to false explicitly.
+	//   The analyzed documents are re-extracted by pages of 200, pass the `next_after_id` of the report as `after_id` to continue.
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/ReextractReq"
+	// responses:
+	//   "200":
+	//     description: The diff report
+	//     schema:
+	//       "$ref": "#/definitions/ReextractReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/reextract", h.reextract)
+}
+
+func (h *HTTP) reextract(c echo.Context) error {
+	r := ReextractReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	resp, err := h.svc.Reextract(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
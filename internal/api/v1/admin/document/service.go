@@ -0,0 +1,27 @@
+package document
+
+import (
+	"context"
+
+	"tyr/internal/reextract"
+	"tyr/internal/repo"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new admin document application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, reextractSvc Reextractor) *Document {
+	return &Document{repo: repo, rbac: rbacSvc, reextract: reextractSvc}
+}
+
+// Document represents admin document application service
+type Document struct {
+	repo      *repo.Service
+	rbac      rbac.Intf
+	reextract Reextractor
+}
+
+// Reextractor represents re-extraction interface
+type Reextractor interface {
+	Run(ctx context.Context, in reextract.Input) (*reextract.Report, error)
+}
@@ -0,0 +1,14 @@
+package document
+
+// ReextractReq contains request data to re-extract documents from stored Azure results
+// swagger:model
+type ReextractReq struct {
+	// Re-extract a single document, leave empty to re-extract the analyzed documents page by page
+	DocumentID string `json:"document_id,omitempty"`
+	// Only report the differences without updating any document, default to true.
+	// Set it to false explicitly to update the changed documents
+	// example: true
+	DryRun *bool `json:"dry_run,omitempty"`
+	// Continue re-extracting the analyzed documents after this document, taken from the next_after_id of the previous report
+	AfterID string `json:"after_id,omitempty"`
+}
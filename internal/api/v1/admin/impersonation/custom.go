@@ -0,0 +1,16 @@
+package impersonation
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrImpersonationNotFound = server.NewHTTPError(http.StatusBadRequest, "IMPERSONATION_NOTFOUND", "Impersonation not found")
+	ErrImpersonationEnded    = server.NewHTTPError(http.StatusConflict, "IMPERSONATION_ENDED", "The impersonation has already ended or expired")
+	ErrUserNotFound          = server.NewHTTPError(http.StatusBadRequest, "USER_NOTFOUND", "User not found")
+	ErrCannotImpersonate     = server.NewHTTPError(http.StatusForbidden, "CANNOT_IMPERSONATE", "Only the active app users can be impersonated, not yourself")
+	ErrNotOrgMember          = server.NewHTTPError(http.StatusBadRequest, "NOT_ORGANIZATION_MEMBER", "The user is not a member of the organization")
+)
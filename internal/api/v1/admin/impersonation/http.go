@@ -0,0 +1,160 @@
+package impersonation
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents impersonation http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents impersonation administration interface
+type Service interface {
+	Start(contextutil.Context, StartImpersonationReq) (*StartImpersonationResp, error)
+	Read(contextutil.Context, string) (*types.Impersonation, error)
+	List(contextutil.Context, ListImpersonationsReq) (*ListImpersonationsResp, error)
+	End(contextutil.Context, string) error
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation POST /v1/admin/impersonations admin-impersonations impersonationsStart
+	// ---
+	// summary: Impersonates an active app user to see the app as the user does, only by the superadmins
+	// description: |
+	//   Returns a short-lived access token of the user carrying the current superadmin as the actor, it cannot be refreshed.
+	//   The token is read-only, the requests which may change the data of the user are rejected with 403.
+	//   Every request made with it is recorded in the audit events of the user.
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/StartImpersonationReq"
+	// responses:
+	//   "200":
+	//     description: The impersonation with its access token
+	//     schema:
+	//       "$ref": "#/definitions/StartImpersonationResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("", h.start)
+
+	// swagger:operation GET /v1/admin/impersonations admin-impersonations impersonationsList
+	// ---
+	// summary: Returns list of impersonations
+	// responses:
+	//   "200":
+	//     description: List of impersonations
+	//     schema:
+	//       "$ref": "#/definitions/ListImpersonationsResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation GET /v1/admin/impersonations/{id} admin-impersonations impersonationsRead
+	// ---
+	// summary: Returns a single impersonation
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of impersonation
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The impersonation
+	//     schema:
+	//       "$ref": "#/definitions/Impersonation"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id", h.read)
+
+	// swagger:operation DELETE /v1/admin/impersonations/{id} admin-impersonations impersonationsEnd
+	// ---
+	// summary: Ends an impersonation before it expires, its access token is denied right away
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of impersonation
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id", h.end)
+}
+
+func (h *HTTP) start(c echo.Context) error {
+	r := StartImpersonationReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Start(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	req := ListImpersonationsReq{}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	resp, err := h.svc.List(contextutil.NewContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) read(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.Read(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) end(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.End(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
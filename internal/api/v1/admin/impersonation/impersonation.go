@@ -0,0 +1,172 @@
+package impersonation
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/M15t/gram/pkg/util/ulidutil"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// Start impersonates an active app user to see the app as the user does.
+// The access token is short-lived and read-only, it carries the current superadmin as the actor
+// and cannot be refreshed. Every request made with it is recorded in the audit events of the user.
+func (s *Impersonation) Start(c contextutil.Context, data StartImpersonationReq) (*StartImpersonationResp, error) {
+	if err := s.enforce(c, rbac.ActionCreateAll); err != nil {
+		return nil, err
+	}
+
+	au, ctx := c.AuthUser(), c.GetContext()
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(ctx, user, data.UserID); err != nil {
+		return nil, ErrUserNotFound.SetInternal(err)
+	}
+	if user.ID == au.ID || user.Status != types.UserStatusActive || !s.rbac.IsAppRole(user.Role) {
+		return nil, ErrCannotImpersonate
+	}
+
+	rec := &types.Impersonation{
+		ActorID:   au.ID,
+		UserID:    user.ID,
+		Reason:    data.Reason,
+		IPAddress: c.RealIP(),
+		UserAgent: c.UserAgent(),
+	}
+	rec.ID = ulidutil.NewString()
+	claims := map[string]interface{}{
+		"id":       user.ID,
+		"email":    user.Email,
+		"name":     user.FirstName + " " + user.LastName,
+		"role":     user.Role,
+		"jti":      rec.ID,
+		"actor_id": au.ID,
+	}
+	if data.OrganizationID != "" {
+		member, err := s.repo.OrganizationMember.FindByUser(ctx, data.OrganizationID, user.ID)
+		if err != nil {
+			return nil, ErrNotOrgMember.SetInternal(err)
+		}
+		rec.OrganizationID = &member.OrganizationID
+		claims["oid"] = member.OrganizationID
+		claims["org_role"] = member.Role
+	}
+
+	token, err := s.jwt.GenerateAccessToken(claims, s.ttl)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error generating access token").SetInternal(err)
+	}
+	rec.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	if err := s.repo.Impersonation.Create(ctx, rec); err != nil {
+		return nil, server.NewHTTPInternalError("error creating impersonation").SetInternal(err)
+	}
+
+	if err := s.audit(c, rec, types.AuditActionImpersonationStarted, data.Reason); err != nil {
+		return nil, server.NewHTTPInternalError("error recording audit event").SetInternal(err)
+	}
+
+	return &StartImpersonationResp{
+		Impersonation: rec,
+		AccessToken:   token.Token,
+		TokenType:     "bearer",
+		ExpiresIn:     token.ExpiresIn,
+	}, nil
+}
+
+// Read returns single impersonation by id
+func (s *Impersonation) Read(c contextutil.Context, id string) (*types.Impersonation, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	rec := &types.Impersonation{}
+	if err := s.repo.Impersonation.ReadByID(c.GetContext(), rec, id); err != nil {
+		return nil, ErrImpersonationNotFound.SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// List returns the list of impersonations
+func (s *Impersonation) List(c contextutil.Context, req ListImpersonationsReq) (*ListImpersonationsResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	lc, err := req.ToListCond()
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64 = 0
+	data := []*types.Impersonation{}
+	if err := s.repo.Impersonation.List(c.GetContext(), &data, &count, lc); err != nil {
+		return nil, server.NewHTTPInternalError("error listing impersonations").SetInternal(err)
+	}
+
+	return &ListImpersonationsResp{
+		Data:       data,
+		TotalCount: count,
+	}, nil
+}
+
+// End ends an impersonation before it expires, its access token is denied right away
+func (s *Impersonation) End(c contextutil.Context, id string) error {
+	rec, err := s.Read(c, id)
+	if err != nil {
+		return err
+	}
+	if err := s.enforce(c, rbac.ActionDeleteAll); err != nil {
+		return err
+	}
+
+	ctx := c.GetContext()
+	if err := s.repo.Impersonation.End(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrImpersonationEnded
+		}
+		return server.NewHTTPInternalError("error ending impersonation").SetInternal(err)
+	}
+
+	if err := s.denylist.RevokeToken(ctx, id, rec.ExpiresAt); err != nil {
+		return server.NewHTTPInternalError("error revoking access token").SetInternal(err)
+	}
+
+	if err := s.audit(c, rec, types.AuditActionImpersonationEnded, ""); err != nil {
+		return server.NewHTTPInternalError("error recording audit event").SetInternal(err)
+	}
+
+	return nil
+}
+
+// audit records an audit event about the impersonation in the audit events of the impersonated user
+func (s *Impersonation) audit(c contextutil.Context, rec *types.Impersonation, action, reason string) error {
+	meta, _ := json.Marshal(map[string]interface{}{"organization_id": lo.FromPtr(rec.OrganizationID), "expires_at": rec.ExpiresAt})
+
+	return s.repo.AuditEvent.Create(c.GetContext(), &types.AuditEvent{
+		UserID:     rec.UserID,
+		ActorID:    c.AuthUser().ID,
+		Action:     action,
+		ObjectType: "impersonation",
+		ObjectID:   rec.ID,
+		Reason:     reason,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   meta,
+	})
+}
+
+// enforce checks user permission to perform the action
+func (s *Impersonation) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectImpersonation, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
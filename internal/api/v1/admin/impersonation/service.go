@@ -0,0 +1,48 @@
+package impersonation
+
+import (
+	"context"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/repo"
+
+	"github.com/M15t/gram/pkg/rbac"
+	"github.com/M15t/gram/pkg/server/middleware/jwt"
+)
+
+// New creates new impersonation administration service
+func New(repo *repo.Service, rbacSvc RBAC, jwtSvc JWT, denylist Denylist, cfg config.Impersonation) *Impersonation {
+	return &Impersonation{
+		repo:     repo,
+		rbac:     rbacSvc,
+		jwt:      jwtSvc,
+		denylist: denylist,
+		ttl:      time.Duration(cfg.TTL) * time.Second,
+	}
+}
+
+// Impersonation represents impersonation administration service
+type Impersonation struct {
+	repo     *repo.Service
+	rbac     RBAC
+	jwt      JWT
+	denylist Denylist
+	ttl      time.Duration
+}
+
+// RBAC represents role based access control interface
+type RBAC interface {
+	rbac.Intf
+	IsAppRole(role string) bool
+}
+
+// JWT represents token generator interface
+type JWT interface {
+	GenerateAccessToken(claims map[string]interface{}, ttl time.Duration) (*jwt.TokenOutput, error)
+}
+
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+}
@@ -0,0 +1,65 @@
+package impersonation
+
+import (
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
+
+	requestutil "github.com/M15t/gram/pkg/util/request"
+)
+
+// StartImpersonationReq contains request data to impersonate a user
+// swagger:model
+type StartImpersonationReq struct {
+	UserID string `json:"user_id" validate:"required"`
+	// Impersonate the user in one of its organizations, out of organizations if empty
+	OrganizationID string `json:"organization_id,omitempty"`
+	// Recorded in the audit events of the user
+	// example: Support ticket #4521, receipt totals extracted wrong
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// StartImpersonationResp contains the impersonation with its read-only access token
+// swagger:model
+type StartImpersonationResp struct {
+	Impersonation *types.Impersonation `json:"impersonation"`
+	AccessToken   string               `json:"access_token"`
+	TokenType     string               `json:"token_type"`
+	ExpiresIn     int                  `json:"expires_in"`
+}
+
+// ListImpersonationsReq contains request data to get list of impersonations
+// swagger:parameters impersonationsList
+type ListImpersonationsReq struct {
+	requestutil.ListQueryRequest
+	// Filter expression, conditions are separated by comma and combined by AND.
+	// Supported operators: =, !=, >, >=, <, <=, ~ (contains), !~ (not contains), between (from..to), in (a|b|c).
+	// Values containing commas must be double-quoted, eg: `actor_id=01HX...,created_at>2026-01-01T00:00:00Z`
+	// in: query
+	Filter string `json:"filter,omitempty" query:"filter"`
+}
+
+// ToListCond transforms the service request to repo conditions
+func (lq *ListImpersonationsReq) ToListCond() (*requestutil.ListCondition[repo.ImpersonationsFilter], error) {
+	query, err := filterutil.ParseList(lq.Filter, lq.Sort, repo.ImpersonationFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requestutil.ListCondition[repo.ImpersonationsFilter]{
+		Page:    lq.Page,
+		PerPage: lq.PerPage,
+		Sort:    lq.Sort,
+		Count:   true,
+		Filter: repo.ImpersonationsFilter{
+			Query: query,
+		},
+	}, nil
+}
+
+// ListImpersonationsResp contains list of paginated impersonations and total numbers after filtered
+// swagger:model
+type ListImpersonationsResp struct {
+	Data       []*types.Impersonation `json:"data"`
+	TotalCount int64                  `json:"total_count"`
+}
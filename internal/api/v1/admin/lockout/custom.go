@@ -0,0 +1,12 @@
+package lockout
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrLockoutNotFound = server.NewHTTPError(http.StatusBadRequest, "LOCKOUT_NOTFOUND", "Lockout not found or already expired")
+)
@@ -0,0 +1,83 @@
+package lockout
+
+import (
+	"net/http"
+	"strings"
+
+	contextutil "tyr/internal/api/context"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents lockout http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents lockout application interface
+type Service interface {
+	List(contextutil.Context) (*ListLockoutsResp, error)
+	Unlock(contextutil.Context, UnlockReq) error
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/admin/lockouts admin-lockouts lockoutsList
+	// ---
+	// summary: Returns the active lockouts of accounts and IP addresses after too many failed login attempts
+	// responses:
+	//   "200":
+	//     description: List of lockouts
+	//     schema:
+	//       "$ref": "#/definitions/ListLockoutsResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation POST /v1/admin/lockouts/unlock admin-lockouts lockoutsUnlock
+	// ---
+	// summary: Unlocks an account or an IP address before the lockout expires
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/UnlockReq"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/unlock", h.unlock)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	resp, err := h.svc.List(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) unlock(c echo.Context) error {
+	r := UnlockReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	r.Key = strings.TrimSpace(r.Key)
+
+	if err := h.svc.Unlock(contextutil.NewContext(c), r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,69 @@
+package lockout
+
+import (
+	"strings"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// List returns the active lockouts of accounts and IP addresses
+func (s *Lockout) List(c contextutil.Context) (*ListLockoutsResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	data, err := s.guard.ListLockouts(c.GetContext())
+	if err != nil {
+		return nil, server.NewHTTPInternalError("Error listing lockouts").SetInternal(err)
+	}
+
+	return &ListLockoutsResp{Data: data}, nil
+}
+
+// Unlock removes the lockout of the account or the IP address before it expires
+func (s *Lockout) Unlock(c contextutil.Context, data UnlockReq) error {
+	if err := s.enforce(c, rbac.ActionDeleteAll); err != nil {
+		return err
+	}
+
+	unlocked, err := s.guard.Unlock(c.GetContext(), data.Key)
+	if err != nil {
+		return server.NewHTTPInternalError("Error unlocking").SetInternal(err)
+	}
+	if !unlocked {
+		return ErrLockoutNotFound
+	}
+
+	// audit the unlock of an account against its user
+	if email, ok := strings.CutPrefix(data.Key, "account:"); ok {
+		if user, err := s.repo.User.FindByEmail(c.GetContext(), email); err == nil {
+			if err := s.repo.AuditEvent.Create(c.GetContext(), &types.AuditEvent{
+				UserID:     user.ID,
+				ActorID:    c.AuthUser().ID,
+				Action:     types.AuditActionAccountUnlocked,
+				ObjectType: "user",
+				ObjectID:   user.ID,
+				Reason:     "unlocked by admin",
+				IPAddress:  c.RealIP(),
+				UserAgent:  c.UserAgent(),
+			}); err != nil {
+				return server.NewHTTPInternalError("Error auditing unlock").SetInternal(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// enforce checks user permission to perform the action
+func (s *Lockout) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectLockout, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
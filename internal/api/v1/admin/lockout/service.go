@@ -0,0 +1,28 @@
+package lockout
+
+import (
+	"context"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new lockout application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, guard Guard) *Lockout {
+	return &Lockout{repo: repo, rbac: rbacSvc, guard: guard}
+}
+
+// Lockout represents lockout application service
+type Lockout struct {
+	repo  *repo.Service
+	rbac  rbac.Intf
+	guard Guard
+}
+
+// Guard represents login brute-force protection interface
+type Guard interface {
+	ListLockouts(ctx context.Context) ([]*types.LoginLockout, error)
+	Unlock(ctx context.Context, key string) (bool, error)
+}
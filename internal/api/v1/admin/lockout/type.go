@@ -0,0 +1,17 @@
+package lockout
+
+import "tyr/internal/types"
+
+// ListLockoutsResp contains the active lockouts
+// swagger:model
+type ListLockoutsResp struct {
+	Data []*types.LoginLockout `json:"data"`
+}
+
+// UnlockReq contains request data to unlock an account or an IP address
+// swagger:model
+type UnlockReq struct {
+	// Key of the lockout
+	// example: account:john.doe@tyr.io
+	Key string `json:"key" validate:"required"`
+}
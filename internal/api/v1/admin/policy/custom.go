@@ -0,0 +1,20 @@
+package policy
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrRoleNotFound     = server.NewHTTPError(http.StatusBadRequest, "ROLE_NOTFOUND", "Role not found")
+	ErrRoleExisted      = server.NewHTTPValidationError("Role already existed")
+	ErrRoleInUse        = server.NewHTTPError(http.StatusConflict, "ROLE_IN_USE", "The role is assigned to users or inherited by other roles")
+	ErrSystemRole       = server.NewHTTPError(http.StatusBadRequest, "SYSTEM_ROLE", "The system roles cannot be deleted")
+	ErrPolicyNotFound   = server.NewHTTPError(http.StatusBadRequest, "POLICY_NOTFOUND", "Policy not found")
+	ErrPolicyExisted    = server.NewHTTPValidationError("Policy already existed")
+	ErrSuperAdminPolicy = server.NewHTTPError(http.StatusBadRequest, "SUPERADMIN_POLICY", "The policies of the superadmin role cannot be changed")
+	ErrInvalidObject    = server.NewHTTPValidationError("Invalid object")
+	ErrInvalidAction    = server.NewHTTPValidationError("Invalid action")
+)
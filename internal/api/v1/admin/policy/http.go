@@ -0,0 +1,236 @@
+package policy
+
+import (
+	"net/http"
+	"strings"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents policy http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents policy application interface
+type Service interface {
+	ListRoles(contextutil.Context) (*ListRolesResp, error)
+	CreateRole(contextutil.Context, CreateRoleReq) (*types.RBACRole, error)
+	DeleteRole(contextutil.Context, string) error
+	ListPolicies(contextutil.Context) (*ListPoliciesResp, error)
+	CreatePolicy(contextutil.Context, CreatePolicyReq) (*types.RBACPolicy, error)
+	DeletePolicy(contextutil.Context, string) error
+	Simulate(contextutil.Context, SimulateReq) (*SimulateResp, error)
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/admin/rbac/roles admin-rbac rolesList
+	// ---
+	// summary: Returns all roles with the roles they inherit
+	// responses:
+	//   "200":
+	//     description: List of roles
+	//     schema:
+	//       "$ref": "#/definitions/ListRolesResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/roles", h.listRoles)
+
+	// swagger:operation POST /v1/admin/rbac/roles admin-rbac rolesCreate
+	// ---
+	// summary: Creates a custom role, effective on all instances within the reload interval
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateRoleReq"
+	// responses:
+	//   "200":
+	//     description: The new role
+	//     schema:
+	//       "$ref": "#/definitions/RBACRole"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/roles", h.createRole)
+
+	// swagger:operation DELETE /v1/admin/rbac/roles/{name} admin-rbac rolesDelete
+	// ---
+	// summary: Deletes a custom role with its policies
+	// parameters:
+	// - name: name
+	//   in: path
+	//   description: name of role
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/roles/:name", h.deleteRole)
+
+	// swagger:operation GET /v1/admin/rbac/policies admin-rbac policiesList
+	// ---
+	// summary: Returns all policies
+	// responses:
+	//   "200":
+	//     description: List of policies
+	//     schema:
+	//       "$ref": "#/definitions/ListPoliciesResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/policies", h.listPolicies)
+
+	// swagger:operation POST /v1/admin/rbac/policies admin-rbac policiesCreate
+	// ---
+	// summary: Creates a policy, effective on all instances within the reload interval
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreatePolicyReq"
+	// responses:
+	//   "200":
+	//     description: The new policy
+	//     schema:
+	//       "$ref": "#/definitions/RBACPolicy"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/policies", h.createPolicy)
+
+	// swagger:operation DELETE /v1/admin/rbac/policies/{id} admin-rbac policiesDelete
+	// ---
+	// summary: Deletes a policy
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of policy
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/policies/:id", h.deletePolicy)
+
+	// swagger:operation GET /v1/admin/rbac/simulate admin-rbac policiesSimulate
+	// ---
+	// summary: Answers if a role can perform an action on an object, with the policies granting it
+	// responses:
+	//   "200":
+	//     description: The simulation result
+	//     schema:
+	//       "$ref": "#/definitions/SimulateResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/simulate", h.simulate)
+}
+
+func (h *HTTP) listRoles(c echo.Context) error {
+	resp, err := h.svc.ListRoles(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) createRole(c echo.Context) error {
+	r := CreateRoleReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	r.Name = strings.TrimSpace(r.Name)
+
+	resp, err := h.svc.CreateRole(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) deleteRole(c echo.Context) error {
+	if err := h.svc.DeleteRole(contextutil.NewContext(c), c.Param("name")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) listPolicies(c echo.Context) error {
+	resp, err := h.svc.ListPolicies(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) createPolicy(c echo.Context) error {
+	r := CreatePolicyReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.CreatePolicy(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) deletePolicy(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.DeletePolicy(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) simulate(c echo.Context) error {
+	r := SimulateReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Simulate(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/samber/lo"
+	"gorm.io/datatypes"
+)
+
+var roleNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ListRoles returns all roles with their parents
+func (s *Policy) ListRoles(c contextutil.Context) (*ListRolesResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.RBAC.ListRoles(c.GetContext())
+	if err != nil {
+		return nil, server.NewHTTPInternalError("Error listing roles").SetInternal(err)
+	}
+
+	return &ListRolesResp{Data: data}, nil
+}
+
+// CreateRole creates a custom role inheriting the permissions of the parent roles
+func (s *Policy) CreateRole(c contextutil.Context, data CreateRoleReq) (*types.RBACRole, error) {
+	if err := s.enforce(c, rbac.ActionCreateAll); err != nil {
+		return nil, err
+	}
+
+	if !roleNameRegexp.MatchString(data.Name) {
+		return nil, server.NewHTTPValidationError("Name should only contain lowercase letters, digits and underscores, starting with a letter")
+	}
+	if s.rbac.HasRole(data.Name) {
+		return nil, ErrRoleExisted
+	}
+	for _, parent := range data.Parents {
+		if !s.rbac.HasRole(parent) {
+			return nil, ErrRoleNotFound
+		}
+	}
+
+	rec := &types.RBACRole{
+		Name:        data.Name,
+		Description: data.Description,
+		Parents:     lo.Uniq(data.Parents),
+	}
+	if err := s.repo.RBAC.CreateRole(c.GetContext(), rec); err != nil {
+		return nil, server.NewHTTPInternalError("Error creating role").SetInternal(err)
+	}
+
+	if err := s.applied(c, types.AuditActionRoleCreated, "rbac_role", rec.Name, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// DeleteRole deletes a custom role with its policies, the role must not be assigned to users or inherited by other roles
+func (s *Policy) DeleteRole(c contextutil.Context, name string) error {
+	if err := s.enforce(c, rbac.ActionDeleteAll); err != nil {
+		return err
+	}
+
+	if lo.ContainsBy(rbac.DefaultRoles, func(r *types.RBACRole) bool { return r.Name == name }) {
+		return ErrSystemRole
+	}
+	if !s.rbac.HasRole(name) {
+		return ErrRoleNotFound
+	}
+
+	if err := s.repo.RBAC.DeleteRole(c.GetContext(), name); err != nil {
+		if errors.Is(err, repo.ErrRoleInUse) {
+			return ErrRoleInUse
+		}
+		return server.NewHTTPInternalError("Error deleting role").SetInternal(err)
+	}
+
+	return s.applied(c, types.AuditActionRoleDeleted, "rbac_role", name, nil)
+}
+
+// ListPolicies returns all policies
+func (s *Policy) ListPolicies(c contextutil.Context) (*ListPoliciesResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.RBAC.ListPolicies(c.GetContext())
+	if err != nil {
+		return nil, server.NewHTTPInternalError("Error listing policies").SetInternal(err)
+	}
+
+	return &ListPoliciesResp{Data: data}, nil
+}
+
+// CreatePolicy grants the role to perform the action on the object
+func (s *Policy) CreatePolicy(c contextutil.Context, data CreatePolicyReq) (*types.RBACPolicy, error) {
+	if err := s.enforce(c, rbac.ActionCreateAll); err != nil {
+		return nil, err
+	}
+
+	if data.Role == rbac.RoleSuperAdmin {
+		return nil, ErrSuperAdminPolicy
+	}
+	if !s.rbac.HasRole(data.Role) {
+		return nil, ErrRoleNotFound
+	}
+	if !lo.Contains(rbac.ValidObjects, data.Object) {
+		return nil, ErrInvalidObject
+	}
+	if !lo.Contains(rbac.ValidActions, data.Action) {
+		return nil, ErrInvalidAction
+	}
+	if existed, err := s.repo.RBAC.PolicyExisted(c.GetContext(), data.Role, data.Object, data.Action); err != nil || existed {
+		return nil, ErrPolicyExisted.SetInternal(err)
+	}
+
+	rec := &types.RBACPolicy{Role: data.Role, Object: data.Object, Action: data.Action}
+	if err := s.repo.RBAC.CreatePolicy(c.GetContext(), rec); err != nil {
+		return nil, server.NewHTTPInternalError("Error creating policy").SetInternal(err)
+	}
+
+	if err := s.applied(c, types.AuditActionPolicyCreated, "rbac_policy", rec.ID, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// DeletePolicy revokes a policy
+func (s *Policy) DeletePolicy(c contextutil.Context, id string) error {
+	if err := s.enforce(c, rbac.ActionDeleteAll); err != nil {
+		return err
+	}
+
+	rec, err := s.repo.RBAC.FindPolicy(c.GetContext(), id)
+	if err != nil {
+		return ErrPolicyNotFound.SetInternal(err)
+	}
+	if rec.Role == rbac.RoleSuperAdmin {
+		return ErrSuperAdminPolicy
+	}
+
+	if err := s.repo.RBAC.DeletePolicy(c.GetContext(), id); err != nil {
+		return server.NewHTTPInternalError("Error deleting policy").SetInternal(err)
+	}
+
+	return s.applied(c, types.AuditActionPolicyDeleted, "rbac_policy", rec.ID, rec)
+}
+
+// Simulate answers if the role can perform the action on the object by the current policies
+func (s *Policy) Simulate(c contextutil.Context, data SimulateReq) (*SimulateResp, error) {
+	if err := s.enforce(c, rbac.ActionReadAll); err != nil {
+		return nil, err
+	}
+
+	if !s.rbac.HasRole(data.Role) {
+		return nil, ErrRoleNotFound
+	}
+
+	sim := s.rbac.Simulate(data.Role, data.Object, data.Action)
+	return &SimulateResp{Allowed: sim.Allowed, Roles: sim.Roles, Policies: sim.Policies}, nil
+}
+
+// applied reloads the enforcer of this instance right away, the other instances pick the change up by polling,
+// then audits the change
+func (s *Policy) applied(c contextutil.Context, action, objectType, objectID string, object interface{}) error {
+	if err := s.rbac.Reload(c.GetContext()); err != nil {
+		return server.NewHTTPInternalError("Error reloading policies").SetInternal(err)
+	}
+
+	var metadata datatypes.JSON
+	if object != nil {
+		metadata, _ = json.Marshal(object)
+	}
+	au := c.AuthUser()
+	if err := s.repo.AuditEvent.Create(c.GetContext(), &types.AuditEvent{
+		UserID:     au.ID,
+		ActorID:    au.ID,
+		Action:     action,
+		ObjectType: objectType,
+		ObjectID:   objectID,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   metadata,
+	}); err != nil {
+		return server.NewHTTPInternalError("Error auditing policy change").SetInternal(err)
+	}
+
+	return nil
+}
+
+// enforce checks user permission to perform the action
+func (s *Policy) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectRBAC, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+var superadmin = fakeContext{au: &types.AuthUser{ID: "01HSUPERADMIN00000000000000", Role: rbac.RoleSuperAdmin}}
+
+// TestChangesRefused proves the system roles and the superadmin policies cannot be changed and the admins cannot change anything,
+// nothing is stored then
+func TestChangesRefused(t *testing.T) {
+	admin := fakeContext{au: &types.AuthUser{ID: "01HADMIN0000000000000000000", Role: rbac.RoleAdmin}}
+
+	cases := []struct {
+		name string
+		op   func(s *Policy) error
+		want error
+	}{
+		{name: "delete the user role", op: deleteRole(superadmin, rbac.RoleUser), want: ErrSystemRole},
+		{name: "delete the admin role", op: deleteRole(superadmin, rbac.RoleAdmin), want: ErrSystemRole},
+		{name: "delete the superadmin role", op: deleteRole(superadmin, rbac.RoleSuperAdmin), want: ErrSystemRole},
+		{name: "delete an unknown role", op: deleteRole(superadmin, "unknown"), want: ErrRoleNotFound},
+		{name: "delete a role assigned to users", op: deleteRole(superadmin, "assigned"), want: ErrRoleInUse},
+		{name: "delete a role inherited by another role", op: deleteRole(superadmin, "auditor"), want: ErrRoleInUse},
+		{name: "grant the superadmin role", op: createPolicy(superadmin, rbac.RoleSuperAdmin, rbac.ObjectRBAC, rbac.ActionReadAll), want: ErrSuperAdminPolicy},
+		{name: "grant an unknown role", op: createPolicy(superadmin, "unknown", rbac.ObjectUser, rbac.ActionRead), want: ErrRoleNotFound},
+		{name: "grant an invalid object", op: createPolicy(superadmin, "auditor", "plaid_link", rbac.ActionRead), want: ErrInvalidObject},
+		{name: "grant an invalid action", op: createPolicy(superadmin, "auditor", rbac.ObjectUser, "approve"), want: ErrInvalidAction},
+		{name: "grant an existing policy", op: createPolicy(superadmin, "auditor", rbac.ObjectActivityLog, rbac.ActionReadAll), want: ErrPolicyExisted},
+		{name: "revoke a superadmin policy", op: deletePolicy(superadmin, "p-superadmin"), want: ErrSuperAdminPolicy},
+		{name: "revoke an unknown policy", op: deletePolicy(superadmin, "p-unknown"), want: ErrPolicyNotFound},
+		{name: "admin creates a role", op: func(s *Policy) error {
+			_, err := s.CreateRole(admin, CreateRoleReq{Name: "support", Parents: []string{rbac.RoleAdmin}})
+			return err
+		}, want: rbac.ErrForbiddenAction},
+		{name: "admin deletes a role", op: deleteRole(admin, "assigned"), want: rbac.ErrForbiddenAction},
+		{name: "admin grants itself", op: createPolicy(admin, rbac.RoleAdmin, rbac.ObjectRBAC, rbac.ActionAny), want: rbac.ErrForbiddenAction},
+		{name: "admin revokes a policy", op: deletePolicy(admin, "p-auditor"), want: rbac.ErrForbiddenAction},
+		{name: "admin simulates", op: func(s *Policy) error {
+			_, err := s.Simulate(admin, SimulateReq{Role: rbac.RoleUser, Object: rbac.ObjectUser, Action: rbac.ActionRead})
+			return err
+		}, want: rbac.ErrForbiddenAction},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db, policies := newTestPolicy(t)
+
+			if err := tc.op(s); !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			if len(db.writes) > 0 || policies.reloads > 0 {
+				t.Errorf("got writes %q and %d reloads", db.writes, policies.reloads)
+			}
+		})
+	}
+}
+
+// TestChangesApplied proves the accepted changes are stored, applied right away and audited
+func TestChangesApplied(t *testing.T) {
+	cases := []struct {
+		name       string
+		op         func(s *Policy) error
+		wantWrites []string
+	}{
+		{name: "create a role", op: func(s *Policy) error {
+			_, err := s.CreateRole(superadmin, CreateRoleReq{Name: "support", Parents: []string{rbac.RoleAdmin, rbac.RoleAdmin}})
+			return err
+		}, wantWrites: []string{"create rbac_roles", "create rbac_role_parents", "create rbac_revisions", "create audit_events"}},
+		{name: "delete a role", op: deleteRole(superadmin, "unused"),
+			wantWrites: []string{"delete rbac_policies", "delete rbac_role_parents", "delete rbac_roles", "create rbac_revisions", "create audit_events"}},
+		{name: "grant a policy", op: createPolicy(superadmin, "auditor", rbac.ObjectLockout, rbac.ActionReadAll),
+			wantWrites: []string{"create rbac_policies", "create rbac_revisions", "create audit_events"}},
+		{name: "revoke a policy", op: deletePolicy(superadmin, "p-auditor"),
+			wantWrites: []string{"delete rbac_policies", "create rbac_revisions", "create audit_events"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db, policies := newTestPolicy(t)
+
+			if err := tc.op(s); err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if !reflect.DeepEqual(db.writes, tc.wantWrites) || policies.reloads != 1 {
+				t.Errorf("got writes %q and %d reloads, want %q and the change applied", db.writes, policies.reloads, tc.wantWrites)
+			}
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	s, db, _ := newTestPolicy(t)
+
+	cases := []struct {
+		name     string
+		req      SimulateReq
+		want     *SimulateResp
+		wantErr  error
+		policies [][3]string
+	}{
+		{
+			name:     "granted to the role",
+			req:      SimulateReq{Role: "auditor", Object: rbac.ObjectActivityLog, Action: rbac.ActionReadAll},
+			want:     &SimulateResp{Allowed: true, Roles: []string{"auditor"}},
+			policies: [][3]string{{"auditor", rbac.ObjectActivityLog, rbac.ActionReadAll}},
+		},
+		{
+			name:     "granted to an inherited role",
+			req:      SimulateReq{Role: "lead_auditor", Object: rbac.ObjectActivityLog, Action: rbac.ActionReadAll},
+			want:     &SimulateResp{Allowed: true, Roles: []string{"lead_auditor", "auditor"}},
+			policies: [][3]string{{"auditor", rbac.ObjectActivityLog, rbac.ActionReadAll}},
+		},
+		{
+			name: "not granted",
+			req:  SimulateReq{Role: "auditor", Object: rbac.ObjectUser, Action: rbac.ActionDeleteAll},
+			want: &SimulateResp{Roles: []string{"auditor"}},
+		},
+		{
+			name:    "unknown role",
+			req:     SimulateReq{Role: "unknown", Object: rbac.ObjectUser, Action: rbac.ActionRead},
+			wantErr: ErrRoleNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Simulate(superadmin, tc.req)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			policies := [][3]string{}
+			for _, p := range got.Policies {
+				policies = append(policies, [3]string{p.Role, p.Object, p.Action})
+			}
+			if tc.policies == nil {
+				tc.policies = [][3]string{}
+			}
+			if got.Allowed != tc.want.Allowed || !reflect.DeepEqual(got.Roles, tc.want.Roles) || !reflect.DeepEqual(policies, tc.policies) {
+				t.Errorf("got %+v with policies %q, want %+v with %q", got, policies, tc.want, tc.policies)
+			}
+		})
+	}
+
+	if len(db.writes) > 0 {
+		t.Errorf("got writes %q", db.writes)
+	}
+}
+
+func deleteRole(c fakeContext, name string) func(s *Policy) error {
+	return func(s *Policy) error {
+		return s.DeleteRole(c, name)
+	}
+}
+
+func createPolicy(c fakeContext, role, object, action string) func(s *Policy) error {
+	return func(s *Policy) error {
+		_, err := s.CreatePolicy(c, CreatePolicyReq{Role: role, Object: object, Action: action})
+		return err
+	}
+}
+
+func deletePolicy(c fakeContext, id string) func(s *Policy) error {
+	return func(s *Policy) error {
+		return s.DeletePolicy(c, id)
+	}
+}
+
+// fakePolicies serves the default roles and policies along with the custom roles of the test:
+// the auditor role granted to read all activity logs and inherited by the lead_auditor role,
+// the assigned role assigned to users and the unused role
+type fakePolicies struct {
+	reloads int
+}
+
+var customRoles = []*types.RBACRole{
+	{Name: "auditor", Parents: []string{}},
+	{Name: "lead_auditor", Parents: []string{"auditor"}},
+	{Name: "assigned", Parents: []string{rbac.RoleUser}},
+	{Name: "unused", Parents: []string{}},
+}
+
+func (f *fakePolicies) Revision(context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakePolicies) ListRoles(context.Context) ([]*types.RBACRole, error) {
+	f.reloads++
+	return append(append([]*types.RBACRole{}, rbac.DefaultRoles...), customRoles...), nil
+}
+
+func (f *fakePolicies) ListPolicies(context.Context) ([]*types.RBACPolicy, error) {
+	policies := []*types.RBACPolicy{{Base: types.Base{ID: "p-auditor"}, Role: "auditor", Object: rbac.ObjectActivityLog, Action: rbac.ActionReadAll}}
+	for i, p := range rbac.DefaultPolicies {
+		policies = append(policies, &types.RBACPolicy{Base: types.Base{ID: fmt.Sprintf("p-default-%d", i)}, Role: p[0], Object: p[1], Action: p[2]})
+	}
+	return policies, nil
+}
+
+// fakeRBACDB answers the dry run statements from the roles and the policies of fakePolicies, it records the writes
+type fakeRBACDB struct {
+	writes []string
+}
+
+func newTestPolicy(t *testing.T) (*Policy, *fakeRBACDB, *fakePolicies) {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeRBACDB{}
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Register("test:query", f.query),
+		gdb.Callback().Create().After("gorm:create").Register("test:create", f.write("create")),
+		gdb.Callback().Delete().After("gorm:delete").Register("test:delete", f.write("delete")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policies := &fakePolicies{}
+	rbacSvc, err := rbac.New(policies, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies.reloads = 0
+
+	return New(repo.New(gdb), rbacSvc), f, policies
+}
+
+func (f *fakeRBACDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	vars := whereVars(db.Statement)
+	switch dest := db.Statement.Dest.(type) {
+	case *types.RBACPolicy:
+		all, _ := (&fakePolicies{}).ListPolicies(context.Background())
+		all = append(all, &types.RBACPolicy{Base: types.Base{ID: "p-superadmin"}, Role: rbac.RoleSuperAdmin, Object: rbac.ObjectAny, Action: rbac.ActionAny})
+		for _, p := range all {
+			if len(vars) == 1 && vars[0] == p.ID {
+				*dest = *p
+				db.RowsAffected = 1
+				return
+			}
+		}
+		db.AddError(gorm.ErrRecordNotFound)
+	case *int64:
+		var count int64
+		switch db.Statement.Table {
+		case "users":
+			if vars[0] == "assigned" {
+				count = 1
+			}
+		case "rbac_role_parents":
+			for _, role := range customRoles {
+				for _, parent := range role.Parents {
+					if parent == vars[0] {
+						count++
+					}
+				}
+			}
+		case "rbac_policies":
+			all, _ := (&fakePolicies{}).ListPolicies(context.Background())
+			for _, p := range all {
+				if p.Role == vars[0] && p.Object == vars[1] && p.Action == vars[2] {
+					count++
+				}
+			}
+		}
+		*dest, db.RowsAffected = count, 1
+	}
+}
+
+func (f *fakeRBACDB) write(kind string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		f.writes = append(f.writes, kind+" "+db.Statement.Table)
+		db.RowsAffected = 1
+	}
+}
+
+// whereVars returns the vars of the where expressions of the statement, the soft delete condition aside
+func whereVars(stmt *gorm.Statement) []interface{} {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	vars := []interface{}{}
+	for _, expr := range where.Exprs {
+		if e, ok := expr.(clause.Expr); ok {
+			vars = append(vars, e.Vars...)
+		}
+	}
+	return vars
+}
+
+type fakeContext struct {
+	au *types.AuthUser
+}
+
+func (fakeContext) GetContext() context.Context { return context.Background() }
+func (c fakeContext) AuthUser() *types.AuthUser { return c.au }
+func (fakeContext) RealIP() string              { return "10.0.0.1" }
+func (fakeContext) UserAgent() string           { return "test" }
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"context"
+
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+
+	gramrbac "github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new policy application service
+func New(repo *repo.Service, rbacSvc RBAC) *Policy {
+	return &Policy{repo: repo, rbac: rbacSvc}
+}
+
+// Policy represents the roles and the policies application service
+type Policy struct {
+	repo *repo.Service
+	rbac RBAC
+}
+
+// RBAC represents role based access control interface
+type RBAC interface {
+	gramrbac.Intf
+	HasRole(role string) bool
+	Reload(ctx context.Context) error
+	Simulate(role, object, action string) *rbac.Simulation
+}
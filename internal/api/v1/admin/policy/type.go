@@ -0,0 +1,66 @@
+package policy
+
+import "tyr/internal/types"
+
+// ListRolesResp contains the roles
+// swagger:model
+type ListRolesResp struct {
+	Data []*types.RBACRole `json:"data"`
+}
+
+// CreateRoleReq contains request data to create a custom role
+// swagger:model
+type CreateRoleReq struct {
+	// Lowercase letters, digits and underscores, starting with a letter
+	// example: auditor
+	Name string `json:"name" validate:"required,min=2,max=50"`
+	// example: Reads the activity logs
+	Description string `json:"description"`
+	// The roles whose permissions are inherited
+	// example: ["user"]
+	Parents []string `json:"parents"`
+}
+
+// ListPoliciesResp contains the policies
+// swagger:model
+type ListPoliciesResp struct {
+	Data []*types.RBACPolicy `json:"data"`
+}
+
+// CreatePolicyReq contains request data to create a policy
+// swagger:model
+type CreatePolicyReq struct {
+	// example: auditor
+	Role string `json:"role" validate:"required"`
+	// `*` matches any object
+	// example: activity_log
+	Object string `json:"object" validate:"required"`
+	// `*` matches any action
+	// example: read_all
+	Action string `json:"action" validate:"required"`
+}
+
+// SimulateReq contains request data to simulate the permission of a role
+// swagger:parameters policiesSimulate
+type SimulateReq struct {
+	// in: query
+	// example: auditor
+	Role string `json:"role" query:"role" validate:"required"`
+	// in: query
+	// example: activity_log
+	Object string `json:"object" query:"object" validate:"required"`
+	// in: query
+	// example: read_all
+	Action string `json:"action" query:"action" validate:"required"`
+}
+
+// SimulateResp contains the result of the simulation
+// swagger:model
+type SimulateResp struct {
+	// Whether the role can perform the action on the object
+	Allowed bool `json:"allowed"`
+	// The role and all roles it inherits
+	Roles []string `json:"roles"`
+	// The policies granting the permission
+	Policies []*types.RBACPolicy `json:"policies"`
+}
@@ -31,12 +31,14 @@ func (s *Session) List(c contextutil.Context, req ListSessionReq) (*ListSessions
 		return nil, err
 	}
 
-	filter := map[string]any{}
-	lqc := req.ToListQueryCond([]any{filter})
+	lc, err := req.ToListCond()
+	if err != nil {
+		return nil, err
+	}
 
 	var count int64 = 0
 	data := []*types.Session{}
-	if err := s.repo.Session.ReadAllByCondition(c.GetContext(), &data, &count, lqc); err != nil {
+	if err := s.repo.Session.List(c.GetContext(), &data, &count, lc); err != nil {
 		return nil, server.NewHTTPInternalError("Error listing session").SetInternal(err)
 	}
 
@@ -56,6 +58,13 @@ func (s *Session) Update(c contextutil.Context, id string, data UpdateSessionReq
 		return nil, server.NewHTTPInternalError("error reading session").SetInternal(err)
 	}
 
+	// deny the outstanding access tokens of the blocked session
+	if data.IsBlocked != nil && *data.IsBlocked {
+		if err := s.denylist.RevokeSessions(c.GetContext(), id); err != nil {
+			return nil, server.NewHTTPInternalError("error revoking session").SetInternal(err)
+		}
+	}
+
 	return s.Read(c, id)
 }
 
@@ -69,7 +78,11 @@ func (s *Session) Delete(c contextutil.Context, id string) error {
 		return ErrSessionNotFound.SetInternal(err)
 	}
 
-	return s.repo.Session.Delete(c.GetContext(), id)
+	if err := s.repo.Session.Delete(c.GetContext(), id); err != nil {
+		return server.NewHTTPInternalError("error deleting session").SetInternal(err)
+	}
+
+	return s.denylist.RevokeSessions(c.GetContext(), id)
 }
 
 // enforce checks user permission to perform the action
@@ -1,7 +1,9 @@
 package session
 
 import (
+	"tyr/internal/repo"
 	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
 
 	requestutil "github.com/M15t/gram/pkg/util/request"
 )
@@ -16,6 +18,29 @@ type UpdateSessionReq struct {
 // swagger:parameters sessionsList
 type ListSessionReq struct {
 	requestutil.ListQueryRequest
+	// Filter expression, conditions are separated by comma and combined by AND.
+	// Supported operators: =, !=, >, >=, <, <=, ~ (contains), !~ (not contains), between (from..to), in (a|b|c).
+	// Values containing commas must be double-quoted, eg: `user_id=01HX...,is_blocked=false,expires_at>2026-01-01T00:00:00Z`
+	// in: query
+	Filter string `json:"filter,omitempty" query:"filter"`
+}
+
+// ToListCond transforms the service request to repo conditions
+func (lq *ListSessionReq) ToListCond() (*requestutil.ListCondition[repo.SessionsFilter], error) {
+	query, err := filterutil.ParseList(lq.Filter, lq.Sort, repo.SessionFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requestutil.ListCondition[repo.SessionsFilter]{
+		Page:    lq.Page,
+		PerPage: lq.PerPage,
+		Sort:    lq.Sort,
+		Count:   true,
+		Filter: repo.SessionsFilter{
+			Query: query,
+		},
+	}, nil
 }
 
 // ListSessionsResp contains list of paginated users and total numbers after filtered
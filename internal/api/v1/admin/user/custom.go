@@ -8,7 +8,10 @@ import (
 
 // Custom errors
 var (
-	ErrIncorrectPassword = server.NewHTTPError(http.StatusBadRequest, "INCORRECT_PASSWORD", "Incorrect old password")
-	ErrUserNotFound      = server.NewHTTPError(http.StatusBadRequest, "USER_NOTFOUND", "User not found")
-	ErrEmailExisted      = server.NewHTTPValidationError("Email already existed")
+	ErrUserNotFound     = server.NewHTTPError(http.StatusBadRequest, "USER_NOTFOUND", "User not found")
+	ErrEmailExisted     = server.NewHTTPValidationError("Email already existed")
+	ErrSelfStatusChange = server.NewHTTPError(http.StatusBadRequest, "SELF_STATUS_CHANGE", "You cannot change the status of yourself")
+	ErrWeakPassword     = server.NewHTTPError(http.StatusBadRequest, "WEAK_PASSWORD", "The password does not satisfy the password policy")
+	ErrInvalidRole      = server.NewHTTPValidationError("Invalid role")
+	ErrRoleNotGranted   = server.NewHTTPError(http.StatusForbidden, "ROLE_NOT_GRANTED", "You cannot manage a role you do not have")
 )
@@ -4,14 +4,11 @@ import (
 	"net/http"
 	"strings"
 
-	"tyr/internal/rbac"
 	"tyr/internal/types"
 
-	"github.com/M15t/gram/pkg/server"
 	httputil "github.com/M15t/gram/pkg/util/http"
 
 	"github.com/labstack/echo/v4"
-	"github.com/samber/lo"
 
 	contextutil "tyr/internal/api/context"
 )
@@ -28,10 +25,7 @@ type Service interface {
 	Read(contextutil.Context, string) (*types.User, error)
 	List(contextutil.Context, ListUserReq) (*ListUsersResp, error)
 	Update(contextutil.Context, string, UpdateUserReq) (*types.User, error)
-	Delete(contextutil.Context, string) error
-
-	Me(contextutil.Context) (*types.User, error)
-	ChangePassword(contextutil.Context, ChangePasswordReq) error
+	Delete(contextutil.Context, string, DeleteUserReq) error
 }
 
 // NewHTTP attaches handlers to Echo routers under given group
@@ -96,6 +90,7 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	// swagger:operation PATCH /v1/admin/users/{id} admin-users usersUpdate
 	// ---
 	// summary: Updates user information
+	// description: Blocking the user revokes all sessions of the user, the status change is audited with the reason
 	// parameters:
 	// - name: id
 	//   in: path
@@ -122,53 +117,26 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	// swagger:operation DELETE /v1/admin/users/{id} admin-users usersDelete
 	// ---
 	// summary: Deletes an user
+	// description: The user is transitioned to the `deleted` status and soft-deleted, all sessions of the user are revoked
 	// parameters:
 	// - name: id
 	//   in: path
 	//   description: id of user
 	//   type: string
 	//   required: true
-	// responses:
-	//   "204":
-	//     "$ref": "#/responses/ok"
-	//   default:
-	//     description: 'Possible errors: 401, 403, 404, 500'
-	//     schema:
-	//       "$ref": "#/definitions/ErrorResponse"
-	eg.DELETE("/:id", h.delete)
-
-	// swagger:operation GET /v1/admin/users/me admin-users usersMe
-	// ---
-	// summary: Returns authenticated user
-	// responses:
-	//   "200":
-	//     description: Authenticated user
-	//     schema:
-	//       "$ref": "#/definitions/User"
-	//   default:
-	//     description: 'Possible errors: 401, 500'
-	//     schema:
-	//       "$ref": "#/definitions/ErrorResponse"
-	eg.GET("/me", h.me)
-
-	// swagger:operation PATCH /v1/admin/users/me/password admin-users usersChangePwd
-	// ---
-	// summary: Changes authenticated user password
-	// parameters:
 	// - name: request
 	//   in: body
 	//   description: Request body
-	//   required: true
 	//   schema:
-	//     "$ref": "#/definitions/ChangePasswordReq"
+	//     "$ref": "#/definitions/DeleteUserReq"
 	// responses:
 	//   "204":
 	//     "$ref": "#/responses/ok"
 	//   default:
-	//     description: 'Possible errors: 400, 401, 500'
+	//     description: 'Possible errors: 401, 403, 404, 500'
 	//     schema:
 	//       "$ref": "#/definitions/ErrorResponse"
-	eg.PATCH("/me/password", h.changePassword)
+	eg.DELETE("/:id", h.delete)
 }
 
 func (h *HTTP) create(c echo.Context) error {
@@ -182,11 +150,6 @@ func (h *HTTP) create(c echo.Context) error {
 	r.Phone = strings.TrimSpace(strings.Replace(r.Phone, " ", "", -1))
 	r.Role = strings.TrimSpace(r.Role)
 
-	// validation role
-	if !lo.Contains(rbac.ValidRoles, r.Role) {
-		return server.NewHTTPValidationError("Invalid role")
-	}
-
 	resp, err := h.svc.Create(contextutil.NewContext(c), r)
 	if err != nil {
 		return err
@@ -237,11 +200,7 @@ func (h *HTTP) update(c echo.Context) error {
 	r.LastName = httputil.TrimSpacePointer(r.LastName)
 	r.Phone = httputil.RemoveSpacePointer(r.Phone)
 	r.Role = httputil.RemoveSpacePointer(r.Role)
-
-	// validation role
-	if r.Role != nil && !lo.Contains(rbac.ValidRoles, *r.Role) {
-		return server.NewHTTPValidationError("Invalid role")
-	}
+	r.Reason = httputil.TrimSpacePointer(r.Reason)
 
 	resp, err := h.svc.Update(contextutil.NewContext(c), id, r)
 	if err != nil {
@@ -256,28 +215,13 @@ func (h *HTTP) delete(c echo.Context) error {
 	if err != nil {
 		return err
 	}
-	if err := h.svc.Delete(contextutil.NewContext(c), id); err != nil {
-		return err
-	}
-
-	return c.NoContent(http.StatusNoContent)
-}
-
-func (h *HTTP) me(c echo.Context) error {
-	resp, err := h.svc.Me(contextutil.NewContext(c))
-	if err != nil {
-		return err
-	}
-
-	return c.JSON(http.StatusOK, resp)
-}
-
-func (h *HTTP) changePassword(c echo.Context) error {
-	r := ChangePasswordReq{}
+	r := DeleteUserReq{}
 	if err := c.Bind(&r); err != nil {
 		return err
 	}
-	if err := h.svc.ChangePassword(contextutil.NewContext(c), r); err != nil {
+	r.Reason = strings.TrimSpace(r.Reason)
+
+	if err := h.svc.Delete(contextutil.NewContext(c), id, r); err != nil {
 		return err
 	}
 
@@ -1,21 +1,33 @@
 package user
 
 import (
+	"context"
+
 	"tyr/internal/repo"
+	"tyr/internal/types"
 
 	"github.com/M15t/gram/pkg/rbac"
 )
 
 // New creates new user application service
-func New(repo *repo.Service, rbacSvc rbac.Intf, cr Crypter) *User {
-	return &User{repo: repo, rbac: rbacSvc, cr: cr}
+func New(repo *repo.Service, rbacSvc RBAC, cr Crypter, password PasswordPolicy, denylist Denylist) *User {
+	return &User{repo: repo, rbac: rbacSvc, cr: cr, password: password, denylist: denylist}
 }
 
 // User represents user application service
 type User struct {
-	repo *repo.Service
-	rbac rbac.Intf
-	cr   Crypter
+	repo     *repo.Service
+	rbac     RBAC
+	cr       Crypter
+	password PasswordPolicy
+	denylist Denylist
+}
+
+// RBAC represents role based access control interface
+type RBAC interface {
+	rbac.Intf
+	HasRole(role string) bool
+	InheritsRole(role, parent string) bool
 }
 
 // Crypter represents security interface
@@ -23,3 +35,14 @@ type Crypter interface {
 	CompareHashAndPassword(string, string) bool
 	HashPassword(string) string
 }
+
+// PasswordPolicy represents password policy interface
+type PasswordPolicy interface {
+	Validate(ctx context.Context, password string, user *types.User) error
+	Remember(ctx context.Context, userID, hash string) error
+}
+
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeSessions(ctx context.Context, sessionIDs ...string) error
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"tyr/internal/repo"
 	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
 
 	requestutil "github.com/M15t/gram/pkg/util/request"
 )
@@ -14,9 +15,11 @@ type CreateUserReq struct {
 	LastName  string `json:"last_name" validate:"required"`
 	Email     string `json:"email" validate:"required,email"`
 	Phone     string `json:"phone" validate:"required,phone"`
-	Password  string `json:"password" validate:"required,min=8"`
+	Password  string `json:"password" validate:"required"` // must satisfy the password policy
 	Role      string `json:"role" validate:"required"`
-	Status    string `json:"status"`
+	// The user is active if empty
+	// example: active
+	Status types.UserStatus `json:"status" validate:"omitempty,oneof=active blocked"`
 }
 
 // UpdateUserReq contains request data to update existing user
@@ -27,21 +30,31 @@ type UpdateUserReq struct {
 	Email     *string `json:"email,omitempty" validate:"omitempty,email"`
 	Phone     *string `json:"phone,omitempty" validate:"omitempty,phone"`
 	Role      *string `json:"role,omitempty"`
-	Status    *string `json:"status,omitempty"`
+	// Users are deleted by the delete endpoint
+	// example: blocked
+	Status *types.UserStatus `json:"status,omitempty" validate:"omitempty,oneof=active blocked"`
+	// Reason of the status change, kept in the audit trail
+	Reason *string `json:"reason,omitempty"`
 }
 
-// ChangePasswordReq contains request data to change user password
+// DeleteUserReq contains request data to delete existing user
 // swagger:model
-type ChangePasswordReq struct {
-	OldPassword        string `json:"old_password" validate:"required"`
-	NewPassword        string `json:"new_password" validate:"required,min=8"`
-	NewPasswordConfirm string `json:"new_password_confirm" validate:"required,eqfield=NewPassword"`
+type DeleteUserReq struct {
+	// Reason of the deletion, kept in the audit trail
+	Reason string `json:"reason,omitempty"`
 }
 
 // ListUserReq contains request data to get list of users
 // swagger:parameters usersList
 type ListUserReq struct {
 	requestutil.ListQueryRequest
+	// Filter expression, conditions are separated by comma and combined by AND.
+	// Supported operators: =, !=, >, >=, <, <=, ~ (contains), !~ (not contains), between (from..to), in (a|b|c).
+	// Values containing commas must be double-quoted, eg: `role=admin,status in active|blocked,created_at>=2026-01-01`
+	// in: query
+	Filter string `json:"filter,omitempty" query:"filter"`
+	// Part of the user full name
+	// in: query
 	Name string `json:"name,omitempty" query:"name"`
 	// Search for user(s) by name, email, or phone
 	Search string `json:"search,omitempty" query:"search"`
@@ -55,14 +68,21 @@ type ListUsersResp struct {
 }
 
 // ToListCond transforms the service request to repo conditions
-func (lq *ListUserReq) ToListCond() *requestutil.ListCondition[repo.UsersFilter] {
+func (lq *ListUserReq) ToListCond() (*requestutil.ListCondition[repo.UsersFilter], error) {
+	query, err := filterutil.ParseList(lq.Filter, lq.Sort, repo.UserFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
 	return &requestutil.ListCondition[repo.UsersFilter]{
 		Page:    lq.Page,
 		PerPage: lq.PerPage,
 		Sort:    lq.Sort,
 		Count:   true,
 		Filter: repo.UsersFilter{
+			Name:   lq.Name,
 			Search: lq.Search,
+			Query:  query,
 		},
-	}
+	}, nil
 }
@@ -1,6 +1,11 @@
 package user
 
 import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tyr/internal/passwordpolicy"
 	"tyr/internal/rbac"
 	"tyr/internal/types"
 
@@ -9,6 +14,8 @@ import (
 	contextutil "tyr/internal/api/context"
 
 	structutil "github.com/M15t/gram/pkg/util/struct"
+	"github.com/samber/lo"
+	"gorm.io/datatypes"
 )
 
 // Create creates new user
@@ -16,23 +23,38 @@ func (s *User) Create(c contextutil.Context, data CreateUserReq) (*types.User, e
 	if err := s.enforce(c, rbac.ActionCreateAll); err != nil {
 		return nil, err
 	}
+	if err := s.checkRole(c, data.Role); err != nil {
+		return nil, err
+	}
 
 	if existed, err := s.repo.User.Existed(c.GetContext(), map[string]interface{}{"email": data.Email}); err != nil || existed {
 		return nil, ErrEmailExisted.SetInternal(err)
 	}
 
+	now := time.Now()
 	rec := &types.User{
-		FirstName: data.FirstName,
-		LastName:  data.LastName,
-		Email:     data.Email,
-		Phone:     data.Phone,
-		Password:  s.cr.HashPassword(data.Password),
-		Role:      data.Role,
+		FirstName:         data.FirstName,
+		LastName:          data.LastName,
+		Email:             data.Email,
+		Phone:             data.Phone,
+		PasswordChangedAt: &now,
+		Role:              data.Role,
+		Status:            types.UserStatusActive,
 	}
+	if data.Status != "" {
+		rec.Status = data.Status
+	}
+	if err := s.password.Validate(c.GetContext(), data.Password, rec); err != nil {
+		return nil, passwordError(err)
+	}
+	rec.Password = s.cr.HashPassword(data.Password)
 
 	if err := s.repo.User.Create(c.GetContext(), rec); err != nil {
 		return nil, server.NewHTTPInternalError("error creating user").SetInternal(err)
 	}
+	if err := s.password.Remember(c.GetContext(), rec.ID, rec.Password); err != nil {
+		return nil, server.NewHTTPInternalError("error creating user").SetInternal(err)
+	}
 
 	return rec, nil
 }
@@ -57,9 +79,14 @@ func (s *User) List(c contextutil.Context, req ListUserReq) (*ListUsersResp, err
 		return nil, err
 	}
 
+	lc, err := req.ToListCond()
+	if err != nil {
+		return nil, err
+	}
+
 	var count int64 = 0
 	data := []*types.User{}
-	if err := s.repo.User.List(c.GetContext(), &data, &count, req.ToListCond()); err != nil {
+	if err := s.repo.User.List(c.GetContext(), &data, &count, lc); err != nil {
 		return nil, server.NewHTTPInternalError("Error listing user").SetInternal(err)
 	}
 
@@ -69,56 +96,126 @@ func (s *User) List(c contextutil.Context, req ListUserReq) (*ListUsersResp, err
 	}, nil
 }
 
-// Update updates user information
+// Update updates user information.
+// The status change is audited with the acting admin and the reason, the sessions of the blocked user are revoked.
 func (s *User) Update(c contextutil.Context, id string, data UpdateUserReq) (*types.User, error) {
 	if err := s.enforce(c, rbac.ActionUpdateAll); err != nil {
 		return nil, err
 	}
+	if data.Role != nil {
+		if err := s.checkRole(c, *data.Role); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := s.repo.User.Update(c.GetContext(), structutil.ToMap(data), id); err != nil {
-		return nil, server.NewHTTPInternalError("error reading user").SetInternal(err)
+	rec, err := s.Read(c, id)
+	if err != nil {
+		return nil, err
+	}
+	// the admins cannot demote the users above them either
+	if data.Role != nil && !s.rbac.InheritsRole(c.AuthUser().Role, rec.Role) {
+		return nil, ErrRoleNotGranted
+	}
+
+	updates := structutil.ToMap(data)
+	delete(updates, "status")
+	delete(updates, "reason")
+	if len(updates) > 0 {
+		if err := s.repo.User.Update(c.GetContext(), updates, id); err != nil {
+			return nil, server.NewHTTPInternalError("error updating user").SetInternal(err)
+		}
+	}
+
+	if data.Status != nil && *data.Status != rec.Status {
+		if err := s.transition(c, rec, *data.Status, lo.FromPtr(data.Reason)); err != nil {
+			return nil, err
+		}
 	}
 
 	return s.Read(c, id)
 }
 
-// Delete deletes user by id
-func (s *User) Delete(c contextutil.Context, id string) error {
+// Delete deletes user by id.
+// The user is transitioned to the deleted status and soft-deleted, audited with the acting admin and the reason.
+func (s *User) Delete(c contextutil.Context, id string, data DeleteUserReq) error {
 	if err := s.enforce(c, rbac.ActionDeleteAll); err != nil {
 		return err
 	}
 
-	if existed, err := s.repo.User.Existed(c.GetContext(), id); err != nil || !existed {
+	rec := &types.User{}
+	if err := s.repo.User.ReadByID(c.GetContext(), rec, id); err != nil {
 		return ErrUserNotFound.SetInternal(err)
 	}
 
-	return s.repo.User.Delete(c.GetContext(), id)
+	return s.transition(c, rec, types.UserStatusDeleted, data.Reason)
 }
 
-// Me returns current authenticated user
-func (s *User) Me(c contextutil.Context) (*types.User, error) {
-	rec := &types.User{}
-	if err := s.repo.User.ReadByID(c.GetContext(), rec, c.AuthUser().ID); err != nil {
-		return nil, server.NewHTTPInternalError("error reading user").SetInternal(err)
+// transition changes the status of the user, the user who is no longer active is logged out everywhere.
+// The admins cannot change their own status.
+func (s *User) transition(c contextutil.Context, rec *types.User, status types.UserStatus, reason string) error {
+	au := c.AuthUser()
+	if rec.ID == au.ID {
+		return ErrSelfStatusChange
 	}
 
-	return rec, nil
-}
+	if err := s.repo.User.SetStatus(c.GetContext(), rec.ID, status); err != nil {
+		return server.NewHTTPInternalError("error changing user status").SetInternal(err)
+	}
 
-// ChangePassword changes user password
-func (s *User) ChangePassword(c contextutil.Context, data ChangePasswordReq) error {
-	rec, err := s.Me(c)
-	if err != nil {
-		return err
+	if status != types.UserStatusActive {
+		revokedIDs, err := s.repo.Session.RevokeAllByUserID(c.GetContext(), rec.ID)
+		if err != nil {
+			return server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+		}
+		if err := s.denylist.RevokeSessions(c.GetContext(), revokedIDs...); err != nil {
+			return server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+		}
+	}
+
+	metadata, _ := json.Marshal(map[string]types.UserStatus{"from": rec.Status, "to": status})
+	if err := s.repo.AuditEvent.Create(c.GetContext(), &types.AuditEvent{
+		UserID:     rec.ID,
+		ActorID:    au.ID,
+		Action:     statusAuditActions[status],
+		ObjectType: "user",
+		ObjectID:   rec.ID,
+		Reason:     reason,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   datatypes.JSON(metadata),
+	}); err != nil {
+		return server.NewHTTPInternalError("error auditing user status").SetInternal(err)
 	}
 
-	if !s.cr.CompareHashAndPassword(rec.Password, data.OldPassword) {
-		return ErrIncorrectPassword
+	return nil
+}
+
+// statusAuditActions maps the statuses to the audit actions of the transitions to them
+var statusAuditActions = map[types.UserStatus]string{
+	types.UserStatusActive:  types.AuditActionUserActivated,
+	types.UserStatusBlocked: types.AuditActionUserBlocked,
+	types.UserStatusDeleted: types.AuditActionUserDeleted,
+}
+
+// passwordError maps the password policy violations to the http errors
+func passwordError(err error) error {
+	var violation *passwordpolicy.ViolationError
+	if errors.As(err, &violation) {
+		return server.NewHTTPError(ErrWeakPassword.Code, ErrWeakPassword.Type, violation.Error())
 	}
+	return server.NewHTTPInternalError("error checking password").SetInternal(err)
+}
 
-	return s.repo.User.Update(c.GetContext(), &types.User{
-		Password: s.cr.HashPassword(data.NewPassword),
-	}, rec.ID)
+// checkRole checks the role exists and is the role of the acting admin or one it inherits,
+// so that the admins cannot grant a role above their own
+func (s *User) checkRole(c contextutil.Context, role string) error {
+	if !s.rbac.HasRole(role) {
+		return ErrInvalidRole
+	}
+	if !s.rbac.InheritsRole(c.AuthUser().Role, role) {
+		return ErrRoleNotGranted
+	}
+	return nil
 }
 
 // enforce checks user permission to perform the action
@@ -0,0 +1,85 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/samber/lo"
+)
+
+// TestRoleChecks proves the admins can only grant the existing roles they have, before anything is stored
+func TestRoleChecks(t *testing.T) {
+	s := New(nil, fakeRBAC{}, nil, nil, nil)
+
+	cases := []struct {
+		actor string
+		role  string
+		want  error
+	}{
+		{actor: rbac.RoleAdmin, role: rbac.RoleUser},
+		{actor: rbac.RoleAdmin, role: rbac.RoleAdmin},
+		{actor: rbac.RoleAdmin, role: rbac.RoleSuperAdmin, want: ErrRoleNotGranted},
+		{actor: rbac.RoleAdmin, role: "auditor", want: ErrRoleNotGranted},
+		{actor: rbac.RoleSuperAdmin, role: "auditor", want: ErrRoleNotGranted},
+		{actor: rbac.RoleSuperAdmin, role: rbac.RoleSuperAdmin},
+		{actor: rbac.RoleSuperAdmin, role: "missing", want: ErrInvalidRole},
+	}
+
+	for _, tc := range cases {
+		c := fakeContext{au: &types.AuthUser{ID: "01HADMIN0000000000000000000", Role: tc.actor}}
+		if err := s.checkRole(c, tc.role); err != tc.want {
+			t.Errorf("%s granting %s got %v, want %v", tc.actor, tc.role, err, tc.want)
+		}
+		if tc.want == nil {
+			continue
+		}
+
+		if _, err := s.Create(c, CreateUserReq{Email: "new@tyr.io", Role: tc.role}); err != tc.want {
+			t.Errorf("%s creating %s got %v, want %v", tc.actor, tc.role, err, tc.want)
+		}
+		if _, err := s.Update(c, "01HUSER00000000000000000000", UpdateUserReq{Role: lo.ToPtr(tc.role)}); err != tc.want {
+			t.Errorf("%s updating to %s got %v, want %v", tc.actor, tc.role, err, tc.want)
+		}
+	}
+}
+
+// fakeRBAC allows every action with the default roles and an auditor role inheriting the admin one
+type fakeRBAC struct{}
+
+var fakeParents = map[string][]string{
+	rbac.RoleUser:       {},
+	rbac.RoleAdmin:      {rbac.RoleUser},
+	rbac.RoleSuperAdmin: {rbac.RoleAdmin},
+	"auditor":           {rbac.RoleAdmin},
+}
+
+func (fakeRBAC) Enforce(...interface{}) bool { return true }
+
+func (fakeRBAC) HasRole(role string) bool {
+	_, ok := fakeParents[role]
+	return ok
+}
+
+func (r fakeRBAC) InheritsRole(role, parent string) bool {
+	if role == parent {
+		return true
+	}
+	for _, p := range fakeParents[role] {
+		if r.InheritsRole(p, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeContext struct {
+	au *types.AuthUser
+}
+
+func (fakeContext) GetContext() context.Context { return context.Background() }
+func (c fakeContext) AuthUser() *types.AuthUser { return c.au }
+func (fakeContext) RealIP() string              { return "10.0.0.1" }
+func (fakeContext) UserAgent() string           { return "test" }
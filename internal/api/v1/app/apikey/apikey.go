@@ -0,0 +1,136 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/apikey"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/samber/lo"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// List returns the API keys of the current user which are not revoked, the newest first
+func (s *APIKey) List(c contextutil.Context) (*ListAPIKeysResp, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.APIKey.ListByUserID(c.GetContext(), c.AuthUser().ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing API keys").SetInternal(err)
+	}
+
+	return &ListAPIKeysResp{Data: data}, nil
+}
+
+// Create creates an API key of the current user, in the organization switched to if any.
+// The token is only returned once, only its hash is stored.
+func (s *APIKey) Create(c contextutil.Context, data CreateAPIKeyReq) (*CreateAPIKeyResp, error) {
+	if err := s.enforce(c, rbac.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	au, ctx, now := c.AuthUser(), c.GetContext(), time.Now()
+	expiresAt := now.Add(s.defaultTTL)
+	if data.ExpiresAt != nil {
+		if !data.ExpiresAt.After(now) || data.ExpiresAt.After(now.Add(s.maxTTL)) {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt = *data.ExpiresAt
+	}
+
+	if s.maxPerUser > 0 {
+		count, err := s.repo.APIKey.CountActiveByUserID(ctx, au.ID, now)
+		if err != nil {
+			return nil, server.NewHTTPInternalError("error counting API keys").SetInternal(err)
+		}
+		if count >= int64(s.maxPerUser) {
+			return nil, ErrTooManyAPIKeys
+		}
+	}
+
+	token, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error generating API key").SetInternal(err)
+	}
+
+	rec := &types.APIKey{
+		UserID:         au.ID,
+		OrganizationID: lo.EmptyableToPtr(au.OrganizationID),
+		Name:           data.Name,
+		Prefix:         prefix,
+		TokenHash:      hash,
+		Scopes:         datatypes.NewJSONSlice(lo.Uniq(data.Scopes)),
+		ExpiresAt:      expiresAt,
+	}
+	if err := s.repo.APIKey.Create(ctx, rec); err != nil {
+		return nil, server.NewHTTPInternalError("error creating API key").SetInternal(err)
+	}
+
+	if err := s.audit(c, au.ID, types.AuditActionAPIKeyCreated, rec, map[string]interface{}{"scopes": rec.Scopes, "expires_at": rec.ExpiresAt}); err != nil {
+		return nil, server.NewHTTPInternalError("error recording audit event").SetInternal(err)
+	}
+
+	return &CreateAPIKeyResp{APIKey: rec, Token: token}, nil
+}
+
+// Revoke revokes an API key of the current user, the requests with its token are rejected right away
+func (s *APIKey) Revoke(c contextutil.Context, id string) error {
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return err
+	}
+
+	rec := &types.APIKey{}
+	ctx := c.GetContext()
+	if err := s.repo.APIKey.Read(ctx, rec, map[string]interface{}{"id": id, "user_id": c.AuthUser().ID}); err != nil {
+		return ErrAPIKeyNotFound.SetInternal(err)
+	}
+
+	if err := s.repo.APIKey.Revoke(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPIKeyNotFound.SetInternal(err)
+		}
+		return server.NewHTTPInternalError("error revoking API key").SetInternal(err)
+	}
+
+	if err := s.audit(c, rec.UserID, types.AuditActionAPIKeyRevoked, rec, nil); err != nil {
+		return server.NewHTTPInternalError("error recording audit event").SetInternal(err)
+	}
+
+	return nil
+}
+
+// audit records an audit event about the API key of the user
+func (s *APIKey) audit(c contextutil.Context, userID, action string, rec *types.APIKey, metadata map[string]interface{}) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["name"], metadata["prefix"] = rec.Name, rec.Prefix
+	meta, _ := json.Marshal(metadata)
+
+	return s.repo.AuditEvent.Create(c.GetContext(), &types.AuditEvent{
+		UserID:     userID,
+		Action:     action,
+		ObjectType: "api_key",
+		ObjectID:   rec.ID,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   meta,
+	})
+}
+
+// enforce checks user permission to perform the action
+func (s *APIKey) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectAPIKey, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
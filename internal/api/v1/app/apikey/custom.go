@@ -0,0 +1,14 @@
+package apikey
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrAPIKeyNotFound = server.NewHTTPError(http.StatusBadRequest, "API_KEY_NOTFOUND", "API key not found")
+	ErrTooManyAPIKeys = server.NewHTTPError(http.StatusConflict, "TOO_MANY_API_KEYS", "You have reached the maximum number of API keys, please revoke one first")
+	ErrInvalidExpiry  = server.NewHTTPValidationError("Invalid expiry, `expires_at` must be in the future and within the maximum lifetime of the API keys")
+)
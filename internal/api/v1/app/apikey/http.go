@@ -0,0 +1,121 @@
+package apikey
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents API key http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents API key application interface
+type Service interface {
+	List(contextutil.Context) (*ListAPIKeysResp, error)
+	Create(contextutil.Context, CreateAPIKeyReq) (*CreateAPIKeyResp, error)
+	Revoke(contextutil.Context, string) error
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/app/api-keys app-api-keys appAPIKeysList
+	// ---
+	// summary: Returns the API keys of the current user which are not revoked
+	// responses:
+	//   "200":
+	//     description: List of API keys
+	//     schema:
+	//       "$ref": "#/definitions/appListAPIKeysResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation POST /v1/app/api-keys app-api-keys appAPIKeysCreate
+	// ---
+	// summary: Creates an API key of the current user for the integrations
+	// description: |
+	//   The token is only returned once. Send it as `Authorization: Bearer ${token}`, the requests are made as the current user
+	//   restricted to the scopes of the key, in the organization switched to when the key was created.
+	//   The API keys cannot manage the account, the sessions nor the API keys.
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateAPIKeyReq"
+	// responses:
+	//   "200":
+	//     description: The new API key with its token
+	//     schema:
+	//       "$ref": "#/definitions/CreateAPIKeyResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("", h.create)
+
+	// swagger:operation DELETE /v1/app/api-keys/{id} app-api-keys appAPIKeysRevoke
+	// ---
+	// summary: Revokes an API key of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of API key
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id", h.revoke)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	resp, err := h.svc.List(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) create(c echo.Context) error {
+	r := CreateAPIKeyReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Create(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) revoke(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Revoke(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
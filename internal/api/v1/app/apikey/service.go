@@ -0,0 +1,30 @@
+package apikey
+
+import (
+	"time"
+
+	"tyr/config"
+	"tyr/internal/repo"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new API key application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, cfg config.APIKey) *APIKey {
+	return &APIKey{
+		repo:       repo,
+		rbac:       rbacSvc,
+		maxPerUser: cfg.MaxPerUser,
+		defaultTTL: time.Duration(cfg.DefaultTTL) * time.Second,
+		maxTTL:     time.Duration(cfg.MaxTTL) * time.Second,
+	}
+}
+
+// APIKey represents API key application service of the current user
+type APIKey struct {
+	repo       *repo.Service
+	rbac       rbac.Intf
+	maxPerUser int
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
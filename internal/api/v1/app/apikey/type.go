@@ -0,0 +1,34 @@
+package apikey
+
+import (
+	"time"
+
+	"tyr/internal/types"
+)
+
+// CreateAPIKeyReq contains request data to create an API key
+// swagger:model
+type CreateAPIKeyReq struct {
+	// example: Accounting sync
+	Name string `json:"name" validate:"required,max=100"`
+	// What the key may access, within the permissions of the current user
+	// example: ["documents:read"]
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=documents:read documents:write expense_reports:read expense_reports:write organizations:read activity_logs:read"`
+	// Defaults to the configured lifetime of the API keys
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKeyResp contains the new API key with its token, which is only returned once
+// swagger:model
+type CreateAPIKeyResp struct {
+	*types.APIKey
+	// The bearer token of the requests, it cannot be retrieved later
+	// example: tyr_3f9a0c1d7b2e_q0Yl9v2s0ZxJ8mK4bW7nR1tE6uH3cA5dF9gI2jL8oP0
+	Token string `json:"token"`
+}
+
+// ListAPIKeysResp contains the API keys of the current user which are not revoked
+// swagger:model appListAPIKeysResp
+type ListAPIKeysResp struct {
+	Data []*types.APIKey `json:"data"`
+}
@@ -0,0 +1,208 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+	"tyr/third_party/azure"
+)
+
+const (
+	newAPIMReqID     = "apim-request-of-the-reanalysis"
+	newOperationPath = "https://azure.test/documentModels/prebuilt-receipt/analyzeResults/" + newAPIMReqID
+	newResult        = `{"status":"succeeded","lastUpdatedDateTime":"2026-10-19T09:00:00Z","analyzeResult":{"content":"Tyr Cafe","pages":[{},{}],"documents":[{"fields":{"MerchantName":{"content":"Tyr Cafe"},"Total":{"valueNumber":30}}}]}}`
+)
+
+// TestGetKeepsAnalyses proves Get stores the result as a new analysis of the document, once per analysis run,
+// and only when the caller may update the document
+func TestGetKeepsAnalyses(t *testing.T) {
+	cases := []struct {
+		name     string
+		c        *testContext
+		stored   bool
+		wantRows int
+	}{
+		{name: "uploader", c: newOrgTestContext(ownerID, types.OrgRoleMember), wantRows: 1},
+		{name: "approver", c: newOrgTestContext(otherID, types.OrgRoleApprover), wantRows: 1},
+		{name: "member", c: newOrgTestContext(otherID, types.OrgRoleMember)},
+		{name: "admin impersonating the uploader", c: impersonated(newOrgTestContext(ownerID, types.OrgRoleMember))},
+		{name: "result already stored", c: newOrgTestContext(ownerID, types.OrgRoleMember), stored: true, wantRows: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, db := newGetTestService(t)
+			if tc.stored {
+				db.analyses = []*types.DocumentAnalysis{{Base: types.Base{ID: "01HANALYSIS0000000000000000"}, DocumentID: documentID, APIMRequestID: apimReqID,
+					Status: azure.StatusSucceeded, RawResult: []byte(succeededResult)}}
+			}
+
+			if _, err := svc.Get(tc.c, apimReqID); err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if len(db.analyses) != tc.wantRows {
+				t.Fatalf("got %d analyses, want %d", len(db.analyses), tc.wantRows)
+			}
+			if tc.wantRows == 0 {
+				return
+			}
+			if a := db.analyses[0]; a.DocumentID != documentID || a.APIMRequestID != apimReqID || a.Status != azure.StatusSucceeded || len(a.RawResult) == 0 {
+				t.Errorf("got analysis %+v", a)
+			}
+		})
+	}
+}
+
+// TestReanalyzeKeepsHistory proves a reanalysis points the document to the new analysis run while the earlier analyses are kept,
+// the new result is then stored by Get as another analysis and both are listed newest first
+func TestReanalyzeKeepsHistory(t *testing.T) {
+	c := newOrgTestContext(ownerID, types.OrgRoleMember)
+	svc, db := newGetTestService(t)
+	az, receipts := &fakeAzure{}, &fakeReceipts{}
+	svc.azure, svc.receipts = az, receipts
+
+	if _, err := svc.Get(c, apimReqID); err != nil {
+		t.Fatalf("got %v", err)
+	}
+
+	db.writes = nil
+	res, err := svc.Reanalyze(c, documentID, AnalyzeDocumentReq{Document: newFileHeader(t, "receipt.pdf", "%PDF-1.4")})
+	if err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if res.APIMRequestID != newAPIMReqID || az.sent != 1 || receipts.stored != 1 {
+		t.Fatalf("got %+v, %d files sent and %d stored", res, az.sent, receipts.stored)
+	}
+	if strings.Join(db.writes, ",") != "update documents" {
+		t.Errorf("got writes %q, want the document updated only", db.writes)
+	}
+	if db.updated["apim_request_id"] != newAPIMReqID || db.updated["operation_location"] != newOperationPath ||
+		db.updated["file_name"] != "receipt.pdf" || db.updated["file_path"] != "receipts/"+documentID+"/receipt.pdf" {
+		t.Errorf("got updates %v, want the document pointed to the new analysis", db.updated)
+	}
+
+	doc, err := svc.Get(c, newAPIMReqID)
+	if err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if az.fetched != newOperationPath {
+		t.Errorf("got the result fetched from %q, want %q", az.fetched, newOperationPath)
+	}
+	if doc.APIMRequestID != newAPIMReqID {
+		t.Errorf("got the document of %q", doc.APIMRequestID)
+	}
+
+	analyses, err := svc.ListAnalyses(c, documentID)
+	if err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if len(analyses) != 2 || analyses[0].APIMRequestID != newAPIMReqID || analyses[1].APIMRequestID != apimReqID {
+		t.Fatalf("got %d analyses, want the new one then the first one", len(analyses))
+	}
+	if analyses[0].TotalPage != 2 || analyses[0].Content != "Tyr Cafe" {
+		t.Errorf("got analysis %+v, want the new result", analyses[0])
+	}
+}
+
+func TestListAnalyses(t *testing.T) {
+	analyses := []*types.DocumentAnalysis{
+		{Base: types.Base{ID: "01HANALYSIS0000000000000001"}, DocumentID: documentID, APIMRequestID: apimReqID},
+		{Base: types.Base{ID: "01HANALYSIS0000000000000002"}, DocumentID: "01HANOTHERDOCUMENT0000000000", APIMRequestID: "apim-request-of-another-document"},
+		{Base: types.Base{ID: "01HANALYSIS0000000000000003"}, DocumentID: documentID, APIMRequestID: newAPIMReqID},
+	}
+
+	cases := []struct {
+		name    string
+		c       *testContext
+		want    []string
+		wantErr error
+	}{
+		{name: "uploader", c: newOrgTestContext(ownerID, types.OrgRoleMember), want: []string{"01HANALYSIS0000000000000003", "01HANALYSIS0000000000000001"}},
+		{name: "member", c: newOrgTestContext(otherID, types.OrgRoleMember), want: []string{"01HANALYSIS0000000000000003", "01HANALYSIS0000000000000001"}},
+		{name: "out of the organization", c: newTestContext(otherID), wantErr: ErrDocumentNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, db := newGetTestService(t)
+			db.analyses = analyses
+
+			got, err := svc.ListAnalyses(tc.c, documentID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+			ids := []string{}
+			for _, a := range got {
+				ids = append(ids, a.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got %q, want %q", ids, tc.want)
+			}
+		})
+	}
+}
+
+// fakeAzure accepts every file, the analysis of which succeeds with the new result
+type fakeAzure struct {
+	sent    int
+	fetched string
+}
+
+func (f *fakeAzure) AnalyzeDocument(c contextutil.Context, modelID, apiVersion string, payload io.Reader) (*azure.ResponseHeaders, error) {
+	f.sent++
+	return &azure.ResponseHeaders{APIMRequestID: []string{newAPIMReqID}, OperationLocation: []string{newOperationPath}}, nil
+}
+
+func (f *fakeAzure) GetAnalyzeDocument(c contextutil.Context, url string) (*azure.ResultAnalyzeResponse, error) {
+	f.fetched = url
+	res := &azure.ResultAnalyzeResponse{}
+	if err := json.Unmarshal([]byte(newResult), res); err != nil {
+		return nil, err
+	}
+	res.Raw = json.RawMessage(newResult)
+	return res, nil
+}
+
+// fakeReceipts stores the receipt files nowhere
+type fakeReceipts struct {
+	stored int
+}
+
+func (f *fakeReceipts) StoreReceipt(ctx context.Context, userID, documentID, fileName string, body io.Reader, contentType string) (string, error) {
+	f.stored++
+	return "receipts/" + documentID + "/" + fileName, nil
+}
+
+// newFileHeader returns the header of a file uploaded in a multipart form
+func newFileHeader(t *testing.T, name, content string) *multipart.FileHeader {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	part, err := w.CreateFormFile("document", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return req.MultipartForm.File["document"][0]
+}
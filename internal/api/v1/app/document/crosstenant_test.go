@@ -0,0 +1,300 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	ownerID    = "01HOWNER0000000000000000000"
+	otherID    = "01HOTHER0000000000000000000"
+	documentID = "01HDOCUMENT00000000000000000"
+	orgID      = "01HORGANIZATION000000000000"
+	apimReqID  = "apim-request-of-the-owner"
+)
+
+// TestCrossTenantAccessIsDenied proves the documents of a user are not accessible by another user through any operation,
+// while the owner keeps its access
+func TestCrossTenantAccessIsDenied(t *testing.T) {
+	svc := newTestService(t)
+
+	ops := map[string]func(c *testContext) error{
+		"read": func(c *testContext) error {
+			_, err := svc.Read(c, documentID)
+			return err
+		},
+		"get": func(c *testContext) error {
+			_, err := svc.Get(c, apimReqID)
+			return err
+		},
+		"update": func(c *testContext) error {
+			vendor := "Changed"
+			_, err := svc.Update(c, documentID, UpdateDocumentReq{VendorName: &vendor})
+			return err
+		},
+		"delete": func(c *testContext) error {
+			return svc.Delete(c, documentID)
+		},
+		"reanalyze": func(c *testContext) error {
+			_, err := svc.Reanalyze(c, documentID, AnalyzeDocumentReq{})
+			return err
+		},
+		"list analyses": func(c *testContext) error {
+			_, err := svc.ListAnalyses(c, documentID)
+			return err
+		},
+		"ocr": func(c *testContext) error {
+			_, err := svc.OCR(c, documentID, OCRReq{})
+			return err
+		},
+	}
+
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			if err := op(newTestContext(otherID)); !errors.Is(err, ErrDocumentNotFound) {
+				t.Errorf("another user got %v, want ErrDocumentNotFound", err)
+			}
+		})
+	}
+
+	t.Run("read by the owner", func(t *testing.T) {
+		doc, err := svc.Read(newTestContext(ownerID), documentID)
+		if err != nil {
+			t.Fatalf("the owner got %v", err)
+		}
+		if doc.ID != documentID {
+			t.Errorf("the owner got document %q, want %q", doc.ID, documentID)
+		}
+	})
+
+	t.Run("update by the owner", func(t *testing.T) {
+		vendor := "Changed"
+		if _, err := svc.Update(newTestContext(ownerID), documentID, UpdateDocumentReq{VendorName: &vendor}); err != nil {
+			t.Errorf("the owner got %v", err)
+		}
+	})
+
+	t.Run("delete by the owner", func(t *testing.T) {
+		if err := svc.Delete(newTestContext(ownerID), documentID); err != nil {
+			t.Errorf("the owner got %v", err)
+		}
+	})
+}
+
+// TestCrossTenantListIsScoped proves the document listing of a user never contains the documents of another user
+func TestCrossTenantListIsScoped(t *testing.T) {
+	svc := newTestService(t)
+
+	for name, req := range map[string]ListDocumentReq{
+		"offset": {},
+		"cursor": {Pagination: "cursor"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := svc.List(newTestContext(otherID), req)
+			if err != nil {
+				t.Fatalf("another user got %v", err)
+			}
+			if len(resp.Data) != 0 {
+				t.Errorf("another user listed %d documents, want none", len(resp.Data))
+			}
+
+			resp, err = svc.List(newTestContext(ownerID), req)
+			if err != nil {
+				t.Fatalf("the owner got %v", err)
+			}
+			if len(resp.Data) != 1 {
+				t.Errorf("the owner listed %d documents, want 1", len(resp.Data))
+			}
+		})
+	}
+}
+
+// TestOrganizationCannotAccessPersonalDocuments proves the personal documents of a user are not shared with the organizations,
+// neither to the owner of an organization nor to the user switched to one
+func TestOrganizationCannotAccessPersonalDocuments(t *testing.T) {
+	svc := newTestService(t)
+
+	for name, c := range map[string]*testContext{
+		"organization owner":    newOrgTestContext(otherID, types.OrgRoleOwner),
+		"owner in organization": newOrgTestContext(ownerID, types.OrgRoleMember),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.Read(c, documentID); !errors.Is(err, ErrDocumentNotFound) {
+				t.Errorf("got %v, want ErrDocumentNotFound", err)
+			}
+
+			resp, err := svc.List(c, ListDocumentReq{})
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if len(resp.Data) != 0 {
+				t.Errorf("listed %d documents, want none", len(resp.Data))
+			}
+		})
+	}
+}
+
+// newTestService returns the document service on a fake database holding a single document of the owner
+func newTestService(t *testing.T) *Document {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerFakeDocuments(t, gdb)
+
+	rbacSvc, err := rbac.New(defaultPolicies{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(repo.New(gdb), rbacSvc, nil, nil, nil)
+}
+
+// registerFakeDocuments answers the dry run statements on the documents table as if it only held the document of the owner.
+// The document is visible to the statements which are not restricted to another owner,
+// so an unscoped query would leak it to everyone.
+func registerFakeDocuments(t *testing.T, gdb *gorm.DB) {
+	t.Helper()
+
+	answer := func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.Table != "documents" {
+			return
+		}
+		if !visibleToStatement(db.Statement) {
+			db.RowsAffected = 0
+			if _, ok := db.Statement.Dest.(*types.Document); ok {
+				db.AddError(gorm.ErrRecordNotFound)
+			}
+			return
+		}
+
+		db.RowsAffected = 1
+		doc := types.Document{Base: types.Base{ID: documentID}, UserID: ownerID, APIMRequestID: apimReqID}
+		switch dest := db.Statement.Dest.(type) {
+		case *int64:
+			*dest = 1
+		case *types.Document:
+			*dest = doc
+		case *[]*types.Document:
+			*dest = append(*dest, &doc)
+		}
+	}
+
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Before("gorm:preload").Register("test:documents", answer),
+		gdb.Callback().Update().After("gorm:update").Register("test:documents", answer),
+		gdb.Callback().Delete().After("gorm:delete").Register("test:documents", answer),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// visibleToStatement checks the where conditions of the statement do not exclude the document of the owner
+func visibleToStatement(stmt *gorm.Statement) bool {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return true
+	}
+
+	for _, expr := range where.Exprs {
+		switch e := expr.(type) {
+		case clause.Eq:
+			col, ok := e.Column.(clause.Column)
+			if !ok {
+				continue
+			}
+			// the document of the owner is personal, out of any organization
+			if (col.Name == "user_id" && e.Value != ownerID) || (col.Name == "organization_id" && e.Value != nil) {
+				return false
+			}
+		case clause.Expr:
+			if e.SQL == "FALSE" {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
+
+// defaultPolicies serves the roles and the policies seeded by the migration
+type defaultPolicies struct{}
+
+func (defaultPolicies) Revision(context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (defaultPolicies) ListRoles(context.Context) ([]*types.RBACRole, error) {
+	return rbac.DefaultRoles, nil
+}
+
+func (defaultPolicies) ListPolicies(context.Context) ([]*types.RBACPolicy, error) {
+	policies := make([]*types.RBACPolicy, 0, len(rbac.DefaultPolicies))
+	for _, p := range rbac.DefaultPolicies {
+		policies = append(policies, &types.RBACPolicy{Role: p[0], Object: p[1], Action: p[2]})
+	}
+	return policies, nil
+}
+
+// testContext is the context of an authenticated app user
+type testContext struct {
+	au *types.AuthUser
+}
+
+func newTestContext(userID string) *testContext {
+	return &testContext{au: &types.AuthUser{ID: userID, Role: rbac.RoleUser}}
+}
+
+func newOrgTestContext(userID, orgRole string) *testContext {
+	return &testContext{au: &types.AuthUser{ID: userID, Role: rbac.RoleUser, OrganizationID: orgID, OrgRole: orgRole}}
+}
+
+func (c *testContext) GetContext() context.Context { return context.Background() }
+func (c *testContext) AuthUser() *types.AuthUser   { return c.au }
+func (c *testContext) RealIP() string              { return "127.0.0.1" }
+func (c *testContext) UserAgent() string           { return "test" }
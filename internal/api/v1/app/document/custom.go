@@ -8,7 +8,15 @@ import (
 
 // Custom errors
 var (
-	ErrDocumentIsEmpty      = server.NewHTTPError(http.StatusBadRequest, "DOCUMENT_EMPTY", "Azure returns empty document")
-	ErrDocumentNotFound     = server.NewHTTPError(http.StatusBadRequest, "DOCUMENT_NOTFOUND", "Document not found")
-	ErrCreateTransferIntent = server.NewHTTPError(http.StatusBadRequest, "PLAID_CREATE_TRANSFER_INTENT_FAILED", "Create transfer intent failed")
+	ErrDocumentIsEmpty       = server.NewHTTPError(http.StatusBadRequest, "DOCUMENT_EMPTY", "Azure returns empty document")
+	ErrDocumentNotFound      = server.NewHTTPError(http.StatusBadRequest, "DOCUMENT_NOTFOUND", "Document not found")
+	ErrDocumentRequired      = server.NewHTTPValidationError("Document is required")
+	ErrAnalysisNotFound      = server.NewHTTPError(http.StatusBadRequest, "ANALYSIS_NOTFOUND", "Document analysis not found")
+	ErrInvalidAmountRange    = server.NewHTTPValidationError("Invalid amount range, `min_total` must not exceed `max_total`")
+	ErrInvalidDateRange      = server.NewHTTPValidationError("Invalid date range, `date_from` must not be after `date_to`")
+	ErrInvalidCursor         = server.NewHTTPValidationError("Invalid cursor, it must be taken from the previous page with the same sorting")
+	ErrSortRankWithoutSearch = server.NewHTTPValidationError("Invalid sort, `search_rank` is only sortable along with `search`")
+	ErrUnsupportedCursorSort = server.NewHTTPValidationError("Invalid sort, cursor pagination only supports sorting by `id` or `transaction_date`")
+	ErrDocumentInReport      = server.NewHTTPError(http.StatusConflict, "DOCUMENT_IN_EXPENSE_REPORT", "The document is claimed in an expense report, it cannot be changed or deleted while in the report")
+	ErrCreateTransferIntent  = server.NewHTTPError(http.StatusBadRequest, "PLAID_CREATE_TRANSFER_INTENT_FAILED", "Create transfer intent failed")
 )
@@ -4,17 +4,28 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	contextutil "tyr/internal/api/context"
 	"tyr/internal/rbac"
+	"tyr/internal/repo"
 	"tyr/internal/types"
 	"tyr/third_party/azure"
 
 	"github.com/M15t/gram/pkg/server"
 	structutil "github.com/M15t/gram/pkg/util/struct"
-	"github.com/iancoleman/strcase"
+	"github.com/M15t/gram/pkg/util/ulidutil"
+	"github.com/samber/lo"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ! move it to configuration on PROD
+const (
+	analyzeAPIVersion = "2023-07-31"
+	analyzeModelID    = "prebuilt-receipt"
 )
 
 // Analyze sends a document to Azure for analysis.
@@ -22,56 +33,34 @@ import (
 // It then creates a new document entry in the repository with the analysis results.
 // Returns the APIM request ID of the analysis.
 func (s *Document) Analyze(c contextutil.Context, req AnalyzeDocumentReq) (*AnalyzeDocumentRes, error) {
-	if err := s.enforce(c, rbac.ActionCreate); err != nil {
-		return nil, err
-	}
-
-	// ! move it to configuration on PROD
-	apiVersion := "2023-07-31"
-	modelID := "prebuilt-receipt"
-
-	// Open file from multipart.FileHeader
-	file, err := req.Document.Open()
-	if err != nil {
+	if _, err := s.authorize(c, rbac.ActionCreate); err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	fileContent, err := io.ReadAll(file)
+	resHeaders, err := s.sendToAzure(c, req.Document)
 	if err != nil {
 		return nil, err
 	}
 
-	// Encode the file content as base64
-	base64Str := base64.StdEncoding.EncodeToString(fileContent)
-
-	reqPayload := map[string]interface{}{
-		"base64Source": base64Str,
-		// "urlSource":    "https://m15t-public-bucket.s3.amazonaws.com/11832804300.pdf", // * support url btw
-	}
-
-	// Encode the map as JSON
-	jsonData, err := json.Marshal(reqPayload)
-	if err != nil {
-		return nil, fmt.Errorf("error encoding JSON: %s", err)
-	}
-
-	// Create a bytes.Reader from the JSON-encoded byte slice
-	payload := bytes.NewReader(jsonData)
-
-	resHeaders, err := s.azure.AnalyzeDocument(c, modelID, apiVersion, payload)
+	// keep the receipt file to be exported with the data of the user
+	documentID := ulidutil.NewString()
+	filePath, err := s.storeReceipt(c, documentID, req.Document)
 	if err != nil {
 		return nil, err
 	}
 
 	newDocument := types.Document{
+		Base:              types.Base{ID: documentID},
+		UserID:            c.AuthUser().ID,
 		FileName:          req.Document.Filename,
-		FilePath:          req.Document.Header.Get("Content-Disposition"),
+		FilePath:          filePath,
 		OriginalFileName:  req.Document.Filename,
 		APIMRequestID:     resHeaders.APIMRequestID[0],
 		OperationLocation: resHeaders.OperationLocation[0],
-		ModelID:           modelID,
-		APIVersion:        apiVersion,
+		ModelID:           analyzeModelID,
+		APIVersion:        analyzeAPIVersion,
+		// shared with the organization the user is switched to
+		OrganizationID: lo.EmptyableToPtr(c.AuthUser().OrganizationID),
 		DocumentItem: &types.DocumentItem{
 			Data: datatypes.JSON([]byte{}),
 		},
@@ -88,127 +77,136 @@ func (s *Document) Analyze(c contextutil.Context, req AnalyzeDocumentReq) (*Anal
 
 // Get retrieves the document information by the given APIM request ID.
 // It fetches the document from the repository based on the APIM request ID.
-// If the document is not found in the activity logs, it requests Azure to get the result document.
-// It then updates the document details including vendor information, invoice details, payment information, and items.
+// If the result is not stored in the document analyses or the activity logs, it requests Azure to get the result document.
+// Every succeeded result is kept as a new document analysis.
+// It then maps the result through azure.ToDocument and updates the document details and items.
 // Finally, it updates the document item and returns the updated document.
+// The results are stored only if the user may update the document, the other readers get them without storing.
+// A new result is refused for a document claimed in an expense report under approval or decided.
 func (s *Document) Get(c contextutil.Context, apimReqID string) (*types.Document, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
 	// get document by apimReqID
-	document, err := s.repo.Document.FindByAPIMRequestID(c.GetContext(), apimReqID)
+	document, err := s.repo.Document.FindByAPIMRequestID(c.GetContext(), scope, apimReqID)
+	if err != nil {
+		return nil, ErrDocumentNotFound.SetInternal(err)
+	}
+
+	updateScope, canUpdate, err := s.canUpdate(c, document.ID)
 	if err != nil {
 		return nil, err
 	}
+	locked := false
+	if canUpdate {
+		if locked, err = s.repo.ExpenseReport.HasLockedDocument(c.GetContext(), document.ID); err != nil {
+			return nil, server.NewHTTPInternalError("error checking expense reports").SetInternal(err)
+		}
+	}
 
-	// check in activity logs first
-	var resRawDocument *azure.ResultAnalyzeResponse
-	activityLog, err := s.repo.ActivityLog.FindByAPIMRequestID(c.GetContext(), apimReqID)
-	if err == nil && activityLog != nil {
-		responseBodyBytes, err := json.Marshal(activityLog.ResponseBody)
+	// check the stored results first
+	resRawDocument, analysis, err := azure.FindStoredResult(c.GetContext(), s.repo, apimReqID)
+	if err != nil {
+		// request Azure to get result document if not existed record in activity logs
+		resRawDocument, err = s.azure.GetAnalyzeDocument(c, document.OperationLocation)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if err := json.Unmarshal(responseBodyBytes, &resRawDocument); err != nil {
+	// keep every succeeded analysis run of the document
+	if canUpdate && !locked && analysis == nil && resRawDocument.Status == azure.StatusSucceeded {
+		analysis, err = azure.ToDocumentAnalysis(resRawDocument)
+		if err != nil {
 			return nil, err
 		}
-	} else {
-		// request Azure to get result document if not existed record in activity logs
-		resRawDocument, err = s.azure.GetAnalyzeDocument(c, document.OperationLocation)
-		if err != nil {
+		analysis.DocumentID = document.ID
+		analysis.APIMRequestID = apimReqID
+		if err := s.repo.DocumentAnalysis.Create(c.GetContext(), analysis); err != nil {
 			return nil, err
 		}
 	}
 
-	if len(resRawDocument.AnalyzeResult.Documents) == 0 {
-		return nil, ErrDocumentIsEmpty
+	// map the raw result to document fields
+	mapped, err := azure.ToDocument(resRawDocument)
+	if err != nil {
+		if errors.Is(err, azure.ErrEmptyDocument) {
+			return nil, ErrDocumentIsEmpty
+		}
+		return nil, err
 	}
 
-	// update document
-	totalPage := len(resRawDocument.AnalyzeResult.Pages)
-	page := 0 // in case proccess multiple pages
-
-	merchantName := resRawDocument.AnalyzeResult.Documents[page].Fields.MerchantName.Content
-	merchantAddress := resRawDocument.AnalyzeResult.Documents[page].Fields.MerchantAddress.Content
-	merchantPhoneNumber := resRawDocument.AnalyzeResult.Documents[page].Fields.MerchantPhoneNumber.Content
-
-	transactionDate := parseStringToDate(resRawDocument.AnalyzeResult.Documents[page].Fields.TransactionDate.Content)
-	transactionTime := resRawDocument.AnalyzeResult.Documents[page].Fields.TransactionTime.Content
-
-	currency := resRawDocument.AnalyzeResult.Documents[page].Fields.TaxDetails.ValueArray[0].ValueObject.Amount.ValueCurrency.CurrencyCode
-	taxDetails := resRawDocument.AnalyzeResult.Documents[page].Fields.TaxDetails.ValueArray[0].Content
-	totalTax := resRawDocument.AnalyzeResult.Documents[page].Fields.TotalTax.ValueNumber
-	total := resRawDocument.AnalyzeResult.Documents[page].Fields.Total.ValueNumber
-
-	items := resRawDocument.AnalyzeResult.Documents[page].Fields.Items
-
-	// update document item
-	mappedItems := make([]map[string]interface{}, 0)
-	for _, item := range items.ValueArray {
-		newItem := make(map[string]interface{})
-		for fieldName, fieldValue := range item.ValueObject {
-			if fieldValueMap, ok := fieldValue.(map[string]interface{}); ok {
-				if valueString, ok := fieldValueMap["content"].(string); ok {
-					newItem[strcase.ToSnake(fieldName)] = valueString
-				}
-			}
+	// the result stored before the document was claimed in a report under approval can still be read
+	if locked {
+		if applyResult(document, mapped) {
+			return nil, ErrDocumentInReport
 		}
-		mappedItems = append(mappedItems, newItem)
+		return document, nil
 	}
-
-	jsonData, err := json.Marshal(mappedItems)
-	if err != nil {
-		return nil, err
+	if !canUpdate {
+		applyResult(document, mapped)
+		return document, nil
 	}
 
+	// update document item
 	if err := s.repo.DocumentItem.Update(c.GetContext(), &types.DocumentItem{
-		Data: jsonData,
+		Data: mapped.DocumentItem.Data,
 	}, "document_id", document.ID); err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.Document.Update(c.GetContext(), &types.Document{
-		UserID:              c.AuthUser().ID,
-		TotalPage:           totalPage,
-		MerchantName:        merchantName,
-		MerchantAddress:     merchantAddress,
-		MerchantPhoneNumber: merchantPhoneNumber,
-		Currency:            currency,
-		TotalTax:            totalTax,
-		Total:               total,
-		TaxDetails:          taxDetails,
-		TransactionDate:     transactionDate,
-		TransactionTime:     transactionTime,
-	}, "id = ?", document.ID); err != nil {
+	mapped.DocumentItem = nil
+	if err := s.repo.Document.UpdateByID(c.GetContext(), updateScope, document.ID, mapped); err != nil {
 		return nil, err
 	}
 
-	return s.repo.Document.FindByAPIMRequestID(c.GetContext(), apimReqID)
+	return s.repo.Document.FindByAPIMRequestID(c.GetContext(), scope, apimReqID)
 }
 
-// Read returns single user by id
+// Read returns single document by id
 func (s *Document) Read(c contextutil.Context, id string) (*types.Document, error) {
-	if err := s.enforce(c, rbac.ActionRead); err != nil {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
 		return nil, err
 	}
 
-	if existed, err := s.repo.Document.Existed(c.GetContext(), `user_id`, c.AuthUser().ID); !existed && err == nil {
-		return nil, ErrDocumentNotFound
+	rec, err := s.repo.Document.FindByID(c.GetContext(), scope, id)
+	if err != nil {
+		return nil, ErrDocumentNotFound.SetInternal(err)
 	}
 
-	return s.repo.Document.ReadByID(c.GetContext(), id)
+	return rec, nil
 }
 
-// List returns the list of users
+// List returns the list of documents
 func (s *Document) List(c contextutil.Context, req ListDocumentReq) (*ListDocumentsResp, error) {
-	if err := s.enforce(c, rbac.ActionRead); err != nil {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
 		return nil, err
 	}
 
+	if req.MinTotal != nil && req.MaxTotal != nil && *req.MinTotal > *req.MaxTotal {
+		return nil, ErrInvalidAmountRange
+	}
+
+	if req.DateFrom != "" && req.DateTo != "" && req.DateFrom > req.DateTo {
+		return nil, ErrInvalidDateRange
+	}
+
+	if req.IsCursor() {
+		return s.listByCursor(c, scope, req)
+	}
+
 	var count int64 = 0
 	data := []*types.Document{}
-	lc := req.ToListCond()
-	lc.Filter.UserID = c.AuthUser().ID
+	lc, err := req.ToListCond()
+	if err != nil {
+		return nil, err
+	}
 	preloadConds := []string{"DocumentItem"}
-	if err := s.repo.Document.List(c.GetContext(), &data, &count, lc, preloadConds); err != nil {
+	if err := s.repo.Document.List(c.GetContext(), scope, &data, &count, lc, preloadConds); err != nil {
 		return nil, server.NewHTTPInternalError("Error listing user").SetInternal(err)
 	}
 
@@ -218,45 +216,304 @@ func (s *Document) List(c contextutil.Context, req ListDocumentReq) (*ListDocume
 	}, nil
 }
 
-// Update updates document information
+// listByCursor returns a page of documents using keyset pagination
+func (s *Document) listByCursor(c contextutil.Context, scope repo.Scope, req ListDocumentReq) (*ListDocumentsResp, error) {
+	cc, err := req.ToCursorCond()
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64 = 0
+	data := []*types.Document{}
+	page, err := s.repo.Document.ListByCursor(c.GetContext(), scope, &data, &count, cc, []string{"DocumentItem"})
+	switch {
+	case errors.Is(err, repo.ErrInvalidCursor):
+		return nil, ErrInvalidCursor
+	case errors.Is(err, repo.ErrUnsupportedCursorSort):
+		return nil, ErrUnsupportedCursorSort
+	case err != nil:
+		return nil, server.NewHTTPInternalError("Error listing document").SetInternal(err)
+	}
+
+	return &ListDocumentsResp{
+		Data:       data,
+		TotalCount: count,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}, nil
+}
+
+// Update updates document information, unless it is claimed in an expense report under approval or decided
 func (s *Document) Update(c contextutil.Context, id string, data UpdateDocumentReq) (*types.Document, error) {
-	if err := s.enforce(c, rbac.ActionUpdate); err != nil {
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
 		return nil, err
 	}
 
-	if existed, err := s.repo.Document.Existed(c.GetContext(), id); err != nil || !existed {
+	if existed, err := s.repo.Document.ExistedByID(c.GetContext(), scope, id); err != nil || !existed {
 		return nil, ErrDocumentNotFound.SetInternal(err)
 	}
+	if err := s.checkUnlocked(c, id); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.Document.Update(c.GetContext(), structutil.ToMap(data), id); err != nil {
+	if err := s.repo.Document.UpdateByID(c.GetContext(), scope, id, structutil.ToMap(data)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotFound.SetInternal(err)
+		}
 		return nil, server.NewHTTPInternalError("error updating document").SetInternal(err)
 	}
 
 	return s.Read(c, id)
 }
 
-// Delete deletes document by id
+// Delete deletes document by id, unless it is claimed in an expense report
 func (s *Document) Delete(c contextutil.Context, id string) error {
-	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+	scope, err := s.authorize(c, rbac.ActionDelete)
+	if err != nil {
 		return err
 	}
 
-	if existed, err := s.repo.Document.Existed(c.GetContext(), id); err != nil || !existed {
+	ctx := c.GetContext()
+	if existed, err := s.repo.Document.ExistedByID(ctx, scope, id); err != nil || !existed {
 		return ErrDocumentNotFound.SetInternal(err)
 	}
+	if claimed, err := s.repo.ExpenseReport.HasDocument(ctx, id); err != nil {
+		return server.NewHTTPInternalError("error checking expense reports").SetInternal(err)
+	} else if claimed {
+		return ErrDocumentInReport
+	}
 
-	if err := s.repo.DocumentItem.Delete(c.GetContext(), `document_id`, id); err != nil {
-		return ErrDocumentNotFound.SetInternal(err)
+	if err := s.repo.Document.DeleteByID(ctx, scope, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDocumentNotFound.SetInternal(err)
+		}
+		return server.NewHTTPInternalError("error deleting document").SetInternal(err)
+	}
+
+	return nil
+}
+
+// Reanalyze sends a new file of an existing document to Azure for analysis.
+// The document is pointed to the new analysis while the previous analyses are kept as history,
+// the result is then retrieved by Get with the returned APIM request ID. The documents claimed in an expense report
+// under approval or decided are not reanalyzed.
+func (s *Document) Reanalyze(c contextutil.Context, id string, req AnalyzeDocumentReq) (*AnalyzeDocumentRes, error) {
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed, err := s.repo.Document.ExistedByID(c.GetContext(), scope, id); err != nil || !existed {
+		return nil, ErrDocumentNotFound.SetInternal(err)
+	}
+	if err := s.checkUnlocked(c, id); err != nil {
+		return nil, err
+	}
+
+	resHeaders, err := s.sendToAzure(c, req.Document)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, err := s.storeReceipt(c, id, req.Document)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repo.Document.Delete(c.GetContext(), id)
+	if err := s.repo.Document.UpdateByID(c.GetContext(), scope, id, map[string]interface{}{
+		"file_name":          req.Document.Filename,
+		"file_path":          filePath,
+		"original_file_name": req.Document.Filename,
+		"apim_request_id":    resHeaders.APIMRequestID[0],
+		"operation_location": resHeaders.OperationLocation[0],
+		"model_id":           analyzeModelID,
+		"api_version":        analyzeAPIVersion,
+	}); err != nil {
+		return nil, server.NewHTTPInternalError("error updating document").SetInternal(err)
+	}
+
+	return &AnalyzeDocumentRes{
+		APIMRequestID: resHeaders.APIMRequestID[0],
+	}, nil
 }
 
-// enforce checks document permission to perform the action
-func (s *Document) enforce(c contextutil.Context, action string) error {
-	au := c.AuthUser()
-	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectDocument, action) {
-		return rbac.ErrForbiddenAction
+// ListAnalyses returns the analysis history of a document, newest first
+func (s *Document) ListAnalyses(c contextutil.Context, id string) ([]*types.DocumentAnalysis, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed, err := s.repo.Document.ExistedByID(c.GetContext(), scope, id); err != nil || !existed {
+		return nil, ErrDocumentNotFound.SetInternal(err)
+	}
+
+	return s.repo.DocumentAnalysis.ListByDocumentID(c.GetContext(), id)
+}
+
+// OCR returns the raw OCR text and the word polygons of a document analysis for client-side highlighting.
+// The latest analysis is used if no analysis id is given.
+func (s *Document) OCR(c contextutil.Context, id string, req OCRReq) (*OCRResp, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed, err := s.repo.Document.ExistedByID(c.GetContext(), scope, id); err != nil || !existed {
+		return nil, ErrDocumentNotFound.SetInternal(err)
+	}
+
+	var analysis *types.DocumentAnalysis
+	if req.AnalysisID != "" {
+		analysis = &types.DocumentAnalysis{}
+		err = s.repo.DocumentAnalysis.Read(c.GetContext(), analysis, map[string]interface{}{"id": req.AnalysisID, "document_id": id})
+	} else {
+		analysis, err = s.repo.DocumentAnalysis.FindLatestByDocumentID(c.GetContext(), id)
+	}
+	if err != nil {
+		return nil, ErrAnalysisNotFound.SetInternal(err)
+	}
+
+	pages := []azure.Page{}
+	if len(analysis.Pages) > 0 {
+		if err := json.Unmarshal(analysis.Pages, &pages); err != nil {
+			return nil, server.NewHTTPInternalError("error reading analysis pages").SetInternal(err)
+		}
+	}
+
+	return &OCRResp{
+		AnalysisID: analysis.ID,
+		ModelID:    analysis.ModelID,
+		APIVersion: analysis.APIVersion,
+		AnalyzedAt: analysis.AnalyzedAt,
+		Content:    analysis.Content,
+		Pages:      pages,
+	}, nil
+}
+
+// sendToAzure encodes the uploaded file as base64 JSON payload and sends it to Azure for analysis
+func (s *Document) sendToAzure(c contextutil.Context, fh *multipart.FileHeader) (*azure.ResponseHeaders, error) {
+	if fh == nil {
+		return nil, ErrDocumentRequired
+	}
+
+	// Open file from multipart.FileHeader
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileContent, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
 	}
+
+	// Encode the file content as base64
+	base64Str := base64.StdEncoding.EncodeToString(fileContent)
+
+	reqPayload := map[string]interface{}{
+		"base64Source": base64Str,
+		// "urlSource":    "https://m15t-public-bucket.s3.amazonaws.com/11832804300.pdf", // * support url btw
+	}
+
+	// Encode the map as JSON
+	jsonData, err := json.Marshal(reqPayload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding JSON: %s", err)
+	}
+
+	// Create a bytes.Reader from the JSON-encoded byte slice
+	payload := bytes.NewReader(jsonData)
+
+	return s.azure.AnalyzeDocument(c, analyzeModelID, analyzeAPIVersion, payload)
+}
+
+// storeReceipt stores the uploaded file of the document, returns the blob key
+func (s *Document) storeReceipt(c contextutil.Context, documentID string, fh *multipart.FileHeader) (string, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	key, err := s.receipts.StoreReceipt(c.GetContext(), c.AuthUser().ID, documentID, fh.Filename, file, fh.Header.Get("Content-Type"))
+	if err != nil {
+		return "", server.NewHTTPInternalError("error storing receipt").SetInternal(err)
+	}
+
+	return key, nil
+}
+
+// authorize checks document permission to perform the action, returns the documents it applies to
+func (s *Document) authorize(c contextutil.Context, action string) (repo.Scope, error) {
+	return rbac.Authorize(s.rbac, c.AuthUser(), rbac.ObjectDocument, action)
+}
+
+// canUpdate checks the current user may update the document, returns the update scope if so.
+// An impersonating admin never may, the impersonation is read only even on the GET requests storing results.
+func (s *Document) canUpdate(c contextutil.Context, id string) (repo.Scope, bool, error) {
+	if c.AuthUser().ActorID != "" {
+		return repo.Scope{}, false, nil
+	}
+
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
+		return repo.Scope{}, false, nil
+	}
+	if _, err := s.repo.Document.FindByID(c.GetContext(), scope, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return repo.Scope{}, false, nil
+		}
+		return repo.Scope{}, false, server.NewHTTPInternalError("error reading document").SetInternal(err)
+	}
+
+	return scope, true, nil
+}
+
+// checkUnlocked checks the document is not claimed in an expense report under approval or decided, whose total is fixed
+func (s *Document) checkUnlocked(c contextutil.Context, id string) error {
+	locked, err := s.repo.ExpenseReport.HasLockedDocument(c.GetContext(), id)
+	if err != nil {
+		return server.NewHTTPInternalError("error checking expense reports").SetInternal(err)
+	}
+	if locked {
+		return ErrDocumentInReport
+	}
+
 	return nil
 }
+
+// applyResult sets the mapped analysis result on the document without storing it,
+// the empty fields are skipped as the stored updates do. Returns whether an extracted field changed.
+func applyResult(rec, mapped *types.Document) bool {
+	changed := set(&rec.MerchantName, mapped.MerchantName)
+	changed = set(&rec.MerchantAddress, mapped.MerchantAddress) || changed
+	changed = set(&rec.MerchantPhoneNumber, mapped.MerchantPhoneNumber) || changed
+	changed = set(&rec.TransactionDate, mapped.TransactionDate) || changed
+	changed = set(&rec.TransactionTime, mapped.TransactionTime) || changed
+	changed = set(&rec.Currency, mapped.Currency) || changed
+	changed = set(&rec.SubTotal, mapped.SubTotal) || changed
+	changed = set(&rec.Total, mapped.Total) || changed
+	changed = set(&rec.TotalTax, mapped.TotalTax) || changed
+	changed = set(&rec.TaxDetails, mapped.TaxDetails) || changed
+	changed = set(&rec.TotalPage, mapped.TotalPage) || changed
+	if mapped.DocumentItem != nil {
+		if rec.DocumentItem == nil {
+			rec.DocumentItem = &types.DocumentItem{DocumentID: rec.ID}
+		}
+		rec.DocumentItem.Data = mapped.DocumentItem.Data
+	}
+
+	return changed
+}
+
+func set[T comparable](dst *T, v T) bool {
+	var zero T
+	if v == zero || *dst == v {
+		return false
+	}
+	*dst = v
+	return true
+}
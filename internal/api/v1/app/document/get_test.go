@@ -0,0 +1,282 @@
+package document
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"gorm.io/datatypes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const succeededResult = `{"status":"succeeded","analyzeResult":{"pages":[{}],"documents":[{"fields":{"MerchantName":{"content":"Tyr Mart"},"Total":{"valueNumber":12.5}}}]}}`
+
+// TestGetStoresResultsUnderUpdateScope proves the analysis results are stored only by the members who may update the document,
+// the other readers and the impersonating admins get them without any write
+func TestGetStoresResultsUnderUpdateScope(t *testing.T) {
+	cases := []struct {
+		name       string
+		c          *testContext
+		wantWrites bool
+	}{
+		{name: "uploader", c: newOrgTestContext(ownerID, types.OrgRoleMember), wantWrites: true},
+		{name: "approver", c: newOrgTestContext(otherID, types.OrgRoleApprover), wantWrites: true},
+		{name: "member", c: newOrgTestContext(otherID, types.OrgRoleMember)},
+		{name: "admin impersonating the uploader", c: impersonated(newOrgTestContext(ownerID, types.OrgRoleMember))},
+		{name: "admin impersonating the approver", c: impersonated(newOrgTestContext(otherID, types.OrgRoleApprover))},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, db := newGetTestService(t)
+
+			doc, err := svc.Get(tc.c, apimReqID)
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if tc.wantWrites != (len(db.writes) > 0) {
+				t.Errorf("got writes %q", db.writes)
+			}
+			if !tc.wantWrites && (doc.MerchantName != "Tyr Mart" || doc.Total != 12.5 || doc.DocumentItem == nil) {
+				t.Errorf("got %+v, want the result applied", doc)
+			}
+		})
+	}
+}
+
+// impersonated makes the context the one of an admin impersonating its user
+func impersonated(c *testContext) *testContext {
+	c.au.ActorID = "01HADMIN0000000000000000000"
+	return c
+}
+
+// TestDocumentInLockedReportIsNotChanged proves a document claimed in an expense report under approval or decided
+// is neither updated, reanalyzed nor given a new analysis result, while its stored result can still be read
+func TestDocumentInLockedReportIsNotChanged(t *testing.T) {
+	c := newOrgTestContext(ownerID, types.OrgRoleMember)
+	vendor := "Changed"
+
+	for name, op := range map[string]func(svc *Document) error{
+		"update": func(svc *Document) error {
+			_, err := svc.Update(c, documentID, UpdateDocumentReq{VendorName: &vendor})
+			return err
+		},
+		"reanalyze": func(svc *Document) error {
+			_, err := svc.Reanalyze(c, documentID, AnalyzeDocumentReq{})
+			return err
+		},
+		"get a new result": func(svc *Document) error {
+			_, err := svc.Get(c, apimReqID)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc, db := newGetTestService(t)
+			db.locked = true
+
+			if err := op(svc); !errors.Is(err, ErrDocumentInReport) {
+				t.Errorf("got %v, want ErrDocumentInReport", err)
+			}
+			if len(db.writes) > 0 {
+				t.Errorf("got writes %q", db.writes)
+			}
+		})
+	}
+
+	t.Run("get the stored result", func(t *testing.T) {
+		svc, db := newGetTestService(t)
+		db.locked, db.applied = true, true
+
+		doc, err := svc.Get(c, apimReqID)
+		if err != nil {
+			t.Fatalf("got %v", err)
+		}
+		if len(db.writes) > 0 || doc.Total != 12.5 {
+			t.Errorf("got writes %q and %+v", db.writes, doc)
+		}
+	})
+
+	t.Run("update out of report", func(t *testing.T) {
+		svc, db := newGetTestService(t)
+
+		if _, err := svc.Update(c, documentID, UpdateDocumentReq{VendorName: &vendor}); err != nil {
+			t.Fatalf("got %v", err)
+		}
+		if len(db.writes) != 1 {
+			t.Errorf("got writes %q, want the update", db.writes)
+		}
+	})
+}
+
+// fakeSharedDocumentDB answers the dry run statements as if the database held the document of the owner shared in the organization,
+// with its analysis result in the activity logs. It records the writes.
+type fakeSharedDocumentDB struct {
+	writes []string
+	// the document is claimed in an expense report under approval or decided
+	locked bool
+	// the document already holds the analysis result
+	applied bool
+	// the analyses of the document, in the order of their runs
+	analyses []*types.DocumentAnalysis
+	// the columns of the document updated by map
+	updated map[string]interface{}
+}
+
+func newGetTestService(t *testing.T) (*Document, *fakeSharedDocumentDB) {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeSharedDocumentDB{}
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Before("gorm:preload").Register("test:query", f.query),
+		gdb.Callback().Create().After("gorm:create").Register("test:create", f.write("create")),
+		gdb.Callback().Update().After("gorm:update").Register("test:update", f.write("update")),
+		gdb.Callback().Delete().After("gorm:delete").Register("test:delete", f.write("delete")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rbacSvc, err := rbac.New(defaultPolicies{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(repo.New(gdb), rbacSvc, nil, nil, nil), f
+}
+
+func (f *fakeSharedDocumentDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	switch dest := db.Statement.Dest.(type) {
+	case *int64:
+		switch {
+		case db.Statement.Table == "documents" && sharedVisibleToStatement(db.Statement),
+			strings.Contains(db.Statement.SQL.String(), "FROM expense_report_documents") && f.locked:
+			*dest, db.RowsAffected = 1, 1
+		}
+	case *types.Document:
+		if !sharedVisibleToStatement(db.Statement) {
+			db.AddError(gorm.ErrRecordNotFound)
+			return
+		}
+		org := orgID
+		*dest = types.Document{Base: types.Base{ID: documentID}, UserID: ownerID, OrganizationID: &org, APIMRequestID: apimReqID}
+		if f.applied {
+			// an unparsed date is stored as the zero date
+			dest.MerchantName, dest.TransactionDate, dest.Total, dest.TotalPage = "Tyr Mart", "0001-01-01", 12.5, 1
+		}
+		if id, ok := f.updated["apim_request_id"].(string); ok {
+			dest.APIMRequestID, dest.OperationLocation = id, f.updated["operation_location"].(string)
+		}
+		db.RowsAffected = 1
+	case *types.ActivityLog:
+		// the result of the first analysis only is logged
+		if vars := whereVars(db.Statement); len(vars) == 0 || vars[0] != apimReqID {
+			db.AddError(gorm.ErrRecordNotFound)
+			return
+		}
+		*dest = types.ActivityLog{ResponseCode: 200, ResponseBody: datatypes.JSON(succeededResult), APIMRequestID: apimReqID}
+		db.RowsAffected = 1
+	case *types.DocumentAnalysis:
+		vars := whereVars(db.Statement)
+		for _, a := range f.analyses {
+			if len(vars) == 1 && vars[0] == a.APIMRequestID {
+				*dest = *a
+				db.RowsAffected = 1
+				return
+			}
+		}
+		db.AddError(gorm.ErrRecordNotFound)
+	case *[]*types.DocumentAnalysis:
+		// the newest first
+		vars := whereVars(db.Statement)
+		for i := len(f.analyses) - 1; i >= 0; i-- {
+			if len(vars) == 1 && vars[0] == f.analyses[i].DocumentID {
+				*dest = append(*dest, f.analyses[i])
+			}
+		}
+		db.RowsAffected = int64(len(*dest))
+	}
+}
+
+func (f *fakeSharedDocumentDB) write(kind string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		if kind == "update" && db.Statement.Table == "documents" && !sharedVisibleToStatement(db.Statement) {
+			return
+		}
+		f.writes = append(f.writes, kind+" "+db.Statement.Table)
+		switch dest := db.Statement.Dest.(type) {
+		case *types.DocumentAnalysis:
+			f.analyses = append(f.analyses, dest)
+		case map[string]interface{}:
+			if f.updated == nil {
+				f.updated = map[string]interface{}{}
+			}
+			for k, v := range dest {
+				f.updated[k] = v
+			}
+		}
+		db.RowsAffected = 1
+	}
+}
+
+// whereVars returns the vars of the where expressions of the statement, the scope and soft delete conditions aside
+func whereVars(stmt *gorm.Statement) []interface{} {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	vars := []interface{}{}
+	for _, expr := range where.Exprs {
+		if e, ok := expr.(clause.Expr); ok {
+			vars = append(vars, e.Vars...)
+		}
+	}
+	return vars
+}
+
+// sharedVisibleToStatement checks the where conditions of the statement do not exclude the shared document of the owner
+func sharedVisibleToStatement(stmt *gorm.Statement) bool {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return true
+	}
+
+	for _, expr := range where.Exprs {
+		e, ok := expr.(clause.Eq)
+		if !ok {
+			continue
+		}
+		col, ok := e.Column.(clause.Column)
+		if !ok {
+			continue
+		}
+		if (col.Name == "user_id" && e.Value != ownerID) || (col.Name == "organization_id" && e.Value != orgID) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -20,6 +20,9 @@ type HTTP struct {
 type Service interface {
 	Analyze(contextutil.Context, AnalyzeDocumentReq) (*AnalyzeDocumentRes, error)
 	Get(contextutil.Context, string) (*types.Document, error)
+	Reanalyze(contextutil.Context, string, AnalyzeDocumentReq) (*AnalyzeDocumentRes, error)
+	ListAnalyses(contextutil.Context, string) ([]*types.DocumentAnalysis, error)
+	OCR(contextutil.Context, string, OCRReq) (*OCRResp, error)
 
 	Read(contextutil.Context, string) (*types.Document, error)
 	List(contextutil.Context, ListDocumentReq) (*ListDocumentsResp, error)
@@ -67,11 +70,79 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	//     schema:
 	//       "$ref": "#/definitions/Document"
 	//   default:
-	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
 	//     schema:
 	//       "$ref": "#/definitions/ErrorResponse"
 	eg.GET("/analyze/get/:id", h.analyzeGet)
 
+	// swagger:operation POST /v1/app/documents/{id}/reanalyze app-documents-analyze appDocumentReanalyze
+	// ---
+	// summary: Re-analyzes an existing document with a new file, the previous analyses are kept as history
+	// consumes:
+	// - multipart/form-data
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of document
+	//   type: string
+	//   required: true
+	// - name: document
+	//   in: formData
+	//   type: file
+	//   description: The document to upload
+	// responses:
+	//   "200":
+	//     description: The request id of the new analysis
+	//     schema:
+	//       "$ref": "#/definitions/AnalyzeDocumentRes"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 404, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/reanalyze", h.reanalyze)
+
+	// swagger:operation GET /v1/app/documents/{id}/analyses app-documents documentsAnalyses
+	// ---
+	// summary: Returns the analysis history of a document, newest first
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of document
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: List of document analyses
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/DocumentAnalysis"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/analyses", h.listAnalyses)
+
+	// swagger:operation GET /v1/app/documents/{id}/ocr app-documents documentsOCR
+	// ---
+	// summary: Returns the raw OCR text and word polygons of a document analysis for highlighting
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of document
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The OCR result
+	//     schema:
+	//       "$ref": "#/definitions/OCRResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/ocr", h.ocr)
+
 	// swagger:operation GET /v1/app/documents/{id} app-documents documentsRead
 	// ---
 	// summary: Returns a single document
@@ -94,7 +165,7 @@ func NewHTTP(svc Service, eg *echo.Group) {
 
 	// swagger:operation GET /v1/app/documents app-documents documentsList
 	// ---
-	// summary: Returns list of documents
+	// summary: Returns list of documents, supports full-text search ranked by relevance and structured filters
 	// responses:
 	//   "200":
 	//     description: List of documents
@@ -127,7 +198,7 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	//     schema:
 	//       "$ref": "#/definitions/Document"
 	//   default:
-	//     description: 'Possible errors: 400, 401, 403, 404, 500'
+	//     description: 'Possible errors: 400, 401, 403, 404, 409, 500'
 	//     schema:
 	//       "$ref": "#/definitions/ErrorResponse"
 	eg.PATCH("/:id", h.update)
@@ -145,7 +216,7 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	//   "204":
 	//     "$ref": "#/responses/ok"
 	//   default:
-	//     description: 'Possible errors: 401, 403, 404, 500'
+	//     description: 'Possible errors: 401, 403, 404, 409, 500'
 	//     schema:
 	//       "$ref": "#/definitions/ErrorResponse"
 	eg.DELETE("/:id", h.delete)
@@ -188,6 +259,61 @@ func (h *HTTP) analyzeGet(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+func (h *HTTP) reanalyze(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := AnalyzeDocumentReq{}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+
+	documents := form.File["document"]
+
+	if len(documents) > 0 {
+		r.Document = documents[0]
+	}
+
+	resp, err := h.svc.Reanalyze(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) listAnalyses(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.ListAnalyses(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) ocr(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := OCRReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	resp, err := h.svc.OCR(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 func (h *HTTP) read(c echo.Context) error {
 	id, err := httputil.ReqID(c)
 	if err != nil {
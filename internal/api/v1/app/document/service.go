@@ -1,6 +1,7 @@
 package document
 
 import (
+	"context"
 	"io"
 	contextutil "tyr/internal/api/context"
 	"tyr/internal/repo"
@@ -10,16 +11,17 @@ import (
 )
 
 // New creates new document application service
-func New(repo *repo.Service, rbac rbac.Intf, cr Crypter, azure Azure) *Document {
-	return &Document{repo: repo, rbac: rbac, cr: cr, azure: azure}
+func New(repo *repo.Service, rbac rbac.Intf, cr Crypter, azure Azure, receipts Receipts) *Document {
+	return &Document{repo: repo, rbac: rbac, cr: cr, azure: azure, receipts: receipts}
 }
 
 // Document represents document application service
 type Document struct {
-	repo  *repo.Service
-	rbac  rbac.Intf
-	cr    Crypter
-	azure Azure
+	repo     *repo.Service
+	rbac     rbac.Intf
+	cr       Crypter
+	azure    Azure
+	receipts Receipts
 }
 
 // Azure represents azure interface
@@ -28,6 +30,11 @@ type Azure interface {
 	GetAnalyzeDocument(c contextutil.Context, url string) (*azure.ResultAnalyzeResponse, error)
 }
 
+// Receipts represents receipt file storage interface
+type Receipts interface {
+	StoreReceipt(ctx context.Context, userID, documentID, fileName string, body io.Reader, contentType string) (string, error)
+}
+
 // Crypter represents security interface
 type Crypter interface {
 }
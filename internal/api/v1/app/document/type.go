@@ -2,10 +2,15 @@ package document
 
 import (
 	"mime/multipart"
+	"time"
 	"tyr/internal/repo"
 	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
+	"tyr/third_party/azure"
 
+	repoutil "github.com/M15t/gram/pkg/util/repo"
 	requestutil "github.com/M15t/gram/pkg/util/request"
+	"github.com/samber/lo"
 )
 
 // AnalyzeDocumentReq struct
@@ -20,6 +25,25 @@ type AnalyzeDocumentRes struct {
 	APIMRequestID string `json:"apim_request_id"`
 }
 
+// OCRReq contains request data to get the OCR result of a document
+// swagger:parameters documentsOCR
+type OCRReq struct {
+	// Analysis to read from, default to the latest analysis
+	// in: query
+	AnalysisID string `json:"analysis_id,omitempty" query:"analysis_id"`
+}
+
+// OCRResp contains the raw OCR text and the page geometry of a document analysis
+// swagger:model
+type OCRResp struct {
+	AnalysisID string       `json:"analysis_id"`
+	ModelID    string       `json:"model_id"`
+	APIVersion string       `json:"api_version"`
+	AnalyzedAt time.Time    `json:"analyzed_at"`
+	Content    string       `json:"content"`
+	Pages      []azure.Page `json:"pages"`
+}
+
 // UpdateDocumentReq contains request data to update existing document
 // swagger:model
 type UpdateDocumentReq struct {
@@ -72,22 +96,92 @@ type UpdateDocumentReq struct {
 // swagger:parameters documentsList
 type ListDocumentReq struct {
 	requestutil.ListQueryRequest
-	// Search for document(s) by?
-	// TODO: TBD
+	// Pagination mode, either `offset` (default) using `p` or `cursor` using `cursor`.
+	// In cursor mode `pp` is the page size and `s` accepts a single key among `id` and `transaction_date`
+	// in: query
+	Pagination string `json:"pagination,omitempty" query:"pagination" validate:"omitempty,oneof=offset cursor"`
+	// Opaque cursor of the next page returned by the previous page, implies cursor pagination
+	// in: query
+	Cursor string `json:"cursor,omitempty" query:"cursor"`
+	// Whether to count the total records, default to true in offset mode and false in cursor mode
+	// in: query
+	Count *bool `json:"count,omitempty" query:"count"`
+	// Filter expression, conditions are separated by comma and combined by AND.
+	// Supported operators: =, !=, >, >=, <, <=, ~ (contains), !~ (not contains), between (from..to), in (a|b|c).
+	// Values containing commas must be double-quoted, eg: `total>=10,merchant_name~coffee,transaction_date between 2026-01-01..2026-02-01`
+	// in: query
+	Filter string `json:"filter,omitempty" query:"filter"`
+	// Full-text search over merchant, OCR content and line item descriptions, words are prefix matched
+	// in: query
 	Search string `json:"search,omitempty" query:"search"`
+	// Part of the merchant name
+	// in: query
+	Merchant string `json:"merchant,omitempty" query:"merchant"`
+	// ISO 4217 currency code, eg: USD
+	// in: query
+	Currency string `json:"currency,omitempty" query:"currency" validate:"omitempty,len=3"`
+	// Minimum total amount
+	// in: query
+	MinTotal *float64 `json:"min_total,omitempty" query:"min_total"`
+	// Maximum total amount
+	// in: query
+	MaxTotal *float64 `json:"max_total,omitempty" query:"max_total"`
+	// Lower bound (inclusive) of transaction date, in YYYY-MM-DD format
+	// in: query
+	DateFrom string `json:"date_from,omitempty" query:"date_from" validate:"omitempty,datetime=2006-01-02"`
+	// Upper bound (inclusive) of transaction date, in YYYY-MM-DD format
+	// in: query
+	DateTo string `json:"date_to,omitempty" query:"date_to" validate:"omitempty,datetime=2006-01-02"`
 }
 
 // ToListCond transforms the service request to repo conditions
-func (lq *ListDocumentReq) ToListCond() *requestutil.ListCondition[repo.DocumentsFilter] {
+func (lq *ListDocumentReq) ToListCond() (*requestutil.ListCondition[repo.DocumentsFilter], error) {
+	query, err := filterutil.ParseList(lq.Filter, lq.Sort, repo.DocumentFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+	// the rank is only selected along with the search
+	if lq.Search == "" && lo.ContainsBy(repoutil.ParseSortParam(lq.Sort), func(v []string) bool { return v[0] == "search_rank" }) {
+		return nil, ErrSortRankWithoutSearch
+	}
+
 	return &requestutil.ListCondition[repo.DocumentsFilter]{
 		Page:    lq.Page,
 		PerPage: lq.PerPage,
 		Sort:    lq.Sort,
-		Count:   true,
+		Count:   lq.Count == nil || *lq.Count,
 		Filter: repo.DocumentsFilter{
-			Search: lq.Search,
+			Search:   lq.Search,
+			Merchant: lq.Merchant,
+			Currency: lq.Currency,
+			MinTotal: lq.MinTotal,
+			MaxTotal: lq.MaxTotal,
+			DateFrom: lq.DateFrom,
+			DateTo:   lq.DateTo,
+			Query:    query,
 		},
+	}, nil
+}
+
+// IsCursor returns whether the listing is requested with cursor pagination
+func (lq *ListDocumentReq) IsCursor() bool {
+	return lq.Cursor != "" || lq.Pagination == "cursor"
+}
+
+// ToCursorCond transforms the service request to repo keyset pagination conditions
+func (lq *ListDocumentReq) ToCursorCond() (*repo.CursorCondition[repo.DocumentsFilter], error) {
+	lc, err := lq.ToListCond()
+	if err != nil {
+		return nil, err
 	}
+
+	return &repo.CursorCondition[repo.DocumentsFilter]{
+		Cursor: lq.Cursor,
+		Limit:  lq.PerPage,
+		Sort:   lq.Sort,
+		Count:  lq.Count != nil && *lq.Count,
+		Filter: lc.Filter,
+	}, nil
 }
 
 // ListDocumentsResp contains list of paginated documents and total numbers after filtered
@@ -95,4 +189,7 @@ func (lq *ListDocumentReq) ToListCond() *requestutil.ListCondition[repo.Document
 type ListDocumentsResp struct {
 	Data       []*types.Document `json:"data"`
 	TotalCount int64             `json:"total_count"`
+	// Cursor of the next page, only in cursor mode
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more,omitempty"`
 }
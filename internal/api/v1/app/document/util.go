@@ -4,8 +4,6 @@ import (
 	"regexp"
 	"strconv"
 	"time"
-
-	"github.com/araddon/dateparse"
 )
 
 func extractNumbers(input string) []int {
@@ -37,9 +35,3 @@ func addDaysToDate(dateString string, daysToAdd int) (string, error) {
 
 	return newDateString, nil
 }
-
-func parseStringToDate(dateString string) string {
-	t, _ := dateparse.ParseAny(dateString)
-
-	return t.Format("2006-01-02")
-}
@@ -0,0 +1,40 @@
+package expensereport
+
+import (
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// ListComments returns the comments of an expense report, the oldest first
+func (s *ExpenseReport) ListComments(c contextutil.Context, id string) (*ListCommentsResp, error) {
+	if err := s.exists(c, id); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.ExpenseReportComment.ListByReportID(c.GetContext(), id)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing expense report comments").SetInternal(err)
+	}
+
+	return &ListCommentsResp{Data: data}, nil
+}
+
+// Comment comments on an expense report, by the submitter or the members who can read the report
+func (s *ExpenseReport) Comment(c contextutil.Context, id string, data CommentReq) (*types.ExpenseReportComment, error) {
+	if err := s.exists(c, id); err != nil {
+		return nil, err
+	}
+
+	rec := &types.ExpenseReportComment{
+		ExpenseReportID: id,
+		UserID:          c.AuthUser().ID,
+		Body:            data.Body,
+	}
+	if err := s.repo.ExpenseReportComment.Create(c.GetContext(), rec); err != nil {
+		return nil, server.NewHTTPInternalError("error creating expense report comment").SetInternal(err)
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,23 @@
+package expensereport
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrReportNotFound       = server.NewHTTPError(http.StatusBadRequest, "EXPENSE_REPORT_NOTFOUND", "Expense report not found")
+	ErrOrganizationRequired = server.NewHTTPError(http.StatusBadRequest, "ORGANIZATION_REQUIRED", "Please switch to an organization to submit expense reports")
+	ErrReportNotEditable    = server.NewHTTPError(http.StatusConflict, "EXPENSE_REPORT_NOT_EDITABLE", "Only the draft and rejected reports can be changed")
+	ErrDocumentNotFound     = server.NewHTTPError(http.StatusBadRequest, "DOCUMENT_NOTFOUND", "Document not found")
+	ErrDocumentClaimed      = server.NewHTTPError(http.StatusConflict, "DOCUMENT_CLAIMED", "The document is already in an expense report")
+	ErrCurrencyMismatch     = server.NewHTTPError(http.StatusBadRequest, "CURRENCY_MISMATCH", "The documents of a report must be in the same currency")
+	ErrEmptyReport          = server.NewHTTPError(http.StatusBadRequest, "EMPTY_EXPENSE_REPORT", "Please add documents to the report before submitting it")
+	ErrPolicyViolations     = server.NewHTTPError(http.StatusUnprocessableEntity, "POLICY_VIOLATIONS", "The report violates the expense policies")
+	ErrNoApprover           = server.NewHTTPError(http.StatusConflict, "NO_APPROVER", "The organization has no other approver or owner to decide on the report")
+	ErrInvalidTransition    = server.NewHTTPError(http.StatusConflict, "INVALID_TRANSITION", "The report is not in a status allowing this step")
+	ErrNotApprover          = server.NewHTTPError(http.StatusForbidden, "NOT_APPROVER", "Only the assigned approver or the owners can decide on the report, never its submitter")
+	ErrNotOwner             = server.NewHTTPError(http.StatusForbidden, "NOT_ORGANIZATION_OWNER", "Only the owners can mark the reports as reimbursed")
+)
@@ -0,0 +1,243 @@
+package expensereport
+
+import (
+	"errors"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	structutil "github.com/M15t/gram/pkg/util/struct"
+	"gorm.io/gorm"
+)
+
+// editableStatuses are the statuses where the submitter can change the report
+var editableStatuses = []string{types.ExpenseReportDraft, types.ExpenseReportRejected}
+
+// List returns the expense reports of the current user, or of all members of the organization for its approvers and owners
+func (s *ExpenseReport) List(c contextutil.Context, req ListExpenseReportsReq) (*ListExpenseReportsResp, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	approverID := ""
+	if req.Assigned {
+		approverID = c.AuthUser().ID
+	}
+	lc, err := req.ToListCond(approverID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	data := []*types.ExpenseReport{}
+	if err := s.repo.ExpenseReport.List(c.GetContext(), scope, &data, &count, lc); err != nil {
+		return nil, server.NewHTTPInternalError("error listing expense reports").SetInternal(err)
+	}
+
+	return &ListExpenseReportsResp{Data: data, TotalCount: count}, nil
+}
+
+// Create creates a draft expense report in the organization the current user is switched to
+func (s *ExpenseReport) Create(c contextutil.Context, data CreateExpenseReportReq) (*types.ExpenseReport, error) {
+	if _, err := s.authorize(c, rbac.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	au := c.AuthUser()
+	if au.OrganizationID == "" {
+		return nil, ErrOrganizationRequired
+	}
+
+	rec := &types.ExpenseReport{
+		UserID:         au.ID,
+		OrganizationID: au.OrganizationID,
+		Title:          data.Title,
+		Description:    data.Description,
+		Status:         types.ExpenseReportDraft,
+	}
+	if err := s.repo.ExpenseReport.CreateWithEvent(c.GetContext(), rec, &types.ExpenseReportEvent{
+		ActorID:  au.ID,
+		ToStatus: types.ExpenseReportDraft,
+	}); err != nil {
+		return nil, server.NewHTTPInternalError("error creating expense report").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// Read returns an expense report with its documents
+func (s *ExpenseReport) Read(c contextutil.Context, id string) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.find(c, scope, id)
+}
+
+// Update updates a draft or rejected expense report of the current user
+func (s *ExpenseReport) Update(c contextutil.Context, id string, data UpdateExpenseReportReq) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.findEditable(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := structutil.ToMap(data)
+	if len(updates) > 0 {
+		if err := s.repo.ExpenseReport.UpdateByID(c.GetContext(), scope, id, editableStatuses, updates); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrReportNotEditable
+			}
+			return nil, server.NewHTTPInternalError("error updating expense report").SetInternal(err)
+		}
+		if data.Title != nil {
+			rec.Title = *data.Title
+		}
+		if data.Description != nil {
+			rec.Description = *data.Description
+		}
+	}
+
+	return rec, nil
+}
+
+// Delete deletes a draft or rejected expense report of the current user, its documents can be claimed in another report
+func (s *ExpenseReport) Delete(c contextutil.Context, id string) error {
+	scope, err := s.authorize(c, rbac.ActionDelete)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.findEditable(c, scope, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.ExpenseReport.DeleteByID(c.GetContext(), scope, id, editableStatuses); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReportNotEditable
+		}
+		return server.NewHTTPInternalError("error deleting expense report").SetInternal(err)
+	}
+
+	return nil
+}
+
+// AddDocument adds a document of the current user shared in the organization to a draft or rejected expense report.
+// A document can only be claimed in one report and the documents of a report must be in the same currency.
+func (s *ExpenseReport) AddDocument(c contextutil.Context, id string, data AddDocumentReq) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.findEditable(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.repo.Document.FindByID(c.GetContext(), repo.ScopeOrganization(rec.OrganizationID, c.AuthUser().ID), data.DocumentID)
+	if err != nil {
+		return nil, ErrDocumentNotFound.SetInternal(err)
+	}
+	if len(rec.Documents) > 0 && doc.Currency != rec.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	added, err := s.repo.ExpenseReport.AddDocument(c.GetContext(), id, doc.ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error adding document").SetInternal(err)
+	}
+	if !added {
+		return nil, ErrDocumentClaimed
+	}
+
+	rec.Documents = append(rec.Documents, doc)
+	return rec, s.updateTotal(c, scope, rec)
+}
+
+// RemoveDocument removes a document from a draft or rejected expense report of the current user
+func (s *ExpenseReport) RemoveDocument(c contextutil.Context, id, documentID string) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.findEditable(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ExpenseReport.RemoveDocument(c.GetContext(), id, documentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, server.NewHTTPInternalError("error removing document").SetInternal(err)
+	}
+
+	docs := rec.Documents[:0]
+	for _, doc := range rec.Documents {
+		if doc.ID != documentID {
+			docs = append(docs, doc)
+		}
+	}
+	rec.Documents = docs
+	return rec, s.updateTotal(c, scope, rec)
+}
+
+// updateTotal updates the total and the currency of the report from its documents
+func (s *ExpenseReport) updateTotal(c contextutil.Context, scope repo.Scope, rec *types.ExpenseReport) error {
+	rec.Total, rec.Currency = 0, ""
+	for _, doc := range rec.Documents {
+		rec.Total += doc.Total
+		rec.Currency = doc.Currency
+	}
+
+	if err := s.repo.ExpenseReport.UpdateByID(c.GetContext(), scope, rec.ID, editableStatuses, map[string]interface{}{
+		"total":    rec.Total,
+		"currency": rec.Currency,
+	}); err != nil {
+		return server.NewHTTPInternalError("error updating expense report total").SetInternal(err)
+	}
+
+	return nil
+}
+
+// find returns an expense report of the scope with its documents
+func (s *ExpenseReport) find(c contextutil.Context, scope repo.Scope, id string) (*types.ExpenseReport, error) {
+	rec, err := s.repo.ExpenseReport.FindByID(c.GetContext(), scope, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, server.NewHTTPInternalError("error reading expense report").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// findEditable returns an expense report of the scope which the submitter can change
+func (s *ExpenseReport) findEditable(c contextutil.Context, scope repo.Scope, id string) (*types.ExpenseReport, error) {
+	rec, err := s.find(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != types.ExpenseReportDraft && rec.Status != types.ExpenseReportRejected {
+		return nil, ErrReportNotEditable
+	}
+
+	return rec, nil
+}
+
+// authorize checks expense report permission to perform the action, returns the reports it applies to
+func (s *ExpenseReport) authorize(c contextutil.Context, action string) (repo.Scope, error) {
+	return rbac.Authorize(s.rbac, c.AuthUser(), rbac.ObjectExpenseReport, action)
+}
@@ -0,0 +1,619 @@
+package expensereport
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents expense report http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents expense report application interface
+type Service interface {
+	List(contextutil.Context, ListExpenseReportsReq) (*ListExpenseReportsResp, error)
+	Create(contextutil.Context, CreateExpenseReportReq) (*types.ExpenseReport, error)
+	Read(contextutil.Context, string) (*types.ExpenseReport, error)
+	Update(contextutil.Context, string, UpdateExpenseReportReq) (*types.ExpenseReport, error)
+	Delete(contextutil.Context, string) error
+	AddDocument(contextutil.Context, string, AddDocumentReq) (*types.ExpenseReport, error)
+	RemoveDocument(contextutil.Context, string, string) (*types.ExpenseReport, error)
+	Submit(contextutil.Context, string) (*types.ExpenseReport, error)
+	Approve(contextutil.Context, string, DecisionReq) (*types.ExpenseReport, error)
+	Reject(contextutil.Context, string, RejectReq) (*types.ExpenseReport, error)
+	Reimburse(contextutil.Context, string, DecisionReq) (*types.ExpenseReport, error)
+	ListEvents(contextutil.Context, string) (*ListEventsResp, error)
+	ListComments(contextutil.Context, string) (*ListCommentsResp, error)
+	Comment(contextutil.Context, string, CommentReq) (*types.ExpenseReportComment, error)
+	Summary(contextutil.Context, string) (*bytes.Buffer, error)
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/app/expense-reports app-expense-reports expenseReportsList
+	// ---
+	// summary: Returns the expense reports accessible by the current user in its active organization
+	// description: The members list their own reports, the approvers and the owners list all reports of the organization
+	// responses:
+	//   "200":
+	//     description: List of expense reports
+	//     schema:
+	//       "$ref": "#/definitions/ListExpenseReportsResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation POST /v1/app/expense-reports app-expense-reports expenseReportsCreate
+	// ---
+	// summary: Creates a draft expense report in the active organization of the current user
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateExpenseReportReq"
+	// responses:
+	//   "200":
+	//     description: The new expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("", h.create)
+
+	// swagger:operation GET /v1/app/expense-reports/{id} app-expense-reports expenseReportsRead
+	// ---
+	// summary: Returns an expense report with its documents
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id", h.read)
+
+	// swagger:operation PATCH /v1/app/expense-reports/{id} app-expense-reports expenseReportsUpdate
+	// ---
+	// summary: Updates a draft or rejected expense report of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/UpdateExpenseReportReq"
+	// responses:
+	//   "200":
+	//     description: The updated expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.PATCH("/:id", h.update)
+
+	// swagger:operation DELETE /v1/app/expense-reports/{id} app-expense-reports expenseReportsDelete
+	// ---
+	// summary: Deletes a draft or rejected expense report of the current user
+	// description: The documents of the report are released to be claimed in another report
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id", h.delete)
+
+	// swagger:operation POST /v1/app/expense-reports/{id}/documents app-expense-reports expenseReportsAddDocument
+	// ---
+	// summary: Adds a document to a draft or rejected expense report of the current user
+	// description: A document can be claimed in only one expense report, in the currency of the report
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/AddDocumentReq"
+	// responses:
+	//   "200":
+	//     description: The updated expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/documents", h.addDocument)
+
+	// swagger:operation DELETE /v1/app/expense-reports/{id}/documents/{document_id} app-expense-reports expenseReportsRemoveDocument
+	// ---
+	// summary: Removes a document from a draft or rejected expense report of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: document_id
+	//   in: path
+	//   description: id of document
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The updated expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id/documents/:document_id", h.removeDocument)
+
+	// swagger:operation POST /v1/app/expense-reports/{id}/submit app-expense-reports expenseReportsSubmit
+	// ---
+	// summary: Submits a draft or rejected expense report of the current user for approval
+	// description: The documents are checked against the expense policies, the violations are flagged on the report for the approver, or rejected with 422 if blocking is configured. An approver of the organization is assigned to the report.
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The submitted expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 422, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/submit", h.submit)
+
+	// swagger:operation POST /v1/app/expense-reports/{id}/approve app-expense-reports expenseReportsApprove
+	// ---
+	// summary: Approves a submitted expense report, by its approver or an owner of the organization
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/DecisionReq"
+	// responses:
+	//   "200":
+	//     description: The approved expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/approve", h.approve)
+
+	// swagger:operation POST /v1/app/expense-reports/{id}/reject app-expense-reports expenseReportsReject
+	// ---
+	// summary: Rejects a submitted expense report with a reason, by its approver or an owner of the organization
+	// description: The submitter can fix the rejected report and submit it again
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/RejectReq"
+	// responses:
+	//   "200":
+	//     description: The rejected expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/reject", h.reject)
+
+	// swagger:operation POST /v1/app/expense-reports/{id}/reimburse app-expense-reports expenseReportsReimburse
+	// ---
+	// summary: Marks an approved expense report as reimbursed, by an owner of the organization
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/DecisionReq"
+	// responses:
+	//   "200":
+	//     description: The reimbursed expense report
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/reimburse", h.reimburse)
+
+	// swagger:operation GET /v1/app/expense-reports/{id}/events app-expense-reports expenseReportsListEvents
+	// ---
+	// summary: Returns the approval trail of an expense report, the oldest step first
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: List of events
+	//     schema:
+	//       "$ref": "#/definitions/ListEventsResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/events", h.listEvents)
+
+	// swagger:operation GET /v1/app/expense-reports/{id}/comments app-expense-reports expenseReportsListComments
+	// ---
+	// summary: Returns the comments of an expense report, the oldest first
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: List of comments
+	//     schema:
+	//       "$ref": "#/definitions/ListCommentsResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/comments", h.listComments)
+
+	// swagger:operation POST /v1/app/expense-reports/{id}/comments app-expense-reports expenseReportsComment
+	// ---
+	// summary: Comments on an expense report
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CommentReq"
+	// responses:
+	//   "200":
+	//     description: The new comment
+	//     schema:
+	//       "$ref": "#/definitions/ExpenseReportComment"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/comments", h.comment)
+
+	// swagger:operation GET /v1/app/expense-reports/{id}/pdf app-expense-reports expenseReportsSummary
+	// ---
+	// summary: Downloads the PDF summary of an expense report
+	// description: The summary lists the documents, the flagged policy violations, the approval trail and the comments
+	// produces:
+	// - application/pdf
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of expense report
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The PDF summary
+	//     schema:
+	//       type: file
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/pdf", h.summary)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	r := ListExpenseReportsReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.List(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) create(c echo.Context) error {
+	r := CreateExpenseReportReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Create(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) read(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Read(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) update(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := UpdateExpenseReportReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Update(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) delete(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Delete(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) addDocument(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := AddDocumentReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.AddDocument(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) removeDocument(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.RemoveDocument(contextutil.NewContext(c), id, c.Param("document_id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) submit(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Submit(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) approve(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := DecisionReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Approve(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) reject(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := RejectReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Reject(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) reimburse(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := DecisionReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Reimburse(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) listEvents(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.ListEvents(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) listComments(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.ListComments(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) comment(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := CommentReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Comment(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) summary(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	buf, err := h.svc.Summary(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="expense-report-%s.pdf"`, id))
+	return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
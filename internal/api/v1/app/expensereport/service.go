@@ -0,0 +1,32 @@
+package expensereport
+
+import (
+	"context"
+	"io"
+
+	"tyr/internal/expense"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new expense report application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, expense Expense) *ExpenseReport {
+	return &ExpenseReport{repo: repo, rbac: rbacSvc, expense: expense}
+}
+
+// ExpenseReport represents expense report application service
+type ExpenseReport struct {
+	repo    *repo.Service
+	rbac    rbac.Intf
+	expense Expense
+}
+
+// Expense represents expense policy, approver assignment and summary interface
+type Expense interface {
+	Check(ctx context.Context, rec *types.ExpenseReport) ([]*types.ExpensePolicyViolation, error)
+	BlockViolations() bool
+	AssignApprover(ctx context.Context, rec *types.ExpenseReport) (string, error)
+	WriteSummary(out io.Writer, s *expense.Summary) error
+}
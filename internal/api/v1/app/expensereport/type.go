@@ -0,0 +1,104 @@
+package expensereport
+
+import (
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	filterutil "tyr/internal/util/filter"
+
+	requestutil "github.com/M15t/gram/pkg/util/request"
+)
+
+// CreateExpenseReportReq contains request data to create an expense report
+// swagger:model
+type CreateExpenseReportReq struct {
+	// example: Client visit in Berlin
+	Title       string `json:"title" validate:"required,max=100"`
+	Description string `json:"description,omitempty" validate:"omitempty,max=2000"`
+}
+
+// UpdateExpenseReportReq contains request data to update a draft or rejected expense report
+// swagger:model
+type UpdateExpenseReportReq struct {
+	// example: Client visit in Berlin
+	Title       *string `json:"title,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=2000"`
+}
+
+// AddDocumentReq contains request data to add a document to an expense report
+// swagger:model
+type AddDocumentReq struct {
+	// A document of the submitter shared in the organization of the report
+	DocumentID string `json:"document_id" validate:"required"`
+}
+
+// DecisionReq contains request data to approve or reimburse an expense report
+// swagger:model
+type DecisionReq struct {
+	// Kept in the approval trail
+	Note string `json:"note,omitempty" validate:"omitempty,max=2000"`
+}
+
+// RejectReq contains request data to reject an expense report
+// swagger:model
+type RejectReq struct {
+	// Kept in the approval trail for the submitter to fix the report
+	Reason string `json:"reason" validate:"required,max=2000"`
+}
+
+// CommentReq contains request data to comment on an expense report
+// swagger:model
+type CommentReq struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+// ListExpenseReportsReq contains request data to get list of expense reports
+// swagger:parameters expenseReportsList
+type ListExpenseReportsReq struct {
+	requestutil.ListQueryRequest
+	// Filter expression, conditions are separated by comma and combined by AND.
+	// Supported operators: =, !=, >, >=, <, <=, ~ (contains), !~ (not contains), between (from..to), in (a|b|c).
+	// Values containing commas must be double-quoted, eg: `status in submitted|approved,total>=100`
+	// in: query
+	Filter string `json:"filter,omitempty" query:"filter"`
+	// Only the reports assigned to the current user to decide on
+	// in: query
+	Assigned bool `json:"assigned,omitempty" query:"assigned"`
+}
+
+// ListExpenseReportsResp contains list of paginated expense reports and total numbers after filtered
+// swagger:model
+type ListExpenseReportsResp struct {
+	Data       []*types.ExpenseReport `json:"data"`
+	TotalCount int64                  `json:"total_count"`
+}
+
+// ListCommentsResp contains the comments of an expense report
+// swagger:model
+type ListCommentsResp struct {
+	Data []*types.ExpenseReportComment `json:"data"`
+}
+
+// ListEventsResp contains the approval trail of an expense report
+// swagger:model
+type ListEventsResp struct {
+	Data []*types.ExpenseReportEvent `json:"data"`
+}
+
+// ToListCond transforms the service request to repo conditions
+func (lq *ListExpenseReportsReq) ToListCond(approverID string) (*requestutil.ListCondition[repo.ExpenseReportsFilter], error) {
+	query, err := filterutil.ParseList(lq.Filter, lq.Sort, repo.ExpenseReportFilterSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &requestutil.ListCondition[repo.ExpenseReportsFilter]{
+		Page:    lq.Page,
+		PerPage: lq.PerPage,
+		Sort:    lq.Sort,
+		Count:   true,
+		Filter: repo.ExpenseReportsFilter{
+			ApproverID: approverID,
+			Query:      query,
+		},
+	}, nil
+}
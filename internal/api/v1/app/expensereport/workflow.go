@@ -0,0 +1,252 @@
+package expensereport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/expense"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Submit submits a draft or rejected expense report of the current user for approval.
+// The documents are checked against the expense policies, the violations are flagged for the approver
+// or block the submission if configured. An approver is assigned among the other members by their organization role.
+// The total is recomputed from the documents, which cannot change anymore until the report is rejected.
+func (s *ExpenseReport) Submit(c contextutil.Context, id string) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.findEditable(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.Documents) == 0 {
+		return nil, ErrEmptyReport
+	}
+	// the documents may have changed while the report was editable, the total is fixed from now on
+	rec.Total, rec.Currency = 0, rec.Documents[0].Currency
+	for _, doc := range rec.Documents {
+		if doc.Currency != rec.Currency {
+			return nil, ErrCurrencyMismatch
+		}
+		rec.Total += doc.Total
+	}
+
+	ctx := c.GetContext()
+	violations, err := s.expense.Check(ctx, rec)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error checking expense policies").SetInternal(err)
+	}
+	if len(violations) > 0 && s.expense.BlockViolations() {
+		messages := make([]string, len(violations))
+		for i, v := range violations {
+			messages[i] = v.Message
+		}
+		return nil, server.NewHTTPError(ErrPolicyViolations.Code, ErrPolicyViolations.Type, strings.Join(messages, "; "))
+	}
+
+	approverID, err := s.expense.AssignApprover(ctx, rec)
+	if err != nil {
+		if errors.Is(err, expense.ErrNoApprover) {
+			return nil, ErrNoApprover
+		}
+		return nil, server.NewHTTPInternalError("error assigning approver").SetInternal(err)
+	}
+
+	flagged, _ := json.Marshal(violations)
+	note := ""
+	if len(violations) > 0 {
+		note = fmt.Sprintf("%d policy violation(s) flagged", len(violations))
+	}
+	now := time.Now()
+	if err := s.transition(c, rec, editableStatuses, types.ExpenseReportSubmitted, note, map[string]interface{}{
+		"approver_id":       approverID,
+		"total":             rec.Total,
+		"currency":          rec.Currency,
+		"submitted_at":      now,
+		"decided_at":        nil,
+		"policy_violations": datatypes.JSON(flagged),
+	}); err != nil {
+		return nil, err
+	}
+
+	rec.ApproverID, rec.SubmittedAt, rec.DecidedAt, rec.PolicyViolations = &approverID, &now, nil, flagged
+	return rec, nil
+}
+
+// Approve approves a submitted expense report, by the assigned approver or an owner of the organization
+func (s *ExpenseReport) Approve(c contextutil.Context, id string, data DecisionReq) (*types.ExpenseReport, error) {
+	return s.decide(c, id, types.ExpenseReportApproved, data.Note)
+}
+
+// Reject rejects a submitted expense report with the reason, by the assigned approver or an owner of the organization.
+// The submitter can then change the report and submit it again.
+func (s *ExpenseReport) Reject(c contextutil.Context, id string, data RejectReq) (*types.ExpenseReport, error) {
+	return s.decide(c, id, types.ExpenseReportRejected, data.Reason)
+}
+
+// Reimburse marks an approved expense report as reimbursed, by an owner of the organization
+func (s *ExpenseReport) Reimburse(c contextutil.Context, id string, data DecisionReq) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.find(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != types.ExpenseReportApproved {
+		return nil, ErrInvalidTransition
+	}
+	if c.AuthUser().OrgRole != types.OrgRoleOwner {
+		return nil, ErrNotOwner
+	}
+
+	now := time.Now()
+	if err := s.transition(c, rec, []string{types.ExpenseReportApproved}, types.ExpenseReportReimbursed, data.Note, map[string]interface{}{
+		"reimbursed_at": now,
+	}); err != nil {
+		return nil, err
+	}
+
+	rec.ReimbursedAt = &now
+	return rec, nil
+}
+
+// ListEvents returns the approval trail of an expense report, the oldest step first
+func (s *ExpenseReport) ListEvents(c contextutil.Context, id string) (*ListEventsResp, error) {
+	if err := s.exists(c, id); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.ExpenseReportEvent.ListByReportID(c.GetContext(), id)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing expense report events").SetInternal(err)
+	}
+
+	return &ListEventsResp{Data: data}, nil
+}
+
+// Summary generates the PDF summary of an expense report
+func (s *ExpenseReport) Summary(c contextutil.Context, id string) (*bytes.Buffer, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.find(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.GetContext()
+	summary := &expense.Summary{Report: rec, Organization: &types.Organization{}, Submitter: &types.User{}}
+	if err := s.repo.Organization.ReadByID(ctx, summary.Organization, rec.OrganizationID); err != nil {
+		return nil, server.NewHTTPInternalError("error reading organization").SetInternal(err)
+	}
+	if err := s.repo.User.ReadByID(ctx, summary.Submitter, rec.UserID); err != nil {
+		return nil, server.NewHTTPInternalError("error reading submitter").SetInternal(err)
+	}
+	if rec.ApproverID != nil {
+		summary.Approver = &types.User{}
+		if err := s.repo.User.ReadByID(ctx, summary.Approver, *rec.ApproverID); err != nil {
+			return nil, server.NewHTTPInternalError("error reading approver").SetInternal(err)
+		}
+	}
+	if len(rec.PolicyViolations) > 0 {
+		if err := json.Unmarshal(rec.PolicyViolations, &summary.Violations); err != nil {
+			return nil, server.NewHTTPInternalError("error reading policy violations").SetInternal(err)
+		}
+	}
+	if summary.Events, err = s.repo.ExpenseReportEvent.ListByReportID(ctx, id); err != nil {
+		return nil, server.NewHTTPInternalError("error listing expense report events").SetInternal(err)
+	}
+	if summary.Comments, err = s.repo.ExpenseReportComment.ListByReportID(ctx, id); err != nil {
+		return nil, server.NewHTTPInternalError("error listing expense report comments").SetInternal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := s.expense.WriteSummary(buf, summary); err != nil {
+		return nil, server.NewHTTPInternalError("error generating expense report summary").SetInternal(err)
+	}
+
+	return buf, nil
+}
+
+// decide approves or rejects a submitted expense report
+func (s *ExpenseReport) decide(c contextutil.Context, id, status, note string) (*types.ExpenseReport, error) {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.find(c, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != types.ExpenseReportSubmitted {
+		return nil, ErrInvalidTransition
+	}
+	if !expense.CanDecide(rec, c.AuthUser().ID, c.AuthUser().OrgRole) {
+		return nil, ErrNotApprover
+	}
+
+	now := time.Now()
+	if err := s.transition(c, rec, []string{types.ExpenseReportSubmitted}, status, note, map[string]interface{}{
+		"decided_at": now,
+	}); err != nil {
+		return nil, err
+	}
+
+	rec.DecidedAt = &now
+	return rec, nil
+}
+
+// transition moves the report from one of the given statuses to the next one with the updates, and records the step
+// by the current user in the approval trail
+func (s *ExpenseReport) transition(c contextutil.Context, rec *types.ExpenseReport, from []string, to, note string, updates map[string]interface{}) error {
+	updates["status"] = to
+	if err := s.repo.ExpenseReport.Transition(c.GetContext(), rec.ID, from, updates, &types.ExpenseReportEvent{
+		ExpenseReportID: rec.ID,
+		ActorID:         c.AuthUser().ID,
+		FromStatus:      rec.Status,
+		ToStatus:        to,
+		Note:            note,
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// changed concurrently
+			return ErrInvalidTransition
+		}
+		return server.NewHTTPInternalError("error updating expense report status").SetInternal(err)
+	}
+
+	rec.Status = to
+	return nil
+}
+
+// exists checks the expense report is accessible by the current user
+func (s *ExpenseReport) exists(c contextutil.Context, id string) error {
+	scope, err := s.authorize(c, rbac.ActionRead)
+	if err != nil {
+		return err
+	}
+
+	if existed, err := s.repo.ExpenseReport.ExistedByID(c.GetContext(), scope, id); err != nil || !existed {
+		return ErrReportNotFound.SetInternal(err)
+	}
+
+	return nil
+}
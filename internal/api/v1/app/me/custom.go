@@ -0,0 +1,19 @@
+package me
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrIncorrectPassword   = server.NewHTTPError(http.StatusBadRequest, "INCORRECT_PASSWORD", "Incorrect password")
+	ErrPhoneExisted        = server.NewHTTPValidationError("Phone already existed")
+	ErrWeakPassword        = server.NewHTTPError(http.StatusBadRequest, "WEAK_PASSWORD", "The password does not satisfy the password policy")
+	ErrErasureScheduled    = server.NewHTTPError(http.StatusConflict, "ERASURE_SCHEDULED", "The erasure of the account has already been scheduled")
+	ErrErasureNotScheduled = server.NewHTTPError(http.StatusBadRequest, "ERASURE_NOT_SCHEDULED", "The erasure of the account has not been scheduled")
+	ErrExportInProgress    = server.NewHTTPError(http.StatusConflict, "EXPORT_IN_PROGRESS", "The previous data export is still in progress")
+	ErrExportNotFound      = server.NewHTTPError(http.StatusBadRequest, "EXPORT_NOTFOUND", "Data export not found")
+	ErrExportNotReady      = server.NewHTTPError(http.StatusConflict, "EXPORT_NOT_READY", "The data export is not completed")
+)
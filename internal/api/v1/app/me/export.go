@@ -0,0 +1,77 @@
+package me
+
+import (
+	"errors"
+	"io"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/privacy"
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// RequestExport requests an archive of all data of the current user, built in background by the privacy job,
+// only one export can be in progress at a time
+func (s *Me) RequestExport(c contextutil.Context) (*types.DataExport, error) {
+	if err := s.enforceExport(c, rbac.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	rec, err := s.privacy.StartExport(c.GetContext(), c.AuthUser().ID)
+	if err != nil {
+		if errors.Is(err, privacy.ErrExportInProgress) {
+			return nil, ErrExportInProgress
+		}
+		return nil, server.NewHTTPInternalError("error starting data export").SetInternal(err)
+	}
+
+	if err := s.audit(c, types.AuditActionDataExported, "data_export", rec.ID, "requested by the user", nil); err != nil {
+		return nil, server.NewHTTPInternalError("error auditing data export").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// ReadExport returns a data export of the current user
+func (s *Me) ReadExport(c contextutil.Context, id string) (*types.DataExport, error) {
+	if err := s.enforceExport(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	rec, err := s.repo.DataExport.FindByID(c.GetContext(), repo.ScopeOwner(c.AuthUser().ID), id)
+	if err != nil {
+		return nil, ErrExportNotFound.SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// DownloadExport opens the archive of a completed data export of the current user, caller must close the reader
+func (s *Me) DownloadExport(c contextutil.Context, id string) (io.ReadCloser, error) {
+	rec, err := s.ReadExport(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != types.DataExportCompleted {
+		return nil, ErrExportNotReady
+	}
+
+	r, err := s.privacy.OpenExport(c.GetContext(), rec)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error reading data export").SetInternal(err)
+	}
+
+	return r, nil
+}
+
+// enforceExport checks data export permission to perform the action
+func (s *Me) enforceExport(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectDataExport, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
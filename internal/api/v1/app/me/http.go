@@ -0,0 +1,283 @@
+package me
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents me http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents me application interface
+type Service interface {
+	Read(contextutil.Context) (*types.User, error)
+	Update(contextutil.Context, UpdateMeReq) (*types.User, error)
+	ChangePassword(contextutil.Context, ChangePasswordReq) error
+	Delete(contextutil.Context, DeleteMeReq) (*types.User, error)
+	CancelErasure(contextutil.Context) error
+	RequestExport(contextutil.Context) (*types.DataExport, error)
+	ReadExport(contextutil.Context, string) (*types.DataExport, error)
+	DownloadExport(contextutil.Context, string) (io.ReadCloser, error)
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/app/me app-me meRead
+	// ---
+	// summary: Returns the current user
+	// responses:
+	//   "200":
+	//     description: The current user
+	//     schema:
+	//       "$ref": "#/definitions/User"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.read)
+
+	// swagger:operation PATCH /v1/app/me app-me meUpdate
+	// ---
+	// summary: Updates the name and the phone of the current user
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/UpdateMeReq"
+	// responses:
+	//   "200":
+	//     description: The updated user
+	//     schema:
+	//       "$ref": "#/definitions/User"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.PATCH("", h.update)
+
+	// swagger:operation PATCH /v1/app/me/password app-me meChangePassword
+	// ---
+	// summary: Changes the password of the current user
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/ChangePasswordReq"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.PATCH("/password", h.changePassword)
+
+	// swagger:operation DELETE /v1/app/me app-me meDelete
+	// ---
+	// summary: Schedules the erasure of all data of the current user after the grace period, confirmed by the password
+	// description: All sessions are revoked, the user can still login and cancel the erasure within the grace period
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/DeleteMeReq"
+	// responses:
+	//   "200":
+	//     description: The current user with the scheduled erasure time
+	//     schema:
+	//       "$ref": "#/definitions/User"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("", h.delete)
+
+	// swagger:operation DELETE /v1/app/me/erasure app-me meCancelErasure
+	// ---
+	// summary: Cancels the scheduled erasure of the current user
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/erasure", h.cancelErasure)
+}
+
+// NewExportHTTP attaches data export handlers to Echo routers under given group
+func NewExportHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation POST /v1/app/me/export app-me meRequestExport
+	// ---
+	// summary: Starts building a ZIP archive of all data of the current user
+	// description: The archive contains the profile, sessions, documents, line items, analyses, analytics and receipt files in JSON and CSV
+	// responses:
+	//   "202":
+	//     description: The pending data export
+	//     schema:
+	//       "$ref": "#/definitions/DataExport"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("", h.requestExport)
+
+	// swagger:operation GET /v1/app/me/export/{id} app-me meReadExport
+	// ---
+	// summary: Returns a data export of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of data export
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The data export
+	//     schema:
+	//       "$ref": "#/definitions/DataExport"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id", h.readExport)
+
+	// swagger:operation GET /v1/app/me/export/{id}/download app-me meDownloadExport
+	// ---
+	// summary: Downloads the ZIP archive of a completed data export of the current user
+	// produces:
+	// - application/zip
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of data export
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The ZIP archive
+	//     schema:
+	//       type: file
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/download", h.downloadExport)
+}
+
+func (h *HTTP) read(c echo.Context) error {
+	resp, err := h.svc.Read(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) update(c echo.Context) error {
+	r := UpdateMeReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	r.FirstName = httputil.TrimSpacePointer(r.FirstName)
+	r.LastName = httputil.TrimSpacePointer(r.LastName)
+	r.Phone = httputil.RemoveSpacePointer(r.Phone)
+
+	resp, err := h.svc.Update(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) changePassword(c echo.Context) error {
+	r := ChangePasswordReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	if err := h.svc.ChangePassword(contextutil.NewContext(c), r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) delete(c echo.Context) error {
+	r := DeleteMeReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	resp, err := h.svc.Delete(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) cancelErasure(c echo.Context) error {
+	if err := h.svc.CancelErasure(contextutil.NewContext(c)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) requestExport(c echo.Context) error {
+	resp, err := h.svc.RequestExport(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+func (h *HTTP) readExport(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.ReadExport(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) downloadExport(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r, err := h.svc.DownloadExport(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="tyr-export-%s.zip"`, id))
+	return c.Stream(http.StatusOK, "application/zip", r)
+}
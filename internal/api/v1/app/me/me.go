@@ -0,0 +1,190 @@
+package me
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/passwordpolicy"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/datatypes"
+)
+
+// Read returns the current user
+func (s *Me) Read(c contextutil.Context) (*types.User, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	rec := &types.User{}
+	if err := s.repo.User.ReadByID(c.GetContext(), rec, c.AuthUser().ID); err != nil {
+		return nil, server.NewHTTPInternalError("error reading user").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// Update updates the name and the phone of the current user, the new phone is not verified
+func (s *Me) Update(c contextutil.Context, data UpdateMeReq) (*types.User, error) {
+	rec, err := s.Read(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enforce(c, rbac.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if data.FirstName != nil {
+		updates["first_name"] = *data.FirstName
+	}
+	if data.LastName != nil {
+		updates["last_name"] = *data.LastName
+	}
+	if data.Phone != nil && *data.Phone != rec.Phone {
+		if existed, err := s.repo.User.Existed(c.GetContext(), map[string]interface{}{"phone": *data.Phone}); err != nil || existed {
+			return nil, ErrPhoneExisted.SetInternal(err)
+		}
+		updates["phone"] = *data.Phone
+		updates["phone_verified_at"] = nil
+		updates["otp"] = nil
+		updates["otp_sent_at"] = nil
+	}
+	if len(updates) == 0 {
+		return rec, nil
+	}
+
+	if err := s.repo.User.Update(c.GetContext(), updates, rec.ID); err != nil {
+		return nil, server.NewHTTPInternalError("error updating user").SetInternal(err)
+	}
+
+	return s.Read(c)
+}
+
+// ChangePassword changes the password of the current user, the new one must satisfy the password policy
+func (s *Me) ChangePassword(c contextutil.Context, data ChangePasswordReq) error {
+	rec, err := s.Read(c)
+	if err != nil {
+		return err
+	}
+	if err := s.enforce(c, rbac.ActionUpdate); err != nil {
+		return err
+	}
+
+	if !s.cr.CompareHashAndPassword(rec.Password, data.OldPassword) {
+		return ErrIncorrectPassword
+	}
+
+	if err := s.password.Validate(c.GetContext(), data.NewPassword, rec); err != nil {
+		var violation *passwordpolicy.ViolationError
+		if errors.As(err, &violation) {
+			return server.NewHTTPError(ErrWeakPassword.Code, ErrWeakPassword.Type, violation.Error())
+		}
+		return server.NewHTTPInternalError("error checking password").SetInternal(err)
+	}
+
+	hash := s.cr.HashPassword(data.NewPassword)
+	if err := s.repo.User.Update(c.GetContext(), map[string]interface{}{
+		"password":            hash,
+		"password_changed_at": time.Now(),
+	}, rec.ID); err != nil {
+		return server.NewHTTPInternalError("error changing password").SetInternal(err)
+	}
+
+	return s.password.Remember(c.GetContext(), rec.ID, hash)
+}
+
+// Delete schedules the erasure of all data of the current user confirmed by the password, all sessions are revoked.
+// The user can still login and cancel it within the grace period.
+func (s *Me) Delete(c contextutil.Context, data DeleteMeReq) (*types.User, error) {
+	rec, err := s.Read(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return nil, err
+	}
+
+	if !s.cr.CompareHashAndPassword(rec.Password, data.Password) {
+		return nil, ErrIncorrectPassword
+	}
+	if rec.ErasureScheduledAt != nil {
+		return nil, ErrErasureScheduled
+	}
+
+	at := s.privacy.ErasureDueAt(time.Now())
+	if err := s.repo.User.ScheduleErasure(c.GetContext(), rec.ID, &at); err != nil {
+		return nil, server.NewHTTPInternalError("error scheduling erasure").SetInternal(err)
+	}
+
+	revokedIDs, err := s.repo.Session.RevokeAllByUserID(c.GetContext(), rec.ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+	if err := s.denylist.RevokeSessions(c.GetContext(), revokedIDs...); err != nil {
+		return nil, server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+
+	if err := s.audit(c, types.AuditActionErasureScheduled, "user", rec.ID, "requested by the user", map[string]interface{}{"scheduled_at": at}); err != nil {
+		return nil, server.NewHTTPInternalError("error auditing erasure").SetInternal(err)
+	}
+
+	return s.Read(c)
+}
+
+// CancelErasure cancels the scheduled erasure of the current user
+func (s *Me) CancelErasure(c contextutil.Context) error {
+	rec, err := s.Read(c)
+	if err != nil {
+		return err
+	}
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return err
+	}
+
+	if rec.ErasureScheduledAt == nil {
+		return ErrErasureNotScheduled
+	}
+
+	if err := s.repo.User.ScheduleErasure(c.GetContext(), rec.ID, nil); err != nil {
+		return server.NewHTTPInternalError("error cancelling erasure").SetInternal(err)
+	}
+
+	if err := s.audit(c, types.AuditActionErasureCancelled, "user", rec.ID, "cancelled by the user", map[string]interface{}{"scheduled_at": rec.ErasureScheduledAt}); err != nil {
+		return server.NewHTTPInternalError("error auditing erasure").SetInternal(err)
+	}
+
+	return nil
+}
+
+// audit records an action of the current user on its own account or data
+func (s *Me) audit(c contextutil.Context, action, objectType, objectID, reason string, metadata map[string]interface{}) error {
+	var meta datatypes.JSON
+	if metadata != nil {
+		meta, _ = json.Marshal(metadata)
+	}
+
+	return s.repo.AuditEvent.Create(c.GetContext(), &types.AuditEvent{
+		UserID:     c.AuthUser().ID,
+		Action:     action,
+		ObjectType: objectType,
+		ObjectID:   objectID,
+		Reason:     reason,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   meta,
+	})
+}
+
+// enforce checks user permission to perform the action
+func (s *Me) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectUser, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package me
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	userID       = "01HUSER00000000000000000000"
+	erasureGrace = 30 * 24 * time.Hour
+)
+
+// TestDelete proves the deletion of the account only schedules the erasure after the grace period,
+// the sessions are revoked right away so the user is logged out until the erasure or its cancellation
+func TestDelete(t *testing.T) {
+	scheduledAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		password  string
+		scheduled *time.Time
+		want      error
+	}{
+		{name: "schedule", password: "secret"},
+		{name: "incorrect password", password: "guess", want: ErrIncorrectPassword},
+		{name: "already scheduled", password: "secret", scheduled: &scheduledAt, want: ErrErasureScheduled},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db, denylist := newTestMe(t)
+			db.user.ErasureScheduledAt = tc.scheduled
+
+			before := time.Now()
+			rec, err := s.Delete(newTestContext(), DeleteMeReq{Password: tc.password})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			if tc.want != nil {
+				if db.user.ErasureScheduledAt != tc.scheduled || len(db.audits) > 0 || len(denylist.revoked) > 0 {
+					t.Errorf("got the erasure scheduled at %v, audits %v and the sessions %q revoked", db.user.ErasureScheduledAt, db.audits, denylist.revoked)
+				}
+				return
+			}
+
+			at := rec.ErasureScheduledAt
+			if at == nil || at.Before(before.Add(erasureGrace)) || at.After(time.Now().Add(erasureGrace)) {
+				t.Fatalf("got the erasure scheduled at %v, want after the grace period", at)
+			}
+			if db.user.Status != types.UserStatusActive || db.user.DeletedAt.Valid {
+				t.Errorf("got the user %s and deleted %v, want the data kept until the erasure", db.user.Status, db.user.DeletedAt.Valid)
+			}
+			if strings.Join(denylist.revoked, ",") != "s1,s2" {
+				t.Errorf("got the sessions %q revoked, want all active sessions", denylist.revoked)
+			}
+			if len(db.audits) != 1 || db.audits[0].Action != types.AuditActionErasureScheduled || db.audits[0].ObjectID != userID {
+				t.Errorf("got audits %+v", db.audits)
+			}
+		})
+	}
+}
+
+func TestCancelErasure(t *testing.T) {
+	scheduledAt := time.Now().Add(erasureGrace)
+
+	cases := []struct {
+		name      string
+		scheduled *time.Time
+		want      error
+	}{
+		{name: "cancel", scheduled: &scheduledAt},
+		{name: "not scheduled", want: ErrErasureNotScheduled},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db, _ := newTestMe(t)
+			db.user.ErasureScheduledAt = tc.scheduled
+
+			if err := s.CancelErasure(newTestContext()); !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			if tc.want != nil {
+				if db.updates > 0 || len(db.audits) > 0 {
+					t.Errorf("got %d updates and audits %+v", db.updates, db.audits)
+				}
+				return
+			}
+
+			if db.user.ErasureScheduledAt != nil {
+				t.Errorf("got the erasure still scheduled at %v", db.user.ErasureScheduledAt)
+			}
+			if len(db.audits) != 1 || db.audits[0].Action != types.AuditActionErasureCancelled {
+				t.Errorf("got audits %+v", db.audits)
+			}
+		})
+	}
+
+	t.Run("scheduled again after the cancellation", func(t *testing.T) {
+		s, db, _ := newTestMe(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := s.Delete(newTestContext(), DeleteMeReq{Password: "secret"}); err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if err := s.CancelErasure(newTestContext()); err != nil {
+				t.Fatalf("got %v", err)
+			}
+		}
+		if db.user.ErasureScheduledAt != nil || len(db.audits) != 4 {
+			t.Errorf("got the erasure scheduled at %v with %d audits", db.user.ErasureScheduledAt, len(db.audits))
+		}
+	})
+}
+
+// fakeMeDB answers the dry run statements from the current user with two active sessions, it records the updates and the audits
+type fakeMeDB struct {
+	user     *types.User
+	sessions *sql.DB
+	updates  int
+	audits   []*types.AuditEvent
+}
+
+func newTestMe(t *testing.T) (*Me, *fakeMeDB, *fakeDenylist) {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeMeDB{
+		user:     &types.User{Base: types.Base{ID: userID}, Email: "jo@tyr.io", Password: "hash:secret", Role: rbac.RoleUser, Status: types.UserStatusActive},
+		sessions: sql.OpenDB(idsConnector{"s1", "s2"}),
+	}
+	t.Cleanup(func() { f.sessions.Close() })
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Register("test:query", f.query),
+		gdb.Callback().Row().After("gorm:row").Register("test:row", f.row),
+		gdb.Callback().Update().After("gorm:update").Register("test:update", f.update),
+		gdb.Callback().Create().After("gorm:create").Register("test:create", f.create),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rbacSvc, err := rbac.New(defaultPolicies{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	denylist := &fakeDenylist{}
+	return New(repo.New(gdb), rbacSvc, fakeCrypter{}, nil, denylist, fakePrivacy{}), f, denylist
+}
+
+func (f *fakeMeDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if dest, ok := db.Statement.Dest.(*types.User); ok {
+		*dest = *f.user
+		db.RowsAffected = 1
+	}
+}
+
+// row answers the revocation of the sessions, returning their ids
+func (f *fakeMeDB) row(db *gorm.DB) {
+	if db.Error != nil || !strings.HasPrefix(db.Statement.SQL.String(), "UPDATE sessions SET is_blocked = true") {
+		return
+	}
+	db.Statement.Dest, db.Error = f.sessions.Query("RETURNING id")
+}
+
+func (f *fakeMeDB) update(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table != "users" {
+		return
+	}
+	f.updates++
+	if updates, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		if at, ok := updates["erasure_scheduled_at"]; ok {
+			f.user.ErasureScheduledAt = at.(*time.Time)
+		}
+	}
+	db.RowsAffected = 1
+}
+
+func (f *fakeMeDB) create(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if rec, ok := db.Statement.Dest.(*types.AuditEvent); ok {
+		f.audits = append(f.audits, rec)
+	}
+	db.RowsAffected = 1
+}
+
+// idsConnector is a database answering every query with the ids as rows of an id column
+type idsConnector []string
+
+func (c idsConnector) Connect(context.Context) (driver.Conn, error) { return idsConn(c), nil }
+func (c idsConnector) Driver() driver.Driver                        { return nil }
+
+type idsConn []string
+
+func (c idsConn) Prepare(string) (driver.Stmt, error) { return idsStmt(c), nil }
+func (idsConn) Close() error                          { return nil }
+func (idsConn) Begin() (driver.Tx, error)             { return nil, errors.New("not supported") }
+
+type idsStmt []string
+
+func (idsStmt) Close() error                               { return nil }
+func (idsStmt) NumInput() int                              { return -1 }
+func (idsStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("not supported") }
+func (s idsStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &idsRows{ids: s}, nil
+}
+
+type idsRows struct {
+	ids []string
+	i   int
+}
+
+func (*idsRows) Columns() []string { return []string{"id"} }
+func (*idsRows) Close() error      { return nil }
+func (r *idsRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.ids) {
+		return io.EOF
+	}
+	dest[0] = r.ids[r.i]
+	r.i++
+	return nil
+}
+
+type fakeDenylist struct {
+	revoked []string
+}
+
+func (f *fakeDenylist) RevokeSessions(_ context.Context, sessionIDs ...string) error {
+	f.revoked = append(f.revoked, sessionIDs...)
+	return nil
+}
+
+// fakePrivacy schedules the erasures after the grace period
+type fakePrivacy struct{}
+
+func (fakePrivacy) StartExport(context.Context, string) (*types.DataExport, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakePrivacy) OpenExport(context.Context, *types.DataExport) (io.ReadCloser, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakePrivacy) ErasureDueAt(at time.Time) time.Time {
+	return at.Add(erasureGrace)
+}
+
+// fakeCrypter hashes the passwords as "hash:" followed by the password
+type fakeCrypter struct{}
+
+func (fakeCrypter) CompareHashAndPassword(hash, password string) bool {
+	return hash == "hash:"+password
+}
+func (fakeCrypter) HashPassword(password string) string { return "hash:" + password }
+
+type testContext struct {
+	au *types.AuthUser
+}
+
+func newTestContext() *testContext {
+	return &testContext{au: &types.AuthUser{ID: userID, Role: rbac.RoleUser}}
+}
+
+func (c *testContext) GetContext() context.Context { return context.Background() }
+func (c *testContext) AuthUser() *types.AuthUser   { return c.au }
+func (c *testContext) RealIP() string              { return "127.0.0.1" }
+func (c *testContext) UserAgent() string           { return "test" }
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+// defaultPolicies serves the roles and the policies seeded by the migration
+type defaultPolicies struct{}
+
+func (defaultPolicies) Revision(context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (defaultPolicies) ListRoles(context.Context) ([]*types.RBACRole, error) {
+	return rbac.DefaultRoles, nil
+}
+
+func (defaultPolicies) ListPolicies(context.Context) ([]*types.RBACPolicy, error) {
+	policies := make([]*types.RBACPolicy, 0, len(rbac.DefaultPolicies))
+	for _, p := range rbac.DefaultPolicies {
+		policies = append(policies, &types.RBACPolicy{Role: p[0], Object: p[1], Action: p[2]})
+	}
+	return policies, nil
+}
@@ -0,0 +1,51 @@
+package me
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new me application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, cr Crypter, password PasswordPolicy, denylist Denylist, privacy Privacy) *Me {
+	return &Me{repo: repo, rbac: rbacSvc, cr: cr, password: password, denylist: denylist, privacy: privacy}
+}
+
+// Me represents the self-service application service of the current user
+type Me struct {
+	repo     *repo.Service
+	rbac     rbac.Intf
+	cr       Crypter
+	password PasswordPolicy
+	denylist Denylist
+	privacy  Privacy
+}
+
+// Crypter represents security interface
+type Crypter interface {
+	CompareHashAndPassword(string, string) bool
+	HashPassword(string) string
+}
+
+// PasswordPolicy represents password policy interface
+type PasswordPolicy interface {
+	Validate(ctx context.Context, password string, user *types.User) error
+	Remember(ctx context.Context, userID, hash string) error
+}
+
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeSessions(ctx context.Context, sessionIDs ...string) error
+}
+
+// Privacy represents account data export and erasure interface
+type Privacy interface {
+	StartExport(ctx context.Context, userID string) (*types.DataExport, error)
+	OpenExport(ctx context.Context, rec *types.DataExport) (io.ReadCloser, error)
+	ErasureDueAt(at time.Time) time.Time
+}
@@ -0,0 +1,31 @@
+package me
+
+// UpdateMeReq contains request data to update the profile of the current user
+// swagger:model
+type UpdateMeReq struct {
+	// example: John
+	FirstName *string `json:"first_name,omitempty" validate:"omitempty,min=1"`
+	// example: Doe
+	LastName *string `json:"last_name,omitempty" validate:"omitempty,min=1"`
+	// The new phone has to be verified again
+	// example: 5551234567
+	Phone *string `json:"phone,omitempty" validate:"omitempty,max=10"`
+}
+
+// ChangePasswordReq contains request data to change the password of the current user
+// swagger:model
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	// Must satisfy the password policy
+	// example: Tyr-Collects-2026
+	NewPassword string `json:"new_password" validate:"required"`
+	// example: Tyr-Collects-2026
+	NewPasswordConfirm string `json:"new_password_confirm" validate:"required,eqfield=NewPassword"`
+}
+
+// DeleteMeReq contains request data to delete the account of the current user
+// swagger:model
+type DeleteMeReq struct {
+	// The current password to confirm the deletion
+	Password string `json:"password" validate:"required"`
+}
@@ -0,0 +1,18 @@
+package organization
+
+import (
+	"net/http"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// Custom errors
+var (
+	ErrOrganizationNotFound = server.NewHTTPError(http.StatusBadRequest, "ORGANIZATION_NOTFOUND", "Organization not found")
+	ErrNotOwner             = server.NewHTTPError(http.StatusForbidden, "NOT_ORGANIZATION_OWNER", "Only the owners can manage the organization")
+	ErrMemberNotFound       = server.NewHTTPError(http.StatusBadRequest, "MEMBER_NOTFOUND", "Member not found")
+	ErrLastOwner            = server.NewHTTPError(http.StatusConflict, "LAST_OWNER", "The organization must keep at least one owner")
+	ErrAlreadyMember        = server.NewHTTPError(http.StatusConflict, "ALREADY_MEMBER", "The user is already a member of the organization")
+	ErrInvitationNotFound   = server.NewHTTPError(http.StatusBadRequest, "INVITATION_NOTFOUND", "Invitation not found")
+	ErrEmailNotVerified     = server.NewHTTPError(http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Please verify your email address to respond to the invitations")
+)
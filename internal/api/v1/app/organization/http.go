@@ -0,0 +1,536 @@
+package organization
+
+import (
+	"net/http"
+	"strings"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents organization http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents organization application interface
+type Service interface {
+	List(contextutil.Context) (*ListOrganizationsResp, error)
+	Create(contextutil.Context, CreateOrganizationReq) (*types.Organization, error)
+	Read(contextutil.Context, string) (*types.Organization, error)
+	Update(contextutil.Context, string, UpdateOrganizationReq) (*types.Organization, error)
+	Delete(contextutil.Context, string) error
+	ListMembers(contextutil.Context, string) (*ListMembersResp, error)
+	UpdateMember(contextutil.Context, string, string, UpdateMemberReq) (*types.OrganizationMember, error)
+	RemoveMember(contextutil.Context, string, string) error
+	ListInvitations(contextutil.Context, string) (*ListInvitationsResp, error)
+	Invite(contextutil.Context, string, InviteReq) (*types.OrganizationInvitation, error)
+	RevokeInvitation(contextutil.Context, string, string) error
+	ListMyInvitations(contextutil.Context) (*ListInvitationsResp, error)
+	AcceptInvitation(contextutil.Context, string) (*types.OrganizationMember, error)
+	DeclineInvitation(contextutil.Context, string) error
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/app/organizations app-organizations organizationsList
+	// ---
+	// summary: Returns the organizations of the current user with its role in each
+	// responses:
+	//   "200":
+	//     description: List of memberships
+	//     schema:
+	//       "$ref": "#/definitions/ListOrganizationsResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation POST /v1/app/organizations app-organizations organizationsCreate
+	// ---
+	// summary: Creates an organization owned by the current user
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateOrganizationReq"
+	// responses:
+	//   "200":
+	//     description: The new organization
+	//     schema:
+	//       "$ref": "#/definitions/Organization"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("", h.create)
+
+	// swagger:operation GET /v1/app/organizations/{id} app-organizations organizationsRead
+	// ---
+	// summary: Returns an organization of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The organization
+	//     schema:
+	//       "$ref": "#/definitions/Organization"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id", h.read)
+
+	// swagger:operation PATCH /v1/app/organizations/{id} app-organizations organizationsUpdate
+	// ---
+	// summary: Updates an organization, only by its owners
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/UpdateOrganizationReq"
+	// responses:
+	//   "200":
+	//     description: The updated organization
+	//     schema:
+	//       "$ref": "#/definitions/Organization"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.PATCH("/:id", h.update)
+
+	// swagger:operation DELETE /v1/app/organizations/{id} app-organizations organizationsDelete
+	// ---
+	// summary: Deletes an organization, only by its owners
+	// description: The documents shared in the organization become the personal documents of their uploaders
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id", h.delete)
+
+	// swagger:operation GET /v1/app/organizations/{id}/members app-organizations organizationsListMembers
+	// ---
+	// summary: Returns the members of an organization of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: List of members
+	//     schema:
+	//       "$ref": "#/definitions/ListMembersResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/members", h.listMembers)
+
+	// swagger:operation PATCH /v1/app/organizations/{id}/members/{user_id} app-organizations organizationsUpdateMember
+	// ---
+	// summary: Changes the role of a member, only by the owners
+	// description: The organization must keep at least one owner. The new role applies once the member switches to the organization or refreshes the token.
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// - name: user_id
+	//   in: path
+	//   description: id of the member user
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/UpdateMemberReq"
+	// responses:
+	//   "200":
+	//     description: The updated member
+	//     schema:
+	//       "$ref": "#/definitions/OrganizationMember"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.PATCH("/:id/members/:user_id", h.updateMember)
+
+	// swagger:operation DELETE /v1/app/organizations/{id}/members/{user_id} app-organizations organizationsRemoveMember
+	// ---
+	// summary: Removes a member from an organization, by the owners or by the member itself to leave it
+	// description: The organization must keep at least one owner
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// - name: user_id
+	//   in: path
+	//   description: id of the member user
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id/members/:user_id", h.removeMember)
+
+	// swagger:operation GET /v1/app/organizations/{id}/invitations app-organizations organizationsListInvitations
+	// ---
+	// summary: Returns the invitations of an organization, only to its owners
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: List of invitations
+	//     schema:
+	//       "$ref": "#/definitions/ListInvitationsResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/:id/invitations", h.listInvitations)
+
+	// swagger:operation POST /v1/app/organizations/{id}/invitations app-organizations organizationsInvite
+	// ---
+	// summary: Invites a user by email to an organization, only by the owners
+	// description: Inviting the same email again revokes the previous pending invitation
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/InviteReq"
+	// responses:
+	//   "200":
+	//     description: The sent invitation
+	//     schema:
+	//       "$ref": "#/definitions/OrganizationInvitation"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/invitations", h.invite)
+
+	// swagger:operation DELETE /v1/app/organizations/{id}/invitations/{invitation_id} app-organizations organizationsRevokeInvitation
+	// ---
+	// summary: Revokes a pending invitation of an organization, only by the owners
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of organization
+	//   type: string
+	//   required: true
+	// - name: invitation_id
+	//   in: path
+	//   description: id of invitation
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id/invitations/:invitation_id", h.revokeInvitation)
+}
+
+// NewInvitationHTTP attaches the handlers of the invitations to the current user to Echo routers under given group
+func NewInvitationHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/app/invitations app-organizations invitationsList
+	// ---
+	// summary: Returns the pending invitations to the verified email address of the current user
+	// responses:
+	//   "200":
+	//     description: List of invitations
+	//     schema:
+	//       "$ref": "#/definitions/ListInvitationsResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.listMyInvitations)
+
+	// swagger:operation POST /v1/app/invitations/{id}/accept app-organizations invitationsAccept
+	// ---
+	// summary: Accepts a pending invitation to join the organization
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of invitation
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: The membership in the organization, switch to it to share the documents
+	//     schema:
+	//       "$ref": "#/definitions/OrganizationMember"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/accept", h.acceptInvitation)
+
+	// swagger:operation POST /v1/app/invitations/{id}/decline app-organizations invitationsDecline
+	// ---
+	// summary: Declines a pending invitation
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of invitation
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/:id/decline", h.declineInvitation)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	resp, err := h.svc.List(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) create(c echo.Context) error {
+	r := CreateOrganizationReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Create(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) read(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Read(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) update(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := UpdateOrganizationReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.Update(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) delete(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Delete(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) listMembers(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.ListMembers(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) updateMember(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := UpdateMemberReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.UpdateMember(contextutil.NewContext(c), id, c.Param("user_id"), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) removeMember(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.RemoveMember(contextutil.NewContext(c), id, c.Param("user_id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) listInvitations(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.ListInvitations(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) invite(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	r := InviteReq{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+
+	resp, err := h.svc.Invite(contextutil.NewContext(c), id, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) revokeInvitation(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.RevokeInvitation(contextutil.NewContext(c), id, c.Param("invitation_id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) listMyInvitations(c echo.Context) error {
+	resp, err := h.svc.ListMyInvitations(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) acceptInvitation(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.svc.AcceptInvitation(contextutil.NewContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) declineInvitation(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.DeclineInvitation(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
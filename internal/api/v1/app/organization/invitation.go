@@ -0,0 +1,240 @@
+package organization
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/mailtemplate"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/gorm"
+)
+
+// ListInvitations returns the invitations of an organization, only to its owners
+func (s *Organization) ListInvitations(c contextutil.Context, id string) (*ListInvitationsResp, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.owner(c, id); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.OrganizationInvitation.ListByOrganizationID(c.GetContext(), id)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing invitations").SetInternal(err)
+	}
+
+	return &ListInvitationsResp{Data: data}, nil
+}
+
+// Invite invites a user by email to an organization and emails the invitation, only by the owners.
+// Inviting the same email again revokes the previous pending invitation.
+func (s *Organization) Invite(c contextutil.Context, id string, data InviteReq) (*types.OrganizationInvitation, error) {
+	if err := s.enforce(c, rbac.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	member, err := s.owner(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.GetContext()
+	if invitee, err := s.repo.User.FindByEmail(ctx, data.Email); err == nil && invitee != nil {
+		if existed, err := s.repo.OrganizationMember.Existed(ctx, map[string]interface{}{"organization_id": id, "user_id": invitee.ID}); err != nil {
+			return nil, server.NewHTTPInternalError("error checking member").SetInternal(err)
+		} else if existed {
+			return nil, ErrAlreadyMember
+		}
+	}
+
+	if err := s.repo.OrganizationInvitation.Update(ctx, map[string]interface{}{"status": types.InvitationRevoked}, map[string]interface{}{
+		"organization_id": id,
+		"email":           data.Email,
+		"status":          types.InvitationPending,
+	}); err != nil {
+		return nil, server.NewHTTPInternalError("error revoking previous invitation").SetInternal(err)
+	}
+
+	ttl := time.Duration(s.accountCfg.InvitationTTL) * time.Second
+	rec := &types.OrganizationInvitation{
+		OrganizationID: id,
+		Email:          data.Email,
+		Role:           data.Role,
+		InvitedBy:      c.AuthUser().ID,
+		Status:         types.InvitationPending,
+		ExpiresAt:      time.Now().Add(ttl),
+	}
+	if err := s.repo.OrganizationInvitation.Create(ctx, rec); err != nil {
+		return nil, server.NewHTTPInternalError("error creating invitation").SetInternal(err)
+	}
+
+	if err := s.sendInvitation(c, member.Organization, rec, ttl); err != nil {
+		return nil, server.NewHTTPInternalError("error sending invitation").SetInternal(err)
+	}
+
+	if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgInvited, "organization_invitation", rec.ID, map[string]interface{}{"organization_id": id, "email": rec.Email, "role": rec.Role}); err != nil {
+		return nil, server.NewHTTPInternalError("error auditing invitation").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// RevokeInvitation revokes a pending invitation of an organization, only by the owners
+func (s *Organization) RevokeInvitation(c contextutil.Context, id, invitationID string) error {
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return err
+	}
+
+	if _, err := s.owner(c, id); err != nil {
+		return err
+	}
+
+	if existed, err := s.repo.OrganizationInvitation.Existed(c.GetContext(), map[string]interface{}{"id": invitationID, "organization_id": id}); err != nil || !existed {
+		return ErrInvitationNotFound.SetInternal(err)
+	}
+
+	if err := s.repo.OrganizationInvitation.Respond(c.GetContext(), invitationID, types.InvitationRevoked, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return server.NewHTTPInternalError("error revoking invitation").SetInternal(err)
+	}
+
+	if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgInviteRevoked, "organization_invitation", invitationID, map[string]interface{}{"organization_id": id}); err != nil {
+		return server.NewHTTPInternalError("error auditing invitation").SetInternal(err)
+	}
+
+	return nil
+}
+
+// ListMyInvitations returns the pending invitations to the verified email address of the current user
+func (s *Organization) ListMyInvitations(c contextutil.Context) (*ListInvitationsResp, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	user, err := s.invitee(c)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.OrganizationInvitation.ListPendingByEmail(c.GetContext(), user.Email, time.Now())
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing invitations").SetInternal(err)
+	}
+
+	return &ListInvitationsResp{Data: data}, nil
+}
+
+// AcceptInvitation joins the organization of a pending invitation to the current user in the invited role
+func (s *Organization) AcceptInvitation(c contextutil.Context, id string) (*types.OrganizationMember, error) {
+	if err := s.enforce(c, rbac.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	rec, err := s.pendingInvitation(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.OrganizationInvitation.Accept(c.GetContext(), rec, c.AuthUser().ID, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, server.NewHTTPInternalError("error accepting invitation").SetInternal(err)
+	}
+
+	if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgInviteAccepted, "organization_invitation", rec.ID, map[string]interface{}{"organization_id": rec.OrganizationID, "role": rec.Role}); err != nil {
+		return nil, server.NewHTTPInternalError("error auditing invitation").SetInternal(err)
+	}
+
+	return s.member(c, rec.OrganizationID)
+}
+
+// DeclineInvitation declines a pending invitation to the current user
+func (s *Organization) DeclineInvitation(c contextutil.Context, id string) error {
+	if err := s.enforce(c, rbac.ActionCreate); err != nil {
+		return err
+	}
+
+	rec, err := s.pendingInvitation(c, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.OrganizationInvitation.Respond(c.GetContext(), rec.ID, types.InvitationDeclined, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return server.NewHTTPInternalError("error declining invitation").SetInternal(err)
+	}
+
+	if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgInviteDeclined, "organization_invitation", rec.ID, map[string]interface{}{"organization_id": rec.OrganizationID}); err != nil {
+		return server.NewHTTPInternalError("error auditing invitation").SetInternal(err)
+	}
+
+	return nil
+}
+
+// invitee returns the current user if its email address is verified, the invitations are only sent by email
+func (s *Organization) invitee(c contextutil.Context) (*types.User, error) {
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(c.GetContext(), user, c.AuthUser().ID); err != nil {
+		return nil, server.NewHTTPInternalError("error reading user").SetInternal(err)
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	return user, nil
+}
+
+// pendingInvitation returns a pending and unexpired invitation to the current user
+func (s *Organization) pendingInvitation(c contextutil.Context, id string) (*types.OrganizationInvitation, error) {
+	user, err := s.invitee(c)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &types.OrganizationInvitation{}
+	if err := s.repo.OrganizationInvitation.Read(c.GetContext(), rec, map[string]interface{}{
+		"id":     id,
+		"email":  user.Email,
+		"status": types.InvitationPending,
+	}); err != nil {
+		return nil, ErrInvitationNotFound.SetInternal(err)
+	}
+	if time.Now().After(rec.ExpiresAt) {
+		return nil, ErrInvitationNotFound
+	}
+
+	return rec, nil
+}
+
+// sendInvitation emails the invitation with the link to the invitations page of the web app
+func (s *Organization) sendInvitation(c contextutil.Context, org *types.Organization, rec *types.OrganizationInvitation, ttl time.Duration) error {
+	inviter := &types.User{}
+	if err := s.repo.User.ReadByID(c.GetContext(), inviter, c.AuthUser().ID); err != nil {
+		return err
+	}
+
+	msg, err := mailtemplate.Render(mailtemplate.OrganizationInvitation, rec.Email, map[string]string{
+		"Inviter":      strings.TrimSpace(inviter.FirstName + " " + inviter.LastName),
+		"Organization": org.Name,
+		"Role":         rec.Role,
+		"Email":        rec.Email,
+		"Link":         strings.TrimRight(s.accountCfg.WebURL, "/") + "/invitations",
+		"ExpiresIn":    mailtemplate.HumanizeDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(c.GetContext(), msg)
+}
@@ -0,0 +1,305 @@
+package organization
+
+import (
+	"encoding/json"
+	"errors"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// List returns the organizations of the current user with its role in each
+func (s *Organization) List(c contextutil.Context) (*ListOrganizationsResp, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.OrganizationMember.ListByUserID(c.GetContext(), c.AuthUser().ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing organizations").SetInternal(err)
+	}
+
+	return &ListOrganizationsResp{Data: data}, nil
+}
+
+// Create creates an organization owned by the current user
+func (s *Organization) Create(c contextutil.Context, data CreateOrganizationReq) (*types.Organization, error) {
+	if err := s.enforce(c, rbac.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	rec := &types.Organization{Name: data.Name, CreatedBy: c.AuthUser().ID}
+	if _, err := s.repo.Organization.CreateWithOwner(c.GetContext(), rec); err != nil {
+		return nil, server.NewHTTPInternalError("error creating organization").SetInternal(err)
+	}
+
+	if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgCreated, "organization", rec.ID, nil); err != nil {
+		return nil, server.NewHTTPInternalError("error auditing organization").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// Read returns an organization of the current user
+func (s *Organization) Read(c contextutil.Context, id string) (*types.Organization, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	member, err := s.member(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return member.Organization, nil
+}
+
+// Update updates an organization, only by its owners
+func (s *Organization) Update(c contextutil.Context, id string, data UpdateOrganizationReq) (*types.Organization, error) {
+	if err := s.enforce(c, rbac.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	member, err := s.owner(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Name != nil {
+		if err := s.repo.Organization.Update(c.GetContext(), map[string]interface{}{"name": *data.Name}, id); err != nil {
+			return nil, server.NewHTTPInternalError("error updating organization").SetInternal(err)
+		}
+		if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgUpdated, "organization", id, map[string]interface{}{"from": member.Organization.Name, "to": *data.Name}); err != nil {
+			return nil, server.NewHTTPInternalError("error auditing organization").SetInternal(err)
+		}
+		member.Organization.Name = *data.Name
+	}
+
+	return member.Organization, nil
+}
+
+// Delete deletes an organization, only by its owners.
+// The documents shared in the organization are kept as the personal documents of their uploaders.
+// The sessions switched to the organization are revoked.
+func (s *Organization) Delete(c contextutil.Context, id string) error {
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return err
+	}
+
+	member, err := s.owner(c, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Organization.DeleteByID(c.GetContext(), id); err != nil {
+		return server.NewHTTPInternalError("error deleting organization").SetInternal(err)
+	}
+	if err := s.revokeSessions(c, id, ""); err != nil {
+		return err
+	}
+
+	if err := s.audit(c, c.AuthUser().ID, types.AuditActionOrgDeleted, "organization", id, map[string]interface{}{"name": member.Organization.Name}); err != nil {
+		return server.NewHTTPInternalError("error auditing organization").SetInternal(err)
+	}
+
+	return nil
+}
+
+// ListMembers returns the members of an organization of the current user
+func (s *Organization) ListMembers(c contextutil.Context, id string) (*ListMembersResp, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.member(c, id); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.OrganizationMember.ListByOrganizationID(c.GetContext(), id)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing members").SetInternal(err)
+	}
+
+	return &ListMembersResp{Data: data}, nil
+}
+
+// UpdateMember changes the role of a member, only by the owners. The organization must keep at least one owner.
+// The sessions of the member switched to the organization are revoked since their tokens hold the previous role,
+// the new role applies once the member logs in and switches to the organization again.
+func (s *Organization) UpdateMember(c contextutil.Context, id, userID string, data UpdateMemberReq) (*types.OrganizationMember, error) {
+	if err := s.enforce(c, rbac.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.owner(c, id); err != nil {
+		return nil, err
+	}
+
+	rec, err := s.findMember(c, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Role == data.Role {
+		return rec, nil
+	}
+	if rec.Role == types.OrgRoleOwner {
+		if err := s.keepOwner(c, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.OrganizationMember.Update(c.GetContext(), map[string]interface{}{"role": data.Role}, rec.ID); err != nil {
+		return nil, server.NewHTTPInternalError("error updating member").SetInternal(err)
+	}
+	if err := s.revokeSessions(c, id, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(c, userID, types.AuditActionOrgMemberUpdated, "organization", id, map[string]interface{}{"from": rec.Role, "to": data.Role}); err != nil {
+		return nil, server.NewHTTPInternalError("error auditing member").SetInternal(err)
+	}
+
+	rec.Role = data.Role
+	return rec, nil
+}
+
+// RemoveMember removes a member from an organization, by the owners or by the member itself to leave it.
+// The organization must keep at least one owner. The documents of the member stay shared in the organization.
+// The sessions of the member switched to the organization are revoked.
+func (s *Organization) RemoveMember(c contextutil.Context, id, userID string) error {
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return err
+	}
+
+	if userID != c.AuthUser().ID {
+		if _, err := s.owner(c, id); err != nil {
+			return err
+		}
+	}
+
+	rec, err := s.findMember(c, id, userID)
+	if err != nil {
+		return err
+	}
+	if rec.Role == types.OrgRoleOwner {
+		if err := s.keepOwner(c, id); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.OrganizationMember.Remove(c.GetContext(), id, userID); err != nil {
+		return server.NewHTTPInternalError("error removing member").SetInternal(err)
+	}
+	if err := s.revokeSessions(c, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.audit(c, userID, types.AuditActionOrgMemberRemoved, "organization", id, map[string]interface{}{"role": rec.Role}); err != nil {
+		return server.NewHTTPInternalError("error auditing member").SetInternal(err)
+	}
+
+	return nil
+}
+
+// member returns the membership of the current user in the organization
+func (s *Organization) member(c contextutil.Context, id string) (*types.OrganizationMember, error) {
+	rec, err := s.repo.OrganizationMember.FindByUser(c.GetContext(), id, c.AuthUser().ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, server.NewHTTPInternalError("error reading organization").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// owner returns the membership of the current user in the organization if it is an owner
+func (s *Organization) owner(c contextutil.Context, id string) (*types.OrganizationMember, error) {
+	rec, err := s.member(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Role != types.OrgRoleOwner {
+		return nil, ErrNotOwner
+	}
+
+	return rec, nil
+}
+
+// findMember returns the membership of a user in the organization
+func (s *Organization) findMember(c contextutil.Context, id, userID string) (*types.OrganizationMember, error) {
+	rec, err := s.repo.OrganizationMember.FindByUser(c.GetContext(), id, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, server.NewHTTPInternalError("error reading member").SetInternal(err)
+	}
+
+	return rec, nil
+}
+
+// revokeSessions revokes the sessions switched to the organization, of the user only if given,
+// since the membership and the role are held by their tokens
+func (s *Organization) revokeSessions(c contextutil.Context, id, userID string) error {
+	revokedIDs, err := s.repo.Session.RevokeByActiveOrganization(c.GetContext(), id, userID)
+	if err != nil {
+		return server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+	if err := s.denylist.RevokeSessions(c.GetContext(), revokedIDs...); err != nil {
+		return server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+
+	return nil
+}
+
+// keepOwner checks an owner can step down without leaving the organization without owners
+func (s *Organization) keepOwner(c contextutil.Context, id string) error {
+	count, err := s.repo.OrganizationMember.CountOwners(c.GetContext(), id)
+	if err != nil {
+		return server.NewHTTPInternalError("error counting owners").SetInternal(err)
+	}
+	if count <= 1 {
+		return ErrLastOwner
+	}
+
+	return nil
+}
+
+// audit records an organization event about the user, performed by the current user
+func (s *Organization) audit(c contextutil.Context, userID, action, objectType, objectID string, metadata map[string]interface{}) error {
+	var meta datatypes.JSON
+	if metadata != nil {
+		meta, _ = json.Marshal(metadata)
+	}
+
+	rec := &types.AuditEvent{
+		UserID:     userID,
+		Action:     action,
+		ObjectType: objectType,
+		ObjectID:   objectID,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.UserAgent(),
+		Metadata:   meta,
+	}
+	if userID != c.AuthUser().ID {
+		rec.ActorID = c.AuthUser().ID
+	}
+
+	return s.repo.AuditEvent.Create(c.GetContext(), rec)
+}
+
+// enforce checks user permission to perform the action
+func (s *Organization) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectOrganization, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
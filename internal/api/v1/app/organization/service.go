@@ -0,0 +1,35 @@
+package organization
+
+import (
+	"context"
+
+	"tyr/config"
+	"tyr/internal/repo"
+	"tyr/third_party/mailer"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new organization application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, denylist Denylist, mailer Mailer, accountCfg config.Account) *Organization {
+	return &Organization{repo: repo, rbac: rbacSvc, denylist: denylist, mailer: mailer, accountCfg: accountCfg}
+}
+
+// Organization represents the organization application service of the current user
+type Organization struct {
+	repo       *repo.Service
+	rbac       rbac.Intf
+	denylist   Denylist
+	mailer     Mailer
+	accountCfg config.Account
+}
+
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeSessions(ctx context.Context, sessionIDs ...string) error
+}
+
+// Mailer represents email sending interface
+type Mailer interface {
+	Send(ctx context.Context, msg *mailer.Message) error
+}
@@ -0,0 +1,53 @@
+package organization
+
+import "tyr/internal/types"
+
+// CreateOrganizationReq contains request data to create an organization
+// swagger:model
+type CreateOrganizationReq struct {
+	// example: Acme Travel Team
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// UpdateOrganizationReq contains request data to update an organization
+// swagger:model
+type UpdateOrganizationReq struct {
+	// example: Acme Travel Team
+	Name *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+}
+
+// ListOrganizationsResp contains the memberships of the current user with their organizations
+// swagger:model
+type ListOrganizationsResp struct {
+	Data []*types.OrganizationMember `json:"data"`
+}
+
+// ListMembersResp contains the members of an organization
+// swagger:model
+type ListMembersResp struct {
+	Data []*types.OrganizationMember `json:"data"`
+}
+
+// UpdateMemberReq contains request data to change the role of a member
+// swagger:model
+type UpdateMemberReq struct {
+	// owner || approver || member
+	// example: approver
+	Role string `json:"role" validate:"required,oneof=owner approver member"`
+}
+
+// InviteReq contains request data to invite a user by email to an organization
+// swagger:model
+type InviteReq struct {
+	// example: jane@example.com
+	Email string `json:"email" validate:"required,email"`
+	// owner || approver || member
+	// example: member
+	Role string `json:"role" validate:"required,oneof=owner approver member"`
+}
+
+// ListInvitationsResp contains organization invitations
+// swagger:model
+type ListInvitationsResp struct {
+	Data []*types.OrganizationInvitation `json:"data"`
+}
@@ -0,0 +1,105 @@
+package session
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+
+	httputil "github.com/M15t/gram/pkg/util/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents session http service
+type HTTP struct {
+	contextutil.Context
+	svc Service
+}
+
+// Service represents session application interface
+type Service interface {
+	List(contextutil.Context) (*ListSessionsResp, error)
+	Revoke(contextutil.Context, string) error
+	RevokeAll(contextutil.Context) (*RevokeAllResp, error)
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /v1/app/sessions app-sessions appSessionsList
+	// ---
+	// summary: Returns the active sessions (devices) of the current user
+	// responses:
+	//   "200":
+	//     description: List of sessions
+	//     schema:
+	//       "$ref": "#/definitions/appListSessionsResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("", h.list)
+
+	// swagger:operation DELETE /v1/app/sessions app-sessions appSessionsRevokeAll
+	// ---
+	// summary: Logs out everywhere by revoking all sessions of the current user
+	// responses:
+	//   "200":
+	//     description: Number of revoked sessions
+	//     schema:
+	//       "$ref": "#/definitions/RevokeAllResp"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("", h.revokeAll)
+
+	// swagger:operation DELETE /v1/app/sessions/{id} app-sessions appSessionsRevoke
+	// ---
+	// summary: Revokes a session (device) of the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of session
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 401, 403, 404, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/:id", h.revoke)
+}
+
+func (h *HTTP) list(c echo.Context) error {
+	resp, err := h.svc.List(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) revokeAll(c echo.Context) error {
+	resp, err := h.svc.RevokeAll(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) revoke(c echo.Context) error {
+	id, err := httputil.ReqID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Revoke(contextutil.NewContext(c), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,26 @@
+package session
+
+import (
+	"context"
+
+	"tyr/internal/repo"
+
+	"github.com/M15t/gram/pkg/rbac"
+)
+
+// New creates new session application service
+func New(repo *repo.Service, rbacSvc rbac.Intf, denylist Denylist) *Session {
+	return &Session{repo: repo, rbac: rbacSvc, denylist: denylist}
+}
+
+// Session represents session application service of the current user
+type Session struct {
+	repo     *repo.Service
+	rbac     rbac.Intf
+	denylist Denylist
+}
+
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeSessions(ctx context.Context, sessionIDs ...string) error
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"tyr/internal/rbac"
+
+	"github.com/M15t/gram/pkg/server"
+
+	contextutil "tyr/internal/api/context"
+)
+
+// List returns the active sessions of the current user, the most recently used first
+func (s *Session) List(c contextutil.Context) (*ListSessionsResp, error) {
+	if err := s.enforce(c, rbac.ActionRead); err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.Session.ListActiveByUserID(c.GetContext(), c.AuthUser().ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("Error listing session").SetInternal(err)
+	}
+
+	return &ListSessionsResp{Data: data}, nil
+}
+
+// Revoke revokes a session of the current user, its refresh token can no longer be used
+func (s *Session) Revoke(c contextutil.Context, id string) error {
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return err
+	}
+
+	if existed, err := s.repo.Session.Existed(c.GetContext(), map[string]interface{}{"id": id, "user_id": c.AuthUser().ID}); err != nil || !existed {
+		return ErrSessionNotFound.SetInternal(err)
+	}
+
+	if err := s.repo.Session.Revoke(c.GetContext(), c.AuthUser().ID, id); err != nil {
+		return server.NewHTTPInternalError("error revoking session").SetInternal(err)
+	}
+
+	if err := s.denylist.RevokeSessions(c.GetContext(), id); err != nil {
+		return server.NewHTTPInternalError("error revoking session").SetInternal(err)
+	}
+
+	return nil
+}
+
+// RevokeAll revokes all sessions of the current user to log out everywhere
+func (s *Session) RevokeAll(c contextutil.Context) (*RevokeAllResp, error) {
+	if err := s.enforce(c, rbac.ActionDelete); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.repo.Session.RevokeAllByUserID(c.GetContext(), c.AuthUser().ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+
+	if err := s.denylist.RevokeSessions(c.GetContext(), ids...); err != nil {
+		return nil, server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+
+	return &RevokeAllResp{Revoked: int64(len(ids))}, nil
+}
+
+// enforce checks session permission to perform the action
+func (s *Session) enforce(c contextutil.Context, action string) error {
+	au := c.AuthUser()
+	if au == nil || !s.rbac.Enforce(au.Role, rbac.ObjectSession, action) {
+		return rbac.ErrForbiddenAction
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package session
+
+import "tyr/internal/types"
+
+// ListSessionsResp contains the active sessions of the current user
+// swagger:model appListSessionsResp
+type ListSessionsResp struct {
+	Data []*types.Session `json:"data"`
+}
+
+// RevokeAllResp contains the result of logging out everywhere
+// swagger:model
+type RevokeAllResp struct {
+	// Number of revoked sessions
+	Revoked int64 `json:"revoked"`
+}
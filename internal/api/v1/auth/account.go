@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/mailtemplate"
+	"tyr/internal/passwordpolicy"
+	"tyr/internal/types"
+	"tyr/internal/usertoken"
+
+	"github.com/M15t/gram/pkg/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ForgotPassword sends a password reset link to the email address.
+// It never tells whether the email address belongs to any user, so that it cannot be used to enumerate the users.
+func (s *Auth) ForgotPassword(c echo.Context, data ForgotPasswordData) error {
+	ctx := c.Request().Context()
+
+	user, err := s.repo.User.FindByEmail(ctx, data.Email)
+	if err != nil || user == nil {
+		return nil
+	}
+
+	ttl := time.Duration(s.accountCfg.ResetPasswordTTL) * time.Second
+	token, err := s.userToken.Issue(ctx, user.ID, types.UserTokenResetPassword, ttl)
+	if err != nil {
+		c.Logger().Errorf("error issuing password reset token: %+v", err)
+		return nil
+	}
+
+	if err := s.sendMail(ctx, mailtemplate.ResetPassword, user, "/reset-password", token, ttl); err != nil {
+		c.Logger().Errorf("error sending password reset email: %+v", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets the new password of the user of the password reset token, it must satisfy the password policy.
+// All sessions of the user are revoked, so that anyone who knew the old password is logged out.
+// The email address is verified too since the user has received the link.
+func (s *Auth) ResetPassword(c echo.Context, data ResetPasswordData) error {
+	ctx := c.Request().Context()
+
+	token, err := s.userToken.Consume(ctx, data.Token, types.UserTokenResetPassword)
+	if err != nil {
+		return userTokenError(err)
+	}
+
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(ctx, user, token.UserID); err != nil {
+		return ErrInvalidUserToken.SetInternal(err)
+	}
+
+	if err := s.password.Validate(ctx, data.NewPassword, user); err != nil {
+		return passwordError(err)
+	}
+
+	hash := s.cr.HashPassword(data.NewPassword)
+	updates := map[string]interface{}{
+		"password":            hash,
+		"password_changed_at": time.Now(),
+	}
+	if user.EmailVerifiedAt == nil {
+		updates["email_verified_at"] = time.Now()
+	}
+	if err := s.repo.User.Update(ctx, updates, user.ID); err != nil {
+		return server.NewHTTPInternalError("error resetting password").SetInternal(err)
+	}
+	if err := s.password.Remember(ctx, user.ID, hash); err != nil {
+		return server.NewHTTPInternalError("error resetting password").SetInternal(err)
+	}
+
+	revokedIDs, err := s.repo.Session.RevokeAllByUserID(ctx, user.ID)
+	if err != nil {
+		return server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+	if err := s.denylist.RevokeSessions(ctx, revokedIDs...); err != nil {
+		return server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email address of the user of the verification token as verified
+func (s *Auth) VerifyEmail(c echo.Context, data VerifyEmailData) error {
+	ctx := c.Request().Context()
+
+	token, err := s.userToken.Consume(ctx, data.Token, types.UserTokenVerifyEmail)
+	if err != nil {
+		return userTokenError(err)
+	}
+
+	if err := s.repo.User.Update(ctx, map[string]interface{}{
+		"email_verified_at": time.Now(),
+	}, token.UserID); err != nil {
+		return server.NewHTTPInternalError("error verifying email").SetInternal(err)
+	}
+
+	return nil
+}
+
+// ResendVerifyEmail sends another verification email to the current user, the previous links are invalidated
+func (s *Auth) ResendVerifyEmail(c contextutil.Context) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+
+	if err := s.sendVerifyEmail(c.GetContext(), user); err != nil {
+		return server.NewHTTPInternalError("error sending verification email").SetInternal(err)
+	}
+
+	return nil
+}
+
+// sendVerifyEmail issues a verification token and emails its link to the user
+func (s *Auth) sendVerifyEmail(ctx context.Context, user *types.User) error {
+	ttl := time.Duration(s.accountCfg.VerifyEmailTTL) * time.Second
+	token, err := s.userToken.Issue(ctx, user.ID, types.UserTokenVerifyEmail, ttl)
+	if err != nil {
+		return err
+	}
+
+	return s.sendMail(ctx, mailtemplate.VerifyEmail, user, "/verify-email", token, ttl)
+}
+
+// sendMail renders the email template with the link of the token to the web app and sends it to the user
+func (s *Auth) sendMail(ctx context.Context, name string, user *types.User, path, token string, ttl time.Duration) error {
+	msg, err := mailtemplate.Render(name, user.Email, map[string]string{
+		"Name":      strings.TrimSpace(user.FirstName + " " + user.LastName),
+		"Email":     user.Email,
+		"Link":      strings.TrimRight(s.accountCfg.WebURL, "/") + path + "?token=" + url.QueryEscape(token),
+		"ExpiresIn": mailtemplate.HumanizeDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// passwordError maps the password policy violations to the http errors
+func passwordError(err error) error {
+	var violation *passwordpolicy.ViolationError
+	if errors.As(err, &violation) {
+		return server.NewHTTPError(ErrWeakPassword.Code, ErrWeakPassword.Type, violation.Error())
+	}
+	return server.NewHTTPInternalError("error checking password").SetInternal(err)
+}
+
+// userTokenError maps the user token errors to the http errors
+func userTokenError(err error) error {
+	switch {
+	case errors.Is(err, usertoken.ErrTokenExpired):
+		return ErrUserTokenExpired
+	case errors.Is(err, usertoken.ErrTokenUsed):
+		return ErrUserTokenUsed
+	case errors.Is(err, usertoken.ErrInvalidToken):
+		return ErrInvalidUserToken
+	default:
+		return server.NewHTTPInternalError("error verifying token").SetInternal(err)
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"database/sql"
 	"time"
 
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/jwtkeys"
 	"tyr/internal/rbac"
 	"tyr/internal/types"
 
+	"github.com/M15t/gram/pkg/server"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
-	"github.com/samber/lo"
 )
 
 // Login tries to authenticate the user provided by given credentials.
@@ -19,45 +22,68 @@ import (
 // If the passwords do not match, it returns an error.
 // It checks the grant type and verifies if the user has the required role based on the grant type.
 // If the user does not have the required role, it returns an error.
-// It checks the status of the user and if the user is blocked, it returns an error.
+// It checks the status of the user and if the user is not active, it returns an error.
+// The password login is guarded against brute force: the failures are tracked per account and per IP address,
+// delayed progressively and locked temporarily after too many, see guardLogin and loginFailed.
+// The password older than the max age of the password policy has to be reset before logging in.
 // Finally, it calls the authenticate function with the user and IsLogin set to true, and returns the result.
-func (s *Auth) Login(c echo.Context, data Credentials) (*types.AuthToken, error) {
+// The `otp` grant type authenticates the app user by the phone and the one-time password texted to it instead,
+// the `oidc` grant type by the ID token of a social login provider, see loginByOIDC.
+// If the user has enabled two-factor authentication or their role requires it, a challenge is returned instead of
+// the access token, see loginOrChallenge.
+func (s *Auth) Login(c echo.Context, data Credentials) (*LoginResp, error) {
+	switch data.GrantType {
+	case "otp":
+		return s.loginByOTP(c, data)
+	case "oidc":
+		return s.loginByOIDC(c, data)
+	}
+
+	if err := s.guardLogin(c, data.Email); err != nil {
+		return nil, err
+	}
+
 	existedUser, err := s.repo.User.FindByEmail(c.Request().Context(), data.Email)
 	if err != nil || existedUser == nil {
-		return nil, ErrInvalidCredentials.SetInternal(err)
+		return nil, s.loginFailed(c, data.Email, nil)
 	}
 
 	if !s.cr.CompareHashAndPassword(existedUser.Password, data.Password) {
-		return nil, ErrInvalidCredentials
+		return nil, s.loginFailed(c, data.Email, existedUser)
 	}
 
 	switch data.GrantType {
 	case "app":
-		if existedUser.Role != rbac.RoleUser {
+		if !s.roles.IsAppRole(existedUser.Role) {
 			return nil, ErrInvalidCredentials
 		}
 	case "portal":
-		if !lo.Contains([]string{rbac.RoleAdmin, rbac.RoleSuperAdmin}, existedUser.Role) {
+		if !s.roles.IsPortalRole(existedUser.Role) {
 			return nil, ErrInvalidCredentials
 		}
 	default:
 		return nil, ErrInvalidGrantType
 	}
 
-	if existedUser.Status == types.UserStatusBlocked.String() {
-		return nil, ErrUserBlocked
+	if err := statusError(existedUser); err != nil {
+		return nil, err
 	}
 
-	return s.authenticate(c, &AuthenticateInput{
-		User:    existedUser,
-		IsLogin: true,
-	})
+	if s.password.Expired(existedUser) {
+		return nil, ErrPasswordExpired
+	}
+
+	if err := s.guard.Succeed(c.Request().Context(), data.Email); err != nil {
+		c.Logger().Errorf("error clearing failed login attempts: %+v", err)
+	}
+
+	return s.loginOrChallenge(c, existedUser, data.Device)
 }
 
 // RefreshToken refreshes the access token.
 // It parses the refresh token using s.jwt.ParseToken function.
 // If there is an error parsing the token, it returns ErrInvalidRefreshToken with the internal error.
-// It then checks if the token is valid and contains the required claims.
+// It then checks if the token is a valid refresh token, not an access token, and contains the required claims.
 // If the token is invalid or does not contain the required claims, it returns ErrInvalidRefreshToken.
 // It retrieves the session ID and user ID from the claims.
 // It then finds the session using s.repo.Session.FindByID function.
@@ -65,7 +91,12 @@ func (s *Auth) Login(c echo.Context, data Credentials) (*types.AuthToken, error)
 // It checks if the session has expired by comparing the current time with the session's expiration time.
 // If the session has expired, it updates the session to blocked and clears the refresh token using s.repo.Session.Update function.
 // If there is an error updating the session, it returns ErrInvalidRefreshToken with the internal error.
-// It finally calls s.authenticate function with the user from the session and IsLogin set to false, and returns the result.
+// Every refresh token is used only once: the session stores the hash of its current token only,
+// a validly signed token of the session which is not the current one has been rotated already,
+// such replay revokes the whole session and is audited.
+// The session of the user who is no longer active is revoked too.
+// It finally calls s.authenticate function with the user and the existing session and IsLogin set to false,
+// which rotates the refresh token of the session, and returns the result.
 func (s *Auth) RefreshToken(c echo.Context, data RefreshTokenData) (*types.AuthToken, error) {
 	token, err := s.jwt.ParseToken(data.RefreshToken)
 	if err != nil {
@@ -74,13 +105,16 @@ func (s *Auth) RefreshToken(c echo.Context, data RefreshTokenData) (*types.AuthT
 
 	// claims token
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || jwtkeys.TokenUse(claims) != jwtkeys.TokenUseRefresh {
 		return nil, ErrInvalidRefreshToken
 	}
 
 	// get user id and session id from claims
-	sessionID, ok := claims["id"].(string)
-	userID, ok := claims["uid"].(string)
+	sessionID, _ := claims["id"].(string)
+	userID, _ := claims["uid"].(string)
+	if sessionID == "" || userID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
 	existedSession, err := s.repo.Session.FindByID(c.Request().Context(), sessionID, userID)
 	if err != nil || existedSession == nil {
 		return nil, ErrInvalidRefreshToken.SetInternal(err)
@@ -91,29 +125,72 @@ func (s *Auth) RefreshToken(c echo.Context, data RefreshTokenData) (*types.AuthT
 		// update session to blocked
 		// clear refresh token
 		if err := s.repo.Session.Update(c.Request().Context(), map[string]interface{}{
-			"is_blocked":    true,
-			"refresh_token": sql.NullString{String: "", Valid: false},
+			"is_blocked":         true,
+			"refresh_token_hash": sql.NullString{String: "", Valid: false},
 		}, existedSession.ID); err != nil {
 			return nil, ErrInvalidRefreshToken.SetInternal(err)
 		}
+		if err := s.denylist.RevokeSessions(c.Request().Context(), existedSession.ID); err != nil {
+			return nil, ErrInvalidRefreshToken.SetInternal(err)
+		}
 
 		return nil, ErrTokenExpired
 	}
 
+	// the user blocked or deleted since login may not refresh, the session is revoked
+	if existedSession.User == nil || existedSession.User.Status != types.UserStatusActive {
+		if err := s.repo.Session.Revoke(c.Request().Context(), userID, existedSession.ID); err != nil {
+			return nil, ErrInvalidRefreshToken.SetInternal(err)
+		}
+		if err := s.denylist.RevokeSessions(c.Request().Context(), existedSession.ID); err != nil {
+			return nil, ErrInvalidRefreshToken.SetInternal(err)
+		}
+		if existedSession.User == nil {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, statusError(existedSession.User)
+	}
+
+	// detect reuse of a rotated refresh token
+	if !existedSession.RefreshTokenHash.Valid || existedSession.RefreshTokenHash.String != hashToken(data.RefreshToken) {
+		return nil, s.revokeReusedSession(c, existedSession)
+	}
+
 	return s.authenticate(c, &AuthenticateInput{
 		User:    existedSession.User,
 		IsLogin: false,
+		Session: existedSession,
 	})
 }
 
+// Logout revokes the session of the current access token.
+// The refresh token of the session can no longer be used and the access tokens of the session are denied right away.
+func (s *Auth) Logout(c contextutil.Context) error {
+	au := c.AuthUser()
+	if au == nil || au.SessionID == "" {
+		return ErrInvalidSession
+	}
+
+	if err := s.repo.Session.Revoke(c.GetContext(), au.ID, au.SessionID); err != nil {
+		return server.NewHTTPInternalError("error revoking session").SetInternal(err)
+	}
+
+	if err := s.denylist.RevokeSessions(c.GetContext(), au.SessionID); err != nil {
+		return server.NewHTTPInternalError("error revoking session").SetInternal(err)
+	}
+
+	return nil
+}
+
 // Signup creates a new user account with the provided data.
 // It checks if the user already exists based on the email and role.
 // If the user already exists, it returns an error.
 // Otherwise, it creates a new user with the given data and returns an authentication token.
 // The authentication token is generated by calling the authenticate function with the created user and IsLogin set to true.
 // The created user is assigned the role of rbac.RoleUser.
-// The user's email and phone are verified at the current time.
-// The user's password is hashed using the s.cr.HashPassword function.
+// The user's email and phone are not verified yet, a verification email and a one-time password text are sent to the user.
+// Failing to send them does not fail the signup, the user can request other ones.
+// The user's password must satisfy the password policy, it is hashed using the s.cr.HashPassword function.
 // If there is an error during user creation, it returns the error.
 // The function requires an echo.Context and a SignupData struct as input.
 // It returns a pointer to types.AuthToken and an error.
@@ -126,26 +203,39 @@ func (s *Auth) Signup(c echo.Context, data SignupData) (*types.AuthToken, error)
 	}
 
 	now := time.Now()
-
 	user := &types.User{
-		FirstName:       data.FirstName,
-		LastName:        data.LastName,
-		Email:           data.Email,
-		EmailVerifiedAt: &now,
-		Phone:           data.Phone,
-		PhoneVerifiedAt: &now,
-		Password:        s.cr.HashPassword(data.Password),
+		FirstName:         data.FirstName,
+		LastName:          data.LastName,
+		Email:             data.Email,
+		Phone:             data.Phone,
+		PasswordChangedAt: &now,
 
 		Role:    rbac.RoleUser,
+		Status:  types.UserStatusActive,
 		Profile: &types.Profile{},
 	}
+	if err := s.password.Validate(c.Request().Context(), data.Password, user); err != nil {
+		return nil, passwordError(err)
+	}
+	user.Password = s.cr.HashPassword(data.Password)
 
 	if err := s.repo.User.Create(c.Request().Context(), user); err != nil {
 		return nil, err
 	}
+	if err := s.password.Remember(c.Request().Context(), user.ID, user.Password); err != nil {
+		c.Logger().Errorf("error remembering password: %+v", err)
+	}
+
+	if err := s.sendVerifyEmail(c.Request().Context(), user); err != nil {
+		c.Logger().Errorf("error sending verification email: %+v", err)
+	}
+	if _, err := s.otp.Send(c.Request().Context(), user); err != nil {
+		c.Logger().Errorf("error sending phone verification otp: %+v", err)
+	}
 
 	return s.authenticate(c, &AuthenticateInput{
 		User:    user,
 		IsLogin: true,
+		Device:  data.Device,
 	})
 }
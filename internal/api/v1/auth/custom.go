@@ -10,10 +10,40 @@ import (
 var (
 	ErrInvalidCredentials  = server.NewHTTPError(http.StatusUnauthorized, "INVALID_CREDENTIALS", "Email or password is incorrect")
 	ErrUserBlocked         = server.NewHTTPError(http.StatusUnauthorized, "USER_BLOCKED", "Your account has been blocked and may not login")
+	ErrLocked              = server.NewHTTPError(http.StatusLocked, "LOCKED", "Too many failed login attempts, please try again later or unlock your account by the link sent to your email")
 	ErrInvalidRefreshToken = server.NewHTTPError(http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "Invalid refresh token")
+	ErrRefreshTokenReused  = server.NewHTTPError(http.StatusUnauthorized, "REFRESH_TOKEN_REUSED", "Refresh token has already been used, please login again")
+	ErrInvalidSession      = server.NewHTTPError(http.StatusUnauthorized, "INVALID_SESSION", "The access token is not bound to any session")
 	ErrTokenExpired        = server.NewHTTPError(http.StatusUnauthorized, "TOKEN_EXPIRED", "Invalid refresh token")
 	ErrInvalidPayloadType  = server.NewHTTPError(http.StatusUnauthorized, "INVALID_PAYLOAD_TYPE", "Invalid payload type")
 	ErrRefreshToken        = server.NewHTTPError(http.StatusInternalServerError, "REFRESH_TOKEN_ERROR", "An error occur while refreshing token")
 	ErrInvalidGrantType    = server.NewHTTPError(http.StatusBadRequest, "INVALID_GRANT_TYPE", "Invalid grant type")
 	ErrUserExisted         = server.NewHTTPValidationError("User already existed")
+	ErrInvalidUserToken    = server.NewHTTPError(http.StatusBadRequest, "INVALID_USER_TOKEN", "The link is invalid")
+	ErrUserTokenExpired    = server.NewHTTPError(http.StatusBadRequest, "USER_TOKEN_EXPIRED", "The link has expired, please request a new one")
+	ErrUserTokenUsed       = server.NewHTTPError(http.StatusBadRequest, "USER_TOKEN_USED", "The link has already been used")
+	ErrEmailVerified       = server.NewHTTPError(http.StatusBadRequest, "EMAIL_VERIFIED", "Your email address has already been verified")
+	ErrPhoneVerified       = server.NewHTTPError(http.StatusBadRequest, "PHONE_VERIFIED", "Your phone number has already been verified")
+	ErrPhoneNotProvided    = server.NewHTTPError(http.StatusBadRequest, "PHONE_NOT_PROVIDED", "Your account does not have any phone number")
+	ErrOTPNotRequested     = server.NewHTTPError(http.StatusBadRequest, "OTP_NOT_REQUESTED", "Please request a code first")
+	ErrOTPExpired          = server.NewHTTPError(http.StatusBadRequest, "OTP_EXPIRED", "The code has expired, please request a new one")
+	ErrInvalidOTP          = server.NewHTTPError(http.StatusBadRequest, "INVALID_OTP", "The code is incorrect")
+	ErrOTPTooManyAttempts  = server.NewHTTPError(http.StatusTooManyRequests, "OTP_TOO_MANY_ATTEMPTS", "Too many incorrect codes, please request a new one")
+	ErrInvalidMFAChallenge = server.NewHTTPError(http.StatusUnauthorized, "INVALID_MFA_CHALLENGE", "The login challenge is invalid or expired, please login again")
+	ErrInvalidMFACode      = server.NewHTTPError(http.StatusUnauthorized, "INVALID_MFA_CODE", "The two-factor authentication code is incorrect")
+	ErrMFATooManyAttempts  = server.NewHTTPError(http.StatusTooManyRequests, "MFA_TOO_MANY_ATTEMPTS", "Too many incorrect two-factor authentication codes, please login again")
+	ErrMFANotEnrolled      = server.NewHTTPError(http.StatusBadRequest, "MFA_NOT_ENROLLED", "Please enroll two-factor authentication first")
+	ErrMFAEnabled          = server.NewHTTPError(http.StatusBadRequest, "MFA_ENABLED", "Two-factor authentication has already been enabled")
+	ErrMFANotEnabled       = server.NewHTTPError(http.StatusBadRequest, "MFA_NOT_ENABLED", "Two-factor authentication is not enabled")
+	ErrMFARequired         = server.NewHTTPError(http.StatusForbidden, "MFA_REQUIRED", "Two-factor authentication is required for your role and cannot be disabled")
+	ErrUnsupportedProvider = server.NewHTTPError(http.StatusBadRequest, "UNSUPPORTED_PROVIDER", "The identity provider is not supported")
+	ErrInvalidIDToken      = server.NewHTTPError(http.StatusUnauthorized, "INVALID_ID_TOKEN", "The ID token of the identity provider is invalid")
+	ErrIDTokenNoEmail      = server.NewHTTPError(http.StatusBadRequest, "ID_TOKEN_NO_EMAIL", "The identity provider did not share the email address")
+	ErrIDTokenUnverified   = server.NewHTTPError(http.StatusConflict, "ID_TOKEN_EMAIL_UNVERIFIED", "An account with this email address exists, please login with password and link the identity")
+	ErrIdentityLinked      = server.NewHTTPError(http.StatusConflict, "IDENTITY_LINKED", "The identity has already been linked to another account")
+	ErrIdentityNotFound    = server.NewHTTPError(http.StatusNotFound, "IDENTITY_NOT_FOUND", "Identity not found")
+	ErrWeakPassword        = server.NewHTTPError(http.StatusBadRequest, "WEAK_PASSWORD", "The password does not satisfy the password policy")
+	ErrPasswordExpired     = server.NewHTTPError(http.StatusForbidden, "PASSWORD_EXPIRED", "Your password has expired, please reset it")
+	ErrOTPResendCooldown   = server.NewHTTPError(http.StatusTooManyRequests, "OTP_RESEND_COOLDOWN", "A code has just been sent, please wait before requesting another one")
+	ErrNotOrgMember        = server.NewHTTPError(http.StatusForbidden, "NOT_ORGANIZATION_MEMBER", "You are not a member of the organization")
 )
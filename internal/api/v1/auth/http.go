@@ -7,6 +7,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
 
+	contextutil "tyr/internal/api/context"
 	"tyr/internal/types"
 
 	"github.com/M15t/gram/pkg/server"
@@ -19,13 +20,33 @@ type HTTP struct {
 
 // Service represents auth service interface
 type Service interface {
-	Login(echo.Context, Credentials) (*types.AuthToken, error)
+	Login(echo.Context, Credentials) (*LoginResp, error)
 	RefreshToken(echo.Context, RefreshTokenData) (*types.AuthToken, error)
 	Signup(echo.Context, SignupData) (*types.AuthToken, error)
+	Logout(contextutil.Context) error
+	SwitchOrganization(contextutil.Context, SwitchOrganizationData) (*types.AuthToken, error)
+	ForgotPassword(echo.Context, ForgotPasswordData) error
+	ResetPassword(echo.Context, ResetPasswordData) error
+	VerifyEmail(echo.Context, VerifyEmailData) error
+	UnlockAccount(echo.Context, UnlockAccountData) error
+	ResendVerifyEmail(contextutil.Context) error
+	RequestOTP(echo.Context, RequestOTPData) (*OTPSentResp, error)
+	SendVerifyPhone(contextutil.Context) (*OTPSentResp, error)
+	VerifyPhone(contextutil.Context, VerifyPhoneData) error
+	EnrollMFAChallenge(echo.Context, MFAChallengeData) (*MFAEnrollResp, error)
+	VerifyMFA(echo.Context, MFAVerifyData) (*MFAVerifyResp, error)
+	EnrollMFA(contextutil.Context) (*MFAEnrollResp, error)
+	ActivateMFA(contextutil.Context, MFACodeData) (*MFARecoveryCodesResp, error)
+	RegenerateMFARecoveryCodes(contextutil.Context, MFACodeData) (*MFARecoveryCodesResp, error)
+	DisableMFA(contextutil.Context, MFACodeData) error
+	ListIdentities(contextutil.Context) (*ListIdentitiesResp, error)
+	LinkIdentity(contextutil.Context, LinkIdentityData) (*types.UserIdentity, error)
+	UnlinkIdentity(contextutil.Context, string) error
 }
 
-// NewHTTP attaches handlers to Echo routers under given group
-func NewHTTP(svc Service, eg *echo.Group) {
+// NewHTTP attaches handlers to Echo routers under given group.
+// The authMW middlewares authenticate the routes which require an access token.
+func NewHTTP(svc Service, eg *echo.Group, authMW ...echo.MiddlewareFunc) {
 	h := HTTP{svc: svc}
 
 	// swagger:operation POST /v1/auth/login auth authLogin
@@ -35,17 +56,17 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	// parameters:
 	// - name: request
 	//   in: body
-	//   description: Request body. `grant_type` should be `app` or `portal`
+	//   description: Request body. `grant_type` should be `app`, `portal`, `otp` or `oidc`. The `otp` grant type requires `phone` and `otp`, the `oidc` grant type requires `provider` and `id_token`, instead of `email` and `password`
 	//   required: true
 	//   schema:
 	//     "$ref": "#/definitions/Credentials"
 	// responses:
 	//   "200":
-	//     description: Access token
+	//     description: Access token, or the two-factor authentication challenge if `mfa_required` is true
 	//     schema:
-	//       "$ref": "#/definitions/AuthToken"
+	//       "$ref": "#/definitions/LoginResp"
 	//   default:
-	//     description: 'Possible errors: 400, 401, 500'
+	//     description: 'Possible errors: 400, 401, 423, 500'
 	//     schema:
 	//       "$ref": "#/definitions/ErrorResponse"
 	eg.POST("/login", h.login)
@@ -92,6 +113,349 @@ func NewHTTP(svc Service, eg *echo.Group) {
 	//   "500":
 	//     "$ref": "#/responses/errDetails"
 	eg.POST("/signup", h.signup)
+
+	// swagger:operation POST /v1/auth/logout auth authLogout
+	// ---
+	// summary: Logs out the current session, its refresh token and access tokens are revoked
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 401, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/logout", h.logout, authMW...)
+
+	// swagger:operation POST /v1/auth/switch-organization auth authSwitchOrganization
+	// ---
+	// summary: Switches the current session to an organization of the user, or back to the personal documents
+	// parameters:
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/SwitchOrganizationData"
+	// responses:
+	//   "200":
+	//     description: The access token in the organization, the refresh token is unchanged
+	//     schema:
+	//       "$ref": "#/definitions/AuthToken"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/switch-organization", h.switchOrganization, authMW...)
+
+	// swagger:operation POST /v1/auth/forgot-password auth authForgotPassword
+	// ---
+	// summary: Sends a password reset link to the email address
+	// description: The response is the same whether the email address belongs to any user or not
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/ForgotPasswordData"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/forgot-password", h.forgotPassword)
+
+	// swagger:operation POST /v1/auth/reset-password auth authResetPassword
+	// ---
+	// summary: Resets the password by the token from the password reset link, all sessions of the user are revoked
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/ResetPasswordData"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/reset-password", h.resetPassword)
+
+	// swagger:operation POST /v1/auth/verify-email auth authVerifyEmail
+	// ---
+	// summary: Verifies the email address by the token from the verification link
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/VerifyEmailData"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/verify-email", h.verifyEmail)
+
+	// swagger:operation POST /v1/auth/unlock auth authUnlockAccount
+	// ---
+	// summary: Unlocks the account locked after too many failed login attempts by the token from the unlock link
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/UnlockAccountData"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/unlock", h.unlockAccount)
+
+	// swagger:operation POST /v1/auth/verify-email/resend auth authResendVerifyEmail
+	// ---
+	// summary: Sends another verification email to the current user, the previous links are invalidated
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/verify-email/resend", h.resendVerifyEmail, authMW...)
+
+	// swagger:operation POST /v1/auth/otp auth authRequestOTP
+	// ---
+	// summary: Texts a one-time password to the phone for the `otp` login grant type
+	// description: The response is the same whether the phone belongs to any user or not
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/RequestOTPData"
+	// responses:
+	//   "200":
+	//     description: Timing of the sent code
+	//     schema:
+	//       "$ref": "#/definitions/OTPSentResp"
+	//   default:
+	//     description: 'Possible errors: 400, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/otp", h.requestOTP)
+
+	// swagger:operation POST /v1/auth/verify-phone/send auth authSendVerifyPhone
+	// ---
+	// summary: Texts a one-time password to the phone of the current user to verify it
+	// responses:
+	//   "200":
+	//     description: Timing of the sent code
+	//     schema:
+	//       "$ref": "#/definitions/OTPSentResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 429, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/verify-phone/send", h.sendVerifyPhone, authMW...)
+
+	// swagger:operation POST /v1/auth/verify-phone auth authVerifyPhone
+	// ---
+	// summary: Verifies the phone of the current user by the texted one-time password
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/VerifyPhoneData"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 429, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/verify-phone", h.verifyPhone, authMW...)
+
+	// swagger:operation POST /v1/auth/mfa/challenge/enroll auth authEnrollMFAChallenge
+	// ---
+	// summary: Generates the TOTP secret during the login challenge, when the role requires two-factor authentication but the user has not enrolled yet
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/MFAChallengeData"
+	// responses:
+	//   "200":
+	//     description: The pending TOTP secret
+	//     schema:
+	//       "$ref": "#/definitions/MFAEnrollResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/mfa/challenge/enroll", h.enrollMFAChallenge)
+
+	// swagger:operation POST /v1/auth/mfa/verify auth authVerifyMFA
+	// ---
+	// summary: Completes the login challenge by a TOTP code or a recovery code
+	// description: If the user is enrolling, the code activates two-factor authentication and the new recovery codes are returned too
+	// security: []
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/MFAVerifyData"
+	// responses:
+	//   "200":
+	//     description: Access token
+	//     schema:
+	//       "$ref": "#/definitions/MFAVerifyResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 429, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/mfa/verify", h.verifyMFA)
+
+	// swagger:operation POST /v1/auth/mfa/enroll auth authEnrollMFA
+	// ---
+	// summary: Generates a new pending TOTP secret of the current user
+	// responses:
+	//   "200":
+	//     description: The pending TOTP secret
+	//     schema:
+	//       "$ref": "#/definitions/MFAEnrollResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/mfa/enroll", h.enrollMFA, authMW...)
+
+	// swagger:operation POST /v1/auth/mfa/activate auth authActivateMFA
+	// ---
+	// summary: Enables two-factor authentication of the current user by a TOTP code of the pending secret
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/MFACodeData"
+	// responses:
+	//   "200":
+	//     description: The recovery codes, which are shown only once
+	//     schema:
+	//       "$ref": "#/definitions/MFARecoveryCodesResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 429, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/mfa/activate", h.activateMFA, authMW...)
+
+	// swagger:operation POST /v1/auth/mfa/recovery-codes auth authRegenerateMFARecoveryCodes
+	// ---
+	// summary: Replaces the recovery codes of the current user, confirmed by a TOTP code or a recovery code
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/MFACodeData"
+	// responses:
+	//   "200":
+	//     description: The new recovery codes, which are shown only once
+	//     schema:
+	//       "$ref": "#/definitions/MFARecoveryCodesResp"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 429, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/mfa/recovery-codes", h.regenerateMFARecoveryCodes, authMW...)
+
+	// swagger:operation POST /v1/auth/mfa/disable auth authDisableMFA
+	// ---
+	// summary: Disables two-factor authentication of the current user, confirmed by a TOTP code or a recovery code
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/MFACodeData"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 403, 429, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/mfa/disable", h.disableMFA, authMW...)
+
+	// swagger:operation GET /v1/auth/identities auth authListIdentities
+	// ---
+	// summary: Lists the social login identities linked to the current user
+	// responses:
+	//   "200":
+	//     description: The linked identities
+	//     schema:
+	//       "$ref": "#/definitions/ListIdentitiesResp"
+	//   default:
+	//     description: 'Possible errors: 401, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.GET("/identities", h.listIdentities, authMW...)
+
+	// swagger:operation POST /v1/auth/identities auth authLinkIdentity
+	// ---
+	// summary: Links the social login identity of the ID token to the current user
+	// parameters:
+	// - name: request
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/LinkIdentityData"
+	// responses:
+	//   "200":
+	//     description: The linked identity
+	//     schema:
+	//       "$ref": "#/definitions/UserIdentity"
+	//   default:
+	//     description: 'Possible errors: 400, 401, 409, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.POST("/identities", h.linkIdentity, authMW...)
+
+	// swagger:operation DELETE /v1/auth/identities/{id} auth authUnlinkIdentity
+	// ---
+	// summary: Unlinks the social login identity from the current user
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of identity
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/ok"
+	//   default:
+	//     description: 'Possible errors: 401, 404, 500'
+	//     schema:
+	//       "$ref": "#/definitions/ErrorResponse"
+	eg.DELETE("/identities/:id", h.unlinkIdentity, authMW...)
 }
 
 func (h *HTTP) login(c echo.Context) error {
@@ -103,9 +467,10 @@ func (h *HTTP) login(c echo.Context) error {
 	if r.Email == "" {
 		r.Email = strings.ToLower(strings.TrimSpace(r.Username))
 	}
+	r.Phone = strings.TrimSpace(r.Phone)
 
 	if !lo.Contains([]string{
-		"app", "portal",
+		"app", "portal", "otp", "oidc",
 	}, r.GrantType) {
 		return server.NewHTTPValidationError("Invalid context")
 	}
@@ -131,6 +496,235 @@ func (h *HTTP) refreshToken(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+func (h *HTTP) logout(c echo.Context) error {
+	if err := h.svc.Logout(contextutil.NewContext(c)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) switchOrganization(c echo.Context) error {
+	r := SwitchOrganizationData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.SwitchOrganization(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) forgotPassword(c echo.Context) error {
+	r := ForgotPasswordData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+
+	if err := h.svc.ForgotPassword(c, r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) resetPassword(c echo.Context) error {
+	r := ResetPasswordData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	if err := h.svc.ResetPassword(c, r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) verifyEmail(c echo.Context) error {
+	r := VerifyEmailData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	if err := h.svc.VerifyEmail(c, r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) unlockAccount(c echo.Context) error {
+	r := UnlockAccountData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	if err := h.svc.UnlockAccount(c, r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) resendVerifyEmail(c echo.Context) error {
+	if err := h.svc.ResendVerifyEmail(contextutil.NewContext(c)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) requestOTP(c echo.Context) error {
+	r := RequestOTPData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+	r.Phone = strings.TrimSpace(r.Phone)
+
+	resp, err := h.svc.RequestOTP(c, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) sendVerifyPhone(c echo.Context) error {
+	resp, err := h.svc.SendVerifyPhone(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) verifyPhone(c echo.Context) error {
+	r := VerifyPhoneData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	if err := h.svc.VerifyPhone(contextutil.NewContext(c), r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) enrollMFAChallenge(c echo.Context) error {
+	r := MFAChallengeData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.EnrollMFAChallenge(c, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) verifyMFA(c echo.Context) error {
+	r := MFAVerifyData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.VerifyMFA(c, r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) enrollMFA(c echo.Context) error {
+	resp, err := h.svc.EnrollMFA(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) activateMFA(c echo.Context) error {
+	r := MFACodeData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.ActivateMFA(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) regenerateMFARecoveryCodes(c echo.Context) error {
+	r := MFACodeData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.RegenerateMFARecoveryCodes(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) disableMFA(c echo.Context) error {
+	r := MFACodeData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	if err := h.svc.DisableMFA(contextutil.NewContext(c), r); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HTTP) listIdentities(c echo.Context) error {
+	resp, err := h.svc.ListIdentities(contextutil.NewContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) linkIdentity(c echo.Context) error {
+	r := LinkIdentityData{}
+	if err := c.Bind(&r); err != nil {
+		return err
+	}
+
+	resp, err := h.svc.LinkIdentity(contextutil.NewContext(c), r)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *HTTP) unlinkIdentity(c echo.Context) error {
+	if err := h.svc.UnlinkIdentity(contextutil.NewContext(c), c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 func (h *HTTP) signup(c echo.Context) error {
 	r := SignupData{}
 	if err := c.Bind(&r); err != nil {
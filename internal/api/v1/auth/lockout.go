@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"tyr/internal/loginguard"
+	"tyr/internal/mailtemplate"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+// guardLogin rejects the password login of the locked account or IP address,
+// otherwise waits for the progressive delay of the recent failures before the password is verified
+func (s *Auth) guardLogin(c echo.Context, email string) error {
+	ctx := c.Request().Context()
+
+	delay, err := s.guard.Check(ctx, email, c.RealIP())
+	if err != nil {
+		if errors.Is(err, loginguard.ErrLocked) {
+			return ErrLocked
+		}
+		return server.NewHTTPInternalError("error checking login attempts").SetInternal(err)
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// loginFailed records the failed password login and audits it against the user if the account exists.
+// Once the account is locked, the user is emailed a link to unlock it, the response is the same whether
+// the account exists or not, so that it cannot be used to enumerate the users.
+func (s *Auth) loginFailed(c echo.Context, email string, user *types.User) error {
+	ctx := c.Request().Context()
+
+	res, err := s.guard.Fail(ctx, email, c.RealIP())
+	if err != nil {
+		return server.NewHTTPInternalError("error recording login attempt").SetInternal(err)
+	}
+
+	if user != nil {
+		s.audit(c, user.ID, types.AuditActionLoginFailed, "wrong password")
+
+		if res.AccountLocked {
+			s.audit(c, user.ID, types.AuditActionAccountLocked, "too many failed login attempts")
+
+			ttl := time.Duration(s.accountCfg.UnlockTTL) * time.Second
+			token, err := s.userToken.Issue(ctx, user.ID, types.UserTokenUnlockAccount, ttl)
+			if err != nil {
+				c.Logger().Errorf("error issuing unlock account token: %+v", err)
+			} else if err := s.sendMail(ctx, mailtemplate.UnlockAccount, user, "/unlock-account", token, ttl); err != nil {
+				c.Logger().Errorf("error sending unlock account email: %+v", err)
+			}
+		}
+	}
+
+	if res.AccountLocked || res.IPLocked {
+		return ErrLocked
+	}
+
+	return ErrInvalidCredentials
+}
+
+// UnlockAccount unlocks the account of the user of the unlock token before the lockout expires
+func (s *Auth) UnlockAccount(c echo.Context, data UnlockAccountData) error {
+	ctx := c.Request().Context()
+
+	token, err := s.userToken.Consume(ctx, data.Token, types.UserTokenUnlockAccount)
+	if err != nil {
+		return userTokenError(err)
+	}
+
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(ctx, user, token.UserID); err != nil {
+		return ErrInvalidUserToken.SetInternal(err)
+	}
+
+	if _, err := s.guard.Unlock(ctx, loginguard.AccountKey(user.Email)); err != nil {
+		return server.NewHTTPInternalError("error unlocking account").SetInternal(err)
+	}
+
+	s.audit(c, user.ID, types.AuditActionAccountUnlocked, "unlocked by email link")
+
+	return nil
+}
+
+// audit records the security event of the user, the failure is logged only
+func (s *Auth) audit(c echo.Context, userID, action, reason string) {
+	if err := s.repo.AuditEvent.Create(c.Request().Context(), &types.AuditEvent{
+		UserID:     userID,
+		Action:     action,
+		ObjectType: "user",
+		ObjectID:   userID,
+		Reason:     reason,
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+	}); err != nil {
+		c.Logger().Errorf("error auditing %s: %+v", action, err)
+	}
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"errors"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/mfa"
+	"tyr/internal/types"
+	"tyr/internal/usertoken"
+
+	"github.com/M15t/gram/pkg/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+// loginOrChallenge completes the first step of the login.
+// If the user has enabled two-factor authentication or their role requires it, a single-use challenge token is
+// returned instead of the access token, the login is completed by VerifyMFA with a code of the authenticator app.
+// The user whose role requires two-factor authentication but has not enrolled yet needs to enroll by
+// EnrollMFAChallenge with the challenge token first.
+func (s *Auth) loginOrChallenge(c echo.Context, user *types.User, device Device) (*LoginResp, error) {
+	if user.MFAEnabledAt == nil && !s.mfa.Required(user.Role) {
+		token, err := s.authenticate(c, &AuthenticateInput{
+			User:    user,
+			IsLogin: true,
+			Device:  device,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResp{AuthToken: token}, nil
+	}
+
+	challengeToken, err := s.userToken.Issue(c.Request().Context(), user.ID, types.UserTokenMFAChallenge, s.mfa.ChallengeTTL())
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error issuing login challenge").SetInternal(err)
+	}
+
+	return &LoginResp{MFAChallenge: &MFAChallenge{
+		MFARequired:        true,
+		ChallengeToken:     challengeToken,
+		ChallengeExpiresIn: int(s.mfa.ChallengeTTL().Seconds()),
+		EnrollmentRequired: user.MFAEnabledAt == nil,
+	}}, nil
+}
+
+// EnrollMFAChallenge generates the TOTP secret of the user of the login challenge,
+// for the user whose role requires two-factor authentication but has not enrolled yet
+func (s *Auth) EnrollMFAChallenge(c echo.Context, data MFAChallengeData) (*MFAEnrollResp, error) {
+	user, err := s.challengedUser(c, data.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.mfa.Enroll(c.Request().Context(), user)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &MFAEnrollResp{Secret: enrollment.Secret, ProvisioningURI: enrollment.ProvisioningURI}, nil
+}
+
+// VerifyMFA completes the login by the challenge token and a code of the authenticator app or a recovery code.
+// If the user is enrolling, the code activates two-factor authentication and the new recovery codes are returned too.
+// The challenge is invalidated after too many wrong codes, so that the user needs to login again.
+func (s *Auth) VerifyMFA(c echo.Context, data MFAVerifyData) (*MFAVerifyResp, error) {
+	ctx := c.Request().Context()
+
+	user, err := s.challengedUser(c, data.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabledAt == nil {
+		recoveryCodes, err = s.mfa.Activate(ctx, user, data.Code)
+	} else {
+		err = s.mfa.Verify(ctx, user, data.Code)
+	}
+	if err != nil {
+		if errors.Is(err, mfa.ErrTooManyAttempts) {
+			if err := s.userToken.Invalidate(ctx, user.ID, types.UserTokenMFAChallenge); err != nil {
+				return nil, server.NewHTTPInternalError("error invalidating login challenge").SetInternal(err)
+			}
+		}
+		return nil, mfaError(err)
+	}
+
+	// only one of concurrent verifications of the same challenge can succeed
+	if _, err := s.userToken.Consume(ctx, data.ChallengeToken, types.UserTokenMFAChallenge); err != nil {
+		return nil, ErrInvalidMFAChallenge.SetInternal(err)
+	}
+
+	token, err := s.authenticate(c, &AuthenticateInput{
+		User:    user,
+		IsLogin: true,
+		Device:  data.Device,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAVerifyResp{AuthToken: token, RecoveryCodes: recoveryCodes}, nil
+}
+
+// EnrollMFA generates a new pending TOTP secret of the current user
+func (s *Auth) EnrollMFA(c contextutil.Context) (*MFAEnrollResp, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.mfa.Enroll(c.GetContext(), user)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &MFAEnrollResp{Secret: enrollment.Secret, ProvisioningURI: enrollment.ProvisioningURI}, nil
+}
+
+// ActivateMFA enables two-factor authentication of the current user by a code of the pending secret
+func (s *Auth) ActivateMFA(c contextutil.Context, data MFACodeData) (*MFARecoveryCodesResp, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.mfa.Activate(c.GetContext(), user, data.Code)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &MFARecoveryCodesResp{RecoveryCodes: recoveryCodes}, nil
+}
+
+// RegenerateMFARecoveryCodes replaces the recovery codes of the current user, confirmed by a two-factor authentication code
+func (s *Auth) RegenerateMFARecoveryCodes(c contextutil.Context, data MFACodeData) (*MFARecoveryCodesResp, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.Verify(c.GetContext(), user, data.Code); err != nil {
+		return nil, mfaError(err)
+	}
+
+	recoveryCodes, err := s.mfa.RegenerateRecoveryCodes(c.GetContext(), user)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &MFARecoveryCodesResp{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableMFA disables two-factor authentication of the current user, confirmed by a two-factor authentication code.
+// It cannot be disabled if the role of the user requires it.
+func (s *Auth) DisableMFA(c contextutil.Context, data MFACodeData) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return err
+	}
+	if s.mfa.Required(user.Role) {
+		return ErrMFARequired
+	}
+
+	if err := s.mfa.Verify(c.GetContext(), user, data.Code); err != nil {
+		return mfaError(err)
+	}
+
+	if err := s.mfa.Disable(c.GetContext(), user); err != nil {
+		return mfaError(err)
+	}
+
+	return nil
+}
+
+// challengedUser reads the user of the login challenge token, the token is not used yet
+func (s *Auth) challengedUser(c echo.Context, challengeToken string) (*types.User, error) {
+	token, err := s.userToken.Verify(c.Request().Context(), challengeToken, types.UserTokenMFAChallenge)
+	if err != nil {
+		if errors.Is(err, usertoken.ErrInvalidToken) || errors.Is(err, usertoken.ErrTokenExpired) || errors.Is(err, usertoken.ErrTokenUsed) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, server.NewHTTPInternalError("error verifying login challenge").SetInternal(err)
+	}
+
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(c.Request().Context(), user, token.UserID); err != nil {
+		return nil, ErrInvalidMFAChallenge.SetInternal(err)
+	}
+	if err := statusError(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// mfaError maps the two-factor authentication errors to the http errors
+func mfaError(err error) error {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return ErrInvalidMFACode
+	case errors.Is(err, mfa.ErrTooManyAttempts):
+		return ErrMFATooManyAttempts
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return ErrMFANotEnrolled
+	case errors.Is(err, mfa.ErrAlreadyEnabled):
+		return ErrMFAEnabled
+	case errors.Is(err, mfa.ErrNotEnabled):
+		return ErrMFANotEnabled
+	default:
+		return server.NewHTTPInternalError("error processing two-factor authentication").SetInternal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"gorm.io/gorm"
+)
+
+// SwitchOrganization switches the current session to an organization the user is a member of, or back out of organizations.
+// A new access token is issued in the organization, the session stays in it when refreshing token.
+// The refresh token is not rotated.
+func (s *Auth) SwitchOrganization(c contextutil.Context, data SwitchOrganizationData) (*types.AuthToken, error) {
+	au := c.AuthUser()
+	if au == nil || au.SessionID == "" {
+		return nil, ErrInvalidSession
+	}
+	ctx := c.GetContext()
+
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(ctx, user, au.ID); err != nil {
+		return nil, ErrInvalidSession.SetInternal(err)
+	}
+	if err := statusError(user); err != nil {
+		return nil, err
+	}
+
+	var member *types.OrganizationMember
+	var organizationID *string
+	if data.OrganizationID != "" {
+		m, err := s.repo.OrganizationMember.FindByUser(ctx, data.OrganizationID, au.ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrNotOrgMember
+			}
+			return nil, server.NewHTTPInternalError("error reading membership").SetInternal(err)
+		}
+		member = m
+		organizationID = &m.OrganizationID
+	}
+
+	if err := s.repo.Session.Update(ctx, map[string]interface{}{"active_organization_id": organizationID}, au.SessionID); err != nil {
+		return nil, server.NewHTTPInternalError("error switching organization").SetInternal(err)
+	}
+
+	out, err := s.generateAccessToken(user, au.SessionID, member)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error generating access token").SetInternal(err)
+	}
+
+	return &types.AuthToken{
+		AccessToken: out.Token,
+		TokenType:   "bearer",
+		ExpiresIn:   out.ExpiresIn,
+	}, nil
+}
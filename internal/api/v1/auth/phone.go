@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/otp"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestOTP texts a login one-time password to the phone.
+// The response is the same whether the phone belongs to any app user or not, also during the resend cooldown,
+// so that it cannot be used to enumerate the users.
+func (s *Auth) RequestOTP(c echo.Context, data RequestOTPData) (*OTPSentResp, error) {
+	resp := &OTPSentResp{
+		ExpiresIn: int(s.otp.TTL().Seconds()),
+		ResendIn:  int(s.otp.ResendCooldown().Seconds()),
+	}
+
+	user, err := s.repo.User.FindByPhone(c.Request().Context(), data.Phone)
+	if err != nil || user == nil || !s.roles.IsAppRole(user.Role) || user.Status != types.UserStatusActive {
+		return resp, nil
+	}
+
+	sent, err := s.otp.Send(c.Request().Context(), user)
+	switch {
+	case errors.Is(err, otp.ErrResendCooldown):
+		return newOTPSentResp(sent), nil
+	case err != nil:
+		c.Logger().Errorf("error sending login otp: %+v", err)
+		return resp, nil
+	}
+
+	return newOTPSentResp(sent), nil
+}
+
+// loginByOTP authenticates the app user by the phone and the one-time password texted to it.
+// The phone is verified too since the user has received the password.
+// Any rejected password fails the same way as an unknown phone, so that it cannot be used to enumerate the users.
+func (s *Auth) loginByOTP(c echo.Context, data Credentials) (*LoginResp, error) {
+	existedUser, err := s.repo.User.FindByPhone(c.Request().Context(), data.Phone)
+	if err != nil || existedUser == nil || !s.roles.IsAppRole(existedUser.Role) {
+		return nil, ErrInvalidCredentials.SetInternal(err)
+	}
+
+	if err := s.otp.Verify(c.Request().Context(), existedUser, data.OTP); err != nil {
+		return nil, loginOTPError(err)
+	}
+
+	if err := statusError(existedUser); err != nil {
+		return nil, err
+	}
+
+	if existedUser.PhoneVerifiedAt == nil {
+		if err := s.repo.User.Update(c.Request().Context(), map[string]interface{}{
+			"phone_verified_at": time.Now(),
+		}, existedUser.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.loginOrChallenge(c, existedUser, data.Device)
+}
+
+// SendVerifyPhone texts a one-time password to the phone of the current user to verify it
+func (s *Auth) SendVerifyPhone(c contextutil.Context) (*OTPSentResp, error) {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if user.PhoneVerifiedAt != nil {
+		return nil, ErrPhoneVerified
+	}
+
+	sent, err := s.otp.Send(c.GetContext(), user)
+	if err != nil {
+		return nil, otpError(err)
+	}
+
+	return newOTPSentResp(sent), nil
+}
+
+// VerifyPhone marks the phone of the current user as verified by the one-time password texted to it
+func (s *Auth) VerifyPhone(c contextutil.Context, data VerifyPhoneData) error {
+	user, err := s.currentUser(c)
+	if err != nil {
+		return err
+	}
+	if user.PhoneVerifiedAt != nil {
+		return ErrPhoneVerified
+	}
+
+	if err := s.otp.Verify(c.GetContext(), user, data.OTP); err != nil {
+		return otpError(err)
+	}
+
+	if err := s.repo.User.Update(c.GetContext(), map[string]interface{}{
+		"phone_verified_at": time.Now(),
+	}, user.ID); err != nil {
+		return server.NewHTTPInternalError("error verifying phone").SetInternal(err)
+	}
+
+	return nil
+}
+
+// currentUser reads the user of the current access token
+func (s *Auth) currentUser(c contextutil.Context) (*types.User, error) {
+	au := c.AuthUser()
+	if au == nil {
+		return nil, ErrInvalidSession
+	}
+
+	user := &types.User{}
+	if err := s.repo.User.ReadByID(c.GetContext(), user, au.ID); err != nil {
+		return nil, server.NewHTTPInternalError("error reading user").SetInternal(err)
+	}
+
+	return user, nil
+}
+
+func newOTPSentResp(sent *otp.Sent) *OTPSentResp {
+	return &OTPSentResp{
+		ExpiresIn: int(sent.ExpiresIn.Seconds()),
+		ResendIn:  int(sent.ResendIn.Seconds()),
+	}
+}
+
+// loginOTPError maps the one-time password errors of the login to the http errors,
+// the reason of the rejection is not revealed
+func loginOTPError(err error) error {
+	for _, e := range []error{otp.ErrNotRequested, otp.ErrExpired, otp.ErrInvalid, otp.ErrTooManyAttempts} {
+		if errors.Is(err, e) {
+			return ErrInvalidCredentials
+		}
+	}
+	return otpError(err)
+}
+
+// otpError maps the one-time password errors to the http errors
+func otpError(err error) error {
+	switch {
+	case errors.Is(err, otp.ErrNotRequested):
+		return ErrOTPNotRequested
+	case errors.Is(err, otp.ErrExpired):
+		return ErrOTPExpired
+	case errors.Is(err, otp.ErrInvalid):
+		return ErrInvalidOTP
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		return ErrOTPTooManyAttempts
+	case errors.Is(err, otp.ErrResendCooldown):
+		return ErrOTPResendCooldown
+	case errors.Is(err, otp.ErrPhoneNotProvided):
+		return ErrPhoneNotProvided
+	default:
+		return server.NewHTTPInternalError("error processing otp").SetInternal(err)
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tyr/internal/otp"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+// TestLoginOTPError proves the rejected one-time passwords of a known phone fail the login
+// the same way as an unknown phone
+func TestLoginOTPError(t *testing.T) {
+	for _, err := range []error{otp.ErrNotRequested, otp.ErrExpired, otp.ErrInvalid, otp.ErrTooManyAttempts, fmt.Errorf("wrapped: %w", otp.ErrInvalid)} {
+		if got := loginOTPError(err); got != ErrInvalidCredentials {
+			t.Errorf("%v got %v, want ErrInvalidCredentials", err, got)
+		}
+	}
+
+	var he *server.HTTPError
+	if got := loginOTPError(errors.New("down")); !errors.As(got, &he) || he.Code != http.StatusInternalServerError {
+		t.Errorf("got %v, want an internal error", got)
+	}
+}
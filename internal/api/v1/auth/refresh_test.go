@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"tyr/config"
+	"tyr/internal/jwtkeys"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const testUserID = "01HUSER00000000000000000000"
+
+// TestRefreshTokenRotation proves a refresh token is rotated on use, and replaying a rotated token
+// revokes the whole session so that neither the attacker nor the legitimate client can keep refreshing
+func TestRefreshTokenRotation(t *testing.T) {
+	svc, db, denylist := newRefreshTestService(t)
+
+	first := db.login(t, svc)
+
+	second, err := svc.RefreshToken(newEchoContext(), RefreshTokenData{RefreshToken: first})
+	if err != nil {
+		t.Fatalf("refreshing got %v", err)
+	}
+	if second.RefreshToken == "" || second.RefreshToken == first || second.AccessToken == "" {
+		t.Fatalf("got refresh token %q, want a rotated one with an access token", second.RefreshToken)
+	}
+	if db.session.RefreshTokenHash.String != hashToken(second.RefreshToken) {
+		t.Fatal("the session does not hold the hash of the rotated token")
+	}
+	if db.revoked || len(denylist.revoked) != 0 || len(db.audits) != 0 {
+		t.Fatal("the session is revoked by a legitimate refresh")
+	}
+
+	// the rotated token is replayed
+	if _, err := svc.RefreshToken(newEchoContext(), RefreshTokenData{RefreshToken: first}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying got %v, want ErrRefreshTokenReused", err)
+	}
+	if !db.revoked {
+		t.Error("the session is not revoked")
+	}
+	if len(denylist.revoked) != 1 || denylist.revoked[0] != db.session.ID {
+		t.Errorf("got denied sessions %v, want the session", denylist.revoked)
+	}
+	if len(db.audits) != 1 || db.audits[0].Action != types.AuditActionRefreshTokenReused || db.audits[0].ObjectID != db.session.ID {
+		t.Errorf("got audit events %+v, want the reuse of the session", db.audits)
+	}
+
+	// the current token of the legitimate client is not accepted anymore either
+	if _, err := svc.RefreshToken(newEchoContext(), RefreshTokenData{RefreshToken: second.RefreshToken}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing the revoked session got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+// TestRefreshTokenConcurrentRotation proves only one of concurrent refreshes with the same token succeeds,
+// the other one is handled as a reuse
+func TestRefreshTokenConcurrentRotation(t *testing.T) {
+	svc, db, denylist := newRefreshTestService(t)
+
+	token := db.login(t, svc)
+	// the token is rotated by a concurrent refresh between the read and the rotation of the session
+	db.beforeRotate = func() { db.session.RefreshTokenHash = sql.NullString{String: hashToken("concurrent"), Valid: true} }
+
+	if _, err := svc.RefreshToken(newEchoContext(), RefreshTokenData{RefreshToken: token}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("got %v, want ErrRefreshTokenReused", err)
+	}
+	if !db.revoked || len(denylist.revoked) != 1 || len(db.audits) != 1 {
+		t.Error("the session is not revoked")
+	}
+}
+
+func TestRefreshTokenInvalid(t *testing.T) {
+	svc, db, _ := newRefreshTestService(t)
+	db.login(t, svc)
+
+	access, err := svc.generateAccessToken(db.user, db.session.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"malformed":    "not a token",
+		"access token": access.Token,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.RefreshToken(newEchoContext(), RefreshTokenData{RefreshToken: token}); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("got %v, want ErrInvalidRefreshToken", err)
+			}
+			if db.revoked {
+				t.Error("the session is revoked by an invalid token")
+			}
+		})
+	}
+}
+
+func newRefreshTestService(t *testing.T) (*Auth, *fakeSessionDB, *fakeDenylist) {
+	t.Helper()
+
+	jwtSvc, err := jwtkeys.New(config.JWT{Secret: "secret", DurationAccessToken: 60, DurationRefreshToken: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &fakeSessionDB{user: &types.User{Base: types.Base{ID: testUserID}, Email: "user@tyr.io", Role: "user", Status: types.UserStatusActive}}
+	denylist := &fakeDenylist{}
+
+	return &Auth{repo: repo.New(db.open(t)), jwt: jwtSvc, denylist: denylist}, db, denylist
+}
+
+func newEchoContext() echo.Context {
+	return echo.New().NewContext(httptest.NewRequest("POST", "/v1/auth/refresh-token", nil), httptest.NewRecorder())
+}
+
+// fakeDenylist records the denied sessions
+type fakeDenylist struct {
+	revoked []string
+}
+
+func (d *fakeDenylist) RevokeSessions(_ context.Context, ids ...string) error {
+	d.revoked = append(d.revoked, ids...)
+	return nil
+}
+
+// fakeSessionDB answers the dry run statements as if the database held a single session of a single user
+type fakeSessionDB struct {
+	user    *types.User
+	session *types.Session
+	revoked bool
+	audits  []*types.AuditEvent
+
+	beforeRotate func()
+}
+
+// login creates the session as the login does, returns its refresh token
+func (f *fakeSessionDB) login(t *testing.T, svc *Auth) string {
+	t.Helper()
+
+	out, err := svc.authenticate(newEchoContext(), &AuthenticateInput{User: f.user, IsLogin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.session == nil || f.session.RefreshTokenHash.String != hashToken(out.RefreshToken) {
+		t.Fatal("the session is not created with the hash of the refresh token")
+	}
+
+	return out.RefreshToken
+}
+
+func (f *fakeSessionDB) open(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Before("gorm:preload").Register("test:query", f.query),
+		gdb.Callback().Create().After("gorm:create").Register("test:create", f.create),
+		gdb.Callback().Update().After("gorm:update").Register("test:update", f.update),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return gdb
+}
+
+func (f *fakeSessionDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	switch dest := db.Statement.Dest.(type) {
+	case *types.Session:
+		if f.session == nil || f.revoked {
+			db.AddError(gorm.ErrRecordNotFound)
+			return
+		}
+		*dest = *f.session
+		db.RowsAffected = 1
+	case *[]*types.User:
+		*dest = append(*dest, f.user)
+		db.RowsAffected = 1
+	}
+}
+
+func (f *fakeSessionDB) create(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	switch rec := db.Statement.Dest.(type) {
+	case *types.Session:
+		s := *rec
+		f.session = &s
+	case *types.AuditEvent:
+		f.audits = append(f.audits, rec)
+	}
+	db.RowsAffected = 1
+}
+
+func (f *fakeSessionDB) update(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table != "sessions" {
+		return
+	}
+	updates, _ := db.Statement.Dest.(map[string]interface{})
+
+	if newHash, ok := updates["refresh_token_hash"].(string); ok {
+		// the rotation only applies on the current hash
+		if f.beforeRotate != nil {
+			f.beforeRotate()
+		}
+		if f.revoked || !whereHas(db.Statement, f.session.RefreshTokenHash.String) {
+			return
+		}
+		f.session.RefreshTokenHash = sql.NullString{String: newHash, Valid: true}
+		db.RowsAffected = 1
+		return
+	}
+	if updates["is_blocked"] == true {
+		f.revoked = true
+		f.session.RefreshTokenHash = sql.NullString{}
+		db.RowsAffected = 1
+	}
+}
+
+// whereHas checks the where conditions of the statement are bound to the given value
+func whereHas(stmt *gorm.Statement, value any) bool {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return false
+	}
+	for _, expr := range where.Exprs {
+		if e, ok := expr.(clause.Expr); ok {
+			for _, v := range e.Vars {
+				if v == value {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
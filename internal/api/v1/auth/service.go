@@ -1,7 +1,17 @@
 package auth
 
 import (
+	"context"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/loginguard"
+	"tyr/internal/mfa"
+	"tyr/internal/oidc"
+	"tyr/internal/otp"
 	"tyr/internal/repo"
+	"tyr/internal/types"
+	"tyr/third_party/mailer"
 
 	"github.com/M15t/gram/pkg/server/middleware/jwt"
 
@@ -9,19 +19,47 @@ import (
 )
 
 // New creates new auth service
-func New(repo *repo.Service, jwt JWT, cr Crypter) *Auth {
+func New(repo *repo.Service, jwt JWT, cr Crypter, denylist Denylist, mailer Mailer, userToken UserToken, otp OTP, mfa MFA, oidc OIDC, guard LoginGuard, password PasswordPolicy, roles Roles, sessionCfg config.Session, accountCfg config.Account) *Auth {
 	return &Auth{
-		repo: repo,
-		jwt:  jwt,
-		cr:   cr,
+		repo:       repo,
+		jwt:        jwt,
+		cr:         cr,
+		denylist:   denylist,
+		mailer:     mailer,
+		userToken:  userToken,
+		otp:        otp,
+		mfa:        mfa,
+		oidc:       oidc,
+		guard:      guard,
+		password:   password,
+		roles:      roles,
+		sessionCfg: sessionCfg,
+		accountCfg: accountCfg,
 	}
 }
 
 // Auth represents auth application service
 type Auth struct {
-	repo *repo.Service
-	jwt  JWT
-	cr   Crypter
+	repo       *repo.Service
+	jwt        JWT
+	cr         Crypter
+	denylist   Denylist
+	mailer     Mailer
+	userToken  UserToken
+	otp        OTP
+	mfa        MFA
+	oidc       OIDC
+	guard      LoginGuard
+	password   PasswordPolicy
+	roles      Roles
+	sessionCfg config.Session
+	accountCfg config.Account
+}
+
+// Roles represents the roles interface to check where the users login
+type Roles interface {
+	IsAppRole(role string) bool
+	IsPortalRole(role string) bool
 }
 
 // JWT represents token generator (jwt) interface
@@ -30,6 +68,63 @@ type JWT interface {
 	ParseToken(string) (*gjwt.Token, error)
 }
 
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeSessions(ctx context.Context, sessionIDs ...string) error
+}
+
+// Mailer represents email sending interface
+type Mailer interface {
+	Send(ctx context.Context, msg *mailer.Message) error
+}
+
+// UserToken represents signed single-use user token interface
+type UserToken interface {
+	Issue(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error)
+	Consume(ctx context.Context, token, purpose string) (*types.UserToken, error)
+	Verify(ctx context.Context, token, purpose string) (*types.UserToken, error)
+	Invalidate(ctx context.Context, userID, purpose string) error
+}
+
+// OTP represents one-time password interface
+type OTP interface {
+	Send(ctx context.Context, user *types.User) (*otp.Sent, error)
+	Verify(ctx context.Context, user *types.User, code string) error
+	TTL() time.Duration
+	ResendCooldown() time.Duration
+}
+
+// MFA represents TOTP two-factor authentication interface
+type MFA interface {
+	Required(role string) bool
+	ChallengeTTL() time.Duration
+	Enroll(ctx context.Context, user *types.User) (*mfa.Enrollment, error)
+	Activate(ctx context.Context, user *types.User, code string) ([]string, error)
+	Verify(ctx context.Context, user *types.User, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, user *types.User) ([]string, error)
+	Disable(ctx context.Context, user *types.User) error
+}
+
+// OIDC represents OpenID Connect ID token verifier interface
+type OIDC interface {
+	Verify(ctx context.Context, provider, idToken, nonce string) (*oidc.Claims, error)
+}
+
+// LoginGuard represents login brute-force protection interface
+type LoginGuard interface {
+	Check(ctx context.Context, email, ip string) (time.Duration, error)
+	Fail(ctx context.Context, email, ip string) (*loginguard.Result, error)
+	Succeed(ctx context.Context, email string) error
+	Unlock(ctx context.Context, key string) (bool, error)
+}
+
+// PasswordPolicy represents password policy interface
+type PasswordPolicy interface {
+	Validate(ctx context.Context, password string, user *types.User) error
+	Remember(ctx context.Context, userID, hash string) error
+	Expired(user *types.User) bool
+}
+
 // Crypter represents security interface
 type Crypter interface {
 	HashPassword(string) string
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/oidc"
+	"tyr/internal/rbac"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// loginByOIDC authenticates the app user by the ID token of an OpenID Connect provider.
+// The user of the linked identity is logged in, otherwise the identity is linked to the user of the same email
+// if the provider has verified it, otherwise a new user is signed up, see signupOrLinkByOIDC.
+func (s *Auth) loginByOIDC(c echo.Context, data Credentials) (*LoginResp, error) {
+	ctx := c.Request().Context()
+
+	claims, err := s.oidc.Verify(ctx, data.Provider, data.IDToken, data.Nonce)
+	if err != nil {
+		return nil, oidcError(err)
+	}
+
+	_, user, err := s.repo.UserIdentity.FindBySubject(ctx, claims.Provider, claims.Subject)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if user, err = s.signupOrLinkByOIDC(c, claims, data.OIDCData); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, server.NewHTTPInternalError("error reading identity").SetInternal(err)
+	}
+
+	if !s.roles.IsAppRole(user.Role) {
+		return nil, ErrInvalidCredentials
+	}
+	if err := statusError(user); err != nil {
+		return nil, err
+	}
+
+	return s.loginOrChallenge(c, user, data.Device)
+}
+
+// signupOrLinkByOIDC links the new identity to the user of the same email, or signs up a new user.
+// Linking requires the email verified by the provider. If the existing user has not verified the email either,
+// its password is replaced and its sessions are revoked, since whoever set them might not own the email.
+func (s *Auth) signupOrLinkByOIDC(c echo.Context, claims *oidc.Claims, data OIDCData) (*types.User, error) {
+	ctx := c.Request().Context()
+	if claims.Email == "" {
+		return nil, ErrIDTokenNoEmail
+	}
+	email := strings.ToLower(claims.Email)
+
+	user, err := s.repo.User.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !s.roles.IsAppRole(user.Role) {
+			return nil, ErrInvalidCredentials
+		}
+		if !claims.EmailVerified {
+			return nil, ErrIDTokenUnverified
+		}
+		if user.EmailVerifiedAt == nil {
+			if err := s.repo.User.Update(ctx, map[string]interface{}{
+				"password":          s.cr.HashPassword(randomPassword()),
+				"email_verified_at": time.Now(),
+			}, user.ID); err != nil {
+				return nil, server.NewHTTPInternalError("error linking identity").SetInternal(err)
+			}
+			revokedIDs, err := s.repo.Session.RevokeAllByUserID(ctx, user.ID)
+			if err != nil {
+				return nil, server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+			}
+			if err := s.denylist.RevokeSessions(ctx, revokedIDs...); err != nil {
+				return nil, server.NewHTTPInternalError("error revoking sessions").SetInternal(err)
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		user = &types.User{
+			FirstName: firstNonEmpty(data.FirstName, claims.GivenName, claims.Name, strings.Split(email, "@")[0]),
+			LastName:  firstNonEmpty(data.LastName, claims.FamilyName),
+			Email:     email,
+			// the user has no password until resetting it
+			Password: s.cr.HashPassword(randomPassword()),
+
+			Role:    rbac.RoleUser,
+			Status:  types.UserStatusActive,
+			Profile: &types.Profile{},
+		}
+		if claims.EmailVerified {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		if err := s.repo.User.Create(ctx, user); err != nil {
+			return nil, server.NewHTTPInternalError("error creating user").SetInternal(err)
+		}
+		if user.EmailVerifiedAt == nil {
+			if err := s.sendVerifyEmail(ctx, user); err != nil {
+				c.Logger().Errorf("error sending verification email: %+v", err)
+			}
+		}
+	default:
+		return nil, server.NewHTTPInternalError("error reading user").SetInternal(err)
+	}
+
+	if err := s.repo.UserIdentity.Create(ctx, &types.UserIdentity{
+		UserID:   user.ID,
+		Provider: claims.Provider,
+		Subject:  claims.Subject,
+		Email:    email,
+	}); err != nil {
+		return nil, server.NewHTTPInternalError("error linking identity").SetInternal(err)
+	}
+
+	return user, nil
+}
+
+// ListIdentities lists the identities linked to the current user
+func (s *Auth) ListIdentities(c contextutil.Context) (*ListIdentitiesResp, error) {
+	au := c.AuthUser()
+	if au == nil {
+		return nil, ErrInvalidSession
+	}
+
+	identities, err := s.repo.UserIdentity.ListByUserID(c.GetContext(), au.ID)
+	if err != nil {
+		return nil, server.NewHTTPInternalError("error listing identities").SetInternal(err)
+	}
+
+	return &ListIdentitiesResp{Data: identities}, nil
+}
+
+// LinkIdentity links the identity of the ID token to the current user
+func (s *Auth) LinkIdentity(c contextutil.Context, data LinkIdentityData) (*types.UserIdentity, error) {
+	au := c.AuthUser()
+	if au == nil {
+		return nil, ErrInvalidSession
+	}
+
+	claims, err := s.oidc.Verify(c.GetContext(), data.Provider, data.IDToken, data.Nonce)
+	if err != nil {
+		return nil, oidcError(err)
+	}
+
+	identity, _, err := s.repo.UserIdentity.FindBySubject(c.GetContext(), claims.Provider, claims.Subject)
+	switch {
+	case err == nil:
+		if identity.UserID != au.ID {
+			return nil, ErrIdentityLinked
+		}
+		return identity, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, server.NewHTTPInternalError("error reading identity").SetInternal(err)
+	}
+
+	identity = &types.UserIdentity{
+		UserID:   au.ID,
+		Provider: claims.Provider,
+		Subject:  claims.Subject,
+		Email:    strings.ToLower(claims.Email),
+	}
+	if err := s.repo.UserIdentity.Create(c.GetContext(), identity); err != nil {
+		return nil, server.NewHTTPInternalError("error linking identity").SetInternal(err)
+	}
+
+	return identity, nil
+}
+
+// UnlinkIdentity unlinks the identity from the current user
+func (s *Auth) UnlinkIdentity(c contextutil.Context, id string) error {
+	au := c.AuthUser()
+	if au == nil {
+		return ErrInvalidSession
+	}
+
+	unlinked, err := s.repo.UserIdentity.Unlink(c.GetContext(), au.ID, id)
+	if err != nil {
+		return server.NewHTTPInternalError("error unlinking identity").SetInternal(err)
+	}
+	if !unlinked {
+		return ErrIdentityNotFound
+	}
+
+	return nil
+}
+
+// oidcError maps the OpenID Connect errors to the http errors
+func oidcError(err error) error {
+	switch {
+	case errors.Is(err, oidc.ErrUnsupportedProvider):
+		return ErrUnsupportedProvider
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		return ErrInvalidIDToken.SetInternal(err)
+	default:
+		return server.NewHTTPInternalError("error verifying id token").SetInternal(err)
+	}
+}
+
+// randomPassword returns an unguessable password for the users who have not set any
+func randomPassword() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,398 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tyr/config"
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/jwtkeys"
+	"tyr/internal/oidc"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	"tyr/third_party/mailer"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const testNonce = "nonce-1"
+
+func TestLoginByOIDC(t *testing.T) {
+	now := time.Now()
+	verified := &types.User{Base: types.Base{ID: "01HVERIFIED0000000000000000"}, Email: "verified@tyr.io", Password: "hash", Role: "user", Status: types.UserStatusActive, EmailVerifiedAt: &now}
+	admin := &types.User{Base: types.Base{ID: "01HADMIN00000000000000000000"}, Email: "admin@tyr.io", Password: "hash", Role: "admin", Status: types.UserStatusActive, EmailVerifiedAt: &now}
+	blocked := &types.User{Base: types.Base{ID: "01HBLOCKED000000000000000000"}, Email: "blocked@tyr.io", Password: "hash", Role: "user", Status: types.UserStatusBlocked}
+	deleted := &types.User{Base: types.Base{ID: "01HDELETED000000000000000000", DeletedAt: gorm.DeletedAt{Time: now, Valid: true}}, Email: "deleted@tyr.io", Password: "hash", Role: "user", Status: types.UserStatusDeleted}
+
+	cases := []struct {
+		name    string
+		subject string
+		claims  map[string]any
+		nonce   string
+		wantErr error
+		// the email of the user the identity is linked to
+		wantLinked string
+		// whether a verification email is sent
+		wantMail bool
+	}{
+		{
+			name:       "new user with verified email",
+			subject:    "sub-new",
+			claims:     map[string]any{"email": "New@Tyr.io", "email_verified": true, "given_name": "New"},
+			wantLinked: "new@tyr.io",
+		},
+		{
+			name:       "new user with unverified email",
+			subject:    "sub-unverified",
+			claims:     map[string]any{"email": "unverified@tyr.io"},
+			wantLinked: "unverified@tyr.io",
+			wantMail:   true,
+		},
+		{name: "new user without email", subject: "sub-no-email", wantErr: ErrIDTokenNoEmail},
+		{
+			name:       "existing user with the same verified email",
+			subject:    "sub-verified",
+			claims:     map[string]any{"email": "verified@tyr.io", "email_verified": true},
+			wantLinked: "verified@tyr.io",
+		},
+		{name: "existing user, the email is not verified by the provider", subject: "sub-verified", claims: map[string]any{"email": "verified@tyr.io"}, wantErr: ErrIDTokenUnverified},
+		{name: "existing user of a portal role", subject: "sub-admin", claims: map[string]any{"email": "admin@tyr.io", "email_verified": true}, wantErr: ErrInvalidCredentials},
+		{name: "linked user which is blocked", subject: "sub-blocked", wantErr: ErrUserBlocked},
+		{name: "linked user which is deleted", subject: "sub-deleted", claims: map[string]any{"email": "deleted@tyr.io", "email_verified": true}, wantErr: ErrInvalidCredentials},
+		{name: "without nonce", subject: "sub-new", claims: map[string]any{"email": "new@tyr.io", "email_verified": true}, nonce: "-", wantErr: ErrInvalidIDToken},
+		{name: "another nonce", subject: "sub-new", claims: map[string]any{"email": "new@tyr.io", "email_verified": true}, nonce: "replayed", wantErr: ErrInvalidIDToken},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &fakeAccountDB{users: []*types.User{clone(verified), clone(admin), clone(blocked), clone(deleted)}}
+			db.identities = []*types.UserIdentity{
+				{Base: types.Base{ID: "01HIDENTITY0000000000000000"}, UserID: blocked.ID, Provider: "fake", Subject: "sub-blocked"},
+				{Base: types.Base{ID: "01HIDENTITY0000000000000001"}, UserID: deleted.ID, Provider: "fake", Subject: "sub-deleted"},
+			}
+			svc, issuer, mails := newOIDCTestService(t, db)
+
+			nonce := testNonce
+			switch tc.nonce {
+			case "-":
+				nonce = ""
+			case "":
+			default:
+				nonce = tc.nonce
+			}
+			claims := map[string]any{"nonce": testNonce}
+			for k, v := range tc.claims {
+				claims[k] = v
+			}
+			idToken, err := issuer.IDToken("app.tyr", tc.subject, claims, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := svc.Login(newEchoContext(), Credentials{GrantType: "oidc", OIDCData: OIDCData{Provider: "fake", IDToken: idToken, Nonce: nonce}})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				if len(db.users) != 4 || len(db.identities) != 2 || db.sessions != 0 {
+					t.Error("a user is created, the identity is linked or a session is created by the failed login")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if resp.AuthToken == nil || resp.AuthToken.AccessToken == "" || db.sessions != 1 {
+				t.Fatal("the user is not logged in")
+			}
+
+			identity := db.identity("fake", tc.subject)
+			if identity == nil {
+				t.Fatal("the identity is not linked")
+			}
+			user := db.user(identity.UserID)
+			if user == nil || user.Email != tc.wantLinked {
+				t.Fatalf("the identity is linked to %+v, want the user of %s", user, tc.wantLinked)
+			}
+			if (user.EmailVerifiedAt == nil) != tc.wantMail || (len(mails.msgs) == 1) != tc.wantMail {
+				t.Errorf("got email verified %v with %d emails sent", user.EmailVerifiedAt != nil, len(mails.msgs))
+			}
+			if user.ID == verified.ID && user.Password != verified.Password {
+				t.Error("the password of the user who has verified the email is replaced")
+			}
+
+			// the linked identity logs in the same user
+			users := len(db.users)
+			if _, err := svc.Login(newEchoContext(), Credentials{GrantType: "oidc", OIDCData: OIDCData{Provider: "fake", IDToken: idToken, Nonce: nonce}}); err != nil {
+				t.Fatalf("logging in again got %v", err)
+			}
+			if len(db.users) != users || len(db.identities) != 3 || db.sessions != 2 {
+				t.Error("logging in again does not log in the linked user")
+			}
+		})
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	user := &types.User{Base: types.Base{ID: testUserID}, Email: "user@tyr.io", Role: "user", Status: types.UserStatusActive}
+	other := &types.User{Base: types.Base{ID: "01HOTHER0000000000000000000"}, Email: "other@tyr.io", Role: "user", Status: types.UserStatusActive}
+	db := &fakeAccountDB{users: []*types.User{user, other}}
+	db.identities = []*types.UserIdentity{{Base: types.Base{ID: "01HIDENTITY0000000000000000"}, UserID: other.ID, Provider: "fake", Subject: "sub-other"}}
+	svc, issuer, _ := newOIDCTestService(t, db)
+
+	link := func(subject, nonce string) (*types.UserIdentity, error) {
+		idToken, err := issuer.IDToken("app.tyr", subject, map[string]any{"nonce": testNonce, "email": "Another@Tyr.io"}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc := &contextutil.HTTPContext{Context: newEchoContext()}
+		cc.Set("id", user.ID)
+		cc.SetAuthUser()
+		return svc.LinkIdentity(cc, LinkIdentityData{Provider: "fake", IDToken: idToken, Nonce: nonce})
+	}
+
+	identity, err := link("sub-1", testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the email of the identity does not need to match the email of the user
+	if identity.UserID != user.ID || identity.Email != "another@tyr.io" || db.identity("fake", "sub-1") == nil {
+		t.Fatalf("got identity %+v, want it linked to the user", identity)
+	}
+
+	again, err := link("sub-1", testNonce)
+	if err != nil || again.ID != identity.ID || len(db.identities) != 2 {
+		t.Errorf("linking again got %+v, %v, want the linked identity", again, err)
+	}
+
+	if _, err := link("sub-other", testNonce); !errors.Is(err, ErrIdentityLinked) {
+		t.Errorf("got %v, want ErrIdentityLinked", err)
+	}
+	if _, err := link("sub-2", ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("got %v without nonce, want ErrInvalidIDToken", err)
+	}
+	if len(db.identities) != 2 {
+		t.Errorf("got %d identities, want 2", len(db.identities))
+	}
+}
+
+func newOIDCTestService(t *testing.T, db *fakeAccountDB) (*Auth, *oidc.FakeIssuer, *fakeMailer) {
+	t.Helper()
+
+	issuer, err := oidc.NewFakeIssuer("https://issuer.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(issuer)
+	t.Cleanup(srv.Close)
+	providers, err := json.Marshal([]oidc.ProviderConfig{{Name: "fake", Issuer: issuer.Issuer, JWKSURL: srv.URL, ClientIDs: []string{"app.tyr"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oidcSvc, err := oidc.New(config.OIDC{Providers: string(providers), JWKSCacheTTL: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwtSvc, err := jwtkeys.New(config.JWT{Secret: "secret", DurationAccessToken: 60, DurationRefreshToken: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mails := &fakeMailer{}
+	return &Auth{
+		repo:       repo.New(db.open(t)),
+		jwt:        jwtSvc,
+		cr:         fakeCrypter{},
+		denylist:   &fakeDenylist{},
+		mailer:     mails,
+		userToken:  fakeUserToken{},
+		mfa:        fakeMFA{},
+		oidc:       oidcSvc,
+		roles:      fakeRoles{},
+		accountCfg: config.Account{WebURL: "https://app.tyr.io", VerifyEmailTTL: 86400},
+	}, issuer, mails
+}
+
+func clone(u *types.User) *types.User {
+	c := *u
+	return &c
+}
+
+type fakeCrypter struct{}
+
+func (fakeCrypter) HashPassword(p string) string               { return "hashed:" + p }
+func (fakeCrypter) CompareHashAndPassword(hash, p string) bool { return hash == "hashed:"+p }
+
+// fakeRoles logs the user role in the app and the other roles in the portal
+type fakeRoles struct{}
+
+func (fakeRoles) IsAppRole(role string) bool    { return role == "user" }
+func (fakeRoles) IsPortalRole(role string) bool { return role != "user" }
+
+// fakeMFA requires no two-factor authentication
+type fakeMFA struct {
+	MFA
+}
+
+func (fakeMFA) Required(string) bool { return false }
+
+type fakeUserToken struct {
+	UserToken
+}
+
+func (fakeUserToken) Issue(_ context.Context, userID, purpose string, _ time.Duration) (string, error) {
+	return purpose + ":" + userID, nil
+}
+
+// fakeMailer records the sent emails
+type fakeMailer struct {
+	msgs []*mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg *mailer.Message) error {
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+// fakeAccountDB answers the dry run statements as if the database held the given users and identities
+type fakeAccountDB struct {
+	users      []*types.User
+	identities []*types.UserIdentity
+	sessions   int
+}
+
+func (f *fakeAccountDB) user(id string) *types.User {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (f *fakeAccountDB) identity(provider, subject string) *types.UserIdentity {
+	for _, i := range f.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return i
+		}
+	}
+	return nil
+}
+
+func (f *fakeAccountDB) open(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Register("test:query", f.query),
+		gdb.Callback().Create().After("gorm:create").Register("test:create", f.create),
+		gdb.Callback().Update().After("gorm:update").Register("test:update", f.update),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return gdb
+}
+
+func (f *fakeAccountDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	vars := whereVars(db.Statement)
+	switch dest := db.Statement.Dest.(type) {
+	case *types.UserIdentity:
+		if len(vars) == 2 {
+			if i := f.identity(vars[0].(string), vars[1].(string)); i != nil {
+				*dest = *i
+				db.RowsAffected = 1
+				return
+			}
+		}
+		db.AddError(gorm.ErrRecordNotFound)
+	case *types.User:
+		for _, u := range f.users {
+			if u.DeletedAt.Valid && !db.Statement.Unscoped {
+				continue
+			}
+			if len(vars) == 1 && (vars[0] == u.ID || vars[0] == u.Email) {
+				*dest = *u
+				db.RowsAffected = 1
+				return
+			}
+		}
+		db.AddError(gorm.ErrRecordNotFound)
+	}
+}
+
+func (f *fakeAccountDB) create(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	switch rec := db.Statement.Dest.(type) {
+	case *types.User:
+		u := *rec
+		f.users = append(f.users, &u)
+	case *types.UserIdentity:
+		// unique by provider and subject
+		if f.identity(rec.Provider, rec.Subject) != nil {
+			db.AddError(gorm.ErrDuplicatedKey)
+			return
+		}
+		i := *rec
+		f.identities = append(f.identities, &i)
+	case *types.Session:
+		f.sessions++
+	}
+	db.RowsAffected = 1
+}
+
+func (f *fakeAccountDB) update(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table != "users" {
+		return
+	}
+	updates, _ := db.Statement.Dest.(map[string]interface{})
+	for _, v := range whereVars(db.Statement) {
+		id, _ := v.(string)
+		if u := f.user(id); u != nil {
+			if p, ok := updates["password"].(string); ok {
+				u.Password = p
+			}
+			if at, ok := updates["email_verified_at"].(time.Time); ok {
+				u.EmailVerifiedAt = &at
+			}
+			db.RowsAffected = 1
+		}
+	}
+}
+
+// whereVars returns the values bound to the where conditions written by the repo
+func whereVars(stmt *gorm.Statement) []any {
+	vars := []any{}
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok {
+		for _, expr := range where.Exprs {
+			// the soft delete condition is a clause.Eq, skipped
+			if e, ok := expr.(clause.Expr); ok {
+				vars = append(vars, e.Vars...)
+			}
+		}
+	}
+	return vars
+}
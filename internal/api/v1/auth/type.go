@@ -6,15 +6,53 @@ import "tyr/internal/types"
 // swagger:model
 type Credentials struct {
 	// example: collector@tyr.io
-	Email string `json:"email" form:"email" validate:"required_without=Username"`
-	// example: user123!@#
-	Password string `json:"password" form:"password" validate:"required"`
+	Email string `json:"email" form:"email" validate:"required_without_all=Username Phone IDToken"`
+	// Required for the `app` and `portal` grant types
+	// example: Tyr-Collects-2026
+	Password string `json:"password" form:"password" validate:"required_if=GrantType app,required_if=GrantType portal"`
+	// Required for the `otp` grant type
+	// example: 5551234567
+	Phone string `json:"phone,omitempty" form:"phone" validate:"required_if=GrantType otp"`
+	// The one-time password texted to the phone, required for the `otp` grant type
+	// example: 123456
+	OTP string `json:"otp,omitempty" form:"otp" validate:"required_if=GrantType otp"`
+
+	OIDCData
 
 	// This is for SwaggerUI authentication which only support `username` field
 	// swagger:ignore
 	Username string `json:"username" form:"username"`
 	// example: app
 	GrantType string `json:"grant_type" form:"grant_type" validate:"required"`
+
+	Device
+}
+
+// OIDCData represents the ID token of an OpenID Connect provider for social login
+// swagger:model
+type OIDCData struct {
+	// Required for the `oidc` grant type
+	// example: google
+	Provider string `json:"provider,omitempty" form:"provider" validate:"required_if=GrantType oidc"`
+	// The ID token the app received from the provider, required for the `oidc` grant type
+	IDToken string `json:"id_token,omitempty" form:"id_token" validate:"required_if=GrantType oidc"`
+	// The raw nonce the app sent to the provider, required for the `oidc` grant type against replayed ID tokens
+	Nonce string `json:"nonce,omitempty" form:"nonce" validate:"required_if=GrantType oidc"`
+	// Apple shares the name with the app on the first sign in only, the app may pass it along
+	// example: John
+	FirstName string `json:"first_name,omitempty" form:"first_name" validate:"omitempty,max=100"`
+	// example: Doe
+	LastName string `json:"last_name,omitempty" form:"last_name" validate:"omitempty,max=100"`
+}
+
+// Device represents the optional metadata of the device which the session is created on
+// swagger:model
+type Device struct {
+	// example: John's iPhone
+	DeviceName string `json:"device_name,omitempty" form:"device_name" validate:"omitempty,max=100"`
+	// Detected from the user agent if omitted
+	// example: ios
+	Platform string `json:"platform,omitempty" form:"platform" validate:"omitempty,oneof=ios android web other"`
 }
 
 // RefreshTokenData represents refresh token request data
@@ -27,6 +65,10 @@ type RefreshTokenData struct {
 type AuthenticateInput struct {
 	User    *types.User
 	IsLogin bool
+	// Device of the new session on login
+	Device Device
+	// Existing session on refreshing token
+	Session *types.Session
 }
 
 // SignupData represents signup request data
@@ -40,6 +82,160 @@ type SignupData struct {
 	Email string `json:"email" validate:"required,email"`
 	// example: 5551234567
 	Phone string `json:"phone" validate:"required,max=10"`
-	// example: passisburden!@#
-	Password string `json:"password" validate:"required,min=6"`
+	// Must satisfy the password policy
+	// example: Tyr-Collects-2026
+	Password string `json:"password" validate:"required"`
+
+	Device
+}
+
+// ForgotPasswordData represents forgot password request data
+// swagger:model
+type ForgotPasswordData struct {
+	// example: john.doe@tyr.io
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordData represents reset password request data
+// swagger:model
+type ResetPasswordData struct {
+	// The token from the password reset link
+	Token string `json:"token" validate:"required"`
+	// Must satisfy the password policy
+	// example: Tyr-Collects-2026
+	NewPassword string `json:"new_password" validate:"required"`
+	// example: Tyr-Collects-2026
+	NewPasswordConfirm string `json:"new_password_confirm" validate:"required,eqfield=NewPassword"`
+}
+
+// VerifyEmailData represents verify email request data
+// swagger:model
+type VerifyEmailData struct {
+	// The token from the email verification link
+	Token string `json:"token" validate:"required"`
+}
+
+// UnlockAccountData represents unlock account request data
+// swagger:model
+type UnlockAccountData struct {
+	// The token from the unlock account link
+	Token string `json:"token" validate:"required"`
+}
+
+// RequestOTPData represents login one-time password request data
+// swagger:model
+type RequestOTPData struct {
+	// example: 5551234567
+	Phone string `json:"phone" validate:"required,max=10"`
+}
+
+// VerifyPhoneData represents verify phone request data
+// swagger:model
+type VerifyPhoneData struct {
+	// The one-time password texted to the phone
+	// example: 123456
+	OTP string `json:"otp" validate:"required"`
+}
+
+// OTPSentResp represents the timing of the sent one-time password
+// swagger:model
+type OTPSentResp struct {
+	// Seconds until the code expires
+	ExpiresIn int `json:"expires_in"`
+	// Seconds until another code can be requested
+	ResendIn int `json:"resend_in"`
+}
+
+// LoginResp represents login response data, either the access token or the two-factor authentication challenge
+// swagger:model
+type LoginResp struct {
+	*types.AuthToken
+	*MFAChallenge
+}
+
+// MFAChallenge represents the second step of the login which requires a two-factor authentication code
+// swagger:model
+type MFAChallenge struct {
+	// Always true, the login needs to be completed by `/v1/auth/mfa/verify`
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	// Seconds until the challenge expires
+	ChallengeExpiresIn int `json:"challenge_expires_in"`
+	// Whether two-factor authentication is required for the role but not enrolled yet,
+	// the user needs to enroll by `/v1/auth/mfa/challenge/enroll` first
+	EnrollmentRequired bool `json:"enrollment_required"`
+}
+
+// MFAChallengeData represents the login challenge request data
+// swagger:model
+type MFAChallengeData struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+// MFAVerifyData represents the second step login request data
+// swagger:model
+type MFAVerifyData struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// The TOTP code of the authenticator app or a recovery code
+	// example: 123456
+	Code string `json:"code" validate:"required"`
+
+	Device
+}
+
+// MFACodeData represents the request data confirmed by a two-factor authentication code
+// swagger:model
+type MFACodeData struct {
+	// The TOTP code of the authenticator app, or a recovery code if two-factor authentication is enabled
+	// example: 123456
+	Code string `json:"code" validate:"required"`
+}
+
+// MFAEnrollResp represents the pending TOTP secret to be added into an authenticator app
+// swagger:model
+type MFAEnrollResp struct {
+	// example: JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	Secret string `json:"secret"`
+	// The `otpauth://` URI to be rendered as QR code
+	// example: otpauth://totp/Tyr:admin@tyr.io?algorithm=SHA1&digits=6&issuer=Tyr&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFARecoveryCodesResp represents the new recovery codes, which are shown only once
+// swagger:model
+type MFARecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAVerifyResp represents the second step login response data
+// swagger:model
+type MFAVerifyResp struct {
+	*types.AuthToken
+	// The new recovery codes if two-factor authentication is enabled by this login, which are shown only once
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// LinkIdentityData represents the request data to link an OpenID Connect identity to the current user
+// swagger:model
+type LinkIdentityData struct {
+	// example: google
+	Provider string `json:"provider" validate:"required"`
+	// The ID token the app received from the provider
+	IDToken string `json:"id_token" validate:"required"`
+	// The raw nonce the app sent to the provider, against replayed ID tokens
+	Nonce string `json:"nonce" validate:"required"`
+}
+
+// ListIdentitiesResp represents the linked identities of the current user
+// swagger:model
+type ListIdentitiesResp struct {
+	Data []*types.UserIdentity `json:"data"`
+}
+
+// SwitchOrganizationData represents the request data to switch the current session to an organization
+// swagger:model
+type SwitchOrganizationData struct {
+	// The organization to switch to, empty to switch back to the personal documents
+	// example: 01HZX3Y8M5Q7V2K9T4R6W1N0PB
+	OrganizationID string `json:"organization_id"`
 }
@@ -1,7 +1,11 @@
 package auth
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"time"
 
 	"tyr/internal/types"
@@ -10,55 +14,100 @@ import (
 	"github.com/M15t/gram/pkg/util/ulidutil"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 func (s *Auth) authenticate(c echo.Context, ai *AuthenticateInput) (*types.AuthToken, error) {
-	// * noted: this case is support only 1 session per user
-	// * multiple sessions per user is not supported yet need to be implemented later
+	// * a new session is created on every login so that a user can have many sessions on different devices,
+	// * the existing session is kept on refreshing token
 	ctx := c.Request().Context()
 
 	sessionID := ulidutil.NewString()
-	accessTokenOutput := jwt.TokenOutput{}
-	refreshTokenOutput := jwt.TokenOutput{}
+	if ai.Session != nil {
+		sessionID = ai.Session.ID
+	}
+
+	// * the organization switched to is kept on refreshing token as long as the user is a member of it
+	var member *types.OrganizationMember
+	if ai.Session != nil && ai.Session.ActiveOrganizationID != nil {
+		m, err := s.repo.OrganizationMember.FindByUser(ctx, *ai.Session.ActiveOrganizationID, ai.User.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		member = m
+	}
+
 	// * generate access token
-	if err := s.jwt.GenerateToken(&jwt.TokenInput{
-		Type: jwt.TypeTokenAccess,
-		Claims: map[string]interface{}{
-			"id":    ai.User.ID,
-			"email": ai.User.Email,
-			"name":  ai.User.FirstName + " " + ai.User.LastName,
-			"role":  ai.User.Role,
-		},
-	}, &accessTokenOutput); err != nil {
+	accessTokenOutput, err := s.generateAccessToken(ai.User, sessionID, member)
+	if err != nil {
 		return nil, err
 	}
 
-	// * generate refresh token
+	refreshTokenOutput := jwt.TokenOutput{}
+
+	// * generate refresh token, jti makes every rotated token of the session unique
 	if err := s.jwt.GenerateToken(&jwt.TokenInput{
 		Type: jwt.TypeTokenRefresh,
 		Claims: map[string]interface{}{
 			"id":  sessionID,
 			"uid": ai.User.ID,
+			"jti": ulidutil.NewString(),
 		},
 	}, &refreshTokenOutput); err != nil {
 		return nil, err
 	}
+	refreshTokenHash := hashToken(refreshTokenOutput.Token)
 
 	if ai.IsLogin {
+		platform := ai.Device.Platform
+		if platform == "" {
+			platform = detectPlatform(c.Request().UserAgent())
+		}
+
 		// * create session
+		now := time.Now()
 		if err := s.repo.Session.Create(ctx, &types.Session{
 			ID:        sessionID,
 			UserID:    ai.User.ID,
 			IPAddress: c.RealIP(),
 			UserAgent: c.Request().UserAgent(),
-			ExpiresAt: time.Now().Add(time.Duration(refreshTokenOutput.ExpiresIn) * time.Second),
-			RefreshToken: sql.NullString{
-				String: refreshTokenOutput.Token,
+			ExpiresAt: now.Add(time.Duration(refreshTokenOutput.ExpiresIn) * time.Second),
+			RefreshTokenHash: sql.NullString{
+				String: refreshTokenHash,
 				Valid:  true,
 			},
+			DeviceName:        ai.Device.DeviceName,
+			Platform:          platform,
+			LastSeenAt:        &now,
+			LastSeenIPAddress: c.RealIP(),
+			LastSeenUserAgent: c.Request().UserAgent(),
 		}); err != nil {
 			return nil, err
 		}
+
+		// * evict the oldest sessions over the limit
+		if s.sessionCfg.MaxPerUser > 0 {
+			evictedIDs, err := s.repo.Session.RevokeOldest(ctx, ai.User.ID, s.sessionCfg.MaxPerUser)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.denylist.RevokeSessions(ctx, evictedIDs...); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// * rotate refresh token, only one of concurrent refreshes with the same token can succeed
+		rotated, err := s.repo.Session.RotateRefreshToken(ctx, sessionID, ai.Session.RefreshTokenHash.String, refreshTokenHash)
+		if err != nil {
+			return nil, err
+		}
+		if !rotated {
+			return nil, s.revokeReusedSession(c, ai.Session)
+		}
+
+		if err := s.repo.Session.Touch(ctx, sessionID, c.RealIP(), c.Request().UserAgent()); err != nil {
+			return nil, err
+		}
 	}
 
 	// * update last_login
@@ -68,16 +117,6 @@ func (s *Auth) authenticate(c echo.Context, ai *AuthenticateInput) (*types.AuthT
 		return nil, err
 	}
 
-	// * update refresh_token
-	if err := s.repo.Session.Update(ctx, map[string]interface{}{
-		"refresh_token": sql.NullString{
-			String: refreshTokenOutput.Token,
-			Valid:  true,
-		},
-	}, sessionID); err != nil {
-		return nil, err
-	}
-
 	// TODO: add more logic if needed
 
 	return &types.AuthToken{
@@ -87,3 +126,86 @@ func (s *Auth) authenticate(c echo.Context, ai *AuthenticateInput) (*types.AuthT
 		RefreshToken: refreshTokenOutput.Token,
 	}, nil
 }
+
+// generateAccessToken generates an access token of the user for the session, in the organization of the membership if any
+func (s *Auth) generateAccessToken(user *types.User, sessionID string, member *types.OrganizationMember) (*jwt.TokenOutput, error) {
+	claims := map[string]interface{}{
+		"id":    user.ID,
+		"email": user.Email,
+		"name":  user.FirstName + " " + user.LastName,
+		"role":  user.Role,
+		"sid":   sessionID,
+		"jti":   ulidutil.NewString(),
+	}
+	if member != nil {
+		claims["oid"] = member.OrganizationID
+		claims["org_role"] = member.Role
+	}
+
+	out := &jwt.TokenOutput{}
+	if err := s.jwt.GenerateToken(&jwt.TokenInput{Type: jwt.TypeTokenAccess, Claims: claims}, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// detectPlatform guesses the platform of the device from its user agent
+func detectPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ios"), strings.Contains(ua, "darwin"):
+		return "ios"
+	case strings.Contains(ua, "android"), strings.Contains(ua, "okhttp"):
+		return "android"
+	case strings.Contains(ua, "mozilla"):
+		return "web"
+	default:
+		return "other"
+	}
+}
+
+// revokeReusedSession revokes the session whose rotated refresh token is replayed, so that neither the attacker nor
+// the legitimate client can keep using the token family, and audits the event
+func (s *Auth) revokeReusedSession(c echo.Context, session *types.Session) error {
+	ctx := c.Request().Context()
+	if err := s.repo.Session.Revoke(ctx, session.UserID, session.ID); err != nil {
+		return ErrRefreshTokenReused.SetInternal(err)
+	}
+	if err := s.denylist.RevokeSessions(ctx, session.ID); err != nil {
+		return ErrRefreshTokenReused.SetInternal(err)
+	}
+
+	if err := s.repo.AuditEvent.Create(ctx, &types.AuditEvent{
+		UserID:     session.UserID,
+		Action:     types.AuditActionRefreshTokenReused,
+		ObjectType: "session",
+		ObjectID:   session.ID,
+		Reason:     "rotated refresh token was replayed, the session is revoked",
+		IPAddress:  c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+	}); err != nil {
+		return ErrRefreshTokenReused.SetInternal(err)
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// hashToken returns the hex encoded SHA-256 hash of the token to be stored instead of the token itself
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// statusError returns the error of the user who may not login or refresh tokens, nil if the user is active.
+// The deleted users are treated as if they did not exist.
+func statusError(user *types.User) error {
+	switch user.Status {
+	case types.UserStatusActive:
+		return nil
+	case types.UserStatusBlocked:
+		return ErrUserBlocked
+	default:
+		return ErrInvalidCredentials
+	}
+}
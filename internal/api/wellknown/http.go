@@ -0,0 +1,41 @@
+package wellknown
+
+import (
+	"net/http"
+
+	"tyr/internal/jwtkeys"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTP represents well-known http service
+type HTTP struct {
+	svc Service
+}
+
+// Service represents the public key provider interface
+type Service interface {
+	JWKS() *jwtkeys.JWKSet
+}
+
+// NewHTTP attaches handlers to Echo routers under given group
+func NewHTTP(svc Service, eg *echo.Group) {
+	h := HTTP{svc: svc}
+
+	// swagger:operation GET /.well-known/jwks.json well-known wellKnownJWKS
+	// ---
+	// summary: Returns the public keys to verify the issued tokens
+	// security: []
+	// responses:
+	//   "200":
+	//     description: JSON Web Key Set
+	//     schema:
+	//       "$ref": "#/definitions/JWKSet"
+	eg.GET("/jwks.json", h.jwks)
+}
+
+func (h *HTTP) jwks(c echo.Context) error {
+	// verifiers may cache the keys for a while, new keys are published ahead of their activation
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.svc.JWKS())
+}
@@ -0,0 +1,132 @@
+package apikey
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/labstack/echo/v4"
+)
+
+// tokenPrefix tells the API keys apart from the access tokens in the Authorization header
+const tokenPrefix = "tyr_"
+
+// touchInterval is how often the last usage of a key is recorded at most
+const touchInterval = time.Minute
+
+// Custom errors
+var (
+	ErrInvalidAPIKey = server.NewHTTPError(http.StatusUnauthorized, "INVALID_API_KEY", "The API key is invalid, expired or revoked")
+)
+
+// Principal is the user authenticated by an API key, in the organization of the key if any
+type Principal struct {
+	Key    *types.APIKey
+	User   *types.User
+	Member *types.OrganizationMember
+}
+
+// Generate generates a new token, returns it with its prefix and its hash to store.
+// The token is `tyr_<prefix>_<secret>`, it is only shown once to the user.
+func Generate() (token, prefix, hash string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = tokenPrefix + hex.EncodeToString(id)
+	token = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return token, prefix, hashToken(token), nil
+}
+
+// IsAPIKey checks whether the bearer token is an API key rather than an access token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, tokenPrefix)
+}
+
+// Authenticate verifies the token and returns the active user it belongs to, the usage of the key is recorded
+func (s *APIKey) Authenticate(ctx context.Context, token, ipAddress string) (*Principal, error) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	rec, err := s.repo.APIKey.FindByPrefix(ctx, parts[0]+"_"+parts[1])
+	if err != nil {
+		return nil, ErrInvalidAPIKey.SetInternal(err)
+	}
+	now := s.now()
+	if !hmac.Equal([]byte(rec.TokenHash), []byte(hashToken(token))) || rec.RevokedAt != nil || !now.Before(rec.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	p := &Principal{Key: rec, User: &types.User{}}
+	if err := s.repo.User.ReadByID(ctx, p.User, rec.UserID); err != nil {
+		return nil, ErrInvalidAPIKey.SetInternal(err)
+	}
+	if p.User.Status != types.UserStatusActive {
+		return nil, ErrInvalidAPIKey
+	}
+	// the key stops working in the organization once the user has left it
+	if rec.OrganizationID != nil {
+		if p.Member, err = s.repo.OrganizationMember.FindByUser(ctx, *rec.OrganizationID, rec.UserID); err != nil {
+			return nil, ErrInvalidAPIKey.SetInternal(err)
+		}
+	}
+
+	if err := s.repo.APIKey.Touch(ctx, rec.ID, ipAddress, now, now.Add(-touchInterval)); err != nil {
+		return nil, server.NewHTTPInternalError("error recording API key usage").SetInternal(err)
+	}
+
+	return p, nil
+}
+
+// MWFunc returns the middleware which authenticates the API keys and sets the user of the key with its scopes into the context,
+// the same way as the claims of the access tokens. The other bearer tokens are passed to the given JWT middleware.
+func (s *APIKey) MWFunc(jwtMW echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		verifyJWT := jwtMW(next)
+		return func(c echo.Context) error {
+			scheme, token, _ := strings.Cut(c.Request().Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "bearer") || !IsAPIKey(token) {
+				return verifyJWT(c)
+			}
+
+			p, err := s.Authenticate(c.Request().Context(), token, c.RealIP())
+			if err != nil {
+				return err
+			}
+
+			c.Set("id", p.User.ID)
+			c.Set("email", p.User.Email)
+			c.Set("name", p.User.FirstName+" "+p.User.LastName)
+			c.Set("role", p.User.Role)
+			c.Set("api_key_id", p.Key.ID)
+			c.Set("scopes", []string(p.Key.Scopes))
+			if p.Member != nil {
+				c.Set("oid", p.Member.OrganizationID)
+				c.Set("org_role", p.Member.Role)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
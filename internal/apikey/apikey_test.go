@@ -0,0 +1,312 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/rbac"
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"gorm.io/datatypes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	keyID  = "01HAPIKEY000000000000000000"
+	userID = "01HUSER00000000000000000000"
+	orgID  = "01HORGANIZATION000000000000"
+)
+
+var testNow = time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+func TestAuthenticate(t *testing.T) {
+	cases := []struct {
+		name   string
+		key    func(k *types.APIKey)
+		user   func(u *types.User)
+		member *types.OrganizationMember
+		token  func(token string) string
+		want   error
+	}{
+		{name: "valid"},
+		{name: "malformed", token: func(string) string { return "tyr_nothing" }, want: ErrInvalidAPIKey},
+		{name: "unknown prefix", token: func(string) string { return "tyr_000000000000_secret" }, want: ErrInvalidAPIKey},
+		{name: "wrong secret", token: func(token string) string { return token[:len(token)-1] + "x" }, want: ErrInvalidAPIKey},
+		{name: "revoked", key: func(k *types.APIKey) { k.RevokedAt = lo.ToPtr(testNow.Add(-time.Hour)) }, want: ErrInvalidAPIKey},
+		{name: "expired", key: func(k *types.APIKey) { k.ExpiresAt = testNow }, want: ErrInvalidAPIKey},
+		{name: "expiring", key: func(k *types.APIKey) { k.ExpiresAt = testNow.Add(time.Second) }},
+		{name: "blocked user", user: func(u *types.User) { u.Status = types.UserStatusBlocked }, want: ErrInvalidAPIKey},
+		{
+			name:   "member of the organization of the key",
+			key:    func(k *types.APIKey) { k.OrganizationID = lo.ToPtr(orgID) },
+			member: &types.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: types.OrgRoleApprover},
+		},
+		{name: "left the organization of the key", key: func(k *types.APIKey) { k.OrganizationID = lo.ToPtr(orgID) }, want: ErrInvalidAPIKey},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db, token := newTestAPIKey(t)
+			if tc.key != nil {
+				tc.key(db.key)
+			}
+			if tc.user != nil {
+				tc.user(db.user)
+			}
+			db.member = tc.member
+			if tc.token != nil {
+				token = tc.token(token)
+			}
+
+			p, err := s.Authenticate(context.Background(), token, "10.0.0.1")
+			if tc.want != nil {
+				if !errors.Is(err, tc.want) {
+					t.Fatalf("got %v, want %v", err, tc.want)
+				}
+				if len(db.touches) > 0 {
+					t.Errorf("got the usage of the rejected key recorded")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if p.Key.ID != keyID || p.User.ID != userID || (p.Member == nil) != (tc.member == nil) || (p.Member != nil && p.Member.Role != tc.member.Role) {
+				t.Errorf("got %+v", p)
+			}
+			if len(db.touches) != 1 || db.touches[0]["last_used_ip"] != "10.0.0.1" || db.touches[0]["last_used_at"] != testNow {
+				t.Errorf("got touches %v, want the usage recorded", db.touches)
+			}
+		})
+	}
+}
+
+func TestMWFunc(t *testing.T) {
+	rbacSvc, err := rbac.New(defaultPolicies{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		auth    func(token string) string
+		object  string
+		action  string
+		wantJWT bool
+		want    error
+	}{
+		{name: "in scope", auth: bearer, object: rbac.ObjectDocument, action: rbac.ActionRead},
+		{name: "scheme is case insensitive", auth: func(token string) string { return "BEARER " + token }, object: rbac.ObjectDocument, action: rbac.ActionRead},
+		{name: "write out of the read scope", auth: bearer, object: rbac.ObjectDocument, action: rbac.ActionUpdate, want: rbac.ErrForbiddenAction},
+		{name: "object out of scope", auth: bearer, object: rbac.ObjectExpenseReport, action: rbac.ActionRead, want: rbac.ErrForbiddenAction},
+		{name: "invalid key", auth: func(token string) string { return bearer(token + "x") }, object: rbac.ObjectDocument, action: rbac.ActionRead, want: ErrInvalidAPIKey},
+		{name: "access token", auth: func(string) string { return "Bearer eyJhbGciOi.e30.sig" }, object: rbac.ObjectDocument, action: rbac.ActionUpdate, wantJWT: true, want: errJWT},
+		{name: "no token", auth: func(string) string { return "" }, object: rbac.ObjectDocument, action: rbac.ActionRead, wantJWT: true, want: errJWT},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db, token := newTestAPIKey(t)
+			db.key.OrganizationID = lo.ToPtr(orgID)
+			db.member = &types.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: types.OrgRoleMember}
+
+			// the JWT middleware of the test rejects every access token
+			jwtCalled := false
+			jwtMW := func(echo.HandlerFunc) echo.HandlerFunc {
+				return func(echo.Context) error {
+					jwtCalled = true
+					return errJWT
+				}
+			}
+
+			var au *types.AuthUser
+			h := s.MWFunc(jwtMW)(contextutil.MWContext()(rbac.MWEnforce(rbacSvc, tc.object, tc.action)(func(c echo.Context) error {
+				au = c.(*contextutil.HTTPContext).AuthUser()
+				return c.NoContent(http.StatusOK)
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if auth := tc.auth(token); auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			err := h(echo.New().NewContext(req, httptest.NewRecorder()))
+
+			if !errors.Is(err, tc.want) || jwtCalled != tc.wantJWT {
+				t.Fatalf("got %v with the JWT middleware called %v, want %v", err, jwtCalled, tc.want)
+			}
+			if tc.want != nil {
+				return
+			}
+
+			want := &types.AuthUser{ID: userID, Name: "Jo Smith", Email: "jo@tyr.io", Role: rbac.RoleUser, OrganizationID: orgID,
+				OrgRole: types.OrgRoleMember, APIKeyID: keyID, Scopes: []string{rbac.ScopeDocumentsRead}}
+			if au == nil || au.ID != want.ID || au.Name != want.Name || au.Email != want.Email || au.Role != want.Role ||
+				au.OrganizationID != want.OrganizationID || au.OrgRole != want.OrgRole || au.APIKeyID != want.APIKeyID ||
+				len(au.Scopes) != 1 || au.Scopes[0] != want.Scopes[0] || au.SessionID != "" {
+				t.Errorf("got %+v, want %+v", au, want)
+			}
+		})
+	}
+}
+
+var errJWT = errors.New("invalid access token")
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+// fakeAPIKeyDB answers the dry run statements of the service from its key, the user of the key and its membership.
+// It records the usage of the key.
+type fakeAPIKeyDB struct {
+	key     *types.APIKey
+	user    *types.User
+	member  *types.OrganizationMember
+	touches []map[string]interface{}
+}
+
+func newTestAPIKey(t *testing.T) (*APIKey, *fakeAPIKeyDB, string) {
+	t.Helper()
+
+	token, prefix, hash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeAPIKeyDB{
+		key: &types.APIKey{Base: types.Base{ID: keyID}, UserID: userID, Prefix: prefix, TokenHash: hash,
+			Scopes: datatypes.NewJSONSlice([]string{rbac.ScopeDocumentsRead}), ExpiresAt: testNow.AddDate(0, 1, 0)},
+		user: &types.User{Base: types.Base{ID: userID}, Email: "jo@tyr.io", FirstName: "Jo", LastName: "Smith", Role: rbac.RoleUser, Status: types.UserStatusActive},
+	}
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Before("gorm:preload").Register("test:query", f.query),
+		gdb.Callback().Update().After("gorm:update").Register("test:update", f.update),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &APIKey{repo: repo.New(gdb), now: func() time.Time { return testNow }}, f, token
+}
+
+func (f *fakeAPIKeyDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	vars := whereVars(db.Statement)
+	found := false
+	switch dest := db.Statement.Dest.(type) {
+	case *types.APIKey:
+		if found = len(vars) == 1 && vars[0] == f.key.Prefix; found {
+			*dest = *f.key
+		}
+	case *types.User:
+		if found = len(vars) == 1 && vars[0] == f.user.ID; found {
+			*dest = *f.user
+		}
+	case *types.OrganizationMember:
+		if found = f.member != nil && len(vars) == 2 && vars[0] == f.member.OrganizationID && vars[1] == f.member.UserID; found {
+			*dest = *f.member
+		}
+	default:
+		return
+	}
+	if !found {
+		db.AddError(gorm.ErrRecordNotFound)
+		return
+	}
+	db.RowsAffected = 1
+}
+
+func (f *fakeAPIKeyDB) update(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table != "api_keys" {
+		return
+	}
+	if updates, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		f.touches = append(f.touches, updates)
+	}
+	db.RowsAffected = 1
+}
+
+// whereVars returns the vars of the where expressions of the statement, the soft delete condition aside
+func whereVars(stmt *gorm.Statement) []interface{} {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	vars := []interface{}{}
+	for _, expr := range where.Exprs {
+		if e, ok := expr.(clause.Expr); ok {
+			vars = append(vars, e.Vars...)
+		}
+	}
+	return vars
+}
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
+
+// defaultPolicies serves the roles and the policies seeded by the migration
+type defaultPolicies struct{}
+
+func (defaultPolicies) Revision(context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (defaultPolicies) ListRoles(context.Context) ([]*types.RBACRole, error) {
+	return rbac.DefaultRoles, nil
+}
+
+func (defaultPolicies) ListPolicies(context.Context) ([]*types.RBACPolicy, error) {
+	policies := make([]*types.RBACPolicy, 0, len(rbac.DefaultPolicies))
+	for _, p := range rbac.DefaultPolicies {
+		policies = append(policies, &types.RBACPolicy{Role: p[0], Object: p[1], Action: p[2]})
+	}
+	return policies, nil
+}
@@ -0,0 +1,18 @@
+package apikey
+
+import (
+	"time"
+
+	"tyr/internal/repo"
+)
+
+// New creates new API key service authenticating the requests of the integrations
+func New(repo *repo.Service) *APIKey {
+	return &APIKey{repo: repo, now: time.Now}
+}
+
+// APIKey represents API key service
+type APIKey struct {
+	repo *repo.Service
+	now  func() time.Time
+}
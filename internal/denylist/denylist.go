@@ -0,0 +1,153 @@
+package denylist
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/labstack/echo/v4"
+)
+
+// Custom errors
+var (
+	ErrTokenRevoked = server.NewHTTPError(http.StatusUnauthorized, "TOKEN_REVOKED", "Your session has been revoked, please login again")
+)
+
+// maximum number of cached lookups before sweeping the expired ones
+const maxCacheSize = 10000
+
+// RevokeSessions denies all access tokens issued for the given sessions.
+// The entries last for the access token lifetime since every token issued before is expired by then.
+func (d *Denylist) RevokeSessions(ctx context.Context, sessionIDs ...string) error {
+	expiresAt := d.now().Add(d.tokenTTL)
+	recs := make([]*types.RevokedToken, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		recs = append(recs, &types.RevokedToken{Key: sessionKey(id), ExpiresAt: expiresAt})
+	}
+	return d.add(ctx, recs)
+}
+
+// RevokeToken denies the single access token of the given id until it expires
+func (d *Denylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return d.add(ctx, []*types.RevokedToken{{Key: tokenKey(jti), ExpiresAt: expiresAt}})
+}
+
+// IsRevoked checks whether the access token of the given session and token id is denied
+func (d *Denylist) IsRevoked(ctx context.Context, sessionID, jti string) (bool, error) {
+	keys := []string{}
+	if sessionID != "" {
+		keys = append(keys, sessionKey(sessionID))
+	}
+	if jti != "" {
+		keys = append(keys, tokenKey(jti))
+	}
+
+	// check the cache first
+	now := d.now()
+	missed := []string{}
+	d.mu.RLock()
+	for _, k := range keys {
+		e, ok := d.cache[k]
+		switch {
+		case ok && now.Before(e.until) && e.revoked:
+			d.mu.RUnlock()
+			return true, nil
+		case !ok || !now.Before(e.until):
+			missed = append(missed, k)
+		}
+	}
+	d.mu.RUnlock()
+	if len(missed) == 0 {
+		return false, nil
+	}
+
+	recs, err := d.repo.FindActive(ctx, missed)
+	if err != nil {
+		return false, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, rec := range recs {
+		d.cache[rec.Key] = cacheEntry{revoked: true, until: rec.ExpiresAt}
+	}
+	if len(recs) > 0 {
+		return true, nil
+	}
+	for _, k := range missed {
+		d.cache[k] = cacheEntry{until: now.Add(d.cacheTTL)}
+	}
+	d.sweep(now)
+
+	return false, nil
+}
+
+// MWFunc returns the middleware which rejects revoked access tokens, it must be used after the JWT middleware.
+// Tokens without `sid` and `jti` claims are issued before the denylist existed and are let through until they expire.
+func (d *Denylist) MWFunc() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			sid, _ := c.Get("sid").(string)
+			jti, _ := c.Get("jti").(string)
+			if sid == "" && jti == "" {
+				return next(c)
+			}
+
+			revoked, err := d.IsRevoked(c.Request().Context(), sid, jti)
+			if err != nil {
+				return server.NewHTTPInternalError("error checking token revocation").SetInternal(err)
+			}
+			if revoked {
+				return ErrTokenRevoked
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// add stores the entries and caches them right away for the current instance
+func (d *Denylist) add(ctx context.Context, recs []*types.RevokedToken) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	if err := d.repo.Add(ctx, recs...); err != nil {
+		return err
+	}
+	// the expired entries are useless, clean them up along the way
+	if err := d.repo.DeleteExpired(ctx); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, rec := range recs {
+		d.cache[rec.Key] = cacheEntry{revoked: true, until: rec.ExpiresAt}
+	}
+	d.sweep(d.now())
+
+	return nil
+}
+
+// sweep removes the expired cache entries once the cache grows too big, the caller must hold the lock
+func (d *Denylist) sweep(now time.Time) {
+	if len(d.cache) < maxCacheSize {
+		return
+	}
+	for k, e := range d.cache {
+		if !now.Before(e.until) {
+			delete(d.cache, k)
+		}
+	}
+}
+
+func sessionKey(id string) string {
+	return "sid:" + id
+}
+
+func tokenKey(id string) string {
+	return "jti:" + id
+}
@@ -0,0 +1,272 @@
+package denylist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	tokenTTL = time.Hour
+	cacheTTL = 30 * time.Second
+)
+
+func TestRevokeSessions(t *testing.T) {
+	clock := newClock()
+	repo := newFakeRepo(clock)
+	d := newTestDenylist(repo, clock)
+
+	if err := d.RevokeSessions(context.Background(), "s1", "s2"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.deletedExpired != 1 {
+		t.Errorf("expired entries deleted %d times, want once", repo.deletedExpired)
+	}
+
+	for _, sid := range []string{"s1", "s2"} {
+		if revoked := mustIsRevoked(t, d, sid, "any"); !revoked {
+			t.Errorf("session %s is not revoked", sid)
+		}
+	}
+	if revoked := mustIsRevoked(t, d, "s3", "any"); revoked {
+		t.Error("another session is revoked")
+	}
+	if repo.lookups != 1 {
+		t.Errorf("looked up %d times, want only for the other session", repo.lookups)
+	}
+
+	// the entries last for the access token lifetime
+	if rec := repo.recs[sessionKey("s1")]; !rec.ExpiresAt.Equal(clock.now.Add(tokenTTL)) {
+		t.Errorf("got expiry %v, want the access token lifetime", rec.ExpiresAt)
+	}
+	clock.add(tokenTTL)
+	if revoked := mustIsRevoked(t, d, "s1", ""); revoked {
+		t.Error("the session is still revoked once all its tokens are expired")
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	clock := newClock()
+	repo := newFakeRepo(clock)
+	d := newTestDenylist(repo, clock)
+
+	expiresAt := clock.now.Add(10 * time.Minute)
+	if err := d.RevokeToken(context.Background(), "t1", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked := mustIsRevoked(t, d, "", "t1"); !revoked {
+		t.Error("the token is not revoked")
+	}
+	// the session of the token is not revoked
+	if revoked := mustIsRevoked(t, d, "s1", "t2"); revoked {
+		t.Error("another token of the session is revoked")
+	}
+
+	// another instance finds it in the storage
+	other := newTestDenylist(repo, clock)
+	if revoked := mustIsRevoked(t, other, "s1", "t1"); !revoked {
+		t.Error("the token is not revoked for another instance")
+	}
+
+	clock.add(10 * time.Minute)
+	if revoked := mustIsRevoked(t, newTestDenylist(repo, clock), "", "t1"); revoked {
+		t.Error("the token is still revoked after it expires")
+	}
+}
+
+// TestNegativeCache proves a token which is not revoked is trusted for the cache TTL only,
+// a revocation by another instance is seen once it passes
+func TestNegativeCache(t *testing.T) {
+	clock := newClock()
+	repo := newFakeRepo(clock)
+	a, b := newTestDenylist(repo, clock), newTestDenylist(repo, clock)
+
+	if revoked := mustIsRevoked(t, b, "s1", "t1"); revoked {
+		t.Fatal("got revoked before any revocation")
+	}
+	if repo.lookups != 1 {
+		t.Fatalf("looked up %d times, want once", repo.lookups)
+	}
+
+	if err := a.RevokeSessions(context.Background(), "s1"); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.add(cacheTTL - time.Second)
+	if revoked := mustIsRevoked(t, b, "s1", "t1"); revoked {
+		t.Error("the cached lookup is not trusted within the cache TTL")
+	}
+	if repo.lookups != 1 {
+		t.Errorf("looked up %d times within the cache TTL, want once", repo.lookups)
+	}
+
+	clock.add(time.Second)
+	if revoked := mustIsRevoked(t, b, "s1", "t1"); !revoked {
+		t.Error("the revocation by another instance is not seen after the cache TTL")
+	}
+	if repo.lookups != 2 {
+		t.Errorf("looked up %d times, want twice", repo.lookups)
+	}
+
+	// the revocation is cached until it expires
+	clock.add(cacheTTL)
+	if revoked := mustIsRevoked(t, b, "s1", "t1"); !revoked || repo.lookups != 2 {
+		t.Errorf("got revoked %v with %d lookups, want the cached revocation", revoked, repo.lookups)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	clock := newClock()
+	repo := newFakeRepo(clock)
+	d := newTestDenylist(repo, clock)
+
+	// the cache is full of lookups which are not trusted anymore
+	for i := 0; i < maxCacheSize; i++ {
+		d.cache[fmt.Sprintf("sid:%d", i)] = cacheEntry{until: clock.now.Add(-time.Second)}
+	}
+	d.cache[sessionKey("live")] = cacheEntry{until: clock.now.Add(time.Minute)}
+
+	if err := d.RevokeToken(context.Background(), "t1", clock.now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.cache) != 2 {
+		t.Errorf("got %d cache entries, want the live ones only", len(d.cache))
+	}
+	if _, ok := d.cache[sessionKey("live")]; !ok {
+		t.Error("the live lookup is swept")
+	}
+	if e := d.cache[tokenKey("t1")]; !e.revoked {
+		t.Error("the revocation is swept")
+	}
+}
+
+func TestSweepBelowMaxSize(t *testing.T) {
+	clock := newClock()
+	d := newTestDenylist(newFakeRepo(clock), clock)
+	d.cache["sid:expired"] = cacheEntry{until: clock.now.Add(-time.Second)}
+
+	d.sweep(clock.now)
+	if len(d.cache) != 1 {
+		t.Error("the cache is swept before it grows too big")
+	}
+}
+
+func TestMWFunc(t *testing.T) {
+	clock := newClock()
+	repo := newFakeRepo(clock)
+	d := newTestDenylist(repo, clock)
+	if err := d.RevokeSessions(context.Background(), "revoked"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		claims   map[string]string
+		repoErr  error
+		wantCode int
+	}{
+		{name: "token issued before the denylist", wantCode: http.StatusOK},
+		{name: "active session", claims: map[string]string{"sid": "active", "jti": "t1"}, wantCode: http.StatusOK},
+		{name: "revoked session", claims: map[string]string{"sid": "revoked", "jti": "t2"}, wantCode: http.StatusUnauthorized},
+		{name: "storage failure", claims: map[string]string{"sid": "unknown"}, repoErr: errors.New("down"), wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo.err = tc.repoErr
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			for k, v := range tc.claims {
+				c.Set(k, v)
+			}
+
+			err := d.MWFunc()(func(c echo.Context) error { return c.NoContent(http.StatusOK) })(c)
+			code := http.StatusOK
+			var he *server.HTTPError
+			if errors.As(err, &he) {
+				code = he.Code
+			} else if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if code != tc.wantCode {
+				t.Errorf("got %d, want %d", code, tc.wantCode)
+			}
+		})
+	}
+}
+
+func newTestDenylist(repo Repository, clock *clock) *Denylist {
+	d := New(repo, tokenTTL, cacheTTL)
+	d.now = func() time.Time { return clock.now }
+	return d
+}
+
+func mustIsRevoked(t *testing.T, d *Denylist, sid, jti string) bool {
+	t.Helper()
+	revoked, err := d.IsRevoked(context.Background(), sid, jti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+type clock struct {
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeRepo is the storage shared by the instances, it only finds the entries which are not expired on the clock
+type fakeRepo struct {
+	recs           map[string]*types.RevokedToken
+	lookups        int
+	deletedExpired int
+	err            error
+	clock          *clock
+}
+
+func newFakeRepo(clock *clock) *fakeRepo {
+	return &fakeRepo{recs: map[string]*types.RevokedToken{}, clock: clock}
+}
+
+func (r *fakeRepo) Add(_ context.Context, recs ...*types.RevokedToken) error {
+	for _, rec := range recs {
+		r.recs[rec.Key] = rec
+	}
+	return nil
+}
+
+func (r *fakeRepo) FindActive(_ context.Context, keys []string) ([]*types.RevokedToken, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.lookups++
+	found := []*types.RevokedToken{}
+	for _, k := range keys {
+		if rec, ok := r.recs[k]; ok && rec.ExpiresAt.After(r.clock.now) {
+			found = append(found, rec)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeRepo) DeleteExpired(context.Context) error {
+	r.deletedExpired++
+	return nil
+}
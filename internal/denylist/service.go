@@ -0,0 +1,47 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// New creates new access token denylist service.
+// tokenTTL is the lifetime of access tokens, cacheTTL is how long a token which is not revoked is trusted
+// before checking the denylist again.
+func New(repo Repository, tokenTTL, cacheTTL time.Duration) *Denylist {
+	return &Denylist{
+		repo:     repo,
+		tokenTTL: tokenTTL,
+		cacheTTL: cacheTTL,
+		cache:    map[string]cacheEntry{},
+		now:      time.Now,
+	}
+}
+
+// Denylist represents access token denylist service
+type Denylist struct {
+	repo     Repository
+	tokenTTL time.Duration
+	cacheTTL time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cacheEntry
+
+	now func() time.Time
+}
+
+// Repository represents the denylist storage interface
+type Repository interface {
+	Add(ctx context.Context, recs ...*types.RevokedToken) error
+	FindActive(ctx context.Context, keys []string) ([]*types.RevokedToken, error)
+	DeleteExpired(ctx context.Context) error
+}
+
+// cacheEntry is the cached result of a denylist lookup
+type cacheEntry struct {
+	revoked bool
+	until   time.Time
+}
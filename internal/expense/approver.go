@@ -0,0 +1,51 @@
+package expense
+
+import (
+	"context"
+	"errors"
+
+	"tyr/internal/types"
+)
+
+// ErrNoApprover is returned when the organization has no other member allowed to approve the report
+var ErrNoApprover = errors.New("expense: no approver in the organization")
+
+// AssignApprover picks the approver of a submitted report among the other members of its organization.
+// The approvers are preferred over the owners, the one with the fewest reports waiting for a decision is picked,
+// the longest-standing member first on ties. The submitter never approves its own report.
+func (e *Expense) AssignApprover(ctx context.Context, rec *types.ExpenseReport) (string, error) {
+	members, err := e.repo.OrganizationMember.ListByOrganizationID(ctx, rec.OrganizationID)
+	if err != nil {
+		return "", err
+	}
+	pending, err := e.repo.ExpenseReport.CountPendingByApprover(ctx, rec.OrganizationID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, role := range []string{types.OrgRoleApprover, types.OrgRoleOwner} {
+		approverID := ""
+		for _, m := range members {
+			if m.Role != role || m.UserID == rec.UserID {
+				continue
+			}
+			if approverID == "" || pending[m.UserID] < pending[approverID] {
+				approverID = m.UserID
+			}
+		}
+		if approverID != "" {
+			return approverID, nil
+		}
+	}
+
+	return "", ErrNoApprover
+}
+
+// CanDecide checks the member can approve or reject the submitted report: the assigned approver or an owner of
+// the organization, never the submitter
+func CanDecide(rec *types.ExpenseReport, userID, orgRole string) bool {
+	if userID == rec.UserID {
+		return false
+	}
+	return orgRole == types.OrgRoleOwner || (rec.ApproverID != nil && *rec.ApproverID == userID)
+}
@@ -0,0 +1,100 @@
+package expense
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tyr/internal/types"
+
+	"github.com/samber/lo"
+)
+
+func TestAssignApprover(t *testing.T) {
+	member := func(userID, role string) *types.OrganizationMember {
+		return &types.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: role}
+	}
+
+	cases := []struct {
+		name    string
+		members []*types.OrganizationMember
+		pending map[string]int64
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "the approvers before the owners",
+			members: []*types.OrganizationMember{member("owner", types.OrgRoleOwner), member("approver", types.OrgRoleApprover)},
+			pending: map[string]int64{"approver": 5},
+			want:    "approver",
+		},
+		{
+			name:    "the fewest pending reports",
+			members: []*types.OrganizationMember{member("a1", types.OrgRoleApprover), member("a2", types.OrgRoleApprover), member("a3", types.OrgRoleApprover)},
+			pending: map[string]int64{"a1": 3, "a2": 1, "a3": 2},
+			want:    "a2",
+		},
+		{
+			name:    "the longest-standing member on ties",
+			members: []*types.OrganizationMember{member("a1", types.OrgRoleApprover), member("a2", types.OrgRoleApprover), member("a3", types.OrgRoleApprover)},
+			pending: map[string]int64{"a1": 2},
+			want:    "a2",
+		},
+		{
+			name:    "never the submitter",
+			members: []*types.OrganizationMember{member(submitterID, types.OrgRoleApprover), member("owner", types.OrgRoleOwner)},
+			want:    "owner",
+		},
+		{
+			name:    "the owners without approver",
+			members: []*types.OrganizationMember{member("m1", types.OrgRoleMember), member("o1", types.OrgRoleOwner), member("o2", types.OrgRoleOwner)},
+			pending: map[string]int64{"o1": 1},
+			want:    "o2",
+		},
+		{
+			name:    "no approver",
+			members: []*types.OrganizationMember{member(submitterID, types.OrgRoleOwner), member("m1", types.OrgRoleMember)},
+			wantErr: ErrNoApprover,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestExpense(t, &fakeExpenseDB{members: tc.members, pending: tc.pending})
+
+			got, err := e.AssignApprover(context.Background(), &types.ExpenseReport{Base: types.Base{ID: reportID}, UserID: submitterID, OrganizationID: orgID})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCanDecide(t *testing.T) {
+	rec := &types.ExpenseReport{UserID: submitterID, ApproverID: lo.ToPtr("approver")}
+
+	cases := []struct {
+		name    string
+		rec     *types.ExpenseReport
+		userID  string
+		orgRole string
+		want    bool
+	}{
+		{name: "the assigned approver", rec: rec, userID: "approver", orgRole: types.OrgRoleApprover, want: true},
+		{name: "another approver", rec: rec, userID: "another", orgRole: types.OrgRoleApprover},
+		{name: "an owner", rec: rec, userID: "owner", orgRole: types.OrgRoleOwner, want: true},
+		{name: "a member", rec: rec, userID: "member", orgRole: types.OrgRoleMember},
+		{name: "the submitter owner", rec: rec, userID: submitterID, orgRole: types.OrgRoleOwner},
+		{name: "the submitter assigned", rec: &types.ExpenseReport{UserID: submitterID, ApproverID: lo.ToPtr(submitterID)}, userID: submitterID, orgRole: types.OrgRoleApprover},
+		{name: "no approver assigned", rec: &types.ExpenseReport{UserID: submitterID}, userID: "approver", orgRole: types.OrgRoleApprover},
+	}
+
+	for _, tc := range cases {
+		if got := CanDecide(tc.rec, tc.userID, tc.orgRole); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package expense
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page in points
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 50
+	contentWidth = pageWidth - 2*pageMargin
+)
+
+// pdfWriter lays out lines of text on A4 pages and writes them as a PDF document in the standard Helvetica fonts,
+// which is all the summaries need without a PDF library
+type pdfWriter struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// pdfCell is a text at an offset from the left margin, right aligned at the offset if right
+type pdfCell struct {
+	x     float64
+	text  string
+	right bool
+}
+
+func newPDFWriter() *pdfWriter {
+	w := &pdfWriter{}
+	w.newPage()
+	return w
+}
+
+func (w *pdfWriter) newPage() {
+	w.pages = append(w.pages, &bytes.Buffer{})
+	w.y = pageHeight - pageMargin
+}
+
+// line writes a line of cells, on a new page if the current one is full
+func (w *pdfWriter) line(size float64, bold bool, cells ...pdfCell) {
+	height := size * 1.5
+	if w.y-height < pageMargin {
+		w.newPage()
+	}
+	w.y -= height
+
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	page := w.pages[len(w.pages)-1]
+	for _, c := range cells {
+		x := pageMargin + c.x
+		if c.right {
+			x -= textWidth(c.text, size, bold)
+		}
+		fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, w.y, pdfEscape(c.text))
+	}
+}
+
+// text writes a line of text at the left margin
+func (w *pdfWriter) text(size float64, bold bool, s string) {
+	w.line(size, bold, pdfCell{text: s})
+}
+
+// paragraph writes the text wrapped to the width of the page
+func (w *pdfWriter) paragraph(size float64, s string) {
+	for _, l := range wrap(s, int(contentWidth/(size*0.5))) {
+		w.text(size, false, l)
+	}
+}
+
+// rule draws a horizontal line across the page after a gap
+func (w *pdfWriter) rule() {
+	if w.y-12 < pageMargin {
+		w.newPage()
+		return
+	}
+	w.y -= 8
+	fmt.Fprintf(w.pages[len(w.pages)-1], "0.5 w %d %.2f m %d %.2f l S\n", pageMargin, w.y, pageWidth-pageMargin, w.y)
+	w.y -= 4
+}
+
+// WriteTo writes the PDF document, the objects are the catalog, the page tree, the 2 fonts then each page with its content
+func (w *pdfWriter) WriteTo(out io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	offsets := []int{}
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range w.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.Len(), page.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(out)
+}
+
+// pdfEscape encodes the text in WinAnsiEncoding for a PDF string, the characters out of Latin-1 are replaced by `?`
+func pdfEscape(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			b = append(b, byte(r))
+		case r == '\t':
+			b = append(b, ' ')
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+// textWidth estimates the width of the text in points, exact for the digits and the separators of the amounts
+func textWidth(s string, size float64, bold bool) float64 {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			width += 0.556
+		case r == '.' || r == ',' || r == ' ':
+			width += 0.278
+		case r == '-':
+			width += 0.333
+		case bold:
+			width += 0.611
+		default:
+			width += 0.556
+		}
+	}
+	return width * size
+}
+
+// truncate shortens the text to n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// wrap splits the text into lines of at most n characters at the spaces, the longer words are cut
+func wrap(s string, n int) []string {
+	lines := []string{}
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len([]rune(word)) > n {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, string([]rune(word)[:n]))
+				word = string([]rune(word)[n:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= n:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
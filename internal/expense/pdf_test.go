@@ -0,0 +1,139 @@
+package expense
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// TestWriteSummary parses the written PDF: the xref offsets must point at the objects, the page tree must list every page
+// and the stream lengths must match their content
+func TestWriteSummary(t *testing.T) {
+	e := newTestExpense(t, &fakeExpenseDB{})
+	submittedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	rec := &types.ExpenseReport{Base: types.Base{ID: reportID}, UserID: submitterID, Title: "Offsite (Đà Nẵng) \\ q4", Status: types.ExpenseReportSubmitted,
+		Currency: "USD", SubmittedAt: &submittedAt, Description: strings.Repeat("A long description of the trip ", 20)}
+	for i := 0; i < 80; i++ {
+		rec.Documents = append(rec.Documents, &types.Document{Base: types.Base{ID: fmt.Sprintf("d%d", i)}, MerchantName: "Tyr Mart",
+			TransactionDate: "2026-10-15", Total: 10.5, Currency: "USD"})
+	}
+
+	out := &bytes.Buffer{}
+	if err := e.WriteSummary(out, &Summary{
+		Report:       rec,
+		Organization: &types.Organization{Name: "Tyr"},
+		Submitter:    &types.User{Email: "jo@tyr.io", FirstName: "Jo"},
+		Violations:   []*types.ExpensePolicyViolation{{Rule: types.ExpensePolicyMissingReceipt, DocumentID: "d1", Message: "The receipt file is missing"}},
+		Events:       []*types.ExpenseReportEvent{{ToStatus: types.ExpenseReportSubmitted, Note: "Please approve"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.String()
+
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("got no PDF header or trailer")
+	}
+
+	m := regexp.MustCompile(`trailer\n<< /Size (\d+) /Root 1 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`).FindStringSubmatch(pdf)
+	if m == nil {
+		t.Fatal("got no trailer")
+	}
+	size, _ := strconv.Atoi(m[1])
+	xref, _ := strconv.Atoi(m[2])
+	if !strings.HasPrefix(pdf[xref:], fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", size)) {
+		t.Fatalf("got startxref %d not pointing at the xref table", xref)
+	}
+
+	entries := strings.Split(pdf[xref:], "\n")[3 : 3+size-1]
+	objects := make([]string, size)
+	for i, entry := range entries {
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("got xref entry %q", entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		header := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !strings.HasPrefix(pdf[offset:], header) {
+			t.Fatalf("got xref offset %d of object %d pointing at %q", offset, i+1, pdf[offset:offset+len(header)])
+		}
+		end := strings.Index(pdf[offset:], "\nendobj\n")
+		objects[i+1] = pdf[offset+len(header) : offset+end]
+	}
+
+	pages := (size - 5) / 2
+	if pages < 2 || size != 5+2*pages {
+		t.Fatalf("got %d objects, want the summary on several pages", size)
+	}
+	if !strings.Contains(objects[2], fmt.Sprintf("/Count %d >>", pages)) {
+		t.Errorf("got page tree %q, want %d pages", objects[2], pages)
+	}
+	for i := 0; i < pages; i++ {
+		page, content := objects[5+2*i], objects[6+2*i]
+		if !strings.Contains(objects[2], fmt.Sprintf("%d 0 R", 5+2*i)) || !strings.Contains(page, fmt.Sprintf("/Contents %d 0 R", 6+2*i)) {
+			t.Errorf("got page %d not linked: %q", i, page)
+		}
+
+		sm := regexp.MustCompile(`(?s)^<< /Length (\d+) >>\nstream\n(.*)\nendstream$`).FindStringSubmatch(content)
+		if sm == nil {
+			t.Fatalf("got content %d not a stream", i)
+		}
+		if length, _ := strconv.Atoi(sm[1]); length != len(sm[2]) {
+			t.Errorf("got stream length %d, want %d", length, len(sm[2]))
+		}
+	}
+
+	if !strings.Contains(objects[6], "(Expense report: Offsite \\(?\xe0 N?ng\\) \\\\ q4) Tj") {
+		t.Errorf("got the title not escaped in WinAnsiEncoding")
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	for s, want := range map[string]string{
+		"plain":     "plain",
+		"(a) \\ b":  `\(a\) \\ b`,
+		"café\tbar": "caf\xe9 bar",
+		"Nẵng €5 ✓": "N?ng ?5 ?",
+	} {
+		if got := pdfEscape(s); got != want {
+			t.Errorf("%q got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want []string
+	}{
+		{s: "the quick brown fox", n: 9, want: []string{"the quick", "brown fox"}},
+		{s: "a verylongword b", n: 4, want: []string{"a", "very", "long", "word", "b"}},
+		{s: "one\n\ntwo", n: 10, want: []string{"one", "", "two"}},
+	} {
+		if got := wrap(tc.s, tc.n); strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%q got %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+// TestWriteSummaryTotal proves the summary shows the total of the report, fixed when its documents were claimed
+func TestWriteSummaryTotal(t *testing.T) {
+	e := newTestExpense(t, &fakeExpenseDB{})
+	rec := &types.ExpenseReport{Title: "Trip", Status: types.ExpenseReportApproved, Currency: "USD", Total: 30, Documents: []*types.Document{
+		{MerchantName: "Tyr Mart", Total: 10, Currency: "USD"},
+		{MerchantName: "Tyr Cafe", Total: 25, Currency: "USD"},
+	}}
+
+	out := &bytes.Buffer{}
+	if err := e.WriteSummary(out, &Summary{Report: rec, Organization: &types.Organization{Name: "Tyr"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "(30.00) Tj") || strings.Contains(out.String(), "(35.00) Tj") {
+		t.Error("got the total of the documents, want the total of the report")
+	}
+}
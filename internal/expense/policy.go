@@ -0,0 +1,92 @@
+package expense
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// BlockViolations returns whether the policy violations block the submission of the reports
+func (e *Expense) BlockViolations() bool {
+	return e.blockViolations
+}
+
+// Check checks the documents of the report against the expense policies, returns the violations.
+// A document is flagged when it misses the receipt file over the threshold, when the same spend is claimed twice,
+// in the report or in another report of the submitter which is not rejected, and when it is spent on a weekend if configured.
+func (e *Expense) Check(ctx context.Context, rec *types.ExpenseReport) ([]*types.ExpensePolicyViolation, error) {
+	violations := []*types.ExpensePolicyViolation{}
+	seen := map[string]string{}
+
+	for _, doc := range rec.Documents {
+		if doc.FilePath == "" && doc.Total > e.receiptThreshold {
+			violations = append(violations, &types.ExpensePolicyViolation{
+				Rule:       types.ExpensePolicyMissingReceipt,
+				DocumentID: doc.ID,
+				Message:    fmt.Sprintf("The receipt file is missing for a total of %.2f %s, over the threshold of %.2f", doc.Total, doc.Currency, e.receiptThreshold),
+			})
+		}
+
+		dups, err := e.duplicates(ctx, rec, doc, seen)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, dups...)
+
+		if e.flagWeekend {
+			// transaction_date is stored as YYYY-MM-DD, the unparsed dates are skipped
+			if t, err := time.Parse(time.DateOnly, doc.TransactionDate); err == nil && t.Year() > 1 &&
+				(t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+				violations = append(violations, &types.ExpensePolicyViolation{
+					Rule:       types.ExpensePolicyWeekend,
+					DocumentID: doc.ID,
+					Message:    fmt.Sprintf("Spent on %s %s", t.Weekday(), doc.TransactionDate),
+				})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// duplicates checks the spend of the document is not claimed by an earlier document of the report or in another report,
+// the documents without merchant and total are not analyzed and skipped
+func (e *Expense) duplicates(ctx context.Context, rec *types.ExpenseReport, doc *types.Document, seen map[string]string) ([]*types.ExpensePolicyViolation, error) {
+	if doc.MerchantName == "" && doc.Total == 0 {
+		return nil, nil
+	}
+
+	violations := []*types.ExpensePolicyViolation{}
+	key := spendKey(doc)
+	if firstID, ok := seen[key]; ok {
+		violations = append(violations, &types.ExpensePolicyViolation{
+			Rule:       types.ExpensePolicyDuplicate,
+			DocumentID: doc.ID,
+			Message:    fmt.Sprintf("The same merchant, date and total as the document %s of the report", firstID),
+		})
+	} else {
+		seen[key] = doc.ID
+	}
+
+	claimed, err := e.repo.ExpenseReport.ListClaimedDuplicates(ctx, rec.UserID, rec.ID, doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, dup := range claimed {
+		violations = append(violations, &types.ExpensePolicyViolation{
+			Rule:       types.ExpensePolicyDuplicate,
+			DocumentID: doc.ID,
+			Message:    fmt.Sprintf("The same merchant, date and total as the document %s claimed in another report", dup.ID),
+		})
+	}
+
+	return violations, nil
+}
+
+// spendKey identifies the spend of a document by the merchant, the transaction date and the total
+func spendKey(doc *types.Document) string {
+	return fmt.Sprintf("%s|%s|%.2f", strings.ToLower(doc.MerchantName), doc.TransactionDate, doc.Total)
+}
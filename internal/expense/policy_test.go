@@ -0,0 +1,94 @@
+package expense
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"tyr/internal/types"
+)
+
+func TestCheck(t *testing.T) {
+	doc := func(id, merchant, date string, total float64, filePath string) *types.Document {
+		return &types.Document{Base: types.Base{ID: id}, MerchantName: merchant, TransactionDate: date, Total: total, Currency: "USD", FilePath: filePath}
+	}
+
+	cases := []struct {
+		name    string
+		docs    []*types.Document
+		claimed []*types.Document
+		weekend bool
+		want    [][2]string
+	}{
+		{
+			name: "compliant",
+			docs: []*types.Document{doc("d1", "Tyr Mart", "2026-10-19", 120, "receipt.pdf"), doc("d2", "Tyr Cafe", "2026-10-16", 12, "")},
+		},
+		{
+			name: "missing receipt over the threshold",
+			docs: []*types.Document{doc("d1", "Tyr Mart", "2026-10-19", 50.01, ""), doc("d2", "Tyr Cafe", "2026-10-19", 50, "")},
+			want: [][2]string{{types.ExpensePolicyMissingReceipt, "d1"}},
+		},
+		{
+			name: "duplicate in the report",
+			docs: []*types.Document{doc("d1", "Tyr Mart", "2026-10-19", 20, "a.pdf"), doc("d2", "TYR MART", "2026-10-19", 20, "b.pdf")},
+			want: [][2]string{{types.ExpensePolicyDuplicate, "d2"}},
+		},
+		{
+			name: "same merchant on another day",
+			docs: []*types.Document{doc("d1", "Tyr Mart", "2026-10-19", 20, "a.pdf"), doc("d2", "Tyr Mart", "2026-10-16", 20, "b.pdf")},
+		},
+		{
+			name:    "duplicate claimed in another report",
+			docs:    []*types.Document{doc("d1", "Tyr Mart", "2026-10-19", 20, "a.pdf")},
+			claimed: []*types.Document{doc("d9", "tyr mart", "2026-10-19", 20, "z.pdf"), doc("d8", "Tyr Mart", "2026-10-19", 21, "y.pdf")},
+			want:    [][2]string{{types.ExpensePolicyDuplicate, "d1"}},
+		},
+		{
+			name: "documents not analyzed are not duplicates",
+			docs: []*types.Document{doc("d1", "", "", 0, "a.pdf"), doc("d2", "", "", 0, "b.pdf")},
+		},
+		{
+			name:    "weekend",
+			docs:    []*types.Document{doc("d1", "Tyr Mart", "2026-10-17", 20, "a.pdf"), doc("d2", "Tyr Cafe", "2026-10-18", 5, ""), doc("d3", "Tyr Bar", "17/10/2026", 5, "")},
+			weekend: true,
+			want:    [][2]string{{types.ExpensePolicyWeekend, "d1"}, {types.ExpensePolicyWeekend, "d2"}},
+		},
+		{
+			name: "weekend not flagged unless configured",
+			docs: []*types.Document{doc("d1", "Tyr Mart", "2026-10-17", 20, "a.pdf")},
+		},
+		{
+			name:    "every violation of a document",
+			docs:    []*types.Document{doc("d1", "Tyr Mart", "2026-10-17", 80, "a.pdf"), doc("d2", "Tyr Mart", "2026-10-17", 80, "")},
+			weekend: true,
+			want:    [][2]string{{types.ExpensePolicyWeekend, "d1"}, {types.ExpensePolicyMissingReceipt, "d2"}, {types.ExpensePolicyDuplicate, "d2"}, {types.ExpensePolicyWeekend, "d2"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestExpense(t, &fakeExpenseDB{claimed: tc.claimed})
+			e.flagWeekend = tc.weekend
+
+			violations, err := e.Check(context.Background(), &types.ExpenseReport{Base: types.Base{ID: reportID}, UserID: submitterID, Documents: tc.docs})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := [][2]string{}
+			for _, v := range violations {
+				if v.Message == "" {
+					t.Errorf("got no message for %+v", v)
+				}
+				got = append(got, [2]string{v.Rule, v.DocumentID})
+			}
+			if tc.want == nil {
+				tc.want = [][2]string{}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
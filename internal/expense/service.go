@@ -0,0 +1,28 @@
+package expense
+
+import (
+	"time"
+
+	"tyr/config"
+	"tyr/internal/repo"
+)
+
+// New creates new expense service to check the expense policies, assign the approvers and summarize the expense reports
+func New(repo *repo.Service, cfg config.Expense) *Expense {
+	return &Expense{
+		repo:             repo,
+		receiptThreshold: cfg.ReceiptThreshold,
+		flagWeekend:      cfg.FlagWeekend,
+		blockViolations:  cfg.BlockViolations,
+		now:              time.Now,
+	}
+}
+
+// Expense represents expense report service
+type Expense struct {
+	repo             *repo.Service
+	receiptThreshold float64
+	flagWeekend      bool
+	blockViolations  bool
+	now              func() time.Time
+}
@@ -0,0 +1,127 @@
+package expense
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	orgID       = "01HORGANIZATION000000000000"
+	submitterID = "01HSUBMITTER000000000000000"
+	reportID    = "01HREPORT000000000000000000"
+)
+
+// fakeExpenseDB answers the dry run statements of the expense service from its members, the pending reports of each approver
+// and the documents claimed in the other reports of the submitter
+type fakeExpenseDB struct {
+	members []*types.OrganizationMember
+	pending map[string]int64
+	claimed []*types.Document
+}
+
+func newTestExpense(t *testing.T, f *fakeExpenseDB) *Expense {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.Callback().Query().After("gorm:query").Before("gorm:preload").Register("test:query", f.query); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Expense{
+		repo:             repo.New(gdb),
+		receiptThreshold: 50,
+		flagWeekend:      true,
+		now:              func() time.Time { return time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC) },
+	}
+}
+
+func (f *fakeExpenseDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	switch dest := db.Statement.Dest.(type) {
+	case *[]*types.OrganizationMember:
+		*dest = f.members
+	case *[]struct {
+		ApproverID string
+		Count      int64
+	}:
+		for approverID, count := range f.pending {
+			*dest = append(*dest, struct {
+				ApproverID string
+				Count      int64
+			}{approverID, count})
+		}
+	case *[]*types.Document:
+		// the vars are the user, the excluded report, the statuses then the document, the merchant, the date and the total
+		vars := whereVars(db.Statement)
+		for _, doc := range f.claimed {
+			if vars[0] == submitterID && vars[1] == reportID && doc.ID != vars[3] &&
+				strings.ToLower(doc.MerchantName) == vars[4] && doc.TransactionDate == vars[5] && doc.Total == vars[6] {
+				*dest = append(*dest, doc)
+			}
+		}
+	}
+	db.RowsAffected = int64(db.Statement.ReflectValue.Len())
+}
+
+// whereVars returns the vars of the where expressions of the statement, the soft delete condition aside
+func whereVars(stmt *gorm.Statement) []interface{} {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	vars := []interface{}{}
+	for _, expr := range where.Exprs {
+		if e, ok := expr.(clause.Expr); ok {
+			vars = append(vars, e.Vars...)
+		}
+	}
+	return vars
+}
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
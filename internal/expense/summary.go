@@ -0,0 +1,121 @@
+package expense
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// Summary contains an expense report with the context to summarize it
+type Summary struct {
+	Report       *types.ExpenseReport
+	Organization *types.Organization
+	Submitter    *types.User
+	// The assigned approver, nil before the submission
+	Approver   *types.User
+	Violations []*types.ExpensePolicyViolation
+	Events     []*types.ExpenseReportEvent
+	Comments   []*types.ExpenseReportComment
+}
+
+// WriteSummary writes the PDF summary of the expense report: the details, the documents with the total of the report,
+// the policy violations, the approval trail and the comments
+func (e *Expense) WriteSummary(out io.Writer, s *Summary) error {
+	rec := s.Report
+	w := newPDFWriter()
+
+	w.text(18, true, truncate("Expense report: "+rec.Title, 50))
+	w.rule()
+	for _, row := range [][2]string{
+		{"Organization", s.Organization.Name},
+		{"Submitter", userLabel(s.Submitter)},
+		{"Status", rec.Status},
+		{"Submitted at", formatTime(rec.SubmittedAt)},
+		{"Approver", userLabel(s.Approver)},
+		{"Decided at", formatTime(rec.DecidedAt)},
+		{"Reimbursed at", formatTime(rec.ReimbursedAt)},
+		{"Generated at", e.now().UTC().Format(time.RFC1123)},
+	} {
+		w.line(10, false, pdfCell{text: row[0]}, pdfCell{x: 100, text: truncate(row[1], 70)})
+	}
+	if rec.Description != "" {
+		w.text(10, false, "")
+		w.paragraph(10, rec.Description)
+	}
+
+	w.text(10, false, "")
+	w.text(12, true, "Documents")
+	w.rule()
+	w.line(9, true, pdfCell{text: "Date"}, pdfCell{x: 70, text: "Merchant"}, pdfCell{x: 330, text: "Receipt"},
+		pdfCell{x: 450, text: "Total", right: true}, pdfCell{x: 460, text: "Currency"})
+	for _, doc := range rec.Documents {
+		receipt := "attached"
+		if doc.FilePath == "" {
+			receipt = "missing"
+		}
+		w.line(9, false, pdfCell{text: doc.TransactionDate}, pdfCell{x: 70, text: truncate(doc.MerchantName, 45)},
+			pdfCell{x: 330, text: receipt}, pdfCell{x: 450, text: fmt.Sprintf("%.2f", doc.Total), right: true}, pdfCell{x: 460, text: doc.Currency})
+	}
+	w.rule()
+	w.line(10, true, pdfCell{text: fmt.Sprintf("%d document(s)", len(rec.Documents))},
+		pdfCell{x: 450, text: fmt.Sprintf("%.2f", rec.Total), right: true}, pdfCell{x: 460, text: rec.Currency})
+
+	if len(s.Violations) > 0 {
+		w.text(10, false, "")
+		w.text(12, true, "Policy violations")
+		w.rule()
+		for _, v := range s.Violations {
+			w.paragraph(9, fmt.Sprintf("- [%s] %s (document %s)", v.Rule, v.Message, v.DocumentID))
+		}
+	}
+
+	w.text(10, false, "")
+	w.text(12, true, "Approval trail")
+	w.rule()
+	for _, ev := range s.Events {
+		step := ev.ToStatus
+		if ev.FromStatus != "" {
+			step = ev.FromStatus + " -> " + ev.ToStatus
+		}
+		w.line(9, false, pdfCell{text: ev.CreatedAt.UTC().Format("2006-01-02 15:04")}, pdfCell{x: 90, text: step},
+			pdfCell{x: 230, text: truncate(userLabel(ev.Actor), 50)})
+		if ev.Note != "" {
+			w.paragraph(9, "    "+ev.Note)
+		}
+	}
+
+	if len(s.Comments) > 0 {
+		w.text(10, false, "")
+		w.text(12, true, "Comments")
+		w.rule()
+		for _, c := range s.Comments {
+			w.text(9, true, fmt.Sprintf("%s, %s", userLabel(c.User), c.CreatedAt.UTC().Format("2006-01-02 15:04")))
+			w.paragraph(9, c.Body)
+		}
+	}
+
+	_, err := w.WriteTo(out)
+	return err
+}
+
+// userLabel returns the full name and the email address of the user
+func userLabel(user *types.User) string {
+	if user == nil {
+		return "-"
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		return user.Email
+	}
+	return name + " <" + user.Email + ">"
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC1123)
+}
@@ -0,0 +1,58 @@
+package impersonation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/server"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+)
+
+// Custom errors
+var (
+	ErrReadOnly = server.NewHTTPError(http.StatusForbidden, "IMPERSONATION_READ_ONLY", "This action is not allowed while impersonating a user")
+)
+
+// safeMethods are the request methods allowed while impersonating, the others may change the data of the user
+var safeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// MWFunc returns the middleware which records every request made with an impersonation token in the audit events
+// of the impersonated user, then rejects the ones which may change its data. The audit event is recorded first
+// so that no request goes unrecorded. It must be used after contextutil.MWContext.
+func (s *Impersonation) MWFunc() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cc, ok := c.(contextutil.Context)
+			if !ok || cc.AuthUser() == nil || cc.AuthUser().ActorID == "" {
+				return next(c)
+			}
+
+			au, req := cc.AuthUser(), c.Request()
+			allowed := lo.Contains(safeMethods, req.Method)
+			jti, _ := c.Get("jti").(string)
+			meta, _ := json.Marshal(map[string]interface{}{"method": req.Method, "uri": req.URL.RequestURI(), "blocked": !allowed})
+			if err := s.repo.Create(req.Context(), &types.AuditEvent{
+				UserID:     au.ID,
+				ActorID:    au.ActorID,
+				Action:     types.AuditActionImpersonatedRequest,
+				ObjectType: "impersonation",
+				ObjectID:   jti,
+				IPAddress:  cc.RealIP(),
+				UserAgent:  cc.UserAgent(),
+				Metadata:   meta,
+			}); err != nil {
+				return server.NewHTTPInternalError("error recording audit event").SetInternal(err)
+			}
+
+			if !allowed {
+				return ErrReadOnly
+			}
+
+			return next(c)
+		}
+	}
+}
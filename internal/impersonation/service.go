@@ -0,0 +1,22 @@
+package impersonation
+
+import (
+	"context"
+
+	"tyr/internal/types"
+)
+
+// New creates new impersonation service guarding the requests made by the superadmins as the impersonated users
+func New(repo Repository) *Impersonation {
+	return &Impersonation{repo: repo}
+}
+
+// Impersonation represents impersonation service
+type Impersonation struct {
+	repo Repository
+}
+
+// Repository represents the audit events storage interface
+type Repository interface {
+	Create(ctx context.Context, rec *types.AuditEvent) error
+}
@@ -0,0 +1,199 @@
+package jwtkeys
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/M15t/gram/pkg/server"
+	gramjwt "github.com/M15t/gram/pkg/server/middleware/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Custom errors
+var (
+	ErrUnauthorized = server.NewHTTPError(http.StatusUnauthorized, "UNAUTHORIZED", "Your session is unauthorized or has expired.")
+)
+
+// Token use claim, telling the access tokens from the refresh tokens which are signed by the same keys
+const (
+	ClaimTokenUse   = "typ"
+	TokenUseAccess  = "access"
+	TokenUseRefresh = "refresh"
+)
+
+// TokenUse returns the use of the token of the given claims.
+// Tokens issued before the claim existed have none, the refresh ones are told by their `uid` claim.
+func TokenUse(claims jwt.MapClaims) string {
+	if use, ok := claims[ClaimTokenUse].(string); ok {
+		return use
+	}
+	if _, ok := claims["uid"]; ok {
+		return TokenUseRefresh
+	}
+	return TokenUseAccess
+}
+
+// GenerateToken generates a new access or refresh token signed by the currently active key
+func (s *Service) GenerateToken(input *gramjwt.TokenInput, output *gramjwt.TokenOutput) error {
+	if input == nil || output == nil {
+		return fmt.Errorf("input and output cannot be nil")
+	}
+
+	switch input.Type {
+	case gramjwt.TypeTokenAccess:
+		return s.generate(input.Claims, TokenUseAccess, s.accessDuration, output)
+	case gramjwt.TypeTokenRefresh:
+		return s.generate(input.Claims, TokenUseRefresh, s.refreshDuration, output)
+	default:
+		return fmt.Errorf("invalid token type")
+	}
+}
+
+// GenerateAccessToken generates an access token expiring after the given duration instead of the configured one,
+// eg: for the short-lived tokens. The duration is capped to the configured one.
+func (s *Service) GenerateAccessToken(claims map[string]interface{}, ttl time.Duration) (*gramjwt.TokenOutput, error) {
+	output := &gramjwt.TokenOutput{}
+	if err := s.generate(claims, TokenUseAccess, min(ttl, s.accessDuration), output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// generate signs the claims of the given token use by the currently active key, expiring after the given duration
+func (s *Service) generate(claims map[string]interface{}, use string, ttl time.Duration, output *gramjwt.TokenOutput) error {
+	now := s.now()
+	expire := now.Add(ttl)
+	claims[ClaimTokenUse] = use
+	claims["exp"] = expire.Unix()
+	claims["iat"] = now.Unix()
+
+	var token *jwt.Token
+	var signKey any
+	if len(s.keys) == 0 {
+		token = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(claims))
+		signKey = s.secret
+	} else {
+		k, err := s.signingKey()
+		if err != nil {
+			return err
+		}
+		token = jwt.NewWithClaims(jwt.GetSigningMethod(k.alg), jwt.MapClaims(claims))
+		token.Header["kid"] = k.kid
+		signKey = k.private
+	}
+
+	tokenString, err := token.SignedString(signKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	output.Token = tokenString
+	output.ExpiresIn = int(expire.Sub(now).Seconds())
+
+	return nil
+}
+
+// ParseToken parses and verifies the token by the key of its `kid` header.
+// Tokens without `kid` are verified by the shared secret if it is configured.
+func (s *Service) ParseToken(input string) (*jwt.Token, error) {
+	return jwt.Parse(input, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			if s.secret == nil || token.Method != jwt.SigningMethodHS256 {
+				return nil, fmt.Errorf("token method mismatched")
+			}
+			return s.secret, nil
+		}
+
+		k := s.verificationKey(kid)
+		if k == nil {
+			return nil, fmt.Errorf("unknown or retired key %q", kid)
+		}
+		if token.Method.Alg() != k.alg {
+			return nil, fmt.Errorf("token method mismatched")
+		}
+		return k.private.Public(), nil
+	}, jwt.WithValidMethods([]string{
+		jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg(),
+	}))
+}
+
+// ParseTokenFromHeader parses the bearer token of the Authorization header
+func (s *Service) ParseTokenFromHeader(c echo.Context) (*jwt.Token, error) {
+	token := c.Request().Header.Get("Authorization")
+	if len(strings.TrimSpace(token)) == 0 {
+		return nil, fmt.Errorf("token not found")
+	}
+	parts := strings.SplitN(token, " ", 2)
+	if !(len(parts) == 2 && strings.ToLower(parts[0]) == "bearer") {
+		return nil, fmt.Errorf("token invalid")
+	}
+
+	return s.ParseToken(parts[1])
+}
+
+// MWFunc returns the middleware which verifies the bearer access token and sets its claims into the context.
+// Refresh tokens are rejected.
+func (s *Service) MWFunc() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, err := s.ParseTokenFromHeader(c)
+			if err != nil || !token.Valid {
+				if err != nil {
+					c.Logger().Errorf("error parsing token: %+v", err)
+				}
+				return ErrUnauthorized.SetInternal(err)
+			}
+
+			claims := token.Claims.(jwt.MapClaims)
+			if TokenUse(claims) != TokenUseAccess {
+				return ErrUnauthorized
+			}
+			for key, val := range claims {
+				c.Set(key, val)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// JWKS returns the public keys which are not retired, including the ones not active yet
+// so that verifiers already know them when the rotation happens
+func (s *Service) JWKS() *JWKSet {
+	now := s.now()
+	set := &JWKSet{Keys: []JWK{}}
+	for _, k := range s.keys {
+		if k.retireAt == nil || now.Before(*k.retireAt) {
+			set.Keys = append(set.Keys, k.jwk())
+		}
+	}
+	return set
+}
+
+// signingKey returns the most recently activated key which is not retired
+func (s *Service) signingKey() (*key, error) {
+	now := s.now()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		k := s.keys[i]
+		if !k.activeFrom.After(now) && (k.retireAt == nil || now.Before(*k.retireAt)) {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("no active JWT signing key")
+}
+
+// verificationKey returns the key of the given id if it is not retired
+func (s *Service) verificationKey(kid string) *key {
+	now := s.now()
+	for _, k := range s.keys {
+		if k.kid == kid && (k.retireAt == nil || now.Before(*k.retireAt)) {
+			return k
+		}
+	}
+	return nil
+}
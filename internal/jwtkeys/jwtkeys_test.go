@@ -0,0 +1,393 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/config"
+
+	"github.com/M15t/gram/pkg/server"
+	gramjwt "github.com/M15t/gram/pkg/server/middleware/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	rsaKey = func() *rsa.PrivateKey {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		return k
+	}()
+	ecKey = func() *ecdsa.PrivateKey {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		return k
+	}()
+	edKey = func() ed25519.PrivateKey {
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		return k
+	}()
+)
+
+func TestNew(t *testing.T) {
+	active := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, []byte(pemPKCS8(t, edKey)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		cfg     config.JWT
+		wantErr string
+	}{
+		{name: "shared secret", cfg: config.JWT{Secret: "secret"}},
+		{name: "shared secret with HS256", cfg: config.JWT{Secret: "secret", Algorithm: "HS256"}},
+		{name: "neither secret nor keys", cfg: config.JWT{}, wantErr: "either JWT_SECRET or JWT_KEYS is required"},
+		{name: "asymmetric algorithm without keys", cfg: config.JWT{Secret: "secret", Algorithm: "RS256"}, wantErr: "JWT_KEYS is required"},
+		{name: "invalid keys", cfg: config.JWT{Keys: "{"}, wantErr: "invalid JWT_KEYS"},
+		{name: "RSA PKCS#1", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: active})},
+		{name: "RSA PKCS#8", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS8(t, rsaKey), ActiveFrom: active})},
+		{name: "EC", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "ES256", PrivateKey: pemEC(t, ecKey), ActiveFrom: active})},
+		{name: "Ed25519 from file", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "EdDSA", PrivateKeyFile: keyFile, ActiveFrom: active})},
+		{name: "algorithm of the configuration", cfg: keysConfig(t, "ES256", KeyConfig{KID: "k1", PrivateKey: pemEC(t, ecKey), ActiveFrom: active})},
+		{name: "missing file", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "EdDSA", PrivateKeyFile: keyFile + ".missing"}), wantErr: "error reading key"},
+		{name: "no PEM data", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: "not a key"}), wantErr: "no PEM data found"},
+		{name: "missing kid", cfg: keysConfig(t, "", KeyConfig{Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey)}), wantErr: "missing or duplicated kid"},
+		{name: "duplicated kid", cfg: keysConfig(t, "",
+			KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: active},
+			KeyConfig{KID: "k1", Algorithm: "ES256", PrivateKey: pemEC(t, ecKey), ActiveFrom: active},
+		), wantErr: "missing or duplicated kid"},
+		{name: "weak RSA key", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(weakRSA)}), wantErr: "at least 2048 bits"},
+		{name: "RSA key with another algorithm", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "ES256", PrivateKey: pemPKCS1(rsaKey)}), wantErr: "RSA key cannot be used with ES256"},
+		{name: "EC key of another curve", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "ES256", PrivateKey: pemEC(t, p384)}), wantErr: "must be P-256"},
+		{name: "Ed25519 key with another algorithm", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS8(t, edKey)}), wantErr: "Ed25519 key cannot be used with RS256"},
+		{name: "no active key", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: future}), wantErr: "no active JWT signing key"},
+		{name: "retired key", cfg: keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: active.Add(-time.Hour), RetireAt: &active}), wantErr: "no active JWT signing key"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.cfg)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestRotation signs by the most recent active key, keeps verifying the tokens of the previous key
+// until it is retired, and publishes the next key before it becomes active
+func TestRotation(t *testing.T) {
+	base := time.Now()
+	k1Retire := base.Add(48 * time.Hour)
+	s, err := New(keysConfig(t, "",
+		// configured out of order
+		KeyConfig{KID: "k2", Algorithm: "ES256", PrivateKey: pemEC(t, ecKey), ActiveFrom: base.Add(24 * time.Hour)},
+		KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: base.Add(-time.Hour), RetireAt: &k1Retire},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := base
+	s.now = func() time.Time { return clock }
+
+	if got := jwksKIDs(s); got != "k1,k2" {
+		t.Errorf("got published keys %s, want the next key published before its activation", got)
+	}
+
+	before := mustGenerate(t, s, gramjwt.TypeTokenAccess)
+	if kid, alg := header(t, before); kid != "k1" || alg != "RS256" {
+		t.Errorf("got kid %s with %s, want k1 with RS256", kid, alg)
+	}
+
+	clock = base.Add(25 * time.Hour)
+	after := mustGenerate(t, s, gramjwt.TypeTokenAccess)
+	if kid, alg := header(t, after); kid != "k2" || alg != "ES256" {
+		t.Errorf("got kid %s with %s, want k2 with ES256", kid, alg)
+	}
+	for _, token := range []string{before, after} {
+		if _, err := s.ParseToken(token); err != nil {
+			t.Errorf("got %v, want the tokens of both keys verified", err)
+		}
+	}
+
+	clock = k1Retire
+	if _, err := s.ParseToken(before); err == nil {
+		t.Error("the token of the retired key is verified")
+	}
+	if _, err := s.ParseToken(after); err != nil {
+		t.Errorf("got %v", err)
+	}
+	if got := jwksKIDs(s); got != "k2" {
+		t.Errorf("got published keys %s, want the retired key unpublished", got)
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	active := time.Now().Add(-time.Hour)
+	withSecret, err := New(keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: active}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withSecret.secret = []byte("secret")
+	withoutSecret, err := New(keysConfig(t, "", KeyConfig{KID: "k1", Algorithm: "RS256", PrivateKey: pemPKCS1(rsaKey), ActiveFrom: active}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretOnly, err := New(config.JWT{Secret: "secret", DurationAccessToken: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the tokens of the shared secret have no kid
+	hs := mustGenerate(t, secretOnly, gramjwt.TypeTokenAccess)
+	if kid, alg := header(t, hs); kid != "" || alg != "HS256" {
+		t.Fatalf("got kid %q with %s, want HS256 without kid", kid, alg)
+	}
+	claims := jwt.MapClaims{"id": "u1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	cases := []struct {
+		name    string
+		svc     *Service
+		token   string
+		wantErr bool
+	}{
+		{name: "shared secret", svc: secretOnly, token: hs},
+		{name: "shared secret still accepted after the keys", svc: withSecret, token: hs},
+		{name: "shared secret no longer accepted", svc: withoutSecret, token: hs, wantErr: true},
+		{name: "another secret", svc: withSecret, token: sign(t, jwt.SigningMethodHS256, "", claims, []byte("another")), wantErr: true},
+		{name: "kid signed by the shared secret", svc: withSecret, token: sign(t, jwt.SigningMethodHS256, "k1", claims, []byte("secret")), wantErr: true},
+		{name: "no kid signed by a key", svc: withSecret, token: sign(t, jwt.SigningMethodRS256, "", claims, rsaKey), wantErr: true},
+		{name: "unknown kid", svc: withSecret, token: sign(t, jwt.SigningMethodRS256, "k0", claims, rsaKey), wantErr: true},
+		{name: "kid of another algorithm", svc: withSecret, token: sign(t, jwt.SigningMethodES256, "k1", claims, ecKey), wantErr: true},
+		{name: "none algorithm", svc: withSecret, token: sign(t, jwt.SigningMethodNone, "", claims, jwt.UnsafeAllowNoneSignatureType), wantErr: true},
+		{name: "expired", svc: secretOnly, token: sign(t, jwt.SigningMethodHS256, "", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, []byte("secret")), wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.svc.ParseToken(tc.token)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	s, err := New(config.JWT{Secret: "secret", DurationAccessToken: 60, DurationRefreshToken: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for typ, want := range map[string]struct {
+		use       string
+		expiresIn int
+	}{
+		gramjwt.TypeTokenAccess:  {use: TokenUseAccess, expiresIn: 60},
+		gramjwt.TypeTokenRefresh: {use: TokenUseRefresh, expiresIn: 3600},
+	} {
+		out := &gramjwt.TokenOutput{}
+		if err := s.GenerateToken(&gramjwt.TokenInput{Type: typ, Claims: map[string]interface{}{"id": "x"}}, out); err != nil {
+			t.Fatal(err)
+		}
+		token, err := s.ParseToken(out.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := TokenUse(token.Claims.(jwt.MapClaims)); got != want.use || out.ExpiresIn != want.expiresIn {
+			t.Errorf("%s token got use %s expiring in %d, want %s in %d", typ, got, out.ExpiresIn, want.use, want.expiresIn)
+		}
+	}
+
+	if err := s.GenerateToken(&gramjwt.TokenInput{Type: "other", Claims: map[string]interface{}{}}, &gramjwt.TokenOutput{}); err == nil {
+		t.Error("got no error for an invalid token type")
+	}
+
+	// the duration of the short-lived tokens is capped
+	for ttl, want := range map[time.Duration]int{10 * time.Second: 10, time.Hour: 60} {
+		out, err := s.GenerateAccessToken(map[string]interface{}{"id": "x"}, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.ExpiresIn != want {
+			t.Errorf("got expiring in %d, want %d", out.ExpiresIn, want)
+		}
+	}
+}
+
+func TestTokenUse(t *testing.T) {
+	for name, tc := range map[string]struct {
+		claims jwt.MapClaims
+		want   string
+	}{
+		"access":                  {claims: jwt.MapClaims{"typ": "access", "id": "u1"}, want: TokenUseAccess},
+		"refresh":                 {claims: jwt.MapClaims{"typ": "refresh", "id": "s1", "uid": "u1"}, want: TokenUseRefresh},
+		"legacy access token":     {claims: jwt.MapClaims{"id": "u1", "email": "user@tyr.io"}, want: TokenUseAccess},
+		"legacy refresh token":    {claims: jwt.MapClaims{"id": "s1", "uid": "u1"}, want: TokenUseRefresh},
+		"unknown use is kept":     {claims: jwt.MapClaims{"typ": "other"}, want: "other"},
+		"use claim wins over uid": {claims: jwt.MapClaims{"typ": "access", "uid": "u1"}, want: TokenUseAccess},
+	} {
+		if got := TokenUse(tc.claims); got != tc.want {
+			t.Errorf("%s: got %s, want %s", name, got, tc.want)
+		}
+	}
+}
+
+func TestMWFunc(t *testing.T) {
+	s, err := New(config.JWT{Secret: "secret", DurationAccessToken: 60, DurationRefreshToken: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	access := mustGenerate(t, s, gramjwt.TypeTokenAccess)
+	refresh := mustGenerate(t, s, gramjwt.TypeTokenRefresh)
+
+	cases := []struct {
+		name   string
+		header string
+		wantOK bool
+	}{
+		{name: "access token", header: "Bearer " + access, wantOK: true},
+		{name: "lowercase scheme", header: "bearer " + access, wantOK: true},
+		{name: "refresh token", header: "Bearer " + refresh},
+		{name: "missing token", header: ""},
+		{name: "another scheme", header: "Basic " + access},
+		{name: "invalid token", header: "Bearer " + access + "x"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tc.header)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			called := false
+			err := s.MWFunc()(func(c echo.Context) error {
+				called = true
+				return nil
+			})(c)
+
+			if !tc.wantOK {
+				var he *server.HTTPError
+				if !errors.As(err, &he) || he.Code != http.StatusUnauthorized || called {
+					t.Errorf("got %v, want unauthorized", err)
+				}
+				return
+			}
+			if err != nil || !called {
+				t.Fatalf("got %v", err)
+			}
+			if c.Get("id") != "x" || c.Get(ClaimTokenUse) != TokenUseAccess {
+				t.Errorf("got id %v and use %v, want the claims set", c.Get("id"), c.Get(ClaimTokenUse))
+			}
+		})
+	}
+}
+
+func mustGenerate(t *testing.T, s *Service, typ string) string {
+	t.Helper()
+	out := &gramjwt.TokenOutput{}
+	if err := s.GenerateToken(&gramjwt.TokenInput{Type: typ, Claims: map[string]interface{}{"id": "x"}}, out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Token
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func header(t *testing.T, token string) (kid, alg string) {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ = parsed.Header["kid"].(string)
+	return kid, parsed.Method.Alg()
+}
+
+func jwksKIDs(s *Service) string {
+	kids := []string{}
+	for _, k := range s.JWKS().Keys {
+		kids = append(kids, k.KeyID)
+	}
+	return strings.Join(kids, ",")
+}
+
+func keysConfig(t *testing.T, alg string, kcs ...KeyConfig) config.JWT {
+	t.Helper()
+	b, err := json.Marshal(kcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.JWT{Keys: string(b), Algorithm: alg, DurationAccessToken: 3600, DurationRefreshToken: 7 * 24 * 3600}
+}
+
+func pemPKCS1(k *rsa.PrivateKey) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}))
+}
+
+func pemEC(t *testing.T, k *ecdsa.PrivateKey) string {
+	t.Helper()
+	b, err := x509.MarshalECPrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}))
+}
+
+func pemPKCS8(t *testing.T, k any) string {
+	t.Helper()
+	b, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}))
+}
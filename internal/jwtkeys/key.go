@@ -0,0 +1,96 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// parseKey parses the PEM encoded private key and checks it matches the algorithm
+func parseKey(kc KeyConfig) (*key, error) {
+	block, _ := pem.Decode([]byte(kc.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	switch k := signer.(type) {
+	case *rsa.PrivateKey:
+		if kc.Algorithm != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", kc.Algorithm)
+		}
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key must be at least 2048 bits")
+		}
+	case *ecdsa.PrivateKey:
+		if kc.Algorithm != jwt.SigningMethodES256.Alg() || k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("EC key must be P-256 and used with ES256")
+		}
+	case ed25519.PrivateKey:
+		if kc.Algorithm != jwt.SigningMethodEdDSA.Alg() {
+			return nil, fmt.Errorf("Ed25519 key cannot be used with %s", kc.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", k)
+	}
+
+	return &key{
+		kid:        kc.KID,
+		alg:        kc.Algorithm,
+		private:    signer,
+		activeFrom: kc.ActiveFrom,
+		retireAt:   kc.RetireAt,
+	}, nil
+}
+
+// jwk returns the public JSON Web Key of the key
+func (k *key) jwk() JWK {
+	res := JWK{KeyID: k.kid, Algorithm: k.alg, Use: "sig"}
+	switch pub := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		res.KeyType = "RSA"
+		res.N = b64(pub.N.Bytes())
+		res.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		res.KeyType = "EC"
+		res.Curve = "P-256"
+		res.X = b64(pub.X.FillBytes(make([]byte, 32)))
+		res.Y = b64(pub.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		res.KeyType = "OKP"
+		res.Curve = "Ed25519"
+		res.X = b64(pub)
+	}
+	return res
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,85 @@
+package jwtkeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"tyr/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// New creates new JWT service signing tokens with the keys of the configuration.
+// Without `JWT_KEYS`, tokens are signed by the shared `JWT_SECRET` (HS256) without `kid`.
+// With `JWT_KEYS`, the shared secret, if still set, is only accepted to verify the tokens issued before.
+func New(cfg config.JWT) (*Service, error) {
+	s := &Service{
+		accessDuration:  time.Duration(cfg.DurationAccessToken) * time.Second,
+		refreshDuration: time.Duration(cfg.DurationRefreshToken) * time.Second,
+		now:             time.Now,
+	}
+	if cfg.Secret != "" {
+		s.secret = []byte(cfg.Secret)
+	}
+
+	if strings.TrimSpace(cfg.Keys) == "" {
+		if s.secret == nil {
+			return nil, fmt.Errorf("either JWT_SECRET or JWT_KEYS is required")
+		}
+		if cfg.Algorithm != "" && cfg.Algorithm != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("JWT_KEYS is required for %s algorithm", cfg.Algorithm)
+		}
+		return s, nil
+	}
+
+	kcs := []KeyConfig{}
+	if err := json.Unmarshal([]byte(cfg.Keys), &kcs); err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEYS: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, kc := range kcs {
+		if kc.Algorithm == "" {
+			kc.Algorithm = cfg.Algorithm
+		}
+		if kc.PrivateKey == "" && kc.PrivateKeyFile != "" {
+			b, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading key %q: %w", kc.KID, err)
+			}
+			kc.PrivateKey = string(b)
+		}
+		if kc.KID == "" || seen[kc.KID] {
+			return nil, fmt.Errorf("missing or duplicated kid %q", kc.KID)
+		}
+		seen[kc.KID] = true
+
+		k, err := parseKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", kc.KID, err)
+		}
+		s.keys = append(s.keys, k)
+	}
+	sort.SliceStable(s.keys, func(i, j int) bool { return s.keys[i].activeFrom.Before(s.keys[j].activeFrom) })
+
+	if _, err := s.signingKey(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Service represents JWT service with key rotation
+type Service struct {
+	// keys ordered by activation time
+	keys   []*key
+	secret []byte
+
+	accessDuration  time.Duration
+	refreshDuration time.Duration
+
+	now func() time.Time
+}
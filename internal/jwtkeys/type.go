@@ -0,0 +1,53 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"time"
+)
+
+// KeyConfig represents a signing key entry of the `JWT_KEYS` configuration
+type KeyConfig struct {
+	// Key ID, set as `kid` header of the issued tokens
+	KID string `json:"kid"`
+	// RS256, ES256 or EdDSA, default to JWT_ALGORITHM
+	Algorithm string `json:"alg"`
+	// PEM encoded private key, either inline or from a file
+	PrivateKey     string `json:"private_key"`
+	PrivateKeyFile string `json:"private_key_file"`
+	// The key signs new tokens from this time until a newer key becomes active
+	ActiveFrom time.Time `json:"active_from"`
+	// The key is no longer accepted nor published from this time, it must be later than
+	// the time the next key becomes active plus the lifetime of refresh tokens
+	RetireAt *time.Time `json:"retire_at"`
+}
+
+// key is a parsed signing key
+type key struct {
+	kid        string
+	alg        string
+	private    crypto.Signer
+	activeFrom time.Time
+	retireAt   *time.Time
+}
+
+// JWK represents a public JSON Web Key
+// swagger:model
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKSet represents a JSON Web Key Set
+// swagger:model
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,152 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// ErrLocked is returned when the account or the IP address is temporarily locked
+var ErrLocked = errors.New("login locked")
+
+// Result contains the outcome of a failed attempt
+type Result struct {
+	// The failed attempts of the account within the window
+	AccountFailures int
+	// The account has just been locked by this attempt
+	AccountLocked bool
+	// The IP address has just been locked by this attempt
+	IPLocked bool
+}
+
+// AccountKey returns the key of the account by its email
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the key of the IP address
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns ErrLocked if either the account or the IP address is locked,
+// otherwise the delay to apply before verifying the attempt, which grows with the recent failures of the account
+func (s *Guard) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	now := s.now()
+	for _, key := range []string{AccountKey(email), IPKey(ip)} {
+		lockout, err := s.store.FindLockout(ctx, key, now)
+		if err != nil {
+			return 0, err
+		}
+		if lockout != nil {
+			return 0, ErrLocked
+		}
+	}
+
+	failures, err := s.store.CountFailures(ctx, AccountKey(email), now.Add(-s.window))
+	if err != nil {
+		return 0, err
+	}
+
+	return s.delay(failures), nil
+}
+
+// Fail records a failed attempt of the account and the IP address,
+// either of them is locked once its failures within the sliding window reach the limit
+func (s *Guard) Fail(ctx context.Context, email, ip string) (*Result, error) {
+	res := &Result{}
+
+	failures, locked, err := s.fail(ctx, AccountKey(email), s.accountMaxFailures)
+	if err != nil {
+		return nil, err
+	}
+	res.AccountFailures, res.AccountLocked = failures, locked
+
+	if _, res.IPLocked, err = s.fail(ctx, IPKey(ip), s.ipMaxFailures); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Succeed clears the failed attempts of the account, the failures of the IP address are kept
+// so that an attacker cannot reset them by logging in to their own account
+func (s *Guard) Succeed(ctx context.Context, email string) error {
+	return s.store.DeleteFailures(ctx, AccountKey(email), s.now())
+}
+
+// Unlock removes the lockout and the failed attempts of the key
+func (s *Guard) Unlock(ctx context.Context, key string) (bool, error) {
+	now := s.now()
+	unlocked, err := s.store.Unlock(ctx, key, now)
+	if err != nil {
+		return false, err
+	}
+	if err := s.store.DeleteFailures(ctx, key, now); err != nil {
+		return false, err
+	}
+	return unlocked, nil
+}
+
+// ListLockouts lists the active lockouts
+func (s *Guard) ListLockouts(ctx context.Context) ([]*types.LoginLockout, error) {
+	return s.store.ListLockouts(ctx, s.now())
+}
+
+// LockoutDuration returns how long the lockouts last
+func (s *Guard) LockoutDuration() time.Duration {
+	return s.lockoutDuration
+}
+
+func (s *Guard) fail(ctx context.Context, key string, maxFailures int) (int, bool, error) {
+	now := s.now()
+	if err := s.store.AddFailure(ctx, key, now); err != nil {
+		return 0, false, err
+	}
+	// the failures out of the window no longer count
+	if err := s.store.DeleteFailures(ctx, key, now.Add(-s.window)); err != nil {
+		return 0, false, err
+	}
+	failures, err := s.store.CountFailures(ctx, key, now.Add(-s.window))
+	if err != nil {
+		return 0, false, err
+	}
+
+	if maxFailures <= 0 || failures < maxFailures {
+		return failures, false, nil
+	}
+
+	if err := s.store.Lock(ctx, &types.LoginLockout{
+		Key:         key,
+		Failures:    failures,
+		LockedUntil: now.Add(s.lockoutDuration),
+		CreatedAt:   now,
+	}); err != nil {
+		return 0, false, err
+	}
+	// start over after the lockout
+	if err := s.store.DeleteFailures(ctx, key, now); err != nil {
+		return 0, false, err
+	}
+
+	return failures, true, nil
+}
+
+// delay doubles from the base delay for every failure after the threshold, up to the maximum
+func (s *Guard) delay(failures int) time.Duration {
+	if failures < s.delayAfter || s.delayBase <= 0 {
+		return 0
+	}
+
+	d := s.delayBase
+	for i := s.delayAfter; i < failures && d < s.delayMax; i++ {
+		d *= 2
+	}
+	if s.delayMax > 0 && d > s.delayMax {
+		d = s.delayMax
+	}
+	return d
+}
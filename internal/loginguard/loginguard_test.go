@@ -0,0 +1,219 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tyr/config"
+)
+
+var testConfig = config.LoginGuard{
+	Store:              StoreMemory,
+	Window:             900,
+	AccountMaxFailures: 3,
+	IPMaxFailures:      5,
+	LockoutDuration:    600,
+	DelayAfter:         1,
+	DelayBaseMS:        250,
+	DelayMaxMS:         1000,
+}
+
+func TestNewStore(t *testing.T) {
+	postgres := NewMemory()
+	for store, want := range map[string]Store{"": postgres, StorePostgres: postgres} {
+		if got, err := NewStore(config.LoginGuard{Store: store}, postgres); err != nil || got != want {
+			t.Errorf("%q got %v, %v, want the given store", store, got, err)
+		}
+	}
+	if got, err := NewStore(config.LoginGuard{Store: StoreMemory}, postgres); err != nil || got == postgres {
+		t.Errorf("got %v, %v, want a memory store", got, err)
+	}
+	if _, err := NewStore(config.LoginGuard{Store: "redis"}, postgres); err == nil {
+		t.Error("got no error for an unsupported store")
+	}
+}
+
+func TestCheckDelay(t *testing.T) {
+	s, _ := newTestGuard()
+	ctx := context.Background()
+
+	for i, want := range []time.Duration{0, 250 * time.Millisecond, 500 * time.Millisecond} {
+		delay, err := s.Check(ctx, "user@tyr.io", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if delay != want {
+			t.Errorf("after %d failures got delay %v, want %v", i, delay, want)
+		}
+		if _, err := s.Fail(ctx, "user@tyr.io", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for failures, want := range map[int]time.Duration{0: 0, 1: 250 * time.Millisecond, 3: time.Second, 10: time.Second} {
+		if got := s.delay(failures); got != want {
+			t.Errorf("%d failures got delay %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestAccountLockout(t *testing.T) {
+	s, clock := newTestGuard()
+	ctx := context.Background()
+
+	for i := 1; i <= testConfig.AccountMaxFailures; i++ {
+		res, err := s.Fail(ctx, "User@Tyr.io ", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.AccountFailures != i || res.AccountLocked != (i == testConfig.AccountMaxFailures) || res.IPLocked {
+			t.Fatalf("failure %d got %+v", i, res)
+		}
+	}
+
+	// the account is locked from any IP address, the IP address is not locked for other accounts
+	if _, err := s.Check(ctx, "user@tyr.io", "10.0.0.2"); !errors.Is(err, ErrLocked) {
+		t.Errorf("got %v, want ErrLocked", err)
+	}
+	if _, err := s.Check(ctx, "other@tyr.io", "10.0.0.1"); err != nil {
+		t.Errorf("got %v for another account", err)
+	}
+
+	// the failures start over after the lockout
+	clock.add(s.LockoutDuration())
+	delay, err := s.Check(ctx, "user@tyr.io", "10.0.0.1")
+	if err != nil || delay != 0 {
+		t.Errorf("got %v, %v after the lockout, want no delay", delay, err)
+	}
+}
+
+func TestIPLockout(t *testing.T) {
+	s, _ := newTestGuard()
+	ctx := context.Background()
+
+	var res *Result
+	for _, email := range []string{"a@tyr.io", "b@tyr.io", "c@tyr.io", "d@tyr.io", "e@tyr.io"} {
+		var err error
+		if res, err = s.Fail(ctx, email, "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !res.IPLocked || res.AccountLocked {
+		t.Fatalf("got %+v, want the IP address locked", res)
+	}
+
+	if _, err := s.Check(ctx, "f@tyr.io", "10.0.0.1"); !errors.Is(err, ErrLocked) {
+		t.Errorf("got %v, want ErrLocked", err)
+	}
+	if _, err := s.Check(ctx, "a@tyr.io", "10.0.0.2"); err != nil {
+		t.Errorf("got %v from another IP address", err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	s, clock := newTestGuard()
+	ctx := context.Background()
+
+	for i := 1; i < testConfig.AccountMaxFailures; i++ {
+		if _, err := s.Fail(ctx, "user@tyr.io", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock.add(time.Duration(testConfig.Window) * time.Second)
+	res, err := s.Fail(ctx, "user@tyr.io", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.AccountFailures != 1 || res.AccountLocked {
+		t.Errorf("got %+v, want the failures out of the window not counted", res)
+	}
+}
+
+// TestSucceed proves the success clears the failures of the account but not the ones of the IP address
+func TestSucceed(t *testing.T) {
+	s, _ := newTestGuard()
+	ctx := context.Background()
+
+	for _, email := range []string{"a@tyr.io", "b@tyr.io", "c@tyr.io", "d@tyr.io"} {
+		if _, err := s.Fail(ctx, email, "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Succeed(ctx, "A@tyr.io"); err != nil {
+		t.Fatal(err)
+	}
+	if delay, err := s.Check(ctx, "a@tyr.io", "10.0.0.1"); err != nil || delay != 0 {
+		t.Errorf("got %v, %v, want the failures of the account cleared", delay, err)
+	}
+
+	res, err := s.Fail(ctx, "a@tyr.io", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IPLocked {
+		t.Error("the failures of the IP address are cleared by the success")
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	s, clock := newTestGuard()
+	ctx := context.Background()
+	lock := func(email, ip string) {
+		for i := 0; i < testConfig.AccountMaxFailures; i++ {
+			if _, err := s.Fail(ctx, email, ip); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	lock("a@tyr.io", "10.0.0.1")
+	clock.add(time.Minute)
+	lock("b@tyr.io", "10.0.0.2")
+
+	recs, err := s.ListLockouts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Key != AccountKey("b@tyr.io") || recs[1].Key != AccountKey("a@tyr.io") {
+		t.Fatalf("got %+v, want both lockouts, the latest first", recs)
+	}
+
+	unlocked, err := s.Unlock(ctx, AccountKey("a@tyr.io"))
+	if err != nil || !unlocked {
+		t.Fatalf("got %v, %v, want unlocked", unlocked, err)
+	}
+	if delay, err := s.Check(ctx, "a@tyr.io", "10.0.0.3"); err != nil || delay != 0 {
+		t.Errorf("got %v, %v, want the account unlocked without failures", delay, err)
+	}
+	if unlocked, _ := s.Unlock(ctx, AccountKey("a@tyr.io")); unlocked {
+		t.Error("got unlocked twice")
+	}
+
+	// the expired lockout is not active at the time of the guard, whatever the wall clock is
+	clock.add(s.LockoutDuration())
+	if unlocked, _ := s.Unlock(ctx, AccountKey("b@tyr.io")); unlocked {
+		t.Error("got the expired lockout unlocked")
+	}
+	if recs, _ := s.ListLockouts(ctx); len(recs) != 0 {
+		t.Errorf("got %d lockouts, want none", len(recs))
+	}
+}
+
+func newTestGuard() (*Guard, *clock) {
+	// far from the wall clock so that the time of the guard is the only one in use
+	clock := &clock{now: time.Date(2036, 10, 19, 9, 0, 0, 0, time.UTC)}
+	s := New(NewMemory(), testConfig)
+	s.now = func() time.Time { return clock.now }
+	return s, clock
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
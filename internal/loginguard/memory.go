@@ -0,0 +1,113 @@
+package loginguard
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// Memory keeps the failed attempts and lockouts in memory, for a single instance or tests only
+type Memory struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	lockouts map[string]*types.LoginLockout
+}
+
+// NewMemory returns in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		failures: map[string][]time.Time{},
+		lockouts: map[string]*types.LoginLockout{},
+	}
+}
+
+// AddFailure records a failed attempt of the key
+func (s *Memory) AddFailure(_ context.Context, key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[key] = append(s.failures[key], at)
+	return nil
+}
+
+// CountFailures counts the failed attempts of the key since the given time
+func (s *Memory) CountFailures(_ context.Context, key string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, t := range s.failures[key] {
+		if t.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteFailures deletes the failed attempts of the key before the given time
+func (s *Memory) DeleteFailures(_ context.Context, key string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.failures[key][:0]
+	for _, t := range s.failures[key] {
+		if t.After(before) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.failures, key)
+	} else {
+		s.failures[key] = kept
+	}
+	return nil
+}
+
+// Lock creates or extends the lockout of the key
+func (s *Memory) Lock(_ context.Context, rec *types.LoginLockout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *rec
+	s.lockouts[rec.Key] = &cp
+	return nil
+}
+
+// FindLockout returns the active lockout of the key at the given time, nil if there is none
+func (s *Memory) FindLockout(_ context.Context, key string, at time.Time) (*types.LoginLockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.lockouts[key]
+	if !ok {
+		return nil, nil
+	}
+	if !rec.LockedUntil.After(at) {
+		delete(s.lockouts, key)
+		return nil, nil
+	}
+	cp := *rec
+	return &cp, nil
+}
+
+// Unlock deletes the lockout of the key, returns false if there is no active lockout at the given time
+func (s *Memory) Unlock(_ context.Context, key string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.lockouts[key]
+	delete(s.lockouts, key)
+	return ok && rec.LockedUntil.After(at), nil
+}
+
+// ListLockouts lists the active lockouts at the given time, the latest first
+func (s *Memory) ListLockouts(_ context.Context, at time.Time) ([]*types.LoginLockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recs := []*types.LoginLockout{}
+	for _, rec := range s.lockouts {
+		if rec.LockedUntil.After(at) {
+			cp := *rec
+			recs = append(recs, &cp)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].CreatedAt.After(recs[j].CreatedAt) })
+	return recs, nil
+}
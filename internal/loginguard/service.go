@@ -0,0 +1,68 @@
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/types"
+)
+
+// consts for login guard stores
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+)
+
+// New creates new login guard service with the given store
+func New(store Store, cfg config.LoginGuard) *Guard {
+	return &Guard{
+		store:              store,
+		window:             time.Duration(cfg.Window) * time.Second,
+		accountMaxFailures: cfg.AccountMaxFailures,
+		ipMaxFailures:      cfg.IPMaxFailures,
+		lockoutDuration:    time.Duration(cfg.LockoutDuration) * time.Second,
+		delayAfter:         cfg.DelayAfter,
+		delayBase:          time.Duration(cfg.DelayBaseMS) * time.Millisecond,
+		delayMax:           time.Duration(cfg.DelayMaxMS) * time.Millisecond,
+		now:                time.Now,
+	}
+}
+
+// NewStore returns the configured store, the postgres one is given since it is shared with other services
+func NewStore(cfg config.LoginGuard, postgres Store) (Store, error) {
+	switch cfg.Store {
+	case StorePostgres, "":
+		return postgres, nil
+	case StoreMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unsupported login guard store: %s", cfg.Store)
+	}
+}
+
+// Guard represents login brute-force protection service
+type Guard struct {
+	store              Store
+	window             time.Duration
+	accountMaxFailures int
+	ipMaxFailures      int
+	lockoutDuration    time.Duration
+	delayAfter         int
+	delayBase          time.Duration
+	delayMax           time.Duration
+
+	now func() time.Time
+}
+
+// Store represents the failed attempts and lockouts storage interface
+type Store interface {
+	AddFailure(ctx context.Context, key string, at time.Time) error
+	CountFailures(ctx context.Context, key string, since time.Time) (int, error)
+	DeleteFailures(ctx context.Context, key string, before time.Time) error
+	Lock(ctx context.Context, rec *types.LoginLockout) error
+	FindLockout(ctx context.Context, key string, at time.Time) (*types.LoginLockout, error)
+	Unlock(ctx context.Context, key string, at time.Time) (bool, error)
+	ListLockouts(ctx context.Context, at time.Time) ([]*types.LoginLockout, error)
+}
@@ -0,0 +1,70 @@
+package mailtemplate
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"tyr/third_party/mailer"
+)
+
+// Template names, each has `<name>.txt` and `<name>.html` files, the text one defines the `subject` block
+const (
+	VerifyEmail   = "verify_email"
+	ResetPassword = "reset_password"
+	UnlockAccount = "unlock_account"
+	// Invitation to join an organization
+	OrganizationInvitation = "organization_invitation"
+)
+
+//go:embed templates
+var files embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(files, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(files, "templates/*.html"))
+)
+
+// Render renders the email of the given template to the recipient
+func Render(name, to string, data any) (*mailer.Message, error) {
+	subject := &bytes.Buffer{}
+	if err := textTemplates.ExecuteTemplate(subject, name+".subject", data); err != nil {
+		return nil, fmt.Errorf("error rendering subject of %s: %w", name, err)
+	}
+
+	text := &bytes.Buffer{}
+	if err := textTemplates.ExecuteTemplate(text, name+".txt", data); err != nil {
+		return nil, fmt.Errorf("error rendering text of %s: %w", name, err)
+	}
+
+	html := &bytes.Buffer{}
+	if err := htmlTemplates.ExecuteTemplate(html, name+".html", data); err != nil {
+		return nil, fmt.Errorf("error rendering html of %s: %w", name, err)
+	}
+
+	return &mailer.Message{
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// HumanizeDuration formats the duration in the largest whole unit, eg: `24 hours`, `30 minutes`
+func HumanizeDuration(d time.Duration) string {
+	value, unit := int64(d/time.Minute), "minute"
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		value, unit = int64(d/(24*time.Hour)), "day"
+	case d >= time.Hour && d%time.Hour == 0:
+		value, unit = int64(d/time.Hour), "hour"
+	}
+	if value != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", value, unit)
+}
@@ -0,0 +1,189 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// Custom errors
+var (
+	ErrNotEnrolled     = errors.New("mfa not enrolled")
+	ErrAlreadyEnabled  = errors.New("mfa already enabled")
+	ErrNotEnabled      = errors.New("mfa not enabled")
+	ErrInvalidCode     = errors.New("invalid mfa code")
+	ErrTooManyAttempts = errors.New("too many mfa attempts")
+)
+
+// Enrollment contains the pending TOTP secret to be added into an authenticator app
+type Enrollment struct {
+	Secret string
+	// The `otpauth://` URI to be rendered as QR code
+	ProvisioningURI string
+}
+
+// Required tells whether the role must login with two-factor authentication
+func (s *MFA) Required(role string) bool {
+	return s.requiredRoles[role]
+}
+
+// ChallengeTTL returns the lifetime of the login challenges
+func (s *MFA) ChallengeTTL() time.Duration {
+	return s.challengeTTL
+}
+
+// Enroll generates a new pending TOTP secret of the user, which replaces the previous pending one.
+// The two-factor authentication is enabled only after a code of the secret is confirmed by Activate.
+func (s *MFA) Enroll(ctx context.Context, user *types.User) (*Enrollment, error) {
+	if user.MFAEnabledAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetMFASecret(ctx, user.ID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningURI: provisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Activate enables the two-factor authentication of the user by a code of the pending secret,
+// returns the new recovery codes which are shown only once
+func (s *MFA) Activate(ctx context.Context, user *types.User, code string) ([]string, error) {
+	if user.MFAEnabledAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+	if user.MFASecret == nil {
+		return nil, ErrNotEnrolled
+	}
+
+	secret, err := s.decrypt(*user.MFASecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(secret, normalizeCode(code), s.now())
+	if !ok {
+		return nil, s.fail(ctx, user)
+	}
+
+	enabled, err := s.users.EnableMFA(ctx, user.ID, step)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrNotEnrolled
+	}
+
+	return s.RegenerateRecoveryCodes(ctx, user)
+}
+
+// Verify checks the TOTP code or a recovery code of the user.
+// Every TOTP code and recovery code can be used only once.
+// The failed attempts are reset when reaching the limit so that the caller can invalidate what they guard.
+func (s *MFA) Verify(ctx context.Context, user *types.User, code string) error {
+	if user.MFAEnabledAt == nil || user.MFASecret == nil {
+		return ErrNotEnabled
+	}
+
+	code = normalizeCode(code)
+	if len(code) == totpDigits {
+		secret, err := s.decrypt(*user.MFASecret)
+		if err != nil {
+			return err
+		}
+		step, ok := validateTOTP(secret, code, s.now())
+		if !ok {
+			return s.fail(ctx, user)
+		}
+		used, err := s.users.UseMFAStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return s.fail(ctx, user)
+		}
+	} else {
+		used, err := s.recoveryCodes.Use(ctx, user.ID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return s.fail(ctx, user)
+		}
+	}
+
+	if user.MFAFailedAttempts > 0 {
+		return s.users.ResetMFAFailedAttempts(ctx, user.ID)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user, returns the new ones which are shown only once
+func (s *MFA) RegenerateRecoveryCodes(ctx context.Context, user *types.User) ([]string, error) {
+	codes := make([]string, s.numCodes)
+	hashes := make([]string, s.numCodes)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(b32.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+
+	if err := s.recoveryCodes.Replace(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes the TOTP secret and the recovery codes of the user
+func (s *MFA) Disable(ctx context.Context, user *types.User) error {
+	if err := s.users.DisableMFA(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.recoveryCodes.Replace(ctx, user.ID, nil)
+}
+
+// fail counts a wrong code of the user
+func (s *MFA) fail(ctx context.Context, user *types.User) error {
+	attempts, err := s.users.IncreaseMFAFailedAttempts(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if s.maxAttempts > 0 && attempts >= s.maxAttempts {
+		if err := s.users.ResetMFAFailedAttempts(ctx, user.ID); err != nil {
+			return err
+		}
+		return ErrTooManyAttempts
+	}
+	return ErrInvalidCode
+}
+
+// normalizeCode removes the separators and spaces which users may type
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,380 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/types"
+)
+
+// the SHA1 secret of the RFC 6238 test vectors, "12345678901234567890" base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var testConfig = config.MFA{EncryptionKey: "key", Issuer: "Tyr", RequiredRoles: []string{"admin"}, MaxAttempts: 3, RecoveryCodes: 4}
+
+// TestTOTPCode checks the codes against the SHA1 test vectors of RFC 6238 appendix B,
+// truncated to the last 6 digits
+func TestTOTPCode(t *testing.T) {
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		got, err := totpCode(rfcSecret, unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("at %d got %s, want %s", unix, got, want)
+		}
+		// lowercase secrets are accepted
+		if lower, _ := totpCode(strings.ToLower(rfcSecret), unix/totpPeriod); lower != want {
+			t.Errorf("at %d got %s for the lowercase secret, want %s", unix, lower, want)
+		}
+	}
+
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("got no error for an invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	code := func(step int64) string {
+		c, err := totpCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	for offset, wantOK := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		step, ok := validateTOTP(rfcSecret, code(current+offset), now)
+		if ok != wantOK || (ok && step != current+offset) {
+			t.Errorf("offset %d got step %d valid %v, want valid %v", offset, step, ok, wantOK)
+		}
+	}
+	for _, c := range []string{"", "05047", "0504711", "abcdef"} {
+		if _, ok := validateTOTP(rfcSecret, c, now); ok {
+			t.Errorf("code %q is valid", c)
+		}
+	}
+	if _, ok := validateTOTP("not base32!", code(current), now); ok {
+		t.Error("the code of an invalid secret is valid")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&fakeUsers{}, &fakeRecoveryCodes{}, config.MFA{}); err == nil {
+		t.Error("got no error without encryption key")
+	}
+
+	s, _, _, _ := newTestService(t)
+	if !s.Required("admin") || s.Required("user") {
+		t.Error("the required roles are not configured")
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	s, _, _, _ := newTestService(t)
+
+	first, err := s.encrypt(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.encrypt(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || strings.Contains(first, rfcSecret) {
+		t.Error("the secret is not encrypted with a random nonce")
+	}
+	for _, encrypted := range []string{first, second} {
+		if got, err := s.decrypt(encrypted); err != nil || got != rfcSecret {
+			t.Errorf("got %q, %v", got, err)
+		}
+	}
+
+	other, err := New(&fakeUsers{}, &fakeRecoveryCodes{}, config.MFA{EncryptionKey: "another key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(first)
+	raw[len(raw)-1] ^= 1
+	for name, tc := range map[string]struct {
+		svc       *MFA
+		encrypted string
+	}{
+		"another key": {svc: other, encrypted: first},
+		"tampered":    {svc: s, encrypted: base64.StdEncoding.EncodeToString(raw)},
+		"not base64":  {svc: s, encrypted: "not base64!"},
+		"too short":   {svc: s, encrypted: base64.StdEncoding.EncodeToString([]byte("short"))},
+	} {
+		if _, err := tc.svc.decrypt(tc.encrypted); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
+
+func TestEnrollAndActivate(t *testing.T) {
+	s, users, codes, clock := newTestService(t)
+	user := users.user
+
+	enrollment, err := s.Enroll(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.MFASecret == nil || strings.Contains(*user.MFASecret, enrollment.Secret) {
+		t.Fatal("the secret is not stored encrypted")
+	}
+	uri, err := url.Parse(enrollment.ProvisioningURI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Query().Get("secret") != enrollment.Secret || uri.Query().Get("issuer") != "Tyr" {
+		t.Errorf("got provisioning uri %s", enrollment.ProvisioningURI)
+	}
+
+	if _, err := s.Activate(context.Background(), user, "abcdef"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v, want ErrInvalidCode", err)
+	}
+
+	code, err := totpCode(enrollment.Secret, clock.now.Unix()/totpPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := s.Activate(context.Background(), user, code[:3]+" "+code[3:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.MFAEnabledAt == nil || user.MFALastStep != clock.now.Unix()/totpPeriod {
+		t.Error("the two-factor authentication is not enabled with the used step")
+	}
+	if len(recovery) != testConfig.RecoveryCodes || len(codes.hashes) != testConfig.RecoveryCodes {
+		t.Errorf("got %d recovery codes, want %d", len(recovery), testConfig.RecoveryCodes)
+	}
+
+	if _, err := s.Activate(context.Background(), user, code); !errors.Is(err, ErrAlreadyEnabled) {
+		t.Errorf("got %v, want ErrAlreadyEnabled", err)
+	}
+	if _, err := s.Enroll(context.Background(), user); !errors.Is(err, ErrAlreadyEnabled) {
+		t.Errorf("got %v, want ErrAlreadyEnabled", err)
+	}
+	if _, err := s.Activate(context.Background(), &types.User{}, code); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("got %v, want ErrNotEnrolled", err)
+	}
+}
+
+// TestVerifyReplay proves a TOTP code cannot be used twice, nor any code of an earlier step
+func TestVerifyReplay(t *testing.T) {
+	s, users, _, clock := newTestService(t)
+	user := users.enabled(t, s)
+	step := clock.now.Unix() / totpPeriod
+
+	previous, _ := totpCode(rfcSecret, step-1)
+	current, _ := totpCode(rfcSecret, step)
+
+	if err := s.Verify(context.Background(), user, current); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if user.MFALastStep != step {
+		t.Errorf("got last step %d, want %d", user.MFALastStep, step)
+	}
+	if err := s.Verify(context.Background(), user, current); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("replaying got %v, want ErrInvalidCode", err)
+	}
+	// still within the skew, but older than the used one
+	if err := s.Verify(context.Background(), user, previous); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("the code of the earlier step got %v, want ErrInvalidCode", err)
+	}
+
+	clock.add(totpPeriod * time.Second)
+	next, _ := totpCode(rfcSecret, step+1)
+	if err := s.Verify(context.Background(), user, next); err != nil {
+		t.Errorf("the code of the next step got %v", err)
+	}
+}
+
+func TestVerifyRecoveryCodes(t *testing.T) {
+	s, users, codes, _ := newTestService(t)
+	user := users.enabled(t, s)
+
+	first, err := s.RegenerateRecoveryCodes(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}$`)
+	for _, c := range first {
+		if !format.MatchString(c) {
+			t.Errorf("got recovery code %q", c)
+		}
+		if codes.hashes[hashRecoveryCode(c)] {
+			t.Error("the recovery code is stored with the separator")
+		}
+	}
+
+	// the code is typed without separator, uppercase
+	if err := s.Verify(context.Background(), user, " "+strings.ToUpper(strings.ReplaceAll(first[0], "-", ""))+" "); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if err := s.Verify(context.Background(), user, first[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("using the code again got %v, want ErrInvalidCode", err)
+	}
+
+	second, err := s.RegenerateRecoveryCodes(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(context.Background(), user, first[1]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("the replaced code got %v, want ErrInvalidCode", err)
+	}
+	if err := s.Verify(context.Background(), user, second[1]); err != nil {
+		t.Errorf("the new code got %v", err)
+	}
+
+	if err := s.Disable(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	if len(codes.hashes) != 0 || user.MFASecret != nil || user.MFAEnabledAt != nil {
+		t.Error("the secret and the recovery codes are not removed")
+	}
+	if err := s.Verify(context.Background(), user, second[2]); !errors.Is(err, ErrNotEnabled) {
+		t.Errorf("got %v, want ErrNotEnabled", err)
+	}
+}
+
+func TestVerifyTooManyAttempts(t *testing.T) {
+	s, users, _, clock := newTestService(t)
+	user := users.enabled(t, s)
+
+	for i := 1; i < testConfig.MaxAttempts; i++ {
+		if err := s.Verify(context.Background(), user, "aaaa-aaaa"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d got %v, want ErrInvalidCode", i, err)
+		}
+	}
+	// a wrong TOTP code counts as well
+	if err := s.Verify(context.Background(), user, "abcdef"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("got %v, want ErrTooManyAttempts", err)
+	}
+	if user.MFAFailedAttempts != 0 {
+		t.Errorf("got %d failed attempts, want them reset for the caller to invalidate what they guard", user.MFAFailedAttempts)
+	}
+
+	// a successful verification resets the failed attempts
+	if err := s.Verify(context.Background(), user, "aaaa-aaaa"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatal(err)
+	}
+	code, _ := totpCode(rfcSecret, clock.now.Unix()/totpPeriod)
+	if err := s.Verify(context.Background(), user, code); err != nil {
+		t.Fatal(err)
+	}
+	if user.MFAFailedAttempts != 0 {
+		t.Errorf("got %d failed attempts after a success, want 0", user.MFAFailedAttempts)
+	}
+}
+
+func newTestService(t *testing.T) (*MFA, *fakeUsers, *fakeRecoveryCodes, *clock) {
+	t.Helper()
+
+	clock := &clock{now: time.Unix(1111111111, 0)}
+	users := &fakeUsers{user: &types.User{Base: types.Base{ID: "01HUSER00000000000000000000"}, Email: "user@tyr.io"}}
+	codes := &fakeRecoveryCodes{hashes: map[string]bool{}}
+	s, err := New(users, codes, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return clock.now }
+
+	return s, users, codes, clock
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeUsers stores the two-factor authentication of a single user as the users table does
+type fakeUsers struct {
+	user *types.User
+}
+
+// enabled enables the two-factor authentication of the user with the RFC 6238 secret
+func (r *fakeUsers) enabled(t *testing.T, s *MFA) *types.User {
+	t.Helper()
+	encrypted, err := s.encrypt(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := s.now()
+	r.user.MFASecret, r.user.MFAEnabledAt = &encrypted, &now
+	return r.user
+}
+
+func (r *fakeUsers) SetMFASecret(_ context.Context, _, secret string) error {
+	r.user.MFASecret = &secret
+	return nil
+}
+
+func (r *fakeUsers) EnableMFA(_ context.Context, _ string, step int64) (bool, error) {
+	if r.user.MFAEnabledAt != nil || r.user.MFASecret == nil {
+		return false, nil
+	}
+	now := time.Now()
+	r.user.MFAEnabledAt, r.user.MFALastStep, r.user.MFAFailedAttempts = &now, step, 0
+	return true, nil
+}
+
+func (r *fakeUsers) DisableMFA(context.Context, string) error {
+	r.user.MFASecret, r.user.MFAEnabledAt, r.user.MFALastStep, r.user.MFAFailedAttempts = nil, nil, 0, 0
+	return nil
+}
+
+func (r *fakeUsers) UseMFAStep(_ context.Context, _ string, step int64) (bool, error) {
+	if step <= r.user.MFALastStep {
+		return false, nil
+	}
+	r.user.MFALastStep = step
+	return true, nil
+}
+
+func (r *fakeUsers) IncreaseMFAFailedAttempts(context.Context, string) (int, error) {
+	r.user.MFAFailedAttempts++
+	return r.user.MFAFailedAttempts, nil
+}
+
+func (r *fakeUsers) ResetMFAFailedAttempts(context.Context, string) error {
+	r.user.MFAFailedAttempts = 0
+	return nil
+}
+
+// fakeRecoveryCodes stores the recovery code hashes of a single user
+type fakeRecoveryCodes struct {
+	hashes map[string]bool
+}
+
+func (r *fakeRecoveryCodes) Replace(_ context.Context, _ string, codeHashes []string) error {
+	r.hashes = map[string]bool{}
+	for _, h := range codeHashes {
+		r.hashes[h] = true
+	}
+	return nil
+}
+
+func (r *fakeRecoveryCodes) Use(_ context.Context, _, codeHash string) (bool, error) {
+	if !r.hashes[codeHash] {
+		return false, nil
+	}
+	delete(r.hashes, codeHash)
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package mfa
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"tyr/config"
+)
+
+// New creates new TOTP two-factor authentication service
+func New(users UserRepository, recoveryCodes RecoveryCodeRepository, cfg config.MFA) (*MFA, error) {
+	if cfg.EncryptionKey == "" {
+		return nil, errors.New("mfa: encryption key is required")
+	}
+
+	key := sha256.Sum256([]byte(cfg.EncryptionKey))
+	requiredRoles := make(map[string]bool, len(cfg.RequiredRoles))
+	for _, role := range cfg.RequiredRoles {
+		requiredRoles[role] = true
+	}
+
+	return &MFA{
+		users:         users,
+		recoveryCodes: recoveryCodes,
+		key:           key[:],
+		issuer:        cfg.Issuer,
+		requiredRoles: requiredRoles,
+		challengeTTL:  time.Duration(cfg.ChallengeTTL) * time.Second,
+		maxAttempts:   cfg.MaxAttempts,
+		numCodes:      cfg.RecoveryCodes,
+		now:           time.Now,
+	}, nil
+}
+
+// MFA represents TOTP two-factor authentication service
+type MFA struct {
+	users         UserRepository
+	recoveryCodes RecoveryCodeRepository
+	key           []byte
+	issuer        string
+	requiredRoles map[string]bool
+	challengeTTL  time.Duration
+	maxAttempts   int
+	numCodes      int
+
+	now func() time.Time
+}
+
+// UserRepository represents the TOTP secret storage interface
+type UserRepository interface {
+	SetMFASecret(ctx context.Context, userID, secret string) error
+	EnableMFA(ctx context.Context, userID string, step int64) (bool, error)
+	DisableMFA(ctx context.Context, userID string) error
+	UseMFAStep(ctx context.Context, userID string, step int64) (bool, error)
+	IncreaseMFAFailedAttempts(ctx context.Context, userID string) (int, error)
+	ResetMFAFailedAttempts(ctx context.Context, userID string) error
+}
+
+// RecoveryCodeRepository represents the recovery code storage interface
+type RecoveryCodeRepository interface {
+	Replace(ctx context.Context, userID string, codeHashes []string) error
+	Use(ctx context.Context, userID, codeHash string) (bool, error)
+}
@@ -0,0 +1,70 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"time"
+
+	"tyr/internal/jwtkeys"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// FakeIssuer is a local OIDC issuer signing ID tokens with an in-memory RSA key, for development and tests.
+// It serves its JSON Web Key Set over HTTP, eg: with httptest.NewServer, to be used as `jwks_url` of a provider.
+type FakeIssuer struct {
+	Issuer string
+
+	kid string
+	key *rsa.PrivateKey
+}
+
+// NewFakeIssuer returns a fake issuer with a new random key
+func NewFakeIssuer(issuer string) (*FakeIssuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &FakeIssuer{Issuer: issuer, kid: "fake-" + time.Now().Format("20060102150405"), key: key}, nil
+}
+
+// IDToken issues an ID token of the subject for the audience, the extra claims such as `email` are merged
+func (f *FakeIssuer) IDToken(audience, subject string, extra map[string]any, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": f.Issuer,
+		"aud": audience,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = f.kid
+	return token.SignedString(f.key)
+}
+
+// JWKS returns the public key set of the issuer
+func (f *FakeIssuer) JWKS() jwtkeys.JWKSet {
+	return jwtkeys.JWKSet{Keys: []jwtkeys.JWK{{
+		KeyType:   "RSA",
+		KeyID:     f.kid,
+		Algorithm: jwt.SigningMethodRS256.Alg(),
+		Use:       "sig",
+		N:         base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+	}}}
+}
+
+// ServeHTTP serves the public key set of the issuer
+func (f *FakeIssuer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(f.JWKS())
+}
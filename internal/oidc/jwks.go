@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"tyr/internal/jwtkeys"
+)
+
+// minRefetchInterval limits refetching the keys on unknown `kid`, so that forged tokens cannot flood the issuer
+const minRefetchInterval = time.Minute
+
+// key returns the public key of the provider by the kid, the keys are refetched when the cache is stale or the kid is unknown
+func (s *Service) key(ctx context.Context, p *provider, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := s.now()
+	stale := p.keys == nil || now.Sub(p.fetchedAt) > s.cacheTTL
+	if k, ok := p.keys[kid]; ok && !stale {
+		return k, nil
+	}
+	if !stale && now.Sub(p.fetchedAt) < minRefetchInterval {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+
+	keys, err := s.fetchKeys(ctx, p.JWKSURL)
+	if err != nil {
+		// keep using the cached keys while the issuer is unavailable
+		if k, ok := p.keys[kid]; ok {
+			return k, nil
+		}
+		return nil, err
+	}
+	p.keys = keys
+	p.fetchedAt = now
+
+	k, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return k, nil
+}
+
+// fetchKeys downloads and parses the JSON Web Key Set, the unsupported keys are skipped
+func (s *Service) fetchKeys(ctx context.Context, url string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching jwks: status %d", resp.StatusCode)
+	}
+
+	set := jwtkeys.JWKSet{}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("error decoding jwks: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if k, err := publicKey(jwk); err == nil {
+			keys[jwk.KeyID] = k
+		}
+	}
+	return keys, nil
+}
+
+// publicKey converts the RSA or P-256 EC JWK to the public key
+func publicKey(jwk jwtkeys.JWK) (any, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
@@ -0,0 +1,90 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/samber/lo"
+)
+
+// Custom errors
+var (
+	ErrUnsupportedProvider = errors.New("unsupported oidc provider")
+	ErrInvalidIDToken      = errors.New("invalid id token")
+)
+
+// Verify validates the ID token issued by the provider: the signature against the keys of the issuer,
+// the issuer, the audience against the client IDs and the expiry.
+// The nonce is required so that a leaked ID token cannot be replayed, the `nonce` claim must be either the nonce
+// or its hex encoded SHA-256 hash as Apple does.
+func (s *Service) Verify(ctx context.Context, providerName, idToken, nonce string) (*Claims, error) {
+	p, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnsupportedProvider
+	}
+	if nonce == "" {
+		return nil, fmt.Errorf("%w: nonce required", ErrInvalidIDToken)
+	}
+
+	token, err := jwt.Parse(idToken, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.key(ctx, p, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	mc, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	aud, err := mc.GetAudience()
+	if err != nil || !lo.Some(aud, p.ClientIDs) {
+		return nil, fmt.Errorf("%w: audience not accepted", ErrInvalidIDToken)
+	}
+
+	claimed, _ := mc["nonce"].(string)
+	hashed := sha256.Sum256([]byte(nonce))
+	if subtle.ConstantTimeCompare([]byte(claimed), []byte(nonce)) != 1 &&
+		subtle.ConstantTimeCompare([]byte(claimed), []byte(hex.EncodeToString(hashed[:]))) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatched", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{Provider: p.Name}
+	claims.Subject, _ = mc["sub"].(string)
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	claims.Email, _ = mc["email"].(string)
+	claims.GivenName, _ = mc["given_name"].(string)
+	claims.FamilyName, _ = mc["family_name"].(string)
+	claims.Name, _ = mc["name"].(string)
+	// Apple sends the boolean claims as strings
+	switch v := mc["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string:
+		claims.EmailVerified, _ = strconv.ParseBool(v)
+	}
+	if iat, err := mc.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+
+	return claims, nil
+}
+
+// Providers returns the names of the configured providers
+func (s *Service) Providers() []string {
+	return lo.Keys(s.providers)
+}
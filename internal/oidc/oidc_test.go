@@ -0,0 +1,244 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tyr/config"
+)
+
+const (
+	testIssuer   = "https://issuer.test"
+	testClientID = "app.tyr"
+	testNonce    = "n-0S6_WzA2Mj"
+)
+
+func TestNew(t *testing.T) {
+	for name, providers := range map[string]string{
+		"invalid json":     "{",
+		"missing name":     `[{"issuer":"i","jwks_url":"u","client_ids":["c"]}]`,
+		"duplicated name":  `[{"name":"a","issuer":"i","jwks_url":"u","client_ids":["c"]},{"name":"a","issuer":"i","jwks_url":"u","client_ids":["c"]}]`,
+		"missing issuer":   `[{"name":"a","jwks_url":"u","client_ids":["c"]}]`,
+		"missing jwks url": `[{"name":"a","issuer":"i","client_ids":["c"]}]`,
+		"no client ids":    `[{"name":"a","issuer":"i","jwks_url":"u"}]`,
+	} {
+		if _, err := New(config.OIDC{Providers: providers}); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+
+	s, err := New(config.OIDC{})
+	if err != nil || len(s.Providers()) != 0 {
+		t.Errorf("got %v with providers %v, want no provider", err, s.Providers())
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s, issuer, _, _ := newTestService(t)
+	hashedNonce := sha256.Sum256([]byte(testNonce))
+
+	cases := []struct {
+		name     string
+		provider string
+		audience string
+		extra    map[string]any
+		ttl      time.Duration
+		nonce    string
+		token    func(string) string
+		want     *Claims
+		wantErr  error
+	}{
+		{
+			name:  "valid",
+			extra: map[string]any{"nonce": testNonce, "email": "user@tyr.io", "email_verified": true, "given_name": "John", "family_name": "Doe", "name": "John Doe"},
+			want:  &Claims{Provider: "fake", Subject: "sub-1", Email: "user@tyr.io", EmailVerified: true, GivenName: "John", FamilyName: "Doe", Name: "John Doe"},
+		},
+		{
+			name:  "hashed nonce and boolean as string, as Apple does",
+			extra: map[string]any{"nonce": hex.EncodeToString(hashedNonce[:]), "email": "user@tyr.io", "email_verified": "true"},
+			want:  &Claims{Provider: "fake", Subject: "sub-1", Email: "user@tyr.io", EmailVerified: true},
+		},
+		{name: "another accepted audience", audience: "web.tyr", extra: map[string]any{"nonce": testNonce}, want: &Claims{Provider: "fake", Subject: "sub-1"}},
+		{name: "unsupported provider", provider: "other", extra: map[string]any{"nonce": testNonce}, wantErr: ErrUnsupportedProvider},
+		{name: "another issuer", extra: map[string]any{"nonce": testNonce, "iss": "https://evil.test"}, wantErr: ErrInvalidIDToken},
+		{name: "another audience", audience: "evil.app", extra: map[string]any{"nonce": testNonce}, wantErr: ErrInvalidIDToken},
+		{name: "expired", ttl: -time.Minute, extra: map[string]any{"nonce": testNonce}, wantErr: ErrInvalidIDToken},
+		{name: "without expiry", extra: map[string]any{"nonce": testNonce, "exp": nil}, wantErr: ErrInvalidIDToken},
+		{name: "without subject", extra: map[string]any{"nonce": testNonce, "sub": ""}, wantErr: ErrInvalidIDToken},
+		{name: "nonce not sent", nonce: "-", extra: map[string]any{"nonce": testNonce}, wantErr: ErrInvalidIDToken},
+		{name: "nonce not claimed", wantErr: ErrInvalidIDToken},
+		{name: "another nonce", extra: map[string]any{"nonce": "replayed"}, wantErr: ErrInvalidIDToken},
+		{name: "hash of the hashed nonce", nonce: hex.EncodeToString(hashedNonce[:]), extra: map[string]any{"nonce": testNonce}, wantErr: ErrInvalidIDToken},
+		{name: "tampered signature", extra: map[string]any{"nonce": testNonce}, token: func(s string) string { return s[:len(s)-4] + "AAAA" }, wantErr: ErrInvalidIDToken},
+		{name: "not a token", extra: map[string]any{"nonce": testNonce}, token: func(string) string { return "not a token" }, wantErr: ErrInvalidIDToken},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, audience, ttl, nonce := "fake", testClientID, time.Hour, testNonce
+			if tc.provider != "" {
+				provider = tc.provider
+			}
+			if tc.audience != "" {
+				audience = tc.audience
+			}
+			if tc.ttl != 0 {
+				ttl = tc.ttl
+			}
+			switch tc.nonce {
+			case "-":
+				nonce = ""
+			case "":
+			default:
+				nonce = tc.nonce
+			}
+
+			token, err := issuer.IDToken(audience, "sub-1", tc.extra, ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.token != nil {
+				token = tc.token(token)
+			}
+
+			got, err := s.Verify(context.Background(), provider, token, nonce)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if got.IssuedAt.IsZero() {
+				t.Error("the issued time is not set")
+			}
+			got.IssuedAt = time.Time{}
+			if *got != *tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestVerifyKeyRotation proves the keys are cached, refetched when the issuer rotates them,
+// without letting unknown kids flood the issuer, and kept while the issuer is unavailable
+func TestVerifyKeyRotation(t *testing.T) {
+	s, issuer, jwks, clock := newTestService(t)
+
+	verify := func(issuer *FakeIssuer) error {
+		token, err := issuer.IDToken(testClientID, "sub-1", map[string]any{"nonce": testNonce}, 3*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Verify(context.Background(), "fake", token, testNonce)
+		return err
+	}
+
+	if err := verify(issuer); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(issuer); err != nil {
+		t.Fatal(err)
+	}
+	if jwks.fetches.Load() != 1 {
+		t.Errorf("fetched the keys %d times, want them cached", jwks.fetches.Load())
+	}
+
+	// the issuer rotates its key
+	rotated, err := NewFakeIssuer(testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated.kid = "rotated"
+	jwks.issuer.Store(rotated)
+
+	if err := verify(rotated); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("got %v right after the last fetch, want ErrInvalidIDToken", err)
+	}
+	if jwks.fetches.Load() != 1 {
+		t.Error("the keys are refetched for an unknown kid right after the last fetch")
+	}
+
+	clock.add(minRefetchInterval)
+	if err := verify(rotated); err != nil {
+		t.Fatalf("got %v, want the rotated key fetched", err)
+	}
+	if jwks.fetches.Load() != 2 {
+		t.Errorf("fetched the keys %d times, want twice", jwks.fetches.Load())
+	}
+	// the previous key is not published anymore
+	clock.add(minRefetchInterval)
+	if err := verify(issuer); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("got %v for the removed key, want ErrInvalidIDToken", err)
+	}
+
+	// the cached keys are kept while the issuer is unavailable
+	jwks.down.Store(true)
+	clock.add(time.Hour)
+	if err := verify(rotated); err != nil {
+		t.Errorf("got %v while the issuer is unavailable, want the cached key", err)
+	}
+	if err := verify(issuer); err == nil {
+		t.Error("got no error for an unknown key while the issuer is unavailable")
+	}
+}
+
+func newTestService(t *testing.T) (*Service, *FakeIssuer, *jwksServer, *clock) {
+	t.Helper()
+
+	issuer, err := NewFakeIssuer(testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := &jwksServer{}
+	jwks.issuer.Store(issuer)
+	srv := httptest.NewServer(jwks)
+	t.Cleanup(srv.Close)
+
+	providers, err := json.Marshal([]ProviderConfig{{Name: "fake", Issuer: testIssuer, JWKSURL: srv.URL, ClientIDs: []string{testClientID, "web.tyr"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(config.OIDC{Providers: string(providers), JWKSCacheTTL: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &clock{now: time.Now()}
+	s.now = func() time.Time { return clock.now }
+
+	return s, issuer, jwks, clock
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// jwksServer serves the keys of the current issuer, counting the fetches
+type jwksServer struct {
+	issuer  atomic.Pointer[FakeIssuer]
+	fetches atomic.Int32
+	down    atomic.Bool
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	s.fetches.Add(1)
+	s.issuer.Load().ServeHTTP(w, r)
+}
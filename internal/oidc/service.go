@@ -0,0 +1,59 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"tyr/config"
+)
+
+// New creates new OIDC client service verifying the ID tokens of the providers of the configuration
+func New(cfg config.OIDC) (*Service, error) {
+	s := &Service{
+		providers: map[string]*provider{},
+		client:    &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:  time.Duration(cfg.JWKSCacheTTL) * time.Second,
+		now:       time.Now,
+	}
+	if strings.TrimSpace(cfg.Providers) == "" {
+		return s, nil
+	}
+
+	pcs := []ProviderConfig{}
+	if err := json.Unmarshal([]byte(cfg.Providers), &pcs); err != nil {
+		return nil, fmt.Errorf("invalid OIDC_PROVIDERS: %w", err)
+	}
+	for _, pc := range pcs {
+		if pc.Name == "" || s.providers[pc.Name] != nil {
+			return nil, fmt.Errorf("missing or duplicated provider name %q", pc.Name)
+		}
+		if pc.Issuer == "" || pc.JWKSURL == "" || len(pc.ClientIDs) == 0 {
+			return nil, fmt.Errorf("provider %q requires issuer, jwks_url and client_ids", pc.Name)
+		}
+		s.providers[pc.Name] = &provider{ProviderConfig: pc}
+	}
+
+	return s, nil
+}
+
+// Service represents OIDC client service
+type Service struct {
+	providers map[string]*provider
+	client    *http.Client
+	cacheTTL  time.Duration
+
+	now func() time.Time
+}
+
+// provider is a configured provider with its cached keys
+type provider struct {
+	ProviderConfig
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+}
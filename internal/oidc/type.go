@@ -0,0 +1,27 @@
+package oidc
+
+import "time"
+
+// ProviderConfig represents a provider entry of the `OIDC_PROVIDERS` configuration
+type ProviderConfig struct {
+	// Name of the provider used by the clients, eg: google, apple
+	Name string `json:"name"`
+	// Expected `iss` claim of the ID tokens, eg: https://accounts.google.com
+	Issuer string `json:"issuer"`
+	// URL of the JSON Web Key Set of the issuer
+	JWKSURL string `json:"jwks_url"`
+	// Accepted `aud` claims, the client IDs of the apps
+	ClientIDs []string `json:"client_ids"`
+}
+
+// Claims represents the verified claims of an ID token
+type Claims struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+	IssuedAt      time.Time
+}
@@ -0,0 +1,140 @@
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"tyr/internal/types"
+	"tyr/third_party/sms"
+)
+
+// Custom errors
+var (
+	ErrNotRequested     = errors.New("otp not requested")
+	ErrResendCooldown   = errors.New("otp resend cooldown")
+	ErrExpired          = errors.New("otp expired")
+	ErrInvalid          = errors.New("invalid otp")
+	ErrTooManyAttempts  = errors.New("too many otp attempts")
+	ErrPhoneNotProvided = errors.New("phone not provided")
+)
+
+// Sent contains the timing of the sent one-time password
+type Sent struct {
+	// Time until the password expires
+	ExpiresIn time.Duration
+	// Time until another password can be sent
+	ResendIn time.Duration
+}
+
+// Send generates a new one-time password of the user and texts it to the user's phone, the previous one is replaced.
+// Another password cannot be sent until the resend cooldown is over, ErrResendCooldown is returned with the remaining time then.
+func (s *OTP) Send(ctx context.Context, user *types.User) (*Sent, error) {
+	if user.Phone == "" {
+		return nil, ErrPhoneNotProvided
+	}
+
+	now := s.now()
+	if user.OTPSentAt != nil {
+		if wait := user.OTPSentAt.Add(s.resendCooldown).Sub(now); wait > 0 {
+			return &Sent{ExpiresIn: user.OTPSentAt.Add(s.ttl).Sub(now), ResendIn: wait}, ErrResendCooldown
+		}
+	}
+
+	code, err := s.generate()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetOTP(ctx, user.ID, s.hash(user.ID, code), now); err != nil {
+		return nil, err
+	}
+
+	if err := s.sender.Send(ctx, &sms.Message{
+		To:   user.Phone,
+		Text: fmt.Sprintf("Your Tyr verification code is %s. It expires in %d minutes, do not share it with anyone.", code, int((s.ttl+time.Minute-1)/time.Minute)),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &Sent{ExpiresIn: s.ttl, ResendIn: s.resendCooldown}, nil
+}
+
+// Verify checks the one-time password of the user, which can be used only once.
+// The password is invalidated after too many wrong attempts, so that it cannot be brute forced.
+func (s *OTP) Verify(ctx context.Context, user *types.User, code string) error {
+	if user.OTP == nil || user.OTPSentAt == nil {
+		return ErrNotRequested
+	}
+	if s.now().After(user.OTPSentAt.Add(s.ttl)) {
+		return ErrExpired
+	}
+	if s.maxAttempts > 0 && user.OTPAttempts >= s.maxAttempts {
+		return ErrTooManyAttempts
+	}
+
+	if !hmac.Equal([]byte(*user.OTP), []byte(s.hash(user.ID, code))) {
+		attempts, err := s.repo.IncreaseOTPAttempts(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if s.maxAttempts > 0 && attempts >= s.maxAttempts {
+			if _, err := s.repo.ClearOTP(ctx, user.ID, *user.OTP); err != nil {
+				return err
+			}
+			return ErrTooManyAttempts
+		}
+		return ErrInvalid
+	}
+
+	// only one of concurrent verifications with the same password can succeed
+	cleared, err := s.repo.ClearOTP(ctx, user.ID, *user.OTP)
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return ErrInvalid
+	}
+
+	return nil
+}
+
+// TTL returns the lifetime of one-time passwords
+func (s *OTP) TTL() time.Duration {
+	return s.ttl
+}
+
+// ResendCooldown returns the minimum time between two sent one-time passwords
+func (s *OTP) ResendCooldown() time.Duration {
+	return s.resendCooldown
+}
+
+// generate returns a random numeric password
+func (s *OTP) generate() (string, error) {
+	length := s.length
+	if length <= 0 {
+		length = 6
+	}
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// hash returns the hex encoded HMAC-SHA256 of the password bound to the user,
+// the short numeric passwords could be brute forced from plain hashes
+func (s *OTP) hash(userID, code string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(userID + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,300 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/types"
+	"tyr/third_party/sms"
+)
+
+const testUserID = "01HUSER00000000000000000000"
+
+var testConfig = config.OTP{Length: 6, TTL: 300, ResendCooldown: 60, MaxAttempts: 3}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&fakeRepo{}, &fakeSender{}, testConfig, ""); err == nil {
+		t.Error("got no error without secret")
+	}
+}
+
+func TestSend(t *testing.T) {
+	s, repo, sender, clock := newTestService(t)
+	user := repo.user
+
+	sent, err := s.Send(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.ExpiresIn != 5*time.Minute || sent.ResendIn != time.Minute {
+		t.Errorf("got %+v", sent)
+	}
+
+	code := sender.lastCode(t)
+	if len(code) != testConfig.Length {
+		t.Errorf("got code %q, want %d digits", code, testConfig.Length)
+	}
+	if sender.msgs[0].To != user.Phone || !strings.Contains(sender.msgs[0].Text, "expires in 5 minutes") {
+		t.Errorf("got message %+v", sender.msgs[0])
+	}
+	// only the keyed hash bound to the user is stored
+	if user.OTP == nil || *user.OTP == code || *user.OTP != s.hash(user.ID, code) || *user.OTP == s.hash("another", code) {
+		t.Error("the stored hash is not the keyed hash of the code bound to the user")
+	}
+	if !user.OTPSentAt.Equal(clock.now) {
+		t.Errorf("got sent at %v, want %v", user.OTPSentAt, clock.now)
+	}
+
+	// during the cooldown nothing is sent, the remaining time is returned
+	clock.add(20 * time.Second)
+	sent, err = s.Send(context.Background(), user)
+	if !errors.Is(err, ErrResendCooldown) {
+		t.Fatalf("got %v, want ErrResendCooldown", err)
+	}
+	if sent.ResendIn != 40*time.Second || sent.ExpiresIn != 280*time.Second {
+		t.Errorf("got %+v, want the remaining times", sent)
+	}
+	if len(sender.msgs) != 1 {
+		t.Error("a code is sent during the cooldown")
+	}
+
+	// another code replaces the previous one after the cooldown
+	clock.add(40 * time.Second)
+	if _, err := s.Send(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	if next := sender.lastCode(t); *user.OTP != s.hash(user.ID, next) {
+		t.Error("the code is not replaced")
+	}
+
+	if _, err := s.Send(context.Background(), &types.User{Base: types.Base{ID: testUserID}}); !errors.Is(err, ErrPhoneNotProvided) {
+		t.Errorf("got %v, want ErrPhoneNotProvided", err)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	s, repo, sender, _ := newTestService(t)
+	sender.err = errors.New("down")
+
+	if _, err := s.Send(context.Background(), repo.user); !errors.Is(err, sender.err) {
+		t.Errorf("got %v, want the sender error", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s, repo, sender, _ := newTestService(t)
+	user := repo.user
+
+	if err := s.Verify(context.Background(), user, "123456"); !errors.Is(err, ErrNotRequested) {
+		t.Fatalf("got %v, want ErrNotRequested", err)
+	}
+
+	if _, err := s.Send(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	code := sender.lastCode(t)
+
+	if err := s.Verify(context.Background(), user, wrong(code)); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("got %v, want ErrInvalid", err)
+	}
+	if user.OTPAttempts != 1 {
+		t.Errorf("got %d attempts, want 1", user.OTPAttempts)
+	}
+
+	if err := s.Verify(context.Background(), user, code); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if user.OTP != nil {
+		t.Error("the code is not cleared once used")
+	}
+	if err := s.Verify(context.Background(), user, code); !errors.Is(err, ErrNotRequested) {
+		t.Errorf("using the code again got %v, want ErrNotRequested", err)
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	s, repo, sender, clock := newTestService(t)
+	if _, err := s.Send(context.Background(), repo.user); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.add(5 * time.Minute)
+	if err := s.Verify(context.Background(), repo.user, sender.lastCode(t)); err != nil {
+		t.Fatalf("got %v at the expiry", err)
+	}
+
+	if _, err := s.Send(context.Background(), repo.user); err != nil {
+		t.Fatal(err)
+	}
+	clock.add(5*time.Minute + time.Second)
+	if err := s.Verify(context.Background(), repo.user, sender.lastCode(t)); !errors.Is(err, ErrExpired) {
+		t.Errorf("got %v, want ErrExpired", err)
+	}
+}
+
+// TestVerifyTooManyAttempts proves the code is invalidated after too many wrong attempts,
+// even the right code is rejected then
+func TestVerifyTooManyAttempts(t *testing.T) {
+	s, repo, sender, _ := newTestService(t)
+	user := repo.user
+	if _, err := s.Send(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	code := sender.lastCode(t)
+	// the user loaded before the attempts
+	loaded := *user
+
+	for i := 1; i < testConfig.MaxAttempts; i++ {
+		if err := s.Verify(context.Background(), user, wrong(code)); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("attempt %d got %v, want ErrInvalid", i, err)
+		}
+	}
+	if err := s.Verify(context.Background(), user, wrong(code)); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("got %v, want ErrTooManyAttempts", err)
+	}
+	if user.OTP != nil {
+		t.Error("the code is not invalidated")
+	}
+
+	// the user loaded before still holds the code
+	if err := s.Verify(context.Background(), &loaded, code); !errors.Is(err, ErrInvalid) {
+		t.Errorf("the invalidated code got %v, want ErrInvalid", err)
+	}
+
+	loaded.OTPAttempts = testConfig.MaxAttempts
+	calls := repo.calls
+	if err := s.Verify(context.Background(), &loaded, code); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("got %v, want ErrTooManyAttempts", err)
+	}
+	if repo.calls != calls {
+		t.Error("the code is checked after too many attempts")
+	}
+}
+
+// TestVerifyConcurrently proves only one of the concurrent verifications of a code succeeds
+func TestVerifyConcurrently(t *testing.T) {
+	s, repo, sender, _ := newTestService(t)
+	if _, err := s.Send(context.Background(), repo.user); err != nil {
+		t.Fatal(err)
+	}
+	code := sender.lastCode(t)
+	first, second := *repo.user, *repo.user
+
+	if err := s.Verify(context.Background(), &first, code); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(context.Background(), &second, code); !errors.Is(err, ErrInvalid) {
+		t.Errorf("got %v, want ErrInvalid", err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s, _, _, _ := newTestService(t)
+	digits := regexp.MustCompile(`^[0-9]+$`)
+
+	for _, length := range []int{0, 4, 8} {
+		s.length = length
+		code, err := s.generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := length
+		if want == 0 {
+			want = 6
+		}
+		if len(code) != want || !digits.MatchString(code) {
+			t.Errorf("length %d got %q", length, code)
+		}
+	}
+}
+
+func newTestService(t *testing.T) (*OTP, *fakeRepo, *fakeSender, *clock) {
+	t.Helper()
+
+	clock := &clock{now: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)}
+	repo := &fakeRepo{user: &types.User{Base: types.Base{ID: testUserID}, Phone: "+84900000000"}}
+	sender := &fakeSender{}
+	s, err := New(repo, sender, testConfig, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return clock.now }
+
+	return s, repo, sender, clock
+}
+
+// wrong returns another code of the same length
+func wrong(code string) string {
+	if code[0] == '0' {
+		return "1" + code[1:]
+	}
+	return "0" + code[1:]
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeRepo stores the one-time password of a single user as the users table does
+type fakeRepo struct {
+	user  *types.User
+	calls int
+}
+
+func (r *fakeRepo) SetOTP(_ context.Context, _, otpHash string, sentAt time.Time) error {
+	r.calls++
+	r.user.OTP, r.user.OTPSentAt, r.user.OTPAttempts = &otpHash, &sentAt, 0
+	return nil
+}
+
+func (r *fakeRepo) ClearOTP(_ context.Context, _, otpHash string) (bool, error) {
+	r.calls++
+	if r.user.OTP == nil || *r.user.OTP != otpHash {
+		return false, nil
+	}
+	r.user.OTP, r.user.OTPSentAt, r.user.OTPAttempts = nil, nil, 0
+	return true, nil
+}
+
+func (r *fakeRepo) IncreaseOTPAttempts(context.Context, string) (int, error) {
+	r.calls++
+	r.user.OTPAttempts++
+	return r.user.OTPAttempts, nil
+}
+
+// fakeSender records the sent messages
+type fakeSender struct {
+	msgs []*sms.Message
+	err  error
+}
+
+var codeRe = regexp.MustCompile(`code is ([0-9]+)\.`)
+
+func (s *fakeSender) Send(_ context.Context, msg *sms.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func (s *fakeSender) lastCode(t *testing.T) string {
+	t.Helper()
+	if len(s.msgs) == 0 {
+		t.Fatal("no code sent")
+	}
+	m := codeRe.FindStringSubmatch(s.msgs[len(s.msgs)-1].Text)
+	if m == nil {
+		t.Fatalf("no code in %q", s.msgs[len(s.msgs)-1].Text)
+	}
+	return m[1]
+}
@@ -0,0 +1,53 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tyr/config"
+	"tyr/third_party/sms"
+)
+
+// New creates new one-time password service hashing the passwords with the given secret
+func New(repo Repository, sender Sender, cfg config.OTP, secret string) (*OTP, error) {
+	if secret == "" {
+		return nil, errors.New("otp: secret is required")
+	}
+
+	return &OTP{
+		repo:           repo,
+		sender:         sender,
+		length:         cfg.Length,
+		ttl:            time.Duration(cfg.TTL) * time.Second,
+		resendCooldown: time.Duration(cfg.ResendCooldown) * time.Second,
+		maxAttempts:    cfg.MaxAttempts,
+		secret:         []byte(secret),
+		now:            time.Now,
+	}, nil
+}
+
+// OTP represents one-time password service
+type OTP struct {
+	repo           Repository
+	sender         Sender
+	length         int
+	ttl            time.Duration
+	resendCooldown time.Duration
+	maxAttempts    int
+	secret         []byte
+
+	now func() time.Time
+}
+
+// Repository represents the one-time password storage interface
+type Repository interface {
+	SetOTP(ctx context.Context, userID, otpHash string, sentAt time.Time) error
+	ClearOTP(ctx context.Context, userID, otpHash string) (bool, error)
+	IncreaseOTPAttempts(ctx context.Context, userID string) (int, error)
+}
+
+// Sender represents text message sender interface
+type Sender interface {
+	Send(ctx context.Context, msg *sms.Message) error
+}
@@ -0,0 +1,69 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//go:embed breached.txt
+var bundledBreached []byte
+
+// bundledPrefixFile returns the bundled prefix file of commonly breached passwords
+func bundledPrefixFile() io.Reader {
+	return bytes.NewReader(bundledBreached)
+}
+
+// PrefixFile looks up passwords in a k-anonymity SHA-1 hash prefix file offline, SHA-1 being the hash of the breached password corpora.
+// The hashes are bucketed by their first 5 hex digits like the range API of Have I Been Pwned,
+// so that a bigger corpus can be downloaded by prefix and dropped in without changing the lookup.
+type PrefixFile struct {
+	buckets map[string]map[string]struct{}
+}
+
+// LoadPrefixFile reads the prefix file which has one `PREFIX:SUFFIX` line per hash, PREFIX being the first 5 hex digits
+// of the upper case SHA-1 hash and SUFFIX the other 35. An optional `:COUNT` after the suffix is ignored,
+// so are the blank lines and the lines starting with `#`.
+func LoadPrefixFile(r io.Reader) (*PrefixFile, error) {
+	f := &PrefixFile{buckets: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(strings.ToUpper(line), ":")
+		if len(parts) < 2 || len(parts[0]) != 5 || len(parts[1]) != 35 {
+			return nil, fmt.Errorf("passwordpolicy: invalid prefix file line %d", n)
+		}
+
+		bucket, ok := f.buckets[parts[0]]
+		if !ok {
+			bucket = map[string]struct{}{}
+			f.buckets[parts[0]] = bucket
+		}
+		bucket[parts[1]] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Breached reports whether the SHA-1 hash of the password is in the file
+func (f *PrefixFile) Breached(_ context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, found := f.buckets[hash[:5]][hash[5:]]
+	return found, nil
+}
@@ -0,0 +1,142 @@
+package passwordpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"tyr/internal/types"
+)
+
+// ViolationError lists the rules of the policy the password violates
+type ViolationError struct {
+	Reasons []string
+}
+
+func (e *ViolationError) Error() string {
+	return "Password " + strings.Join(e.Reasons, ", ")
+}
+
+// Validate checks the password against the policy, the user being the one whose password is set.
+// The user info similarity and the history are checked only if the user is given,
+// the history only if the user exists already.
+// It returns a *ViolationError if the password violates the policy.
+func (p *Policy) Validate(ctx context.Context, password string, user *types.User) error {
+	reasons := []string{}
+
+	length := len([]rune(password))
+	if length < p.minLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters", p.minLength))
+	}
+	if p.maxLength > 0 && length > p.maxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d characters", p.maxLength))
+	}
+	if charClasses(password) < p.minCharClasses {
+		reasons = append(reasons, fmt.Sprintf("must contain at least %d of lowercase letters, uppercase letters, digits and symbols", p.minCharClasses))
+	}
+	if p.checkUserInfo && user != nil && containsUserInfo(password, user) {
+		reasons = append(reasons, "must not contain your name, email or phone")
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Breached(ctx, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			reasons = append(reasons, "has appeared in a data breach, please choose another one")
+		}
+	}
+
+	if p.historySize > 0 && user != nil && user.ID != "" {
+		reused, err := p.reused(ctx, password, user)
+		if err != nil {
+			return err
+		}
+		if reused {
+			reasons = append(reasons, fmt.Sprintf("must not be one of your last %d passwords", p.historySize))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &ViolationError{Reasons: reasons}
+	}
+	return nil
+}
+
+// Remember adds the new password hash of the user to the history, only the latest ones are kept
+func (p *Policy) Remember(ctx context.Context, userID, hash string) error {
+	if p.historySize <= 0 {
+		return nil
+	}
+	return p.history.Add(ctx, userID, hash, p.historySize)
+}
+
+// Expired reports whether the password of the user is older than the max age
+func (p *Policy) Expired(user *types.User) bool {
+	if p.maxAge <= 0 || user.PasswordChangedAt == nil {
+		return false
+	}
+	return p.now().Sub(*user.PasswordChangedAt) > p.maxAge
+}
+
+// reused reports whether the password is the current one or one of the latest ones of the user
+func (p *Policy) reused(ctx context.Context, password string, user *types.User) (bool, error) {
+	hashes, err := p.history.ListHashes(ctx, user.ID, p.historySize)
+	if err != nil {
+		return false, err
+	}
+	if user.Password != "" {
+		hashes = append(hashes, user.Password)
+	}
+
+	for _, hash := range hashes {
+		if p.cr.CompareHashAndPassword(hash, password) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// charClasses counts the classes of lowercase letters, uppercase letters, digits and symbols in the password
+func charClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// containsUserInfo reports whether the password contains the name, the email local part or the phone of the user,
+// ignoring case and the parts shorter than 3 characters
+func containsUserInfo(password string, user *types.User) bool {
+	password = strings.ToLower(password)
+
+	parts := strings.Fields(strings.ToLower(user.FirstName + " " + user.LastName))
+	if local, _, ok := strings.Cut(strings.ToLower(user.Email), "@"); ok {
+		parts = append(parts, local)
+		parts = append(parts, strings.FieldsFunc(local, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	if phone := strings.TrimLeft(user.Phone, "+"); len(phone) >= 6 {
+		parts = append(parts, phone, phone[len(phone)-6:])
+	}
+
+	for _, part := range parts {
+		if len([]rune(part)) >= 3 && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
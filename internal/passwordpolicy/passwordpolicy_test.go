@@ -0,0 +1,250 @@
+package passwordpolicy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/types"
+)
+
+var testConfig = config.PasswordPolicy{
+	MinLength:      10,
+	MaxLength:      32,
+	MinCharClasses: 3,
+	CheckUserInfo:  true,
+	HistorySize:    2,
+	MaxAgeDays:     90,
+	CheckBreached:  true,
+}
+
+func TestValidate(t *testing.T) {
+	p, history := newTestPolicy(t)
+	history.hashes[testUserID] = []string{"hashed:Old-Password-1", "hashed:Old-Password-2"}
+	user := &types.User{Base: types.Base{ID: testUserID}, Email: "john.doe@tyr.io", FirstName: "Johnny", LastName: "Doe", Phone: "+84901234567", Password: "hashed:Current-Pass-1"}
+
+	cases := []struct {
+		name     string
+		password string
+		user     *types.User
+		want     []string
+	}{
+		{name: "valid", password: "Tyr-Correct-Horse-9", user: user},
+		{name: "too short", password: "Ab1-short", want: []string{"must be at least 10 characters"}},
+		{name: "too long", password: "Ab1-" + strings.Repeat("x", 29), want: []string{"must be at most 32 characters"}},
+		{name: "the length counts the characters", password: "Ảb1-ảảảảảả"},
+		{name: "too few character classes", password: "correcthorse9", want: []string{"must contain at least 3 of lowercase letters, uppercase letters, digits and symbols"}},
+		{name: "breached", password: "Password1!", want: []string{"has appeared in a data breach, please choose another one"}},
+		{name: "contains the name", password: "My-JOHNNY-pass-9", user: user, want: []string{"must not contain your name, email or phone"}},
+		{name: "user info is checked only if the user is given", password: "My-JOHNNY-pass-9"},
+		{name: "the current password", password: "Current-Pass-1", user: user, want: []string{"must not be one of your last 2 passwords"}},
+		{name: "a previous password", password: "Old-Password-2", user: user, want: []string{"must not be one of your last 2 passwords"}},
+		{name: "the history is not checked for a new user", password: "Old-Password-2", user: &types.User{Email: "new@tyr.io"}},
+		{name: "several violations", password: "johnny", user: user, want: []string{
+			"must be at least 10 characters",
+			"must contain at least 3 of lowercase letters, uppercase letters, digits and symbols",
+			"must not contain your name, email or phone",
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.Validate(context.Background(), tc.password, tc.user)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("got %v", err)
+				}
+				return
+			}
+
+			var verr *ViolationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("got %v, want a ViolationError", err)
+			}
+			if !reflect.DeepEqual(verr.Reasons, tc.want) {
+				t.Errorf("got %q, want %q", verr.Reasons, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateHistoryError(t *testing.T) {
+	p, history := newTestPolicy(t)
+	history.err = errors.New("down")
+
+	if err := p.Validate(context.Background(), "Tyr-Correct-Horse-9", &types.User{Base: types.Base{ID: testUserID}}); !errors.Is(err, history.err) {
+		t.Errorf("got %v, want the history error", err)
+	}
+}
+
+func TestRemember(t *testing.T) {
+	p, history := newTestPolicy(t)
+
+	for _, hash := range []string{"a", "b", "c"} {
+		if err := p.Remember(context.Background(), testUserID, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := history.hashes[testUserID]; !reflect.DeepEqual(got, []string{"c", "b"}) {
+		t.Errorf("got %q, want the latest 2 hashes", got)
+	}
+
+	p.historySize = 0
+	if err := p.Remember(context.Background(), "another", "a"); err != nil || len(history.hashes["another"]) != 0 {
+		t.Errorf("got %v, want nothing remembered without history", err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	p, _ := newTestPolicy(t)
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	for name, tc := range map[string]struct {
+		changedAt time.Duration
+		want      bool
+	}{
+		"changed recently":     {changedAt: -time.Hour},
+		"changed at max age":   {changedAt: -90 * 24 * time.Hour},
+		"older than max age":   {changedAt: -90*24*time.Hour - time.Second, want: true},
+		"never changed so far": {},
+	} {
+		user := &types.User{}
+		if tc.changedAt != 0 {
+			changedAt := now.Add(tc.changedAt)
+			user.PasswordChangedAt = &changedAt
+		}
+		if got := p.Expired(user); got != tc.want {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+
+	p.maxAge = 0
+	old := now.AddDate(-10, 0, 0)
+	if p.Expired(&types.User{PasswordChangedAt: &old}) {
+		t.Error("got expired without max age")
+	}
+}
+
+func TestCharClasses(t *testing.T) {
+	for password, want := range map[string]int{
+		"":           0,
+		"abc":        1,
+		"abcABC":     2,
+		"abcABC123":  3,
+		"abcABC123!": 4,
+		"ảẢ٣ ":       4,
+		"123-456":    2,
+	} {
+		if got := charClasses(password); got != want {
+			t.Errorf("%q got %d, want %d", password, got, want)
+		}
+	}
+}
+
+func TestContainsUserInfo(t *testing.T) {
+	user := &types.User{Email: "jo.smith-99@tyr.io", FirstName: "Jo", LastName: "Smith", Phone: "+84901234567"}
+
+	for password, want := range map[string]bool{
+		"Unrelated-Pass-1":      false,
+		"xxSMITHxx":             true,
+		"jo.smith-99!":          true,
+		"my-99-pass":            false,
+		"Jo-is-short":           false,
+		"call-901234567":        true,
+		"pin-234567":            true,
+		"tyr.io-is-not-checked": false,
+	} {
+		if got := containsUserInfo(password, user); got != want {
+			t.Errorf("%q got %v, want %v", password, got, want)
+		}
+	}
+
+	if containsUserInfo("any-12345", &types.User{Phone: "12345"}) {
+		t.Error("got the short phone checked")
+	}
+}
+
+func TestLoadPrefixFile(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	f, err := LoadPrefixFile(strings.NewReader(`# comment
+
+5baa6:1e4c9b93f3f0682250b6cf8331b7ee68fd8:3861493
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for password, want := range map[string]bool{"password": true, "Password": false} {
+		if got, _ := f.Breached(context.Background(), password); got != want {
+			t.Errorf("%q got %v, want %v", password, got, want)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"no suffix":      "5BAA6",
+		"short prefix":   "5BAA:61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		"short suffix":   "5BAA6:1E4C9B93F3F0682250B6CF8331B7EE68FD",
+		"not the prefix": "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1",
+	} {
+		if _, err := LoadPrefixFile(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+
+	// the bundled file is valid
+	if _, err := New(nil, nil, config.PasswordPolicy{CheckBreached: true}); err != nil {
+		t.Errorf("got %v loading the bundled file", err)
+	}
+	if _, err := New(nil, nil, config.PasswordPolicy{CheckBreached: true, BreachedFile: "missing.txt"}); err == nil {
+		t.Error("got no error for a missing file")
+	}
+}
+
+const testUserID = "01HUSER00000000000000000000"
+
+func newTestPolicy(t *testing.T) (*Policy, *fakeHistory) {
+	t.Helper()
+
+	history := &fakeHistory{hashes: map[string][]string{}}
+	p, err := New(history, fakeCrypter{}, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, history
+}
+
+// fakeHistory keeps the hashes of every user, the latest first
+type fakeHistory struct {
+	hashes map[string][]string
+	err    error
+}
+
+func (h *fakeHistory) ListHashes(_ context.Context, userID string, limit int) ([]string, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	hashes := h.hashes[userID]
+	if len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+	return hashes, nil
+}
+
+func (h *fakeHistory) Add(_ context.Context, userID, hash string, keep int) error {
+	hashes := append([]string{hash}, h.hashes[userID]...)
+	if len(hashes) > keep {
+		hashes = hashes[:keep]
+	}
+	h.hashes[userID] = hashes
+	return nil
+}
+
+type fakeCrypter struct{}
+
+func (fakeCrypter) CompareHashAndPassword(hash, password string) bool {
+	return hash == "hashed:"+password
+}
@@ -0,0 +1,73 @@
+package passwordpolicy
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"tyr/config"
+)
+
+// New creates new password policy service, the breached password hash prefix file is loaded if the check is enabled
+func New(history HistoryRepository, cr Crypter, cfg config.PasswordPolicy) (*Policy, error) {
+	p := &Policy{
+		history:        history,
+		cr:             cr,
+		minLength:      cfg.MinLength,
+		maxLength:      cfg.MaxLength,
+		minCharClasses: cfg.MinCharClasses,
+		checkUserInfo:  cfg.CheckUserInfo,
+		historySize:    cfg.HistorySize,
+		maxAge:         time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		now:            time.Now,
+	}
+
+	if cfg.CheckBreached {
+		var err error
+		if cfg.BreachedFile == "" {
+			p.breached, err = LoadPrefixFile(bundledPrefixFile())
+		} else {
+			var f *os.File
+			if f, err = os.Open(cfg.BreachedFile); err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			p.breached, err = LoadPrefixFile(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Policy represents password policy service
+type Policy struct {
+	history        HistoryRepository
+	cr             Crypter
+	breached       BreachedChecker
+	minLength      int
+	maxLength      int
+	minCharClasses int
+	checkUserInfo  bool
+	historySize    int
+	maxAge         time.Duration
+	now            func() time.Time
+}
+
+// HistoryRepository represents the password history storage interface
+type HistoryRepository interface {
+	ListHashes(ctx context.Context, userID string, limit int) ([]string, error)
+	Add(ctx context.Context, userID, hash string, keep int) error
+}
+
+// BreachedChecker represents breached password lookup interface
+type BreachedChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// Crypter represents security interface
+type Crypter interface {
+	CompareHashAndPassword(string, string) bool
+}
@@ -0,0 +1,73 @@
+package privacy
+
+import (
+	"cmp"
+	"slices"
+
+	"tyr/internal/types"
+)
+
+// Analytics is the spending summary of the documents of the user in the archive
+type Analytics struct {
+	Documents int             `json:"documents"`
+	Monthly   []MonthlySpend  `json:"monthly"`
+	Merchants []MerchantSpend `json:"merchants"`
+}
+
+// MonthlySpend is the total spending of a month in a currency, by the document creation time
+type MonthlySpend struct {
+	Month     string  `json:"month"`
+	Currency  string  `json:"currency"`
+	Documents int     `json:"documents"`
+	Total     float64 `json:"total"`
+	TotalTax  float64 `json:"total_tax"`
+}
+
+// MerchantSpend is the total spending at a merchant in a currency
+type MerchantSpend struct {
+	Name      string  `json:"name"`
+	Currency  string  `json:"currency"`
+	Documents int     `json:"documents"`
+	Total     float64 `json:"total"`
+}
+
+// summarize aggregates the spending of the documents by month and by merchant, the most visited merchants first
+func summarize(documents []*types.Document) Analytics {
+	monthly := map[[2]string]*MonthlySpend{}
+	merchants := map[[2]string]*MerchantSpend{}
+	for _, doc := range documents {
+		mk := [2]string{doc.CreatedAt.Format("2006-01"), doc.Currency}
+		if monthly[mk] == nil {
+			monthly[mk] = &MonthlySpend{Month: mk[0], Currency: mk[1]}
+		}
+		monthly[mk].Documents++
+		monthly[mk].Total += doc.Total
+		monthly[mk].TotalTax += doc.TotalTax
+
+		if doc.MerchantName == "" {
+			continue
+		}
+		sk := [2]string{doc.MerchantName, doc.Currency}
+		if merchants[sk] == nil {
+			merchants[sk] = &MerchantSpend{Name: sk[0], Currency: sk[1]}
+		}
+		merchants[sk].Documents++
+		merchants[sk].Total += doc.Total
+	}
+
+	a := Analytics{Documents: len(documents), Monthly: []MonthlySpend{}, Merchants: []MerchantSpend{}}
+	for _, m := range monthly {
+		a.Monthly = append(a.Monthly, *m)
+	}
+	slices.SortFunc(a.Monthly, func(x, y MonthlySpend) int {
+		return cmp.Or(cmp.Compare(x.Month, y.Month), cmp.Compare(x.Currency, y.Currency))
+	})
+	for _, m := range merchants {
+		a.Merchants = append(a.Merchants, *m)
+	}
+	slices.SortFunc(a.Merchants, func(x, y MerchantSpend) int {
+		return cmp.Or(cmp.Compare(y.Documents, x.Documents), cmp.Compare(x.Name, y.Name), cmp.Compare(x.Currency, y.Currency))
+	})
+
+	return a
+}
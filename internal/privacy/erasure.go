@@ -0,0 +1,123 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"tyr/internal/loginguard"
+	"tyr/internal/types"
+)
+
+// runBatchSize is the maximum number of the users erased and the exports purged per run
+const runBatchSize = 500
+
+// ErasureDueAt returns the time to erase the data of the user who requests the erasure at the given time
+func (p *Privacy) ErasureDueAt(at time.Time) time.Time {
+	return at.Add(p.erasureGrace)
+}
+
+// Erase permanently deletes all data of the user from the database and blob storage.
+// The blobs are deleted first so a failed erasure keeps the schedule of the user to be retried.
+func (p *Privacy) Erase(ctx context.Context, userID string) error {
+	user := &types.User{}
+	if err := p.repo.User.ReadByID(ctx, user, userID); err != nil {
+		return err
+	}
+
+	for _, prefix := range []string{path.Join(p.receiptPrefix, userID) + "/", path.Join(p.exportPrefix, userID) + "/"} {
+		if err := p.deleteBlobs(ctx, prefix); err != nil {
+			return err
+		}
+	}
+
+	revokedIDs, err := p.repo.Session.RevokeAllByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := p.denylist.RevokeSessions(ctx, revokedIDs...); err != nil {
+		return err
+	}
+
+	// the record is kept for the audit trail without any personal data
+	if err := p.repo.User.Erase(ctx, userID, loginguard.AccountKey(user.Email), map[string]interface{}{
+		"first_name":           "",
+		"last_name":            "",
+		"email":                fmt.Sprintf("deleted-%s@invalid", userID),
+		"email_verified_at":    nil,
+		"phone":                "",
+		"phone_verified_at":    nil,
+		"password":             "",
+		"otp":                  nil,
+		"otp_sent_at":          nil,
+		"mfa_secret":           nil,
+		"mfa_enabled_at":       nil,
+		"last_login":           nil,
+		"erasure_scheduled_at": nil,
+		"status":               types.UserStatusDeleted,
+	}); err != nil {
+		return err
+	}
+
+	return p.repo.AuditEvent.Create(ctx, &types.AuditEvent{
+		UserID:     userID,
+		Action:     types.AuditActionUserErased,
+		ObjectType: "user",
+		ObjectID:   userID,
+		Reason:     "scheduled erasure",
+	})
+}
+
+// RunDue erases the users whose scheduled erasure is due and purges the expired data exports.
+// The failures do not stop the others and are returned joined.
+func (p *Privacy) RunDue(ctx context.Context) (erased, purged int, err error) {
+	now := p.now()
+	errs := []error{}
+
+	userIDs, err := p.repo.User.ListErasureDue(ctx, now, runBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, userID := range userIDs {
+		if err := p.Erase(ctx, userID); err != nil {
+			errs = append(errs, fmt.Errorf("error erasing user %s: %w", userID, err))
+			continue
+		}
+		erased++
+	}
+
+	exports, err := p.repo.DataExport.ListExpired(ctx, now, runBatchSize)
+	if err != nil {
+		return erased, 0, errors.Join(append(errs, err)...)
+	}
+	for _, rec := range exports {
+		if rec.BlobKey != "" {
+			if err := p.blob.Delete(ctx, rec.BlobKey); err != nil {
+				errs = append(errs, fmt.Errorf("error deleting data export %s: %w", rec.ID, err))
+				continue
+			}
+		}
+		if err := p.repo.DataExport.Delete(ctx, rec.ID); err != nil {
+			errs = append(errs, fmt.Errorf("error deleting data export %s: %w", rec.ID, err))
+			continue
+		}
+		purged++
+	}
+
+	return erased, purged, errors.Join(errs...)
+}
+
+func (p *Privacy) deleteBlobs(ctx context.Context, prefix string) error {
+	keys, err := p.blob.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := p.blob.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
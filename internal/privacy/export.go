@@ -0,0 +1,358 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// ErrExportInProgress is returned when the user requests a new export while the previous one is being built
+var ErrExportInProgress = errors.New("privacy: data export in progress")
+
+// ReceiptKey returns the blob key of a receipt file of the document
+func (p *Privacy) ReceiptKey(userID, documentID, fileName string) string {
+	return path.Join(p.receiptPrefix, userID, documentID, path.Base("/"+fileName))
+}
+
+// StoreReceipt stores a receipt file of the document, returns the blob key
+func (p *Privacy) StoreReceipt(ctx context.Context, userID, documentID, fileName string, body io.Reader, contentType string) (string, error) {
+	key := p.ReceiptKey(userID, documentID, fileName)
+	if err := p.blob.Put(ctx, key, body, contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// StartExport creates a pending data export of the user, the archive is built by the privacy job
+func (p *Privacy) StartExport(ctx context.Context, userID string) (*types.DataExport, error) {
+	if existed, err := p.repo.DataExport.Existed(ctx, map[string]interface{}{"user_id": userID, "status": types.DataExportPending}); err != nil {
+		return nil, err
+	} else if existed {
+		return nil, ErrExportInProgress
+	}
+
+	rec := &types.DataExport{
+		UserID:    userID,
+		Status:    types.DataExportPending,
+		ExpiresAt: p.now().Add(p.exportTTL),
+	}
+	if err := p.repo.DataExport.Create(ctx, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// BuildPending builds the archives of the pending data exports, the oldest first.
+// The exports pending for longer than the timeout are failed first, their build being interrupted
+// by every run, so that the users can request another one.
+// The failures do not stop the others and are returned joined.
+func (p *Privacy) BuildPending(ctx context.Context) (built, timedOut int, err error) {
+	now := p.now()
+	n, err := p.repo.DataExport.FailPending(ctx, now.Add(-p.exportTimeout), now, "timed out")
+	if err != nil {
+		return 0, 0, err
+	}
+	timedOut = int(n)
+
+	recs, err := p.repo.DataExport.ListPending(ctx, runBatchSize)
+	if err != nil {
+		return 0, timedOut, err
+	}
+	errs := []error{}
+	for _, rec := range recs {
+		// the failure is kept in the export record
+		if err := p.Export(ctx, rec); err != nil {
+			errs = append(errs, fmt.Errorf("error building data export %s: %w", rec.ID, err))
+			continue
+		}
+		built++
+	}
+
+	return built, timedOut, errors.Join(errs...)
+}
+
+// Export builds the ZIP archive of all data of the user and stores it in blob storage,
+// the export record is then marked as completed or failed
+func (p *Privacy) Export(ctx context.Context, rec *types.DataExport) error {
+	key := path.Join(p.exportPrefix, rec.UserID, rec.ID+".zip")
+
+	pr, pw := io.Pipe()
+	cw := &countWriter{w: pw}
+	go func() {
+		pw.CloseWithError(p.writeArchive(ctx, rec.UserID, cw))
+	}()
+
+	err := p.blob.Put(ctx, key, pr, "application/zip")
+	if err != nil {
+		pr.CloseWithError(err)
+	}
+
+	now := p.now()
+	updates := map[string]interface{}{"status": types.DataExportCompleted, "blob_key": key, "size": cw.n, "completed_at": now}
+	if err != nil {
+		updates = map[string]interface{}{"status": types.DataExportFailed, "error": err.Error(), "completed_at": now}
+	}
+	if uerr := p.repo.DataExport.Update(ctx, updates, rec.ID); uerr != nil {
+		return errors.Join(err, uerr)
+	}
+
+	return err
+}
+
+// OpenExport opens the archive of a completed data export, caller must close the reader
+func (p *Privacy) OpenExport(ctx context.Context, rec *types.DataExport) (io.ReadCloser, error) {
+	if rec.Status != types.DataExportCompleted || rec.BlobKey == "" {
+		return nil, fmt.Errorf("privacy: data export %s is not completed", rec.ID)
+	}
+	return p.blob.Get(ctx, rec.BlobKey)
+}
+
+// exportProfile is the profile of the user in the archive
+type exportProfile struct {
+	User       *types.User           `json:"user"`
+	Identities []*types.UserIdentity `json:"identities"`
+}
+
+func (p *Privacy) writeArchive(ctx context.Context, userID string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	user := &types.User{}
+	if err := p.repo.User.ReadByID(ctx, user, userID); err != nil {
+		return err
+	}
+	identities, err := p.repo.UserIdentity.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "profile.json", exportProfile{User: user, Identities: identities}); err != nil {
+		return err
+	}
+
+	sessions := []*types.Session{}
+	if err := p.repo.Session.Repo.List(ctx, &sessions, map[string]interface{}{"user_id": userID}); err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "sessions.json", sessions); err != nil {
+		return err
+	}
+
+	apiKeys := []*types.APIKey{}
+	if err := p.repo.APIKey.Repo.List(ctx, &apiKeys, map[string]interface{}{"user_id": userID}); err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "api_keys.json", apiKeys); err != nil {
+		return err
+	}
+
+	documents := []*types.Document{}
+	if err := p.repo.Document.Repo.List(ctx, &documents, map[string]interface{}{"user_id": userID}); err != nil {
+		return err
+	}
+	if err := p.writeDocuments(ctx, zw, documents); err != nil {
+		return err
+	}
+
+	reports := []*types.ExpenseReport{}
+	if err := p.repo.ExpenseReport.Repo.List(ctx, &reports, map[string]interface{}{"user_id": userID}); err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "expense_reports.json", reports); err != nil {
+		return err
+	}
+
+	events := []*types.AuditEvent{}
+	if err := p.repo.AuditEvent.List(ctx, &events, map[string]interface{}{"user_id": userID}); err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "audit_events.json", events); err != nil {
+		return err
+	}
+
+	if err := p.writeReceipts(ctx, zw, userID); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeDocuments writes the documents, their line items, analysis history and the spending analytics
+func (p *Privacy) writeDocuments(ctx context.Context, zw *zip.Writer, documents []*types.Document) error {
+	ids := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		ids = append(ids, doc.ID)
+	}
+
+	items := []*types.DocumentItem{}
+	if len(ids) > 0 {
+		if err := p.repo.DocumentItem.List(ctx, &items, `document_id IN ?`, ids); err != nil {
+			return err
+		}
+	}
+	itemsByDocument := map[string]*types.DocumentItem{}
+	for _, item := range items {
+		itemsByDocument[item.DocumentID] = item
+	}
+	for _, doc := range documents {
+		doc.DocumentItem = itemsByDocument[doc.ID]
+	}
+	if err := writeJSON(zw, "documents.json", documents); err != nil {
+		return err
+	}
+
+	rows := [][]string{{"id", "created_at", "file_name", "merchant_name", "merchant_address", "merchant_phone_number",
+		"transaction_date", "transaction_time", "currency", "sub_total", "total_tax", "total", "tax_details"}}
+	for _, doc := range documents {
+		rows = append(rows, []string{doc.ID, doc.CreatedAt.Format(time.RFC3339), doc.FileName, doc.MerchantName, doc.MerchantAddress,
+			doc.MerchantPhoneNumber, doc.TransactionDate, doc.TransactionTime, doc.Currency,
+			formatAmount(doc.SubTotal), formatAmount(doc.TotalTax), formatAmount(doc.Total), doc.TaxDetails})
+	}
+	if err := writeCSV(zw, "documents.csv", rows); err != nil {
+		return err
+	}
+
+	if err := writeCSV(zw, "line_items.csv", lineItemRows(documents)); err != nil {
+		return err
+	}
+
+	analyses := map[string][]*types.DocumentAnalysis{}
+	for _, doc := range documents {
+		recs, err := p.repo.DocumentAnalysis.ListByDocumentID(ctx, doc.ID)
+		if err != nil {
+			return err
+		}
+		analyses[doc.ID] = recs
+	}
+	if err := writeJSON(zw, "analyses.json", analyses); err != nil {
+		return err
+	}
+
+	return writeJSON(zw, "analytics.json", summarize(documents))
+}
+
+// writeReceipts copies the stored receipt files of the user into the receipts folder of the archive
+func (p *Privacy) writeReceipts(ctx context.Context, zw *zip.Writer, userID string) error {
+	prefix := path.Join(p.receiptPrefix, userID) + "/"
+	keys, err := p.blob.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := copyBlob(ctx, p.blob, zw, key, path.Join("receipts", strings.TrimPrefix(key, prefix))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyBlob(ctx context.Context, blob Blob, zw *zip.Writer, key, name string) error {
+	r, err := blob.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// lineItemRows flattens the line items of the documents, the columns are the union of the item fields
+func lineItemRows(documents []*types.Document) [][]string {
+	type docItems struct {
+		documentID string
+		items      []map[string]interface{}
+	}
+
+	all := []docItems{}
+	columns := []string{}
+	for _, doc := range documents {
+		if doc.DocumentItem == nil || len(doc.DocumentItem.Data) == 0 {
+			continue
+		}
+		items := []map[string]interface{}{}
+		if err := json.Unmarshal(doc.DocumentItem.Data, &items); err != nil {
+			continue
+		}
+		for _, item := range items {
+			for col := range item {
+				if !slices.Contains(columns, col) {
+					columns = append(columns, col)
+				}
+			}
+		}
+		all = append(all, docItems{documentID: doc.ID, items: items})
+	}
+	slices.Sort(columns)
+
+	rows := [][]string{append([]string{"document_id", "line"}, columns...)}
+	for _, di := range all {
+		for i, item := range di.items {
+			row := []string{di.documentID, strconv.Itoa(i + 1)}
+			for _, col := range columns {
+				v := ""
+				if item[col] != nil {
+					v = fmt.Sprint(item[col])
+				}
+				row = append(row, v)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeCSV(zw *zip.Writer, name string, rows [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// countWriter counts the written bytes
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,51 @@
+package privacy
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"tyr/config"
+	"tyr/internal/repo"
+)
+
+// New creates new privacy service to export and erase the data of the users
+func New(repo *repo.Service, blob Blob, denylist Denylist, cfg config.Privacy) *Privacy {
+	return &Privacy{
+		repo:          repo,
+		blob:          blob,
+		denylist:      denylist,
+		receiptPrefix: cfg.ReceiptPrefix,
+		exportPrefix:  cfg.ExportPrefix,
+		exportTTL:     time.Duration(cfg.ExportTTL) * time.Second,
+		exportTimeout: time.Duration(cfg.ExportTimeout) * time.Second,
+		erasureGrace:  time.Duration(cfg.ErasureGrace) * time.Second,
+		now:           time.Now,
+	}
+}
+
+// Privacy represents account data export and erasure service
+type Privacy struct {
+	repo          *repo.Service
+	blob          Blob
+	denylist      Denylist
+	receiptPrefix string
+	exportPrefix  string
+	exportTTL     time.Duration
+	exportTimeout time.Duration
+	erasureGrace  time.Duration
+	now           func() time.Time
+}
+
+// Blob represents blob storage interface
+type Blob interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Denylist represents access token revocation interface
+type Denylist interface {
+	RevokeSessions(ctx context.Context, sessionIDs ...string) error
+}
@@ -0,0 +1,55 @@
+package rbac
+
+import (
+	"tyr/internal/types"
+
+	"github.com/samber/lo"
+)
+
+// API key scopes
+const (
+	ScopeDocumentsRead       = "documents:read"
+	ScopeDocumentsWrite      = "documents:write"
+	ScopeExpenseReportsRead  = "expense_reports:read"
+	ScopeExpenseReportsWrite = "expense_reports:write"
+	ScopeOrganizationsRead   = "organizations:read"
+	ScopeActivityLogsRead    = "activity_logs:read"
+)
+
+// ValidScopes for validation of the API keys
+var ValidScopes = []string{ScopeDocumentsRead, ScopeDocumentsWrite, ScopeExpenseReportsRead, ScopeExpenseReportsWrite, ScopeOrganizationsRead, ScopeActivityLogsRead}
+
+// scopeGrant is the object and the actions a scope grants
+type scopeGrant struct {
+	object  string
+	actions []string
+}
+
+var (
+	readActions  = []string{ActionRead, ActionReadAll}
+	writeActions = []string{ActionCreate, ActionCreateAll, ActionUpdate, ActionUpdateAll, ActionDelete, ActionDeleteAll, ActionAnalyze}
+)
+
+// scopeGrants maps the scopes to what they grant, on top of the role of the key owner which still applies.
+// The objects out of any scope, eg: the account, the sessions and the API keys themselves, are never accessible by the API keys.
+var scopeGrants = map[string]scopeGrant{
+	ScopeDocumentsRead:       {object: ObjectDocument, actions: readActions},
+	ScopeDocumentsWrite:      {object: ObjectDocument, actions: writeActions},
+	ScopeExpenseReportsRead:  {object: ObjectExpenseReport, actions: readActions},
+	ScopeExpenseReportsWrite: {object: ObjectExpenseReport, actions: writeActions},
+	ScopeOrganizationsRead:   {object: ObjectOrganization, actions: readActions},
+	ScopeActivityLogsRead:    {object: ObjectActivityLog, actions: readActions},
+}
+
+// ScopeAllows checks the scopes of the API key of the authenticated user grant the action on the object.
+// The access tokens are not restricted by scopes.
+func ScopeAllows(au *types.AuthUser, object, action string) bool {
+	if au.APIKeyID == "" {
+		return true
+	}
+
+	return lo.SomeBy(au.Scopes, func(scope string) bool {
+		grant, ok := scopeGrants[scope]
+		return ok && grant.object == object && lo.Contains(grant.actions, action)
+	})
+}
@@ -0,0 +1,88 @@
+package rbac
+
+import (
+	"net/http"
+
+	contextutil "tyr/internal/api/context"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/rbac"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+)
+
+// AllActions maps the request methods to the actions on all records of the object, for the admin routes
+var AllActions = map[string]string{
+	http.MethodGet:    ActionReadAll,
+	http.MethodPost:   ActionCreateAll,
+	http.MethodPut:    ActionUpdateAll,
+	http.MethodPatch:  ActionUpdateAll,
+	http.MethodDelete: ActionDeleteAll,
+}
+
+// OwnActions maps the request methods to the actions on the own records of the object, for the app routes
+var OwnActions = map[string]string{
+	http.MethodGet:    ActionRead,
+	http.MethodPost:   ActionCreate,
+	http.MethodPut:    ActionUpdate,
+	http.MethodPatch:  ActionUpdate,
+	http.MethodDelete: ActionDelete,
+}
+
+// MWRoles returns a middleware which only lets the authenticated users of the given roles through.
+// It must be used after contextutil.MWContext.
+func MWRoles(roles ...string) echo.MiddlewareFunc {
+	return MWRolesFunc(func(role string) bool {
+		return lo.Contains(roles, role)
+	})
+}
+
+// MWRolesFunc returns a middleware which only lets the authenticated users of the roles allowed by the function through.
+// It must be used after contextutil.MWContext.
+func MWRolesFunc(allow func(role string) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if au := authUser(c); au == nil || !allow(au.Role) {
+				return ErrForbiddenAccess
+			}
+			return next(c)
+		}
+	}
+}
+
+// MWEnforce returns a middleware which enforces the role of the authenticated user to perform the action on the object,
+// and the scopes of its API key if the request is authenticated by one. It must be used after contextutil.MWContext.
+func MWEnforce(enforcer rbac.Intf, object, action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if au := authUser(c); au == nil || !enforcer.Enforce(au.Role, object, action) || !ScopeAllows(au, object, action) {
+				return ErrForbiddenAction
+			}
+			return next(c)
+		}
+	}
+}
+
+// MWEnforceByMethod is MWEnforce with the action derived from the request method by the given map,
+// eg: AllActions or OwnActions. The requests of the other methods are forbidden.
+func MWEnforceByMethod(enforcer rbac.Intf, object string, actions map[string]string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			action, ok := actions[c.Request().Method]
+			if au := authUser(c); au == nil || !ok || !enforcer.Enforce(au.Role, object, action) || !ScopeAllows(au, object, action) {
+				return ErrForbiddenAction
+			}
+			return next(c)
+		}
+	}
+}
+
+// authUser returns the authenticated user of the request, nil if there is none
+func authUser(c echo.Context) *types.AuthUser {
+	cc, ok := c.(contextutil.Context)
+	if !ok {
+		return nil
+	}
+	return cc.AuthUser()
+}
@@ -19,6 +19,15 @@ const (
 	ObjectSession  = "session"
 	ObjectDocument = "document"
 	ObjectPlaid    = "plaid"
+
+	ObjectActivityLog   = "activity_log"
+	ObjectLockout       = "lockout"
+	ObjectDataExport    = "data_export"
+	ObjectRBAC          = "rbac"
+	ObjectOrganization  = "organization"
+	ObjectExpenseReport = "expense_report"
+	ObjectAPIKey        = "api_key"
+	ObjectImpersonation = "impersonation"
 )
 
 // Custom errors
@@ -27,8 +36,11 @@ var (
 	ErrForbiddenAction = server.NewHTTPError(http.StatusForbidden, "FORBIDDEN", "You don't have permission to perform this action")
 )
 
-// ValidRoles for validation
-var ValidRoles = []string{RoleSuperAdmin, RoleAdmin, RoleUser}
+// ValidObjects for validation of the policies
+var ValidObjects = []string{ObjectAny, ObjectUser, ObjectSession, ObjectDocument, ObjectPlaid, ObjectActivityLog, ObjectLockout, ObjectDataExport, ObjectRBAC, ObjectOrganization, ObjectExpenseReport, ObjectAPIKey, ObjectImpersonation}
+
+// ValidActions for validation of the policies
+var ValidActions = []string{ActionAny, ActionReadAll, ActionRead, ActionCreateAll, ActionCreate, ActionUpdateAll, ActionUpdate, ActionDeleteAll, ActionDelete, ActionAnalyze}
 
 // RBAC actions
 const (
@@ -0,0 +1,63 @@
+package rbac
+
+import (
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"github.com/M15t/gram/pkg/rbac"
+	"github.com/samber/lo"
+)
+
+// allActions maps the actions on the own records to the same actions on the records of all users
+var allActions = map[string]string{
+	ActionRead:   ActionReadAll,
+	ActionCreate: ActionCreateAll,
+	ActionUpdate: ActionUpdateAll,
+	ActionDelete: ActionDeleteAll,
+}
+
+// OrgWideActions are the objects whose records are shared with the members of the active organization of the user,
+// with the actions each organization role can perform on the records of all members.
+// The other actions are limited to the own records of the member.
+var OrgWideActions = map[string]map[string][]string{
+	ObjectDocument: {
+		types.OrgRoleOwner:    {ActionRead, ActionUpdate, ActionDelete},
+		types.OrgRoleApprover: {ActionRead, ActionUpdate},
+		types.OrgRoleMember:   {ActionRead},
+	},
+	// the reports are changed by the workflow transitions, not by the updates of the other members
+	ObjectExpenseReport: {
+		types.OrgRoleOwner:    {ActionRead},
+		types.OrgRoleApprover: {ActionRead},
+	},
+}
+
+// Authorize checks the authenticated user can perform the action on the object and returns the rows it applies to.
+// The rows of all users are accessible if the role is granted the action on all records too, eg: ActionReadAll for ActionRead,
+// otherwise only the rows owned by the user are. The shared objects are restricted to the active organization of the user,
+// where the organization role decides whether the rows of the other members are accessible.
+// The requests authenticated by an API key are restricted to its scopes as well.
+// The scope must be applied to every repo query of the object.
+func Authorize(enforcer rbac.Intf, au *types.AuthUser, object, action string) (repo.Scope, error) {
+	if au == nil || !ScopeAllows(au, object, action) {
+		return repo.Scope{}, ErrForbiddenAction
+	}
+
+	if all, ok := allActions[action]; ok && enforcer.Enforce(au.Role, object, all) {
+		return repo.ScopeAll(), nil
+	}
+	if !enforcer.Enforce(au.Role, object, action) {
+		return repo.Scope{}, ErrForbiddenAction
+	}
+	if lo.Contains(lo.Values(allActions), action) {
+		return repo.ScopeAll(), nil
+	}
+	if orgWide, shared := OrgWideActions[object]; shared && au.OrganizationID != "" {
+		if lo.Contains(orgWide[au.OrgRole], action) {
+			return repo.ScopeOrganization(au.OrganizationID, ""), nil
+		}
+		return repo.ScopeOrganization(au.OrganizationID, au.ID), nil
+	}
+
+	return repo.ScopeOwner(au.ID), nil
+}
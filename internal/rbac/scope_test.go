@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+)
+
+func TestAuthorize(t *testing.T) {
+	svc, err := New(defaultPolicies{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		au        *types.AuthUser
+		object    string
+		action    string
+		want      repo.Scope
+		forbidden bool
+	}{
+		{name: "user reads own documents", au: &types.AuthUser{ID: "u1", Role: RoleUser}, object: ObjectDocument, action: ActionRead, want: repo.ScopeOwner("u1")},
+		{name: "user updates own documents", au: &types.AuthUser{ID: "u1", Role: RoleUser}, object: ObjectDocument, action: ActionUpdate, want: repo.ScopeOwner("u1")},
+		{name: "user cannot read all documents", au: &types.AuthUser{ID: "u1", Role: RoleUser}, object: ObjectDocument, action: ActionReadAll, forbidden: true},
+		{name: "user cannot read activity logs", au: &types.AuthUser{ID: "u1", Role: RoleUser}, object: ObjectActivityLog, action: ActionRead, forbidden: true},
+		{name: "admin reads all documents", au: &types.AuthUser{ID: "a1", Role: RoleAdmin}, object: ObjectDocument, action: ActionRead, want: repo.ScopeAll()},
+		{name: "admin reads all activity logs", au: &types.AuthUser{ID: "a1", Role: RoleAdmin}, object: ObjectActivityLog, action: ActionReadAll, want: repo.ScopeAll()},
+		{name: "admin creates own data exports only", au: &types.AuthUser{ID: "a1", Role: RoleAdmin}, object: ObjectDataExport, action: ActionCreate, want: repo.ScopeOwner("a1")},
+		{name: "organization owner reads all documents of the organization", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleOwner}, object: ObjectDocument, action: ActionRead, want: repo.ScopeOrganization("o1", "")},
+		{name: "organization approver updates all documents of the organization", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleApprover}, object: ObjectDocument, action: ActionUpdate, want: repo.ScopeOrganization("o1", "")},
+		{name: "organization approver deletes own documents", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleApprover}, object: ObjectDocument, action: ActionDelete, want: repo.ScopeOrganization("o1", "u1")},
+		{name: "organization member reads all documents of the organization", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleMember}, object: ObjectDocument, action: ActionRead, want: repo.ScopeOrganization("o1", "")},
+		{name: "organization member updates own documents", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleMember}, object: ObjectDocument, action: ActionUpdate, want: repo.ScopeOrganization("o1", "u1")},
+		{name: "organization approver reads all expense reports of the organization", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleApprover}, object: ObjectExpenseReport, action: ActionRead, want: repo.ScopeOrganization("o1", "")},
+		{name: "organization approver updates own expense reports", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleApprover}, object: ObjectExpenseReport, action: ActionUpdate, want: repo.ScopeOrganization("o1", "u1")},
+		{name: "organization member reads own expense reports", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleMember}, object: ObjectExpenseReport, action: ActionRead, want: repo.ScopeOrganization("o1", "u1")},
+		{name: "organization member does not share data exports", au: &types.AuthUser{ID: "u1", Role: RoleUser, OrganizationID: "o1", OrgRole: types.OrgRoleMember}, object: ObjectDataExport, action: ActionRead, want: repo.ScopeOwner("u1")},
+		{name: "API key reads own documents in its scope", au: &types.AuthUser{ID: "u1", Role: RoleUser, APIKeyID: "k1", Scopes: []string{ScopeDocumentsRead}}, object: ObjectDocument, action: ActionRead, want: repo.ScopeOwner("u1")},
+		{name: "API key cannot update documents out of its scope", au: &types.AuthUser{ID: "u1", Role: RoleUser, APIKeyID: "k1", Scopes: []string{ScopeDocumentsRead}}, object: ObjectDocument, action: ActionUpdate, forbidden: true},
+		{name: "API key of admin reads all documents in its scope", au: &types.AuthUser{ID: "a1", Role: RoleAdmin, APIKeyID: "k1", Scopes: []string{ScopeDocumentsRead}}, object: ObjectDocument, action: ActionRead, want: repo.ScopeAll()},
+		{name: "API key scope does not extend the role", au: &types.AuthUser{ID: "u1", Role: RoleUser, APIKeyID: "k1", Scopes: []string{ScopeActivityLogsRead}}, object: ObjectActivityLog, action: ActionReadAll, forbidden: true},
+		{name: "API key cannot manage API keys", au: &types.AuthUser{ID: "u1", Role: RoleUser, APIKeyID: "k1", Scopes: ValidScopes}, object: ObjectAPIKey, action: ActionCreate, forbidden: true},
+		{name: "anonymous", object: ObjectDocument, action: ActionRead, forbidden: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := Authorize(svc, tc.au, tc.object, tc.action)
+			if tc.forbidden {
+				if err != ErrForbiddenAction {
+					t.Errorf("got %v, want ErrForbiddenAction", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if scope != tc.want {
+				t.Errorf("got scope %+v, want %+v", scope, tc.want)
+			}
+		})
+	}
+}
+
+// defaultPolicies serves the roles and the policies seeded by the migration
+type defaultPolicies struct{}
+
+func (defaultPolicies) Revision(context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (defaultPolicies) ListRoles(context.Context) ([]*types.RBACRole, error) {
+	return DefaultRoles, nil
+}
+
+func (defaultPolicies) ListPolicies(context.Context) ([]*types.RBACPolicy, error) {
+	policies := make([]*types.RBACPolicy, 0, len(DefaultPolicies))
+	for _, p := range DefaultPolicies {
+		policies = append(policies, &types.RBACPolicy{Role: p[0], Object: p[1], Action: p[2]})
+	}
+	return policies, nil
+}
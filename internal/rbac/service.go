@@ -1,36 +1,237 @@
 package rbac
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tyr/internal/types"
+
 	"github.com/M15t/gram/pkg/rbac"
+	"github.com/samber/lo"
 )
 
-// New returns new RBAC service
-func New(enableLog bool) *rbac.RBAC {
-	r := rbac.NewWithConfig(rbac.Config{EnableLog: enableLog})
+// DefaultRoles are the system roles seeded by the migrations
+var DefaultRoles = []*types.RBACRole{
+	{Name: RoleUser, Description: "App user, manages the own records", System: true, Parents: []string{}},
+	{Name: RoleAdmin, Description: "Portal administrator", System: true, Parents: []string{RoleUser}},
+	{Name: RoleSuperAdmin, Description: "Portal super administrator, manages the roles and the policies", System: true, Parents: []string{RoleAdmin}},
+}
+
+// DefaultPolicies are the policies seeded by the migrations, as role, object and action.
+// The migrations seed their own copies, a policy added here needs a new migration.
+var DefaultPolicies = [][3]string{
+	// user role
+	{RoleUser, ObjectUser, ActionRead},
+	{RoleUser, ObjectUser, ActionUpdate},
+	{RoleUser, ObjectUser, ActionDelete},
+
+	{RoleUser, ObjectDocument, ActionCreate},
+	{RoleUser, ObjectDocument, ActionRead},
+	{RoleUser, ObjectDocument, ActionUpdate},
+	{RoleUser, ObjectDocument, ActionDelete},
+
+	{RoleUser, ObjectPlaid, ActionCreate},
+
+	{RoleUser, ObjectSession, ActionRead},
+	{RoleUser, ObjectSession, ActionDelete},
+
+	{RoleUser, ObjectDataExport, ActionCreate},
+	{RoleUser, ObjectDataExport, ActionRead},
+
+	{RoleUser, ObjectOrganization, ActionCreate},
+	{RoleUser, ObjectOrganization, ActionRead},
+	{RoleUser, ObjectOrganization, ActionUpdate},
+	{RoleUser, ObjectOrganization, ActionDelete},
+
+	{RoleUser, ObjectExpenseReport, ActionCreate},
+	{RoleUser, ObjectExpenseReport, ActionRead},
+	{RoleUser, ObjectExpenseReport, ActionUpdate},
+	{RoleUser, ObjectExpenseReport, ActionDelete},
+
+	{RoleUser, ObjectAPIKey, ActionCreate},
+	{RoleUser, ObjectAPIKey, ActionRead},
+	{RoleUser, ObjectAPIKey, ActionDelete},
+
+	// admin role
+	{RoleAdmin, ObjectUser, ActionAny},
+	{RoleAdmin, ObjectSession, ActionAny},
+	{RoleAdmin, ObjectDocument, ActionAny},
+	{RoleAdmin, ObjectActivityLog, ActionReadAll},
+	{RoleAdmin, ObjectLockout, ActionAny},
+	{RoleAdmin, ObjectAPIKey, ActionReadAll},
+	{RoleAdmin, ObjectAPIKey, ActionDeleteAll},
+
+	// superadmin role
+	{RoleSuperAdmin, ObjectAny, ActionAny},
+}
+
+// New returns new RBAC service with the roles and the policies loaded from the database
+func New(repo Repository, enableLog bool) (*Service, error) {
+	s := &Service{repo: repo, enableLog: enableLog}
+	if err := s.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Service represents RBAC service, the enforcer is rebuilt and swapped as a whole on reload
+type Service struct {
+	repo      Repository
+	enableLog bool
+	current   atomic.Pointer[snapshot]
+	reloadMu  sync.Mutex
+}
+
+// Repository represents the roles and the policies storage interface
+type Repository interface {
+	Revision(ctx context.Context) (int64, error)
+	ListRoles(ctx context.Context) ([]*types.RBACRole, error)
+	ListPolicies(ctx context.Context) ([]*types.RBACPolicy, error)
+}
+
+// snapshot is an immutable state of the roles and the policies at a revision
+type snapshot struct {
+	revision int64
+	enforcer *rbac.RBAC
+	parents  map[string][]string
+	policies []*types.RBACPolicy
+}
+
+// Enforce checks if the role can perform the action on the object, as role, object and action
+func (s *Service) Enforce(rvals ...interface{}) bool {
+	return s.current.Load().enforcer.Enforce(rvals...)
+}
+
+// Revision returns the revision of the loaded roles and policies
+func (s *Service) Revision() int64 {
+	return s.current.Load().revision
+}
+
+// Reload loads the roles and the policies from the database and swaps the enforcer
+func (s *Service) Reload(ctx context.Context) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	// the revision is read first, a change in between is picked up by the next reload
+	revision, err := s.repo.Revision(ctx)
+	if err != nil {
+		return err
+	}
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+	policies, err := s.repo.ListPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	enforcer := rbac.NewWithConfig(rbac.Config{Model: rbac.NewRBACModel(), EnableLog: s.enableLog})
+	parents := map[string][]string{}
+	for _, p := range policies {
+		enforcer.AddPolicy(p.Role, p.Object, p.Action)
+	}
+	for _, role := range roles {
+		parents[role.Name] = role.Parents
+		for _, parent := range role.Parents {
+			enforcer.AddGroupingPolicy(role.Name, parent)
+		}
+	}
+	if s.enableLog {
+		enforcer.GetModel().PrintPolicy()
+	}
+
+	s.current.Store(&snapshot{revision: revision, enforcer: enforcer, parents: parents, policies: policies})
+	return nil
+}
+
+// ReloadIfChanged reloads the roles and the policies if their revision in the database has changed
+func (s *Service) ReloadIfChanged(ctx context.Context) (bool, error) {
+	revision, err := s.repo.Revision(ctx)
+	if err != nil || revision == s.Revision() {
+		return false, err
+	}
 
-	// Add permission for user role
-	r.AddPolicy(RoleUser, ObjectUser, ActionReadAll)
+	return true, s.Reload(ctx)
+}
 
-	r.AddPolicy(RoleUser, ObjectDocument, ActionCreate)
-	r.AddPolicy(RoleUser, ObjectDocument, ActionRead)
-	r.AddPolicy(RoleUser, ObjectDocument, ActionUpdate)
-	r.AddPolicy(RoleUser, ObjectDocument, ActionDelete)
+// Watch polls the revision in the database at the interval and reloads on change until the context is done,
+// so the changes made by the other instances are applied
+func (s *Service) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	r.AddPolicy(RoleUser, ObjectPlaid, ActionCreate)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ReloadIfChanged(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
 
-	// Add permission for admin role
-	r.AddPolicy(RoleAdmin, ObjectUser, ActionAny)
-	r.AddPolicy(RoleAdmin, ObjectSession, ActionAny)
-	r.AddPolicy(RoleAdmin, ObjectDocument, ActionAny)
+// HasRole checks if the role exists
+func (s *Service) HasRole(role string) bool {
+	_, ok := s.current.Load().parents[role]
+	return ok
+}
 
-	// Add permission for superadmin role
-	r.AddPolicy(RoleSuperAdmin, ObjectAny, ActionAny)
+// InheritsRole checks if the role is the parent role or inherits it, directly or not
+func (s *Service) InheritsRole(role, parent string) bool {
+	return lo.Contains(s.current.Load().inheritedRoles(role), parent)
+}
 
-	// Roles inheritance
-	r.AddGroupingPolicy(RoleAdmin, RoleUser)
-	r.AddGroupingPolicy(RoleSuperAdmin, RoleAdmin)
+// IsPortalRole checks if the role logs in the portal, that is any role except the user role and the roles only inheriting it
+func (s *Service) IsPortalRole(role string) bool {
+	return s.HasRole(role) && (s.InheritsRole(role, RoleAdmin) || !s.InheritsRole(role, RoleUser))
+}
 
-	r.GetModel().PrintPolicy()
+// IsAppRole checks if the role logs in the app, that is the user role and the roles only inheriting it
+func (s *Service) IsAppRole(role string) bool {
+	return s.HasRole(role) && !s.IsPortalRole(role)
+}
+
+// Simulation is the result of simulating the permission of a role
+type Simulation struct {
+	Allowed bool
+	// The role and all roles it inherits
+	Roles []string
+	// The policies of the roles which match the object and the action
+	Policies []*types.RBACPolicy
+}
+
+// Simulate answers if the role can perform the action on the object, with the policies granting it
+func (s *Service) Simulate(role, object, action string) *Simulation {
+	snap := s.current.Load()
+	sim := &Simulation{
+		Allowed:  snap.enforcer.Enforce(role, object, action),
+		Roles:    snap.inheritedRoles(role),
+		Policies: []*types.RBACPolicy{},
+	}
+	for _, p := range snap.policies {
+		if lo.Contains(sim.Roles, p.Role) && (p.Object == object || p.Object == ObjectAny) && (p.Action == action || p.Action == ActionAny) {
+			sim.Policies = append(sim.Policies, p)
+		}
+	}
+
+	return sim
+}
 
-	return r
+// inheritedRoles returns the role followed by all roles it inherits, breadth first
+func (snap *snapshot) inheritedRoles(role string) []string {
+	roles := []string{role}
+	for i := 0; i < len(roles); i++ {
+		for _, parent := range snap.parents[roles[i]] {
+			if !lo.Contains(roles, parent) {
+				roles = append(roles, parent)
+			}
+		}
+	}
+	return roles
 }
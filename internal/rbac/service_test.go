@@ -0,0 +1,144 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"tyr/internal/types"
+)
+
+func TestReloadIfChanged(t *testing.T) {
+	repo := &fakeRepository{revision: 1, roles: []*types.RBACRole{
+		{Name: RoleUser, Parents: []string{}},
+		{Name: "auditor", Parents: []string{}},
+	}}
+	svc, err := New(repo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Revision() != 1 || svc.Enforce("auditor", ObjectActivityLog, ActionReadAll) {
+		t.Fatalf("got revision %d with the policy not yet granted enforced", svc.Revision())
+	}
+
+	// a change without a new revision is not picked up
+	repo.policies = append(repo.policies, &types.RBACPolicy{Role: "auditor", Object: ObjectActivityLog, Action: ActionReadAll})
+	if changed, err := svc.ReloadIfChanged(context.Background()); err != nil || changed {
+		t.Fatalf("got changed %v and %v, want no reload", changed, err)
+	}
+	if svc.Enforce("auditor", ObjectActivityLog, ActionReadAll) {
+		t.Error("got the policy enforced before the revision bump")
+	}
+
+	repo.revision = 2
+	if changed, err := svc.ReloadIfChanged(context.Background()); err != nil || !changed {
+		t.Fatalf("got changed %v and %v, want a reload", changed, err)
+	}
+	if svc.Revision() != 2 || !svc.Enforce("auditor", ObjectActivityLog, ActionReadAll) {
+		t.Errorf("got revision %d without the policy enforced after the revision bump", svc.Revision())
+	}
+
+	// a failed reload keeps the loaded policies
+	repo.revision, repo.policies, repo.err = 3, nil, errors.New("connection refused")
+	if changed, err := svc.ReloadIfChanged(context.Background()); err == nil || !changed {
+		t.Fatalf("got changed %v and %v, want the error of the reload", changed, err)
+	}
+	if svc.Revision() != 2 || !svc.Enforce("auditor", ObjectActivityLog, ActionReadAll) {
+		t.Errorf("got revision %d and the policies dropped by the failed reload", svc.Revision())
+	}
+}
+
+func TestPortalAndAppRoles(t *testing.T) {
+	svc, err := New(&fakeRepository{revision: 1, roles: append(append([]*types.RBACRole{}, DefaultRoles...),
+		&types.RBACRole{Name: "auditor", Parents: []string{}},
+		&types.RBACRole{Name: "support", Parents: []string{RoleAdmin}},
+		&types.RBACRole{Name: "support_lead", Parents: []string{"support"}},
+		&types.RBACRole{Name: "premium", Parents: []string{RoleUser}},
+		&types.RBACRole{Name: "premium_plus", Parents: []string{"premium"}},
+		&types.RBACRole{Name: "support_user", Parents: []string{RoleUser, "support"}},
+	)}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		role   string
+		portal bool
+		app    bool
+	}{
+		{role: RoleUser, app: true},
+		{role: RoleAdmin, portal: true},
+		{role: RoleSuperAdmin, portal: true},
+		{role: "auditor", portal: true},
+		{role: "support", portal: true},
+		{role: "support_lead", portal: true},
+		{role: "premium", app: true},
+		{role: "premium_plus", app: true},
+		{role: "support_user", portal: true},
+		{role: "unknown"},
+	}
+
+	for _, tc := range cases {
+		if got := svc.IsPortalRole(tc.role); got != tc.portal {
+			t.Errorf("IsPortalRole(%q) = %v, want %v", tc.role, got, tc.portal)
+		}
+		if got := svc.IsAppRole(tc.role); got != tc.app {
+			t.Errorf("IsAppRole(%q) = %v, want %v", tc.role, got, tc.app)
+		}
+	}
+
+	if got, want := svc.current.Load().inheritedRoles("support_lead"), []string{"support_lead", "support", RoleAdmin, RoleUser}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got the roles %q inherited, want %q", got, want)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	svc, err := New(&fakeRepository{revision: 1, roles: append(append([]*types.RBACRole{}, DefaultRoles...),
+		&types.RBACRole{Name: "support", Parents: []string{RoleAdmin}},
+	), policies: append(defaultPolicyRecords(), &types.RBACPolicy{Role: "support", Object: ObjectLockout, Action: ActionReadAll})}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sim := svc.Simulate("support", ObjectLockout, ActionReadAll)
+	if !sim.Allowed || !reflect.DeepEqual(sim.Roles, []string{"support", RoleAdmin, RoleUser}) || len(sim.Policies) != 2 ||
+		sim.Policies[0].Role != RoleAdmin || sim.Policies[0].Action != ActionAny || sim.Policies[1].Role != "support" {
+		t.Errorf("got %+v, want the policies of support and admin granting it", sim)
+	}
+
+	sim = svc.Simulate(RoleUser, ObjectActivityLog, ActionReadAll)
+	if sim.Allowed || len(sim.Policies) != 0 {
+		t.Errorf("got %+v, want the user not allowed", sim)
+	}
+
+	sim = svc.Simulate(RoleSuperAdmin, ObjectRBAC, ActionDeleteAll)
+	if !sim.Allowed || len(sim.Policies) != 1 || sim.Policies[0].Object != ObjectAny {
+		t.Errorf("got %+v, want the superadmin allowed by the any policy", sim)
+	}
+}
+
+// fakeRepository serves the roles and the policies of the test at its revision
+type fakeRepository struct {
+	revision int64
+	roles    []*types.RBACRole
+	policies []*types.RBACPolicy
+	err      error
+}
+
+func (f *fakeRepository) Revision(context.Context) (int64, error) {
+	return f.revision, nil
+}
+
+func (f *fakeRepository) ListRoles(context.Context) ([]*types.RBACRole, error) {
+	return f.roles, f.err
+}
+
+func (f *fakeRepository) ListPolicies(context.Context) ([]*types.RBACPolicy, error) {
+	return f.policies, f.err
+}
+
+func defaultPolicyRecords() []*types.RBACPolicy {
+	policies, _ := defaultPolicies{}.ListPolicies(context.Background())
+	return policies
+}
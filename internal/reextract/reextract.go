@@ -0,0 +1,185 @@
+package reextract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"tyr/internal/types"
+	"tyr/third_party/azure"
+
+	"gorm.io/gorm"
+)
+
+// Custom errors
+var (
+	// ErrNoStoredResult is returned when there is no succeeded analyze result stored for the document
+	ErrNoStoredResult = errors.New("no stored analyze result")
+	// ErrInReport is returned when the document is claimed in an expense report under approval or decided, it must not change
+	ErrInReport = errors.New("claimed in an expense report under approval or decided")
+)
+
+// Run reprocesses one or all analyzed documents from their stored raw Azure responses.
+// Documents are mapped through the current mapping code and compared with the stored fields.
+// Changed documents are updated unless it is a dry run, the report lists every changed, skipped or failed document.
+// The documents claimed in an expense report under approval or decided are skipped.
+// All documents are processed in the order of their IDs, up to the limit if any.
+func (s *Service) Run(ctx context.Context, in Input) (*Report, error) {
+	if in.BatchSize <= 0 {
+		in.BatchSize = 100
+	}
+
+	report := &Report{DryRun: in.DryRun, Documents: []DocumentReport{}}
+
+	if in.DocumentID != "" {
+		doc, err := s.repo.Document.ReadByID(ctx, in.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		s.process(ctx, doc, in.DryRun, report)
+		return report, nil
+	}
+
+	afterID := in.AfterID
+	for {
+		size := in.BatchSize
+		if in.Limit > 0 {
+			size = min(size, in.Limit-report.Total)
+		}
+		docs, err := s.repo.Document.ListAnalyzedAfter(ctx, afterID, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			s.process(ctx, doc, in.DryRun, report)
+		}
+		if len(docs) < size {
+			break
+		}
+		afterID = docs[len(docs)-1].ID
+		if in.Limit > 0 && report.Total >= in.Limit {
+			report.NextAfterID = afterID
+			break
+		}
+	}
+
+	return report, nil
+}
+
+func (s *Service) process(ctx context.Context, doc *types.Document, dryRun bool, report *Report) {
+	report.Total++
+	dr := DocumentReport{ID: doc.ID, APIMRequestID: doc.APIMRequestID}
+
+	changes, err := s.reextract(ctx, doc, dryRun)
+	switch {
+	case errors.Is(err, ErrNoStoredResult), errors.Is(err, ErrInReport):
+		report.Skipped++
+		dr.Status = StatusSkipped
+		dr.Error = err.Error()
+	case err != nil:
+		report.Failed++
+		dr.Status = StatusFailed
+		dr.Error = err.Error()
+	case len(changes) == 0:
+		report.Unchanged++
+		return
+	default:
+		report.Changed++
+		dr.Status = StatusChanged
+		dr.Changes = changes
+	}
+
+	report.Documents = append(report.Documents, dr)
+}
+
+func (s *Service) reextract(ctx context.Context, doc *types.Document, dryRun bool) ([]FieldChange, error) {
+	raw, _, err := azure.FindStoredResult(ctx, s.repo, doc.APIMRequestID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoStoredResult
+		}
+		return nil, err
+	}
+
+	mapped, err := azure.ToDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, updates := diff(doc, mapped)
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+	if locked, err := s.repo.ExpenseReport.HasLockedDocument(ctx, doc.ID); err != nil {
+		return nil, err
+	} else if locked {
+		return nil, ErrInReport
+	}
+
+	if err := s.repo.Document.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if itemsData, ok := updates["items"]; ok {
+			delete(updates, "items")
+			if err := tx.Model(&types.DocumentItem{}).Where(`document_id = ?`, doc.ID).Update("data", itemsData).Error; err != nil {
+				return err
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&types.Document{}).Where(`id = ?`, doc.ID).Updates(updates).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// diff compares the extracted fields, returns the changes and the column updates to apply
+func diff(current, mapped *types.Document) ([]FieldChange, map[string]interface{}) {
+	changes := []FieldChange{}
+	updates := map[string]interface{}{}
+
+	oldFields, newFields := extractedFields(current), extractedFields(mapped)
+	for _, f := range fieldOrder {
+		if oldFields[f] != newFields[f] {
+			changes = append(changes, FieldChange{Field: f, Old: oldFields[f], New: newFields[f]})
+			updates[f] = newFields[f]
+		}
+	}
+
+	var oldItems, newItems interface{}
+	if current.DocumentItem != nil && len(current.DocumentItem.Data) > 0 {
+		_ = json.Unmarshal(current.DocumentItem.Data, &oldItems)
+	}
+	_ = json.Unmarshal(mapped.DocumentItem.Data, &newItems)
+	if !reflect.DeepEqual(oldItems, newItems) {
+		changes = append(changes, FieldChange{Field: "items", Old: oldItems, New: newItems})
+		updates["items"] = mapped.DocumentItem.Data
+	}
+
+	return changes, updates
+}
+
+// fieldOrder keeps the report in a stable order
+var fieldOrder = []string{
+	"merchant_name", "merchant_address", "merchant_phone_number",
+	"transaction_date", "transaction_time", "currency",
+	"sub_total", "total", "total_tax", "tax_details", "total_page",
+}
+
+func extractedFields(d *types.Document) map[string]interface{} {
+	return map[string]interface{}{
+		"merchant_name":         d.MerchantName,
+		"merchant_address":      d.MerchantAddress,
+		"merchant_phone_number": d.MerchantPhoneNumber,
+		"transaction_date":      d.TransactionDate,
+		"transaction_time":      d.TransactionTime,
+		"currency":              d.Currency,
+		"sub_total":             d.SubTotal,
+		"total":                 d.Total,
+		"total_tax":             d.TotalTax,
+		"tax_details":           d.TaxDetails,
+		"total_page":            d.TotalPage,
+	}
+}
@@ -0,0 +1,288 @@
+package reextract
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+	"tyr/third_party/azure"
+
+	"gorm.io/datatypes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const storedResult = `{"status":"succeeded","analyzeResult":{"pages":[{}],"documents":[{"fields":{"MerchantName":{"content":"Tyr Mart"},"Total":{"valueNumber":12.5}}}]}}`
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		name        string
+		in          Input
+		want        Report
+		wantStatus  map[string]string
+		wantUpdates []string
+	}{
+		{
+			name:       "dry run",
+			in:         Input{DryRun: true},
+			want:       Report{DryRun: true, Total: 4, Changed: 2, Unchanged: 1, Skipped: 1},
+			wantStatus: map[string]string{"d2": StatusChanged, "d3": StatusSkipped, "d4": StatusChanged},
+		},
+		{
+			name:        "run",
+			want:        Report{Total: 4, Changed: 1, Unchanged: 1, Skipped: 2},
+			wantStatus:  map[string]string{"d2": StatusChanged, "d3": StatusSkipped, "d4": StatusSkipped},
+			wantUpdates: []string{"d2"},
+		},
+		{
+			name:       "dry run of a single document",
+			in:         Input{DocumentID: "d4", DryRun: true},
+			want:       Report{DryRun: true, Total: 1, Changed: 1},
+			wantStatus: map[string]string{"d4": StatusChanged},
+		},
+		{
+			name:       "dry run up to the limit",
+			in:         Input{DryRun: true, BatchSize: 2, Limit: 3},
+			want:       Report{DryRun: true, Total: 3, Changed: 1, Unchanged: 1, Skipped: 1, NextAfterID: "d3"},
+			wantStatus: map[string]string{"d2": StatusChanged, "d3": StatusSkipped},
+		},
+		{
+			name:       "dry run after the limit",
+			in:         Input{DryRun: true, BatchSize: 2, AfterID: "d3"},
+			want:       Report{DryRun: true, Total: 1, Changed: 1},
+			wantStatus: map[string]string{"d4": StatusChanged},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, db := newTestService(t)
+
+			report, err := s.Run(context.Background(), tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status := map[string]string{}
+			for _, dr := range report.Documents {
+				status[dr.ID] = dr.Status
+			}
+			got := *report
+			got.Documents = nil
+			if !reflect.DeepEqual(got, tc.want) || !equalMaps(status, tc.wantStatus) {
+				t.Errorf("got %+v with %v, want %+v with %v", got, status, tc.want, tc.wantStatus)
+			}
+			if strings.Join(db.updated, ",") != strings.Join(tc.wantUpdates, ",") {
+				t.Errorf("got the documents %q updated, want %q", db.updated, tc.wantUpdates)
+			}
+		})
+	}
+}
+
+// TestRunReportsChanges checks the changes of the changed and the skipped documents in the report
+func TestRunReportsChanges(t *testing.T) {
+	s, _ := newTestService(t)
+
+	report, err := s.Run(context.Background(), Input{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dr := range report.Documents {
+		switch dr.ID {
+		case "d2":
+			if len(dr.Changes) != 2 || dr.Changes[0] != (FieldChange{Field: "merchant_name", Old: "Tyr Mar", New: "Tyr Mart"}) ||
+				dr.Changes[1] != (FieldChange{Field: "total", Old: 10.0, New: 12.5}) {
+				t.Errorf("got changes %+v", dr.Changes)
+			}
+		case "d3":
+			if dr.Error != ErrNoStoredResult.Error() {
+				t.Errorf("got error %q, want %q", dr.Error, ErrNoStoredResult)
+			}
+		case "d4":
+			if dr.Error != ErrInReport.Error() || len(dr.Changes) != 0 {
+				t.Errorf("got error %q and changes %+v, want %q", dr.Error, dr.Changes, ErrInReport)
+			}
+		}
+	}
+}
+
+// fakeReextractDB answers the dry run statements as if the database held 4 analyzed documents:
+// d1 is up to date with its stored result, d2 is not, d3 has no stored result and d4 is not up to date
+// but claimed in an expense report under approval. It records the updated documents.
+type fakeReextractDB struct {
+	docs    []*types.Document
+	updated []string
+}
+
+func newTestService(t *testing.T) (*Service, *fakeReextractDB) {
+	t.Helper()
+
+	res := &azure.ResultAnalyzeResponse{}
+	if err := json.Unmarshal([]byte(storedResult), res); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := azure.ToDocument(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := func(id string, change func(d *types.Document)) *types.Document {
+		d := *mapped
+		d.ID, d.APIMRequestID = id, "apim-"+id
+		d.DocumentItem = &types.DocumentItem{Data: datatypes.JSON(mapped.DocumentItem.Data)}
+		if change != nil {
+			change(&d)
+		}
+		return &d
+	}
+	outdated := func(d *types.Document) { d.MerchantName, d.Total = "Tyr Mar", 10 }
+
+	f := &fakeReextractDB{docs: []*types.Document{doc("d1", nil), doc("d2", outdated), doc("d3", nil), doc("d4", outdated)}}
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		gdb.Callback().Query().After("gorm:query").Before("gorm:preload").Register("test:query", f.query),
+		gdb.Callback().Update().After("gorm:update").Register("test:update", f.update),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return New(repo.New(gdb)), f
+}
+
+func (f *fakeReextractDB) query(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	vars := whereVars(db.Statement)
+	switch dest := db.Statement.Dest.(type) {
+	case *types.Document:
+		for _, d := range f.docs {
+			if vars[0] == d.ID {
+				*dest = *d
+				db.RowsAffected = 1
+				return
+			}
+		}
+		db.AddError(gorm.ErrRecordNotFound)
+	case *[]*types.Document:
+		limit := *db.Statement.Clauses["LIMIT"].Expression.(clause.Limit).Limit
+		for _, d := range f.docs {
+			if d.ID > vars[0].(string) && len(*dest) < limit {
+				*dest = append(*dest, d)
+			}
+		}
+		db.RowsAffected = int64(len(*dest))
+	case *[]*types.DocumentItem:
+		// the items of the listed documents are preloaded by document_id IN (...)
+		where := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+		for _, expr := range where.Exprs {
+			in, ok := expr.(clause.IN)
+			if !ok {
+				continue
+			}
+			for _, d := range f.docs {
+				for _, id := range in.Values {
+					if id == d.ID {
+						*dest = append(*dest, &types.DocumentItem{DocumentID: d.ID, Data: d.DocumentItem.Data})
+					}
+				}
+			}
+		}
+		db.RowsAffected = int64(len(*dest))
+	case *types.DocumentAnalysis:
+		db.AddError(gorm.ErrRecordNotFound)
+	case *types.ActivityLog:
+		if vars[0] == "apim-d3" {
+			db.AddError(gorm.ErrRecordNotFound)
+			return
+		}
+		*dest = types.ActivityLog{ResponseCode: 200, ResponseBody: datatypes.JSON(storedResult)}
+		db.RowsAffected = 1
+	case *int64:
+		if strings.Contains(db.Statement.SQL.String(), "FROM expense_report_documents") && vars[0] == "d4" {
+			*dest, db.RowsAffected = 1, 1
+		}
+	}
+}
+
+func (f *fakeReextractDB) update(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if db.Statement.Table == "documents" {
+		f.updated = append(f.updated, whereVars(db.Statement)[0].(string))
+	}
+	db.RowsAffected = 1
+}
+
+func equalMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// whereVars returns the vars of the where expressions of the statement, the soft delete condition aside
+func whereVars(stmt *gorm.Statement) []interface{} {
+	where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	vars := []interface{}{}
+	for _, expr := range where.Exprs {
+		if e, ok := expr.(clause.Expr); ok {
+			vars = append(vars, e.Vars...)
+		}
+	}
+	return vars
+}
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
@@ -0,0 +1,15 @@
+package reextract
+
+import (
+	"tyr/internal/repo"
+)
+
+// New creates new re-extraction service
+func New(repo *repo.Service) *Service {
+	return &Service{repo: repo}
+}
+
+// Service re-applies the current mapping code on the stored raw Azure analyze results
+type Service struct {
+	repo *repo.Service
+}
@@ -0,0 +1,54 @@
+package reextract
+
+// Input represents re-extraction input
+type Input struct {
+	// Re-extract a single document if set, otherwise all analyzed documents
+	DocumentID string
+	// Only report the differences without updating any document
+	DryRun bool
+	// Number of documents to be processed per batch, default to 100
+	BatchSize int
+	// Maximum number of documents to be processed when re-extracting all documents, 0 means unlimited
+	Limit int
+	// Continue re-extracting all documents after this document, taken from the next_after_id of the previous report
+	AfterID string
+}
+
+// Report represents re-extraction result
+// swagger:model ReextractReport
+type Report struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Changed   int              `json:"changed"`
+	Unchanged int              `json:"unchanged"`
+	Skipped   int              `json:"skipped"`
+	Failed    int              `json:"failed"`
+	Documents []DocumentReport `json:"documents"`
+	// Set when the limit is reached before all documents are processed, to be passed as after_id to continue
+	NextAfterID string `json:"next_after_id,omitempty"`
+}
+
+// DocumentReport represents re-extraction result of a single document, unchanged documents are not reported
+// swagger:model ReextractDocumentReport
+type DocumentReport struct {
+	ID            string        `json:"id"`
+	APIMRequestID string        `json:"apim_request_id"`
+	Status        string        `json:"status"` // changed || skipped || failed
+	Changes       []FieldChange `json:"changes,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// FieldChange represents the difference of a single field
+// swagger:model ReextractFieldChange
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// consts for document report status
+const (
+	StatusChanged = "changed"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
@@ -2,13 +2,24 @@ package repo
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
+
 	"tyr/internal/types"
 
 	repoutil "github.com/M15t/gram/pkg/util/repo"
+	requestutil "github.com/M15t/gram/pkg/util/request"
 
 	"gorm.io/gorm"
 )
 
+// ActivityLogPartitionPrefix is the name prefix of monthly activity log partitions, eg: activity_logs_p202404
+const ActivityLogPartitionPrefix = "activity_logs_p"
+
+// ActivityLogDefaultPartition is the partition of the activity logs out of the monthly partitions
+const ActivityLogDefaultPartition = "activity_logs_default"
+
 // ActivityLog represents the client for activity log table
 type ActivityLog struct {
 	*repoutil.Repo[types.ActivityLog]
@@ -28,3 +39,208 @@ func (r *ActivityLog) FindByAPIMRequestID(ctx context.Context, APIMRequestID str
 
 	return rec, nil
 }
+
+// FindAnalyzeResult finds the latest succeeded analyze result response by apim_request_id
+func (r *ActivityLog) FindAnalyzeResult(ctx context.Context, APIMRequestID string) (*types.ActivityLog, error) {
+	rec := &types.ActivityLog{}
+	if err := r.GDB.WithContext(ctx).
+		Where(`apim_request_id = ? AND response_code = 200 AND response_body->>'status' = 'succeeded'`, APIMRequestID).
+		Order(`created_at DESC`).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ActivityLogKeysets contains the supported sortings of the keyset paginated activity log listing
+var ActivityLogKeysets = Keysets[types.ActivityLog]{
+	Default: "-created_at",
+	ID:      func(l *types.ActivityLog) string { return l.ID },
+	Columns: map[string]Keyset[types.ActivityLog]{
+		"created_at": {Column: "created_at", Time: true, Value: func(l *types.ActivityLog) any { return l.CreatedAt }},
+	},
+}
+
+// List reads all activity logs by given conditions
+func (r *ActivityLog) List(ctx context.Context, output interface{}, count *int64, lc *requestutil.ListCondition[ActivityLogsFilter]) error {
+	return r.ReadAllByCondition(ctx, output, count, &requestutil.ListQueryCondition{
+		Page:    lc.Page,
+		PerPage: lc.PerPage,
+		Sort:    lc.Sort,
+		Count:   lc.Count,
+		Filter:  activityLogConds(lc.Filter),
+	})
+}
+
+// ListByCursor reads a page of activity logs by given conditions using keyset pagination ordered by created time
+func (r *ActivityLog) ListByCursor(ctx context.Context, output *[]*types.ActivityLog, count *int64, cc *CursorCondition[ActivityLogsFilter]) (*CursorPage, error) {
+	db := r.GDB.WithContext(ctx).Model(&types.ActivityLog{})
+	if conds := activityLogConds(cc.Filter); conds[0] != "" {
+		db = db.Where(conds[0], conds[1:]...)
+	}
+
+	if cc.Count {
+		if err := db.Session(&gorm.Session{}).Count(count).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return paginateByCursor(db, ActivityLogKeysets, cc, output)
+}
+
+// activityLogConds builds the SQL conditions of the given filter, the first element is the query string followed by its vars
+func activityLogConds(f ActivityLogsFilter) []any {
+	conds := []string{}
+	vars := []any{}
+	if f.URL != "" {
+		conds = append(conds, "request_url like ?")
+		sVal := strings.ReplaceAll(f.URL, "%", "")
+		sVal = strings.ReplaceAll(sVal, "?", "")
+		vars = append(vars, "%"+sVal+"%")
+	}
+
+	if f.Method != "" {
+		conds = append(conds, "request_method = ?")
+		vars = append(vars, f.Method)
+	}
+
+	if f.ResponseCode != 0 {
+		conds = append(conds, "response_code = ?")
+		vars = append(vars, f.ResponseCode)
+	}
+
+	if f.APIMRequestID != "" {
+		conds = append(conds, "apim_request_id = ?")
+		vars = append(vars, f.APIMRequestID)
+	}
+
+	if f.From != nil {
+		conds = append(conds, "created_at >= ?")
+		vars = append(vars, *f.From)
+	}
+
+	if f.To != nil {
+		conds = append(conds, "created_at < ?")
+		vars = append(vars, *f.To)
+	}
+
+	if f.MinDurationMS > 0 {
+		conds = append(conds, "duration_ms >= ?")
+		vars = append(vars, f.MinDurationMS)
+	}
+
+	if f.MaxDurationMS > 0 {
+		conds = append(conds, "duration_ms <= ?")
+		vars = append(vars, f.MaxDurationMS)
+	}
+
+	return append([]any{strings.Join(conds, " AND ")}, vars...)
+}
+
+// ListBefore reads a batch of records created before the given time, including soft-deleted ones.
+// Records are ordered by (created_at, id) and start right after the given cursor if any.
+func (r *ActivityLog) ListBefore(ctx context.Context, before time.Time, after *types.ActivityLog, limit int) ([]*types.ActivityLog, error) {
+	db := r.GDB.WithContext(ctx).Unscoped().Where(`created_at < ?`, before)
+	if after != nil {
+		db = db.Where(`(created_at, id) > (?, ?)`, after.CreatedAt, after.ID)
+	}
+
+	recs := []*types.ActivityLog{}
+	if err := db.Order(`created_at ASC, id ASC`).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// HardDeleteBefore permanently deletes the given records created before the given time
+func (r *ActivityLog) HardDeleteBefore(ctx context.Context, before time.Time, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.GDB.WithContext(ctx).Unscoped().Delete(&types.ActivityLog{}, `created_at < ? AND id IN ?`, before, ids).Error
+}
+
+// EnsurePartitions creates the missing monthly partitions covering the given time range.
+// The records of the month which landed in the default partition meanwhile are moved into the new partition,
+// postgres refuses to create a partition overlapping the rows of the default one.
+func (r *ActivityLog) EnsurePartitions(ctx context.Context, from, to time.Time) error {
+	db := r.GDB.WithContext(ctx)
+	for m := monthStart(from); !m.After(to); m = m.AddDate(0, 1, 0) {
+		var existed bool
+		if err := db.Raw(`SELECT to_regclass(?) IS NOT NULL`, activityLogPartitionName(m)).Scan(&existed).Error; err != nil {
+			return err
+		}
+		if existed {
+			continue
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			for _, sql := range activityLogPartitionSQL(m) {
+				if err := tx.Exec(sql).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropEmptyPartitionsBefore drops the monthly partitions that end before the given time and have no records left
+func (r *ActivityLog) DropEmptyPartitionsBefore(ctx context.Context, before time.Time) ([]string, error) {
+	db := r.GDB.WithContext(ctx)
+
+	names := []string{}
+	if err := db.Raw(`SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = ? AND c.relname LIKE ?`, "activity_logs", ActivityLogPartitionPrefix+"%").Scan(&names).Error; err != nil {
+		return nil, err
+	}
+
+	dropped := []string{}
+	for _, name := range names {
+		m, err := time.Parse("200601", strings.TrimPrefix(name, ActivityLogPartitionPrefix))
+		if err != nil || m.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+
+		var existed bool
+		if err := db.Raw(fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s)`, name)).Scan(&existed).Error; err != nil {
+			return dropped, err
+		}
+		if existed {
+			continue
+		}
+
+		if err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, name)).Error; err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, name)
+	}
+
+	return dropped, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func activityLogPartitionName(m time.Time) string {
+	return ActivityLogPartitionPrefix + m.Format("200601")
+}
+
+// activityLogPartitionSQL returns the statements creating the partition of the month out of the rows of the default partition
+func activityLogPartitionSQL(m time.Time) []string {
+	name, from, to := activityLogPartitionName(m), m.Format(time.DateOnly), m.AddDate(0, 1, 0).Format(time.DateOnly)
+	return []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE activity_logs INCLUDING DEFAULTS)`, name),
+		fmt.Sprintf(`WITH moved AS (DELETE FROM %s WHERE created_at >= '%s' AND created_at < '%s' RETURNING *) INSERT INTO %s SELECT * FROM moved`,
+			ActivityLogDefaultPartition, from, to, name),
+		fmt.Sprintf(`ALTER TABLE activity_logs ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`, name, from, to),
+	}
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+	requestutil "github.com/M15t/gram/pkg/util/request"
+
+	"gorm.io/gorm"
+)
+
+// APIKey represents the client for api_keys table
+type APIKey struct {
+	*repoutil.Repo[types.APIKey]
+}
+
+// NewAPIKey returns a new API key database instance
+func NewAPIKey(gdb *gorm.DB) *APIKey {
+	return &APIKey{repoutil.NewRepo[types.APIKey](gdb)}
+}
+
+// List reads all API keys by given conditions, the newest first unless sorted
+func (r *APIKey) List(ctx context.Context, output interface{}, count *int64, lc *requestutil.ListCondition[APIKeysFilter]) error {
+	db := r.GDB.WithContext(ctx).Model(&types.APIKey{})
+
+	if lc.Filter.Revoked != nil {
+		if *lc.Filter.Revoked {
+			db = db.Where(`revoked_at IS NOT NULL`)
+		} else {
+			db = db.Where(`revoked_at IS NULL`)
+		}
+	}
+
+	if len(lc.Filter.Query) > 0 {
+		qConds, qVars := lc.Filter.Query.SQL()
+		db = db.Where(qConds, qVars...)
+	}
+
+	if lc.Count {
+		if err := db.Session(&gorm.Session{}).Count(count).Error; err != nil {
+			return err
+		}
+	}
+
+	db = repoutil.WithPaging(db, lc.Page, lc.PerPage)
+	db = repoutil.WithSorting(db, lc.Sort, r.QuoteCol)
+	if lc.Sort == "" {
+		db = db.Order(`created_at DESC`)
+	}
+
+	return db.Find(output).Error
+}
+
+// ListByUserID lists the API keys of the user which are not revoked, the newest first
+func (r *APIKey) ListByUserID(ctx context.Context, userID string) ([]*types.APIKey, error) {
+	recs := []*types.APIKey{}
+	if err := r.GDB.WithContext(ctx).
+		Where(`user_id = ? AND revoked_at IS NULL`, userID).Order(`created_at DESC`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// CountActiveByUserID counts the API keys of the user which are neither revoked nor expired at the given time
+func (r *APIKey) CountActiveByUserID(ctx context.Context, userID string, at time.Time) (int64, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Model(&types.APIKey{}).
+		Where(`user_id = ? AND revoked_at IS NULL AND expires_at > ?`, userID, at).Count(&count).Error
+	return count, err
+}
+
+// FindByPrefix finds an API key by the prefix of its token
+func (r *APIKey) FindByPrefix(ctx context.Context, prefix string) (*types.APIKey, error) {
+	rec := &types.APIKey{}
+	if err := r.GDB.WithContext(ctx).Where(`prefix = ?`, prefix).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// Revoke revokes an API key, returns gorm.ErrRecordNotFound if it is already revoked
+func (r *APIKey) Revoke(ctx context.Context, id string, at time.Time) error {
+	db := r.GDB.WithContext(ctx).Model(&types.APIKey{}).
+		Where(`id = ? AND revoked_at IS NULL`, id).Update("revoked_at", at)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Touch records the last usage of the API key, unless it is already recorded after the given time
+func (r *APIKey) Touch(ctx context.Context, id, ipAddress string, at, after time.Time) error {
+	return r.GDB.WithContext(ctx).Model(&types.APIKey{}).
+		Where(`id = ? AND (last_used_at IS NULL OR last_used_at < ?)`, id, after).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ipAddress}).Error
+}
@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+)
+
+// AuditEvent represents the client for audit_events table
+type AuditEvent struct {
+	*repoutil.Repo[types.AuditEvent]
+}
+
+// NewAuditEvent returns a new audit event database instance
+func NewAuditEvent(gdb *gorm.DB) *AuditEvent {
+	return &AuditEvent{repoutil.NewRepo[types.AuditEvent](gdb)}
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+	"gorm.io/gorm"
+)
+
+// Default and maximum number of records of a keyset paginated page
+const (
+	DefaultCursorLimit = 25
+	MaxCursorLimit     = 100
+)
+
+// Keyset pagination errors
+var (
+	// ErrInvalidCursor is returned when the cursor is malformed or does not match the requested sorting
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrUnsupportedCursorSort is returned when the sorting is not supported by the keyset paginated listing
+	ErrUnsupportedCursorSort = errors.New("unsupported cursor sorting")
+)
+
+// CursorCondition represents a generic type for listing data with keyset pagination
+type CursorCondition[T any] struct {
+	// Opaque cursor returned with the previous page, empty for the first page
+	Cursor string
+	// Number of records per page
+	Limit int
+	// Single sort key with direction prefix, eg: `-id`. Default to the sorting of the cursor or of the listing
+	Sort string
+	// Whether to count the total records. If not, the returning `count` number will always be zero.
+	Count bool
+	// Custom filter type
+	Filter T
+}
+
+// CursorPage contains the position of a keyset paginated page
+type CursorPage struct {
+	// Cursor to request the next page, empty if there is no more records
+	NextCursor string
+	HasMore    bool
+}
+
+// Keyset describes a column to paginate by, the record id is always used as the tie breaker
+type Keyset[T any] struct {
+	Column string
+	// Whether the column is a timestamp, to decode the cursor value
+	Time bool
+	// Value returns the column value of a record, either string or time.Time
+	Value func(*T) any
+}
+
+// Keysets contains the supported sortings of a keyset paginated listing
+type Keysets[T any] struct {
+	// Default sorting with direction prefix, eg: `-id`
+	Default string
+	// ID returns the unique id of a record
+	ID func(*T) string
+	// Supported columns keyed by the sort key
+	Columns map[string]Keyset[T]
+}
+
+// cursorToken is the decoded content of a cursor
+type cursorToken struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// paginateByCursor applies the keyset conditions, ordering and limit to the query and reads the page into output.
+// The ordering is total since the id is unique, so that records inserted concurrently never shift the pages.
+func paginateByCursor[T, F any](db *gorm.DB, ks Keysets[T], cc *CursorCondition[F], output *[]*T) (*CursorPage, error) {
+	var token *cursorToken
+	if cc.Cursor != "" {
+		token = &cursorToken{}
+		b, err := base64.RawURLEncoding.DecodeString(cc.Cursor)
+		if err != nil || json.Unmarshal(b, token) != nil || token.ID == "" {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	sort := normalizeSort(cc.Sort)
+	switch {
+	case sort == "" && token != nil:
+		sort = token.Sort
+	case sort == "":
+		sort = ks.Default
+	case token != nil && sort != token.Sort:
+		return nil, ErrInvalidCursor
+	}
+
+	col, dir := repoutil.ParseSortValue(sort)
+	key, ok := ks.Columns[col]
+	if !ok {
+		return nil, ErrUnsupportedCursorSort
+	}
+	cmp := ">"
+	if dir == "DESC" {
+		cmp = "<"
+	}
+
+	if token != nil {
+		var value any = token.Value
+		if key.Time {
+			t, err := time.Parse(time.RFC3339Nano, token.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			value = t
+		}
+		if key.Column == "id" {
+			db = db.Where("id "+cmp+" ?", token.ID)
+		} else {
+			db = db.Where("("+key.Column+", id) "+cmp+" (?, ?)", value, token.ID)
+		}
+	}
+
+	limit := cc.Limit
+	if limit <= 0 {
+		limit = DefaultCursorLimit
+	}
+	if limit > MaxCursorLimit {
+		limit = MaxCursorLimit
+	}
+
+	if key.Column != "id" {
+		db = db.Order(key.Column + " " + dir)
+	}
+	if err := db.Order("id " + dir).Limit(limit + 1).Find(output).Error; err != nil {
+		return nil, err
+	}
+
+	page := &CursorPage{}
+	if len(*output) > limit {
+		*output = (*output)[:limit]
+		last := (*output)[limit-1]
+		next := cursorToken{Sort: sort, ID: ks.ID(last)}
+		switch v := key.Value(last).(type) {
+		case time.Time:
+			next.Value = v.UTC().Format(time.RFC3339Nano)
+		case string:
+			next.Value = v
+		}
+		b, err := json.Marshal(next)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(b)
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+// normalizeSort prefixes the sort key with its direction, eg: `id` => `+id`
+func normalizeSort(sort string) string {
+	col, dir := repoutil.ParseSortValue(sort)
+	switch {
+	case col == "":
+		return ""
+	case dir == "DESC":
+		return "-" + col
+	default:
+		return "+" + col
+	}
+}
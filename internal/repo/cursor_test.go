@@ -0,0 +1,315 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type cursorRecord struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+var cursorRecordKeysets = Keysets[cursorRecord]{
+	Default: "-created_at",
+	ID:      func(r *cursorRecord) string { return r.ID },
+	Columns: map[string]Keyset[cursorRecord]{
+		"id":         {Column: "id", Value: func(r *cursorRecord) any { return r.ID }},
+		"name":       {Column: "name", Value: func(r *cursorRecord) any { return r.Name }},
+		"created_at": {Column: "created_at", Time: true, Value: func(r *cursorRecord) any { return r.CreatedAt }},
+	},
+}
+
+// TestPaginateByCursorVisitsAllRecords pages through records sharing the same sort values,
+// every record must be listed exactly once in the order of the sort key then the id
+func TestPaginateByCursorVisitsAllRecords(t *testing.T) {
+	base := time.Date(2026, 10, 19, 9, 0, 0, 123456789, time.UTC)
+	records := []cursorRecord{}
+	for i := 0; i < 11; i++ {
+		// 4 records per timestamp and 2 names only, so most pages end in the middle of a tie
+		records = append(records, cursorRecord{
+			ID:        fmt.Sprintf("01H%02d", i),
+			Name:      []string{"alpha", "beta"}[i%2],
+			CreatedAt: base.Add(time.Duration(i/4) * time.Second),
+		})
+	}
+	gdb := newCursorTestDB(t, records)
+
+	for _, tc := range []struct {
+		sort string
+		less func(a, b cursorRecord) bool
+	}{
+		{sort: "", less: func(a, b cursorRecord) bool { return desc(a.CreatedAt.Compare(b.CreatedAt), a.ID, b.ID) }},
+		{sort: "created_at", less: func(a, b cursorRecord) bool { return asc(a.CreatedAt.Compare(b.CreatedAt), a.ID, b.ID) }},
+		{sort: "-name", less: func(a, b cursorRecord) bool { return desc(compareStrings(a.Name, b.Name), a.ID, b.ID) }},
+		{sort: "+id", less: func(a, b cursorRecord) bool { return a.ID < b.ID }},
+		{sort: "-id", less: func(a, b cursorRecord) bool { return a.ID > b.ID }},
+	} {
+		for _, limit := range []int{1, 3, 4, 10, 11, 20} {
+			t.Run(fmt.Sprintf("%s by %d", tc.sort, limit), func(t *testing.T) {
+				want := append([]cursorRecord{}, records...)
+				sort.Slice(want, func(i, j int) bool { return tc.less(want[i], want[j]) })
+
+				got := []cursorRecord{}
+				cc := &CursorCondition[struct{}]{Limit: limit, Sort: tc.sort}
+				for pages := 0; ; pages++ {
+					if pages > len(records) {
+						t.Fatal("the pages never end")
+					}
+					output := []*cursorRecord{}
+					page, err := paginateByCursor(gdb.Table("cursor_records"), cursorRecordKeysets, cc, &output)
+					if err != nil {
+						t.Fatalf("got %v", err)
+					}
+					for _, r := range output {
+						got = append(got, *r)
+					}
+					if page.HasMore != (page.NextCursor != "") {
+						t.Fatalf("got has more %v with cursor %q", page.HasMore, page.NextCursor)
+					}
+					if !page.HasMore {
+						break
+					}
+					// the sorting is kept by the cursor
+					cc = &CursorCondition[struct{}]{Limit: limit, Cursor: page.NextCursor}
+				}
+
+				if len(got) != len(want) {
+					t.Fatalf("listed %d records, want %d", len(got), len(want))
+				}
+				for i := range want {
+					if got[i].ID != want[i].ID {
+						t.Fatalf("record %d is %s, want %s", i, got[i].ID, want[i].ID)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPaginateByCursorRejectsInvalidCursors(t *testing.T) {
+	gdb := newCursorTestDB(t, nil)
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	cases := []struct {
+		name   string
+		cursor string
+		sort   string
+		want   error
+	}{
+		{name: "not base64", cursor: "not a cursor!", want: ErrInvalidCursor},
+		{name: "standard base64 padding", cursor: base64.StdEncoding.EncodeToString([]byte(`{"s":"+id","id":"a"}`)), want: ErrInvalidCursor},
+		{name: "not JSON", cursor: encode("plain"), want: ErrInvalidCursor},
+		{name: "without id", cursor: encode(`{"s":"+id","v":"a"}`), want: ErrInvalidCursor},
+		{name: "tampered time value", cursor: encode(`{"s":"-created_at","v":"1 OR 1=1","id":"a"}`), want: ErrInvalidCursor},
+		{name: "another sorting", cursor: encode(`{"s":"-created_at","v":"2026-10-19T09:00:00Z","id":"a"}`), sort: "+created_at", want: ErrInvalidCursor},
+		{name: "tampered sorting", cursor: encode(`{"s":"-password","v":"x","id":"a"}`), want: ErrUnsupportedCursorSort},
+		{name: "unsupported sorting", sort: "password", want: ErrUnsupportedCursorSort},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := []*cursorRecord{}
+			_, err := paginateByCursor(gdb.Table("cursor_records"), cursorRecordKeysets, &CursorCondition[struct{}]{Cursor: tc.cursor, Sort: tc.sort}, &output)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestPaginateByCursorLimit(t *testing.T) {
+	records := []cursorRecord{}
+	for i := 0; i < MaxCursorLimit+10; i++ {
+		records = append(records, cursorRecord{ID: fmt.Sprintf("01H%03d", i)})
+	}
+	gdb := newCursorTestDB(t, records)
+
+	for limit, want := range map[int]int{0: DefaultCursorLimit, -1: DefaultCursorLimit, 7: 7, MaxCursorLimit + 1: MaxCursorLimit} {
+		output := []*cursorRecord{}
+		page, err := paginateByCursor(gdb.Table("cursor_records"), cursorRecordKeysets, &CursorCondition[struct{}]{Limit: limit, Sort: "id"}, &output)
+		if err != nil {
+			t.Fatalf("got %v", err)
+		}
+		if len(output) != want || !page.HasMore {
+			t.Errorf("limit %d listed %d records with has more %v, want %d", limit, len(output), page.HasMore, want)
+		}
+	}
+}
+
+func TestNormalizeSort(t *testing.T) {
+	for sort, want := range map[string]string{"": "", "id": "+id", "+id": "+id", "-created_at": "-created_at"} {
+		if got := normalizeSort(sort); got != want {
+			t.Errorf("normalizeSort(%q) = %q, want %q", sort, got, want)
+		}
+	}
+}
+
+func asc(cmp int, aID, bID string) bool {
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return aID < bID
+}
+
+func desc(cmp int, aID, bID string) bool {
+	if cmp != 0 {
+		return cmp > 0
+	}
+	return aID > bID
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+var (
+	keysetWhereRe = regexp.MustCompile(`WHERE (?:\((\w+), id\)|id) ([<>]) `)
+	orderRe       = regexp.MustCompile(`ORDER BY (?:(\w+) (ASC|DESC),)?id (ASC|DESC)`)
+	limitRe       = regexp.MustCompile(`LIMIT \$(\d+)`)
+)
+
+// newCursorTestDB returns a dry run database answering the keyset paginated queries from the given records,
+// evaluating the row comparison, ordering and limit of the built statement as postgres does
+func newCursorTestDB(t *testing.T, records []cursorRecord) *gorm.DB {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := func(r cursorRecord, col string) any {
+		switch col {
+		case "name":
+			return r.Name
+		case "created_at":
+			return r.CreatedAt
+		}
+		return r.ID
+	}
+	compare := func(a, b any) int {
+		switch a := a.(type) {
+		case time.Time:
+			return a.Compare(b.(time.Time))
+		case string:
+			return compareStrings(a, b.(string))
+		}
+		panic(fmt.Sprintf("unexpected value %T", a))
+	}
+
+	answer := func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		query, vars := db.Statement.SQL.String(), db.Statement.Vars
+
+		rows := append([]cursorRecord{}, records...)
+		if m := keysetWhereRe.FindStringSubmatch(query); m != nil {
+			col, cmp := m[1], m[2]
+			rows = rows[:0]
+			for _, r := range records {
+				// (col, id) < (v, id) compares the columns in order, the id breaks the ties
+				c, idVar := 0, vars[0]
+				if col != "" {
+					c, idVar = compare(value(r, col), vars[0]), vars[1]
+				}
+				if c == 0 {
+					c = compareStrings(r.ID, idVar.(string))
+				}
+				if (cmp == "<" && c < 0) || (cmp == ">" && c > 0) {
+					rows = append(rows, r)
+				}
+			}
+		}
+
+		m := orderRe.FindStringSubmatch(query)
+		if m == nil {
+			db.AddError(fmt.Errorf("unordered query: %s", query))
+			return
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			c := 0
+			if m[1] != "" {
+				c = compare(value(rows[i], m[1]), value(rows[j], m[1]))
+				if m[2] == "DESC" {
+					c = -c
+				}
+			}
+			if c == 0 {
+				c = compareStrings(rows[i].ID, rows[j].ID)
+				if m[3] == "DESC" {
+					c = -c
+				}
+			}
+			return c < 0
+		})
+
+		// the limit is the last var
+		if m := limitRe.FindStringSubmatch(query); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			limit := vars[n-1].(int)
+			rows = rows[:min(limit, len(rows))]
+		}
+
+		dest := db.Statement.Dest.(*[]*cursorRecord)
+		for i := range rows {
+			*dest = append(*dest, &rows[i])
+		}
+		db.RowsAffected = int64(len(rows))
+	}
+	if err := gdb.Callback().Query().After("gorm:query").Register("test:cursor_records", answer); err != nil {
+		t.Fatal(err)
+	}
+
+	return gdb
+}
+
+// dryRunConnPool is the connection of the dry run database, the statements are never sent
+type dryRunConnPool struct{}
+
+func (*dryRunConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConnPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunConnPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunConnPool) Commit() error   { return nil }
+func (*dryRunConnPool) Rollback() error { return nil }
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+)
+
+// DataExport represents the client for data_exports table
+type DataExport struct {
+	*repoutil.Repo[types.DataExport]
+}
+
+// NewDataExport returns a new data export database instance
+func NewDataExport(gdb *gorm.DB) *DataExport {
+	return &DataExport{repoutil.NewRepo[types.DataExport](gdb)}
+}
+
+// FindByID finds a data export of the scope by id
+func (r *DataExport) FindByID(ctx context.Context, scope Scope, id string) (*types.DataExport, error) {
+	rec := &types.DataExport{}
+	if err := r.GDB.WithContext(ctx).Scopes(scope.Apply).Where(`id = ?`, id).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ListPending lists the pending data exports, the oldest first
+func (r *DataExport) ListPending(ctx context.Context, limit int) ([]*types.DataExport, error) {
+	recs := []*types.DataExport{}
+	if err := r.GDB.WithContext(ctx).Where(`status = ?`, types.DataExportPending).Order(`created_at`).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// FailPending marks the data exports pending since before the given time as failed at the time with the reason
+func (r *DataExport) FailPending(ctx context.Context, before, at time.Time, reason string) (int64, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.DataExport{}).
+		Where(`status = ? AND created_at < ?`, types.DataExportPending, before).
+		Updates(map[string]interface{}{"status": types.DataExportFailed, "error": reason, "completed_at": at})
+	return res.RowsAffected, res.Error
+}
+
+// ListExpired lists the data exports expired at the given time
+func (r *DataExport) ListExpired(ctx context.Context, at time.Time, limit int) ([]*types.DataExport, error) {
+	recs := []*types.DataExport{}
+	if err := r.GDB.WithContext(ctx).Where(`expires_at <= ?`, at).Order(`expires_at`).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
@@ -3,6 +3,8 @@ package repo
 import (
 	"context"
 	"strings"
+	"unicode"
+
 	"tyr/internal/types"
 
 	repoutil "github.com/M15t/gram/pkg/util/repo"
@@ -20,16 +22,61 @@ func NewDocument(gdb *gorm.DB) *Document {
 	return &Document{repoutil.NewRepo[types.Document](gdb)}
 }
 
-// FindByAPIMRequestID finds a document by the given apimrequestID
-func (r *Document) FindByAPIMRequestID(ctx context.Context, apimReqID string) (*types.Document, error) {
+// FindByAPIMRequestID finds a document of the scope by the given apimrequestID
+func (r *Document) FindByAPIMRequestID(ctx context.Context, scope Scope, apimReqID string) (*types.Document, error) {
+	rec := &types.Document{}
+	if err := r.GDB.WithContext(ctx).Scopes(scope.ApplyShared).Preload("DocumentItem").Where(`apim_request_id = ?`, apimReqID).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// FindByID finds a document of the scope by the given id
+func (r *Document) FindByID(ctx context.Context, scope Scope, documentID string) (*types.Document, error) {
 	rec := &types.Document{}
-	if err := r.GDB.WithContext(ctx).Preload("DocumentItem").Where(`apim_request_id = ?`, apimReqID).Take(rec).Error; err != nil {
+	if err := r.GDB.WithContext(ctx).Scopes(scope.ApplyShared).Preload("DocumentItem").Where(`id = ?`, documentID).Take(rec).Error; err != nil {
 		return nil, err
 	}
 
 	return rec, nil
 }
 
+// ExistedByID checks if a document of the scope exists by the given id
+func (r *Document) ExistedByID(ctx context.Context, scope Scope, documentID string) (bool, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Model(&types.Document{}).Scopes(scope.ApplyShared).Where(`id = ?`, documentID).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateByID updates a document of the scope by the given id, returns gorm.ErrRecordNotFound if there is none
+func (r *Document) UpdateByID(ctx context.Context, scope Scope, documentID string, updates any) error {
+	db := r.GDB.WithContext(ctx).Model(&types.Document{}).Scopes(scope.ApplyShared).Where(`id = ?`, documentID).Omit("id").Updates(updates)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteByID deletes a document of the scope with its items by the given id, returns gorm.ErrRecordNotFound if there is none
+func (r *Document) DeleteByID(ctx context.Context, scope Scope, documentID string) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		db := tx.Scopes(scope.ApplyShared).Where(`id = ?`, documentID).Delete(&types.Document{})
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Where(`document_id = ?`, documentID).Delete(&types.DocumentItem{}).Error
+	})
+}
+
 // ReadByID read a document by given id
 func (r *Document) ReadByID(ctx context.Context, documentID string) (*types.Document, error) {
 	rec := &types.Document{}
@@ -40,29 +87,144 @@ func (r *Document) ReadByID(ctx context.Context, documentID string) (*types.Docu
 	return rec, nil
 }
 
-// List reads all documents by given conditions
-func (r *Document) List(ctx context.Context, output interface{}, count *int64, lc *requestutil.ListCondition[DocumentsFilter], preloadConds []string) error {
-	conds := []string{}
-	vars := []any{}
-	if lc.Filter.Search != "" {
-		conds = append(conds, "(vendor_name like ? OR customer_name like ?)")
-		sVal := strings.ReplaceAll(lc.Filter.Search, "%", "")
+// ListAnalyzedAfter reads a batch of analyzed documents ordered by id, starting right after the given id
+func (r *Document) ListAnalyzedAfter(ctx context.Context, afterID string, limit int) ([]*types.Document, error) {
+	recs := []*types.Document{}
+	if err := r.GDB.WithContext(ctx).Preload("DocumentItem").
+		Where(`apim_request_id <> '' AND id > ?`, afterID).
+		Order(`id ASC`).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// DocumentKeysets contains the supported sortings of the keyset paginated document listing
+var DocumentKeysets = Keysets[types.Document]{
+	Default: "-id",
+	ID:      func(d *types.Document) string { return d.ID },
+	Columns: map[string]Keyset[types.Document]{
+		"id":               {Column: "id", Value: func(d *types.Document) any { return d.ID }},
+		"transaction_date": {Column: "transaction_date", Value: func(d *types.Document) any { return d.TransactionDate }},
+	},
+}
+
+// List reads all documents of the scope by given conditions.
+// The search is a full-text search with prefix matching over the merchant, the OCR content and the line item descriptions,
+// the results are ranked by relevance and come with a highlighted snippet unless another sorting is given.
+func (r *Document) List(ctx context.Context, scope Scope, output interface{}, count *int64, lc *requestutil.ListCondition[DocumentsFilter], preloadConds []string) error {
+	db, tsQuery := r.filter(ctx, scope, lc.Filter)
+
+	if lc.Count {
+		if err := db.Session(&gorm.Session{}).Count(count).Error; err != nil {
+			return err
+		}
+	}
+
+	if tsQuery != "" {
+		db = withSearchRank(db, tsQuery)
+		if lc.Sort == "" {
+			db = db.Order(`search_rank DESC`)
+		}
+	}
+
+	db = repoutil.WithPaging(db, lc.Page, lc.PerPage)
+	db = repoutil.WithSorting(db, lc.Sort, r.QuoteCol)
+	for _, p := range preloadConds {
+		db = db.Preload(p)
+	}
+
+	return db.Find(output).Error
+}
+
+// ListByCursor reads a page of documents of the scope by given conditions using keyset pagination, ordered by id or transaction date.
+// The full-text search narrows down the results and fills the highlighted snippets but does not change the ordering.
+func (r *Document) ListByCursor(ctx context.Context, scope Scope, output *[]*types.Document, count *int64, cc *CursorCondition[DocumentsFilter], preloadConds []string) (*CursorPage, error) {
+	db, tsQuery := r.filter(ctx, scope, cc.Filter)
+
+	if cc.Count {
+		if err := db.Session(&gorm.Session{}).Count(count).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if tsQuery != "" {
+		db = withSearchRank(db, tsQuery)
+	}
+	for _, p := range preloadConds {
+		db = db.Preload(p)
+	}
+
+	return paginateByCursor(db, DocumentKeysets, cc, output)
+}
+
+// filter builds the document query of the scope and the given filter, returns the query with the tsquery of the search if any
+func (r *Document) filter(ctx context.Context, scope Scope, f DocumentsFilter) (*gorm.DB, string) {
+	db := r.GDB.WithContext(ctx).Model(&types.Document{}).Scopes(scope.ApplyShared)
+
+	if f.Merchant != "" {
+		sVal := strings.ReplaceAll(f.Merchant, "%", "")
 		sVal = strings.ReplaceAll(sVal, "?", "")
-		sVal += "%"
-		vars = append(vars, sVal, sVal, sVal, sVal)
+		db = db.Where(`merchant_name ILIKE ?`, "%"+sVal+"%")
+	}
+
+	if f.Currency != "" {
+		db = db.Where(`currency = ?`, strings.ToUpper(f.Currency))
 	}
 
-	if lc.Filter.UserID != "" {
-		conds = append(conds, "user_id = ?")
-		vars = append(vars, lc.Filter.UserID)
+	if f.MinTotal != nil {
+		db = db.Where(`total >= ?`, *f.MinTotal)
 	}
 
-	return r.ReadAllByCondition(ctx, output, count, &requestutil.ListQueryCondition{
-		Page:    lc.Page,
-		PerPage: lc.PerPage,
-		Sort:    lc.Sort,
-		Count:   lc.Count,
-		Filter:  append([]any{strings.Join(conds, " AND ")}, vars...),
-	}, preloadConds...)
+	if f.MaxTotal != nil {
+		db = db.Where(`total <= ?`, *f.MaxTotal)
+	}
+
+	// transaction_date is stored as YYYY-MM-DD so that it is comparable as string
+	if f.DateFrom != "" {
+		db = db.Where(`transaction_date >= ?`, f.DateFrom)
+	}
+
+	if f.DateTo != "" {
+		db = db.Where(`transaction_date <= ?`, f.DateTo)
+	}
+
+	if len(f.Query) > 0 {
+		qConds, qVars := f.Query.SQL()
+		db = db.Where(qConds, qVars...)
+	}
 
+	tsQuery := toPrefixTSQuery(f.Search)
+	if tsQuery != "" {
+		db = db.Where(`search_vector @@ to_tsquery('simple', ?)`, tsQuery)
+	}
+
+	return db, tsQuery
+}
+
+// withSearchRank selects the relevance and the highlighted snippet of the search along with the document columns
+func withSearchRank(db *gorm.DB, tsQuery string) *gorm.DB {
+	return db.Select(`documents.*,
+		ts_rank(search_vector, to_tsquery('simple', ?)) AS search_rank,
+		ts_headline('simple', COALESCE(NULLIF((
+			SELECT a.content FROM document_analyses a
+			WHERE a.document_id = documents.id AND a.deleted_at IS NULL
+			ORDER BY a.analyzed_at DESC LIMIT 1
+		), ''), documents.merchant_name), to_tsquery('simple', ?), ?) AS search_highlight`,
+		tsQuery, tsQuery, searchHighlightOptions)
+}
+
+// searchHighlightOptions configures the snippets returned by ts_headline
+const searchHighlightOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5, MaxFragments=2"
+
+// toPrefixTSQuery converts free text into a prefix matching tsquery, eg: "blue bottle" => "blue:* & bottle:*".
+// Every character other than letters and digits is dropped so the input cannot inject tsquery operators.
+func toPrefixTSQuery(search string) string {
+	terms := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, t := range terms {
+		terms[i] = t + ":*"
+	}
+	return strings.Join(terms, " & ")
 }
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tyr/internal/types"
+
+	requestutil "github.com/M15t/gram/pkg/util/request"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestToPrefixTSQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		search string
+		want   string
+	}{
+		{name: "single term", search: "coffee", want: "coffee:*"},
+		{name: "several terms", search: "Blue  Bottle", want: "blue:* & bottle:*"},
+		{name: "unicode letters", search: "Phở Đà Nẵng", want: "phở:* & đà:* & nẵng:*"},
+		{name: "digits", search: "invoice 2026-10", want: "invoice:* & 2026:* & 10:*"},
+		{name: "operators", search: "a & b | !c <-> (d)", want: "a:* & b:* & c:* & d:*"},
+		{name: "prefix and weight", search: "blue:* bottle:AB", want: "blue:* & bottle:* & ab:*"},
+		{name: "quotes", search: `"tyr's" 'mart'`, want: "tyr:* & s:* & mart:*"},
+		{name: "empty", search: "", want: ""},
+		{name: "nothing left after stripping", search: ` & | ! ( ) :* ' " <-> `, want: ""},
+	} {
+		if got := toPrefixTSQuery(tc.search); got != tc.want {
+			t.Errorf("%s: toPrefixTSQuery(%q) = %q, want %q", tc.name, tc.search, got, tc.want)
+		}
+	}
+}
+
+// TestDocumentListSearch checks the listing query: the search narrows down the documents, selects the rank and the highlight
+// and orders by relevance unless another sorting is given
+func TestDocumentListSearch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		search   string
+		sort     string
+		wantRank bool
+		wantSort string
+	}{
+		{name: "search", search: "blue bottle", wantRank: true, wantSort: "ORDER BY search_rank DESC"},
+		{name: "search sorted", search: "blue bottle", sort: "-id", wantRank: true, wantSort: `ORDER BY "id" DESC`},
+		{name: "search of operators only", search: "& | !"},
+		{name: "no search"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, statements := newDocumentTestRepo(t)
+
+			output := []*types.Document{}
+			lc := &requestutil.ListCondition[DocumentsFilter]{Page: 1, PerPage: 10, Sort: tc.sort, Filter: DocumentsFilter{Search: tc.search}}
+			if err := r.List(context.Background(), ScopeOwner("01HUSER00000000000000000000"), &output, nil, lc, nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(*statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(*statements))
+			}
+			query, vars := (*statements)[0].sql, (*statements)[0].vars
+
+			for _, part := range []string{"search_vector @@ to_tsquery('simple', $", "ts_rank(search_vector, to_tsquery('simple', $", "AS search_rank", "ts_headline('simple'", "AS search_highlight"} {
+				if strings.Contains(query, part) != tc.wantRank {
+					t.Errorf("got %q in the query %v, want %v", part, !tc.wantRank, tc.wantRank)
+				}
+			}
+			if strings.Contains(query, "ORDER BY search_rank DESC") != (tc.wantSort == "ORDER BY search_rank DESC") ||
+				(tc.wantSort != "" && !strings.Contains(query, tc.wantSort)) {
+				t.Errorf("got query %q, want %q", query, tc.wantSort)
+			}
+			if !tc.wantRank {
+				return
+			}
+
+			// the tsquery is bound to the filter, the rank and the highlight, never inlined
+			bound := 0
+			for _, v := range vars {
+				if v == "blue:* & bottle:*" {
+					bound++
+				}
+			}
+			if bound != 3 || !containsVar(vars, searchHighlightOptions) || strings.Contains(query, "blue") {
+				t.Errorf("got vars %v, want the tsquery bound 3 times along with the highlight options", vars)
+			}
+		})
+	}
+}
+
+type statement struct {
+	sql  string
+	vars []interface{}
+}
+
+// newDocumentTestRepo returns the document repository of a dry run database, recording the built queries
+func newDocumentTestRepo(t *testing.T) (*Document, *[]statement) {
+	t.Helper()
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := &[]statement{}
+	if err := gdb.Callback().Query().After("gorm:query").Register("test:record", func(db *gorm.DB) {
+		*statements = append(*statements, statement{sql: db.Statement.SQL.String(), vars: db.Statement.Vars})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewDocument(gdb), statements
+}
+
+func containsVar(vars []interface{}, want interface{}) bool {
+	for _, v := range vars {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+	"gorm.io/gorm"
+)
+
+// DocumentAnalysis represents the client for document analysis table
+type DocumentAnalysis struct {
+	*repoutil.Repo[types.DocumentAnalysis]
+}
+
+// NewDocumentAnalysis returns a new document analysis database instance
+func NewDocumentAnalysis(gdb *gorm.DB) *DocumentAnalysis {
+	return &DocumentAnalysis{repoutil.NewRepo[types.DocumentAnalysis](gdb)}
+}
+
+// FindByAPIMRequestID finds an analysis by the given apim_request_id
+func (r *DocumentAnalysis) FindByAPIMRequestID(ctx context.Context, apimReqID string) (*types.DocumentAnalysis, error) {
+	rec := &types.DocumentAnalysis{}
+	if err := r.GDB.WithContext(ctx).Where(`apim_request_id = ?`, apimReqID).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// FindLatestByDocumentID finds the latest analysis of the given document
+func (r *DocumentAnalysis) FindLatestByDocumentID(ctx context.Context, documentID string) (*types.DocumentAnalysis, error) {
+	rec := &types.DocumentAnalysis{}
+	if err := r.GDB.WithContext(ctx).Where(`document_id = ?`, documentID).Order(`analyzed_at DESC, id DESC`).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ListByDocumentID lists the analysis history of the given document, newest first, without the heavy columns
+func (r *DocumentAnalysis) ListByDocumentID(ctx context.Context, documentID string) ([]*types.DocumentAnalysis, error) {
+	recs := []*types.DocumentAnalysis{}
+	if err := r.GDB.WithContext(ctx).Omit("content", "pages", "raw_result").
+		Where(`document_id = ?`, documentID).Order(`analyzed_at DESC, id DESC`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
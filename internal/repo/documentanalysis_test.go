@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestDocumentAnalysisQueries checks the analyses of a document are read newest first, the listing without the heavy columns
+func TestDocumentAnalysisQueries(t *testing.T) {
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var query string
+	if err := gdb.Callback().Query().After("gorm:query").Register("test:record", func(db *gorm.DB) {
+		query = db.Statement.SQL.String()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := NewDocumentAnalysis(gdb)
+
+	if _, err := r.ListByDocumentID(context.Background(), "01HDOCUMENT00000000000000000"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "WHERE document_id = $1") || !strings.HasSuffix(query, "ORDER BY analyzed_at DESC, id DESC") {
+		t.Errorf("got listing %q, want the analyses of the document newest first", query)
+	}
+	for _, col := range []string{"*", `"content"`, `"pages"`, `"raw_result"`} {
+		if strings.Contains(strings.Split(query, " FROM ")[0], col) {
+			t.Errorf("got listing %q selecting %s", query, col)
+		}
+	}
+
+	// the latest is not found in the dry run
+	_, _ = r.FindLatestByDocumentID(context.Background(), "01HDOCUMENT00000000000000000")
+	if !strings.Contains(query, "WHERE document_id = $1") || !strings.Contains(query, "ORDER BY analyzed_at DESC, id DESC LIMIT") {
+		t.Errorf("got latest %q, want the newest analysis of the document", query)
+	}
+}
@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"strings"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+	requestutil "github.com/M15t/gram/pkg/util/request"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExpenseReport represents the client for expense_reports table
+type ExpenseReport struct {
+	*repoutil.Repo[types.ExpenseReport]
+}
+
+// NewExpenseReport returns a new expense report database instance
+func NewExpenseReport(gdb *gorm.DB) *ExpenseReport {
+	return &ExpenseReport{repoutil.NewRepo[types.ExpenseReport](gdb)}
+}
+
+// CreateWithEvent creates an expense report with the first step of its trail
+func (r *ExpenseReport) CreateWithEvent(ctx context.Context, rec *types.ExpenseReport, event *types.ExpenseReportEvent) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rec).Error; err != nil {
+			return err
+		}
+		event.ExpenseReportID = rec.ID
+		return tx.Create(event).Error
+	})
+}
+
+// FindByID finds an expense report of the scope by the given id, with its documents in the order of the transaction dates
+func (r *ExpenseReport) FindByID(ctx context.Context, scope Scope, id string) (*types.ExpenseReport, error) {
+	rec := &types.ExpenseReport{}
+	if err := r.GDB.WithContext(ctx).Scopes(scope.ApplyShared).
+		Preload(`Documents`, func(db *gorm.DB) *gorm.DB { return db.Order(`transaction_date, id`) }).
+		Where(`id = ?`, id).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ExistedByID checks if an expense report of the scope exists by the given id
+func (r *ExpenseReport) ExistedByID(ctx context.Context, scope Scope, id string) (bool, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Model(&types.ExpenseReport{}).Scopes(scope.ApplyShared).Where(`id = ?`, id).Count(&count).Error
+	return count > 0, err
+}
+
+// List reads all expense reports of the scope by given conditions
+func (r *ExpenseReport) List(ctx context.Context, scope Scope, output interface{}, count *int64, lc *requestutil.ListCondition[ExpenseReportsFilter]) error {
+	db := r.GDB.WithContext(ctx).Model(&types.ExpenseReport{}).Scopes(scope.ApplyShared)
+
+	if lc.Filter.ApproverID != "" {
+		db = db.Where(`approver_id = ?`, lc.Filter.ApproverID)
+	}
+
+	if len(lc.Filter.Query) > 0 {
+		qConds, qVars := lc.Filter.Query.SQL()
+		db = db.Where(qConds, qVars...)
+	}
+
+	if lc.Count {
+		if err := db.Session(&gorm.Session{}).Count(count).Error; err != nil {
+			return err
+		}
+	}
+
+	db = repoutil.WithPaging(db, lc.Page, lc.PerPage)
+	db = repoutil.WithSorting(db, lc.Sort, r.QuoteCol)
+	if lc.Sort == "" {
+		db = db.Order(`created_at DESC`)
+	}
+
+	return db.Find(output).Error
+}
+
+// UpdateByID updates an expense report of the scope in one of the given statuses,
+// returns gorm.ErrRecordNotFound if there is none
+func (r *ExpenseReport) UpdateByID(ctx context.Context, scope Scope, id string, statuses []string, updates any) error {
+	return updateReport(r.GDB.WithContext(ctx).Scopes(scope.ApplyShared), id, statuses, updates)
+}
+
+// Transition moves an expense report from one of the given statuses to another and records the step in its trail,
+// returns gorm.ErrRecordNotFound if the report is not in one of the statuses anymore
+func (r *ExpenseReport) Transition(ctx context.Context, id string, from []string, updates map[string]interface{}, event *types.ExpenseReportEvent) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateReport(tx, id, from, updates); err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+// DeleteByID deletes an expense report of the scope in one of the given statuses with its comments and trail,
+// the documents are released to be claimed in another report. Returns gorm.ErrRecordNotFound if there is none.
+func (r *ExpenseReport) DeleteByID(ctx context.Context, scope Scope, id string, statuses []string) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		db := tx.Scopes(scope.ApplyShared).Where(`id = ? AND status IN ?`, id, statuses).Delete(&types.ExpenseReport{})
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		steps := []*gorm.DB{
+			tx.Exec(`DELETE FROM expense_report_documents WHERE expense_report_id = ?`, id),
+			tx.Where(`expense_report_id = ?`, id).Delete(&types.ExpenseReportComment{}),
+			tx.Where(`expense_report_id = ?`, id).Delete(&types.ExpenseReportEvent{}),
+		}
+		for _, step := range steps {
+			if step.Error != nil {
+				return step.Error
+			}
+		}
+
+		return nil
+	})
+}
+
+// AddDocument adds a document to an expense report, returns false if the document is already in a report
+func (r *ExpenseReport) AddDocument(ctx context.Context, id, documentID string) (bool, error) {
+	db := r.GDB.WithContext(ctx).Table(`expense_report_documents`).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]interface{}{"expense_report_id": id, "document_id": documentID})
+	return db.RowsAffected > 0, db.Error
+}
+
+// RemoveDocument removes a document from an expense report, returns gorm.ErrRecordNotFound if it is not in the report
+func (r *ExpenseReport) RemoveDocument(ctx context.Context, id, documentID string) error {
+	db := r.GDB.WithContext(ctx).Exec(`DELETE FROM expense_report_documents WHERE expense_report_id = ? AND document_id = ?`, id, documentID)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// HasDocument checks if a document is in any expense report
+func (r *ExpenseReport) HasDocument(ctx context.Context, documentID string) (bool, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Table(`expense_report_documents`).Where(`document_id = ?`, documentID).Count(&count).Error
+	return count > 0, err
+}
+
+// HasLockedDocument checks if a document is in an expense report which is neither draft nor rejected,
+// the documents of such a report must not change as its total is fixed
+func (r *ExpenseReport) HasLockedDocument(ctx context.Context, documentID string) (bool, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Table(`expense_report_documents erd`).
+		Joins(`JOIN expense_reports er ON er.id = erd.expense_report_id AND er.deleted_at IS NULL`).
+		Where(`erd.document_id = ? AND er.status NOT IN ?`, documentID, []string{types.ExpenseReportDraft, types.ExpenseReportRejected}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountPendingByApprover counts the submitted expense reports of the organization waiting for each approver
+func (r *ExpenseReport) CountPendingByApprover(ctx context.Context, organizationID string) (map[string]int64, error) {
+	rows := []struct {
+		ApproverID string
+		Count      int64
+	}{}
+	if err := r.GDB.WithContext(ctx).Model(&types.ExpenseReport{}).Select(`approver_id, COUNT(*) AS count`).
+		Where(`organization_id = ? AND status = ?`, organizationID, types.ExpenseReportSubmitted).
+		Group(`approver_id`).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ApproverID] = row.Count
+	}
+	return counts, nil
+}
+
+// ListClaimedDuplicates lists the documents of the user claimed in the other expense reports which are not rejected,
+// having the same merchant, transaction date and total as the given document
+func (r *ExpenseReport) ListClaimedDuplicates(ctx context.Context, userID, excludeID string, doc *types.Document) ([]*types.Document, error) {
+	recs := []*types.Document{}
+	if err := r.GDB.WithContext(ctx).Model(&types.Document{}).
+		Joins(`JOIN expense_report_documents erd ON erd.document_id = documents.id`).
+		Joins(`JOIN expense_reports er ON er.id = erd.expense_report_id AND er.deleted_at IS NULL`).
+		Where(`er.user_id = ? AND er.id <> ? AND er.status IN ?`, userID, excludeID,
+			[]string{types.ExpenseReportSubmitted, types.ExpenseReportApproved, types.ExpenseReportReimbursed}).
+		Where(`documents.id <> ? AND LOWER(documents.merchant_name) = ? AND documents.transaction_date = ? AND documents.total = ?`,
+			doc.ID, strings.ToLower(doc.MerchantName), doc.TransactionDate, doc.Total).
+		Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+func updateReport(db *gorm.DB, id string, statuses []string, updates any) error {
+	db = db.Model(&types.ExpenseReport{}).Where(`id = ? AND status IN ?`, id, statuses).Omit("id").Updates(updates)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ExpenseReportComment represents the client for expense_report_comments table
+type ExpenseReportComment struct {
+	*repoutil.Repo[types.ExpenseReportComment]
+}
+
+// NewExpenseReportComment returns a new expense report comment database instance
+func NewExpenseReportComment(gdb *gorm.DB) *ExpenseReportComment {
+	return &ExpenseReportComment{repoutil.NewRepo[types.ExpenseReportComment](gdb)}
+}
+
+// ListByReportID lists the comments of an expense report with their authors, the oldest first
+func (r *ExpenseReportComment) ListByReportID(ctx context.Context, reportID string) ([]*types.ExpenseReportComment, error) {
+	recs := []*types.ExpenseReportComment{}
+	if err := r.GDB.WithContext(ctx).Preload(`User`).
+		Where(`expense_report_id = ?`, reportID).Order(`created_at`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// ExpenseReportEvent represents the client for expense_report_events table
+type ExpenseReportEvent struct {
+	*repoutil.Repo[types.ExpenseReportEvent]
+}
+
+// NewExpenseReportEvent returns a new expense report event database instance
+func NewExpenseReportEvent(gdb *gorm.DB) *ExpenseReportEvent {
+	return &ExpenseReportEvent{repoutil.NewRepo[types.ExpenseReportEvent](gdb)}
+}
+
+// ListByReportID lists the trail of an expense report with the actors, the oldest first
+func (r *ExpenseReportEvent) ListByReportID(ctx context.Context, reportID string) ([]*types.ExpenseReportEvent, error) {
+	recs := []*types.ExpenseReportEvent{}
+	if err := r.GDB.WithContext(ctx).Preload(`Actor`).
+		Where(`expense_report_id = ?`, reportID).Order(`created_at`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
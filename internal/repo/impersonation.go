@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+	requestutil "github.com/M15t/gram/pkg/util/request"
+
+	"gorm.io/gorm"
+)
+
+// Impersonation represents the client for impersonations table
+type Impersonation struct {
+	*repoutil.Repo[types.Impersonation]
+}
+
+// NewImpersonation returns a new impersonation database instance
+func NewImpersonation(gdb *gorm.DB) *Impersonation {
+	return &Impersonation{repoutil.NewRepo[types.Impersonation](gdb)}
+}
+
+// List reads all impersonations by given conditions
+func (r *Impersonation) List(ctx context.Context, output interface{}, count *int64, lc *requestutil.ListCondition[ImpersonationsFilter]) error {
+	filter := []any{}
+	if len(lc.Filter.Query) > 0 {
+		qConds, qVars := lc.Filter.Query.SQL()
+		filter = append([]any{qConds}, qVars...)
+	}
+
+	return r.ReadAllByCondition(ctx, output, count, &requestutil.ListQueryCondition{
+		Page:    lc.Page,
+		PerPage: lc.PerPage,
+		Sort:    lc.Sort,
+		Count:   lc.Count,
+		Filter:  filter,
+	})
+}
+
+// End ends an impersonation which is neither ended nor expired at the given time,
+// returns gorm.ErrRecordNotFound if there is none
+func (r *Impersonation) End(ctx context.Context, id string, at time.Time) error {
+	db := r.GDB.WithContext(ctx).Model(&types.Impersonation{}).
+		Where(`id = ? AND ended_at IS NULL AND expires_at > ?`, id, at).Update("ended_at", at)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginGuard represents the client for login_failures and login_lockouts tables
+type LoginGuard struct {
+	GDB *gorm.DB
+}
+
+// NewLoginGuard returns a new login guard database instance
+func NewLoginGuard(gdb *gorm.DB) *LoginGuard {
+	return &LoginGuard{GDB: gdb}
+}
+
+// AddFailure records a failed attempt of the key
+func (r *LoginGuard) AddFailure(ctx context.Context, key string, at time.Time) error {
+	return r.GDB.WithContext(ctx).Create(&types.LoginFailure{Key: key, CreatedAt: at}).Error
+}
+
+// CountFailures counts the failed attempts of the key since the given time
+func (r *LoginGuard) CountFailures(ctx context.Context, key string, since time.Time) (int, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Model(&types.LoginFailure{}).Where(`key = ? AND created_at > ?`, key, since).Count(&count).Error
+	return int(count), err
+}
+
+// DeleteFailures deletes the failed attempts of the key before the given time
+func (r *LoginGuard) DeleteFailures(ctx context.Context, key string, before time.Time) error {
+	return r.GDB.WithContext(ctx).Where(`key = ? AND created_at <= ?`, key, before).Delete(&types.LoginFailure{}).Error
+}
+
+// Lock creates or extends the lockout of the key
+func (r *LoginGuard) Lock(ctx context.Context, rec *types.LoginLockout) error {
+	return r.GDB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"failures", "locked_until", "created_at"}),
+	}).Create(rec).Error
+}
+
+// FindLockout returns the active lockout of the key at the given time, nil if there is none
+func (r *LoginGuard) FindLockout(ctx context.Context, key string, at time.Time) (*types.LoginLockout, error) {
+	recs := []*types.LoginLockout{}
+	if err := r.GDB.WithContext(ctx).Where(`key = ? AND locked_until > ?`, key, at).Limit(1).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, nil
+	}
+
+	return recs[0], nil
+}
+
+// Unlock deletes the lockout of the key, returns false if there is no active lockout at the given time
+func (r *LoginGuard) Unlock(ctx context.Context, key string, at time.Time) (bool, error) {
+	res := r.GDB.WithContext(ctx).Where(`key = ? AND locked_until > ?`, key, at).Delete(&types.LoginLockout{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListLockouts lists the active lockouts at the given time, the latest first
+func (r *LoginGuard) ListLockouts(ctx context.Context, at time.Time) ([]*types.LoginLockout, error) {
+	recs := []*types.LoginLockout{}
+	if err := r.GDB.WithContext(ctx).Where(`locked_until > ?`, at).Order(`created_at DESC`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+)
+
+// MFARecoveryCode represents the client for mfa_recovery_codes table
+type MFARecoveryCode struct {
+	*repoutil.Repo[types.MFARecoveryCode]
+}
+
+// NewMFARecoveryCode returns a new MFA recovery code database instance
+func NewMFARecoveryCode(gdb *gorm.DB) *MFARecoveryCode {
+	return &MFARecoveryCode{repoutil.NewRepo[types.MFARecoveryCode](gdb)}
+}
+
+// Replace deletes all recovery codes of the user then creates the given ones
+func (r *MFARecoveryCode) Replace(ctx context.Context, userID string, codeHashes []string) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codeHashes) == 0 {
+			return nil
+		}
+
+		recs := make([]*types.MFARecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			recs[i] = &types.MFARecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&recs).Error
+	})
+}
+
+// Use marks the unused recovery code of the user as used, returns false if there is no such code
+func (r *MFARecoveryCode) Use(ctx context.Context, userID, codeHash string) (bool, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.MFARecoveryCode{}).
+		Where(`user_id = ? AND code_hash = ? AND used_at IS NULL`, userID, codeHash).
+		Update(`used_at`, time.Now())
+	return res.RowsAffected == 1, res.Error
+}
@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Organization represents the client for organizations table
+type Organization struct {
+	*repoutil.Repo[types.Organization]
+}
+
+// NewOrganization returns a new organization database instance
+func NewOrganization(gdb *gorm.DB) *Organization {
+	return &Organization{repoutil.NewRepo[types.Organization](gdb)}
+}
+
+// CreateWithOwner creates an organization with its creator as the owner, returns the membership of the owner
+func (r *Organization) CreateWithOwner(ctx context.Context, org *types.Organization) (*types.OrganizationMember, error) {
+	member := &types.OrganizationMember{UserID: org.CreatedBy, Role: types.OrgRoleOwner}
+	err := r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		member.OrganizationID = org.ID
+		return tx.Create(member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	member.Organization = org
+	return member, nil
+}
+
+// DeleteByID deletes an organization with its members, invitations and expense reports.
+// The documents of the organization become the personal documents of their uploaders, released from the reports.
+func (r *Organization) DeleteByID(ctx context.Context, id string) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reports := tx.Model(&types.ExpenseReport{}).Select(`id`).Where(`organization_id = ?`, id)
+		if err := tx.Exec(`DELETE FROM expense_report_documents WHERE expense_report_id IN (?)`, reports).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(`organization_id = ?`, id).Delete(&types.ExpenseReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&types.Document{}).Where(`organization_id = ?`, id).Update("organization_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&types.Session{}).Where(`active_organization_id = ?`, id).Update("active_organization_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(`organization_id = ?`, id).Delete(&types.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&types.OrganizationInvitation{}).
+			Where(`organization_id = ? AND status = ?`, id, types.InvitationPending).
+			Update("status", types.InvitationRevoked).Error; err != nil {
+			return err
+		}
+		return tx.Where(`id = ?`, id).Delete(&types.Organization{}).Error
+	})
+}
+
+// OrganizationMember represents the client for organization_members table
+type OrganizationMember struct {
+	*repoutil.Repo[types.OrganizationMember]
+}
+
+// NewOrganizationMember returns a new organization member database instance
+func NewOrganizationMember(gdb *gorm.DB) *OrganizationMember {
+	return &OrganizationMember{repoutil.NewRepo[types.OrganizationMember](gdb)}
+}
+
+// FindByUser finds the membership of the user in the organization
+func (r *OrganizationMember) FindByUser(ctx context.Context, organizationID, userID string) (*types.OrganizationMember, error) {
+	rec := &types.OrganizationMember{}
+	if err := r.GDB.WithContext(ctx).Preload(`Organization`).
+		Where(`organization_id = ? AND user_id = ?`, organizationID, userID).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ListByUserID lists the memberships of the user with their organizations, the oldest first
+func (r *OrganizationMember) ListByUserID(ctx context.Context, userID string) ([]*types.OrganizationMember, error) {
+	recs := []*types.OrganizationMember{}
+	if err := r.GDB.WithContext(ctx).Preload(`Organization`).
+		Where(`user_id = ?`, userID).Order(`created_at`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// ListByOrganizationID lists the members of the organization with their users, the oldest first
+func (r *OrganizationMember) ListByOrganizationID(ctx context.Context, organizationID string) ([]*types.OrganizationMember, error) {
+	recs := []*types.OrganizationMember{}
+	if err := r.GDB.WithContext(ctx).Preload(`User`).
+		Where(`organization_id = ?`, organizationID).Order(`created_at`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// CountOwners counts the owners of the organization
+func (r *OrganizationMember) CountOwners(ctx context.Context, organizationID string) (int64, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Model(&types.OrganizationMember{}).
+		Where(`organization_id = ? AND role = ?`, organizationID, types.OrgRoleOwner).Count(&count).Error
+	return count, err
+}
+
+// Remove removes the user from the organization, the sessions of the user switched to it are switched out on the next refresh
+func (r *OrganizationMember) Remove(ctx context.Context, organizationID, userID string) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.Session{}).
+			Where(`user_id = ? AND active_organization_id = ?`, userID, organizationID).
+			Update("active_organization_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where(`organization_id = ? AND user_id = ?`, organizationID, userID).Delete(&types.OrganizationMember{}).Error
+	})
+}
+
+// OrganizationInvitation represents the client for organization_invitations table
+type OrganizationInvitation struct {
+	*repoutil.Repo[types.OrganizationInvitation]
+}
+
+// NewOrganizationInvitation returns a new organization invitation database instance
+func NewOrganizationInvitation(gdb *gorm.DB) *OrganizationInvitation {
+	return &OrganizationInvitation{repoutil.NewRepo[types.OrganizationInvitation](gdb)}
+}
+
+// ListPendingByEmail lists the pending invitations of the email address which are not expired at the given time, with their organizations
+func (r *OrganizationInvitation) ListPendingByEmail(ctx context.Context, email string, at time.Time) ([]*types.OrganizationInvitation, error) {
+	recs := []*types.OrganizationInvitation{}
+	if err := r.GDB.WithContext(ctx).Preload(`Organization`).
+		Where(`email = ? AND status = ? AND expires_at > ?`, email, types.InvitationPending, at).
+		Order(`created_at DESC`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// ListByOrganizationID lists the invitations of the organization, the newest first
+func (r *OrganizationInvitation) ListByOrganizationID(ctx context.Context, organizationID string) ([]*types.OrganizationInvitation, error) {
+	recs := []*types.OrganizationInvitation{}
+	if err := r.GDB.WithContext(ctx).
+		Where(`organization_id = ?`, organizationID).Order(`created_at DESC`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// Respond changes a pending invitation to the given status, returns gorm.ErrRecordNotFound if it is not pending anymore
+func (r *OrganizationInvitation) Respond(ctx context.Context, id, status string, at time.Time) error {
+	return respondInvitation(r.GDB.WithContext(ctx), id, status, at)
+}
+
+// Accept accepts a pending invitation and adds the user to the organization in the role of the invitation,
+// returns gorm.ErrRecordNotFound if it is not pending anymore. An existing membership of the user is kept as is.
+func (r *OrganizationInvitation) Accept(ctx context.Context, inv *types.OrganizationInvitation, userID string, at time.Time) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := respondInvitation(tx, inv.ID, types.InvitationAccepted, at); err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.OrganizationMember{
+			OrganizationID: inv.OrganizationID,
+			UserID:         userID,
+			Role:           inv.Role,
+		}).Error
+	})
+}
+
+func respondInvitation(db *gorm.DB, id, status string, at time.Time) error {
+	db = db.Model(&types.OrganizationInvitation{}).
+		Where(`id = ? AND status = ?`, id, types.InvitationPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": at})
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
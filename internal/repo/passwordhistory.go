@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+)
+
+// PasswordHistory represents the client for password_histories table
+type PasswordHistory struct {
+	*repoutil.Repo[types.PasswordHistory]
+}
+
+// NewPasswordHistory returns a new password history database instance
+func NewPasswordHistory(gdb *gorm.DB) *PasswordHistory {
+	return &PasswordHistory{repoutil.NewRepo[types.PasswordHistory](gdb)}
+}
+
+// ListHashes returns the latest password hashes of the user, newest first
+func (r *PasswordHistory) ListHashes(ctx context.Context, userID string, limit int) ([]string, error) {
+	hashes := []string{}
+	err := r.GDB.WithContext(ctx).Model(&types.PasswordHistory{}).
+		Where(`user_id = ?`, userID).
+		Order(`id DESC`).
+		Limit(limit).
+		Pluck(`hash`, &hashes).Error
+	return hashes, err
+}
+
+// Add adds the password hash of the user then deletes the older ones beyond the latest `keep` hashes
+func (r *PasswordHistory) Add(ctx context.Context, userID, hash string, keep int) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&types.PasswordHistory{UserID: userID, Hash: hash}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().
+			Where(`user_id = ? AND id NOT IN (?)`, userID,
+				tx.Model(&types.PasswordHistory{}).Select(`id`).Where(`user_id = ?`, userID).Order(`id DESC`).Limit(keep),
+			).
+			Delete(&types.PasswordHistory{}).Error
+	})
+}
@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"tyr/internal/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRoleInUse is returned when deleting a role which is assigned to users or inherited by other roles
+var ErrRoleInUse = errors.New("role is in use")
+
+// RBAC represents the client for rbac_roles, rbac_role_parents, rbac_policies and rbac_revisions tables.
+// Every change increases the revision in the same transaction.
+type RBAC struct {
+	GDB *gorm.DB
+}
+
+// NewRBAC returns a new RBAC database instance
+func NewRBAC(gdb *gorm.DB) *RBAC {
+	return &RBAC{GDB: gdb}
+}
+
+// Revision returns the current revision of the roles and the policies
+func (r *RBAC) Revision(ctx context.Context) (int64, error) {
+	var revision int64
+	err := r.GDB.WithContext(ctx).Model(&types.RBACRevision{}).Where(`id = 1`).Pluck(`revision`, &revision).Error
+	return revision, err
+}
+
+// ListRoles lists all roles with their parents, ordered by name
+func (r *RBAC) ListRoles(ctx context.Context) ([]*types.RBACRole, error) {
+	roles := []*types.RBACRole{}
+	if err := r.GDB.WithContext(ctx).Order(`name`).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	parents := []*types.RBACRoleParent{}
+	if err := r.GDB.WithContext(ctx).Order(`role, parent`).Find(&parents).Error; err != nil {
+		return nil, err
+	}
+	byRole := map[string][]string{}
+	for _, p := range parents {
+		byRole[p.Role] = append(byRole[p.Role], p.Parent)
+	}
+	for _, role := range roles {
+		role.Parents = byRole[role.Name]
+		if role.Parents == nil {
+			role.Parents = []string{}
+		}
+	}
+
+	return roles, nil
+}
+
+// ListPolicies lists all policies, ordered by role, object and action
+func (r *RBAC) ListPolicies(ctx context.Context) ([]*types.RBACPolicy, error) {
+	recs := []*types.RBACPolicy{}
+	if err := r.GDB.WithContext(ctx).Order(`role, object, action`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// FindPolicy finds a policy by id
+func (r *RBAC) FindPolicy(ctx context.Context, id string) (*types.RBACPolicy, error) {
+	rec := &types.RBACPolicy{}
+	if err := r.GDB.WithContext(ctx).Where(`id = ?`, id).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// PolicyExisted checks if the role has the policy of the object and the action
+func (r *RBAC) PolicyExisted(ctx context.Context, role, object, action string) (bool, error) {
+	var count int64
+	err := r.GDB.WithContext(ctx).Model(&types.RBACPolicy{}).
+		Where(`role = ? AND object = ? AND action = ?`, role, object, action).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateRole creates a role with its parents
+func (r *RBAC) CreateRole(ctx context.Context, role *types.RBACRole) error {
+	return r.change(ctx, func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		for _, parent := range role.Parents {
+			if err := tx.Create(&types.RBACRoleParent{Role: role.Name, Parent: parent}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRole deletes a role with its policies, returns ErrRoleInUse if it is assigned to users or inherited by other roles
+func (r *RBAC) DeleteRole(ctx context.Context, name string) error {
+	return r.change(ctx, func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&types.User{}).Where(`role = ?`, name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleInUse
+		}
+		if err := tx.Model(&types.RBACRoleParent{}).Where(`parent = ?`, name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleInUse
+		}
+
+		if err := tx.Unscoped().Where(`role = ?`, name).Delete(&types.RBACPolicy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(`role = ?`, name).Delete(&types.RBACRoleParent{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(`name = ?`, name).Delete(&types.RBACRole{}).Error
+	})
+}
+
+// CreatePolicy creates a policy
+func (r *RBAC) CreatePolicy(ctx context.Context, rec *types.RBACPolicy) error {
+	return r.change(ctx, func(tx *gorm.DB) error {
+		return tx.Create(rec).Error
+	})
+}
+
+// DeletePolicy permanently deletes a policy
+func (r *RBAC) DeletePolicy(ctx context.Context, id string) error {
+	return r.change(ctx, func(tx *gorm.DB) error {
+		return tx.Unscoped().Where(`id = ?`, id).Delete(&types.RBACPolicy{}).Error
+	})
+}
+
+// change applies the changes then increases the revision in a transaction
+func (r *RBAC) change(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"revision": gorm.Expr(`rbac_revisions.revision + 1`), "updated_at": gorm.Expr(`now()`)}),
+		}).Create(&types.RBACRevision{ID: 1, Revision: 1}).Error
+	})
+}
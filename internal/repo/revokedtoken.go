@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken represents the client for revoked_tokens table
+type RevokedToken struct {
+	*repoutil.Repo[types.RevokedToken]
+}
+
+// NewRevokedToken returns a new revoked token database instance
+func NewRevokedToken(gdb *gorm.DB) *RevokedToken {
+	return &RevokedToken{repoutil.NewRepo[types.RevokedToken](gdb)}
+}
+
+// Add adds the entries to the denylist, the latest expiry wins if an entry already exists
+func (r *RevokedToken) Add(ctx context.Context, recs ...*types.RevokedToken) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	return r.GDB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"expires_at": gorm.Expr(`GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)`)}),
+	}).Create(&recs).Error
+}
+
+// FindActive returns the unexpired entries among the given keys
+func (r *RevokedToken) FindActive(ctx context.Context, keys []string) ([]*types.RevokedToken, error) {
+	recs := []*types.RevokedToken{}
+	if err := r.GDB.WithContext(ctx).Where(`key IN ? AND expires_at > ?`, keys, time.Now()).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// DeleteExpired deletes the entries which no longer cover any valid token
+func (r *RevokedToken) DeleteExpired(ctx context.Context) error {
+	return r.GDB.WithContext(ctx).Delete(&types.RevokedToken{}, `expires_at <= ?`, time.Now()).Error
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scope restricts the queries of an owned table to the rows of a user, or lets the rows of all users through.
+// The tables shared in organizations are restricted to the rows of an organization as well.
+// It is built by rbac.Authorize from the permissions of the authenticated user.
+// The zero value matches no rows, so a missing scope never leaks the records of the other users.
+type Scope struct {
+	// All lets the rows of all users through
+	All bool
+	// UserID is the owner of the accessible rows, unless All
+	UserID string
+	// OrganizationID is the organization of the accessible rows of the shared tables, the rows of all its members unless UserID is set.
+	// Only the personal rows out of organizations are accessible if empty.
+	OrganizationID string
+}
+
+// ScopeAll returns the scope of the rows of all users, for the internal jobs which are not on behalf of a user
+func ScopeAll() Scope {
+	return Scope{All: true}
+}
+
+// ScopeOwner returns the scope of the rows owned by the user
+func ScopeOwner(userID string) Scope {
+	return Scope{UserID: userID}
+}
+
+// ScopeOrganization returns the scope of the rows of the organization, only those owned by the user unless userID is empty
+func ScopeOrganization(organizationID, userID string) Scope {
+	return Scope{OrganizationID: organizationID, UserID: userID}
+}
+
+// Apply is the gorm scope restricting the query on the user_id column of the current table
+func (s Scope) Apply(db *gorm.DB) *gorm.DB {
+	switch {
+	case s.All:
+		return db
+	case s.UserID == "":
+		// the documents analyzed before the owner was recorded at upload have an empty user_id
+		return db.Where(clause.Expr{SQL: "FALSE"})
+	}
+	return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "user_id"}, Value: s.UserID})
+}
+
+// ApplyShared is the gorm scope restricting the query of a table shared in organizations,
+// on the organization_id and the user_id columns of the current table
+func (s Scope) ApplyShared(db *gorm.DB) *gorm.DB {
+	switch {
+	case s.All:
+		return db
+	case s.OrganizationID == "":
+		return s.Apply(db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "organization_id"}, Value: nil}))
+	}
+
+	db = db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "organization_id"}, Value: s.OrganizationID})
+	if s.UserID == "" {
+		return db
+	}
+	return s.Apply(db)
+}
@@ -10,6 +10,28 @@ type Service struct {
 	Document     *Document
 	DocumentItem *DocumentItem
 	Profile      *Profile
+
+	DocumentAnalysis *DocumentAnalysis
+	AuditEvent       *AuditEvent
+	RevokedToken     *RevokedToken
+	UserToken        *UserToken
+	MFARecoveryCode  *MFARecoveryCode
+	UserIdentity     *UserIdentity
+	LoginGuard       *LoginGuard
+	PasswordHistory  *PasswordHistory
+	DataExport       *DataExport
+	RBAC             *RBAC
+
+	Organization           *Organization
+	OrganizationMember     *OrganizationMember
+	OrganizationInvitation *OrganizationInvitation
+
+	ExpenseReport        *ExpenseReport
+	ExpenseReportComment *ExpenseReportComment
+	ExpenseReportEvent   *ExpenseReportEvent
+
+	APIKey        *APIKey
+	Impersonation *Impersonation
 }
 
 // New creates db service
@@ -21,5 +43,27 @@ func New(db *gorm.DB) *Service {
 		Document:     NewDocument(db),
 		DocumentItem: NewDocumentItem(db),
 		Profile:      NewProfile(db),
+
+		DocumentAnalysis: NewDocumentAnalysis(db),
+		AuditEvent:       NewAuditEvent(db),
+		RevokedToken:     NewRevokedToken(db),
+		UserToken:        NewUserToken(db),
+		MFARecoveryCode:  NewMFARecoveryCode(db),
+		UserIdentity:     NewUserIdentity(db),
+		LoginGuard:       NewLoginGuard(db),
+		PasswordHistory:  NewPasswordHistory(db),
+		DataExport:       NewDataExport(db),
+		RBAC:             NewRBAC(db),
+
+		Organization:           NewOrganization(db),
+		OrganizationMember:     NewOrganizationMember(db),
+		OrganizationInvitation: NewOrganizationInvitation(db),
+
+		ExpenseReport:        NewExpenseReport(db),
+		ExpenseReportComment: NewExpenseReportComment(db),
+		ExpenseReportEvent:   NewExpenseReportEvent(db),
+
+		APIKey:        NewAPIKey(db),
+		Impersonation: NewImpersonation(db),
 	}
 }
@@ -2,10 +2,12 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"tyr/internal/types"
 
 	repoutil "github.com/M15t/gram/pkg/util/repo"
+	requestutil "github.com/M15t/gram/pkg/util/request"
 
 	"gorm.io/gorm"
 )
@@ -20,6 +22,23 @@ func NewSession(gdb *gorm.DB) *Session {
 	return &Session{repoutil.NewRepo[types.Session](gdb)}
 }
 
+// List reads all sessions by given conditions
+func (r *Session) List(ctx context.Context, output interface{}, count *int64, lc *requestutil.ListCondition[SessionsFilter]) error {
+	filter := []any{}
+	if len(lc.Filter.Query) > 0 {
+		qConds, qVars := lc.Filter.Query.SQL()
+		filter = append([]any{qConds}, qVars...)
+	}
+
+	return r.ReadAllByCondition(ctx, output, count, &requestutil.ListQueryCondition{
+		Page:    lc.Page,
+		PerPage: lc.PerPage,
+		Sort:    lc.Sort,
+		Count:   lc.Count,
+		Filter:  filter,
+	})
+}
+
 // FindByID finds a session by the given ID and preload User
 func (r *Session) FindByID(ctx context.Context, id, userID string) (*types.Session, error) {
 	rec := &types.Session{}
@@ -34,3 +53,86 @@ func (r *Session) FindByID(ctx context.Context, id, userID string) (*types.Sessi
 func (r *Session) DeleteExpired(ctx context.Context, userID string) error {
 	return r.GDB.WithContext(ctx).Delete(&types.Session{}, `expires_at < NOW() AND user_id = ?`, userID).Error
 }
+
+// ListActiveByUserID lists the active sessions of the given user, the most recently used first
+func (r *Session) ListActiveByUserID(ctx context.Context, userID string) ([]*types.Session, error) {
+	recs := []*types.Session{}
+	if err := r.GDB.WithContext(ctx).
+		Where(`user_id = ? AND is_blocked = false AND expires_at > NOW()`, userID).
+		Order(`COALESCE(last_seen_at, created_at) DESC`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// Touch records the last usage of the given session
+func (r *Session) Touch(ctx context.Context, id, ipAddress, userAgent string) error {
+	return r.GDB.WithContext(ctx).Model(&types.Session{}).Where(`id = ?`, id).Updates(map[string]interface{}{
+		"last_seen_at":         time.Now(),
+		"last_seen_ip_address": ipAddress,
+		"last_seen_user_agent": userAgent,
+	}).Error
+}
+
+// Revoke blocks the given sessions of the user and clears their refresh tokens
+func (r *Session) Revoke(ctx context.Context, userID string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.GDB.WithContext(ctx).Model(&types.Session{}).
+		Where(`user_id = ? AND id IN ?`, userID, ids).
+		Updates(map[string]interface{}{"is_blocked": true, "refresh_token_hash": nil}).Error
+}
+
+// RevokeAllByUserID blocks all active sessions of the given user, returns the ids of the revoked sessions
+func (r *Session) RevokeAllByUserID(ctx context.Context, userID string) ([]string, error) {
+	ids := []string{}
+	if err := r.GDB.WithContext(ctx).Raw(`UPDATE sessions SET is_blocked = true, refresh_token_hash = NULL, updated_at = NOW()
+		WHERE user_id = ? AND is_blocked = false
+		RETURNING id`, userID).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// RevokeByActiveOrganization blocks the active sessions switched to the given organization, of the given user only if not empty.
+// It returns the ids of the revoked sessions.
+func (r *Session) RevokeByActiveOrganization(ctx context.Context, organizationID, userID string) ([]string, error) {
+	ids := []string{}
+	if err := r.GDB.WithContext(ctx).Raw(`UPDATE sessions SET is_blocked = true, refresh_token_hash = NULL, updated_at = NOW()
+		WHERE active_organization_id = ? AND (? = '' OR user_id = ?) AND is_blocked = false
+		RETURNING id`, organizationID, userID, userID).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// RevokeOldest blocks the oldest active sessions of the given user, keeping only the newest ones.
+// It returns the ids of the revoked sessions.
+func (r *Session) RevokeOldest(ctx context.Context, userID string, keep int) ([]string, error) {
+	ids := []string{}
+	if err := r.GDB.WithContext(ctx).Raw(`UPDATE sessions SET is_blocked = true, refresh_token_hash = NULL, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM sessions
+			WHERE user_id = ? AND is_blocked = false AND expires_at > NOW()
+			ORDER BY created_at DESC, id DESC
+			OFFSET ?
+		)
+		RETURNING id`, userID, keep).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// RotateRefreshToken replaces the current refresh token hash of the session only if it still matches the given one,
+// returns false if the token has been rotated in the meantime
+func (r *Session) RotateRefreshToken(ctx context.Context, id, oldHash, newHash string) (bool, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.Session{}).
+		Where(`id = ? AND is_blocked = false AND refresh_token_hash = ?`, id, oldHash).
+		Update(`refresh_token_hash`, newHash)
+	return res.RowsAffected == 1, res.Error
+}
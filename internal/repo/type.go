@@ -1,15 +1,159 @@
 package repo
 
+import (
+	"time"
+
+	filterutil "tyr/internal/util/filter"
+)
+
 // * definition of custom filters
 type (
 	// UsersFilter represents the filter type for listing and filtering users
 	UsersFilter struct {
+		Name   string
 		Search string
+		Query  filterutil.Query
 	}
 
 	// DocumentsFilter represents the filter type for listing and filtering documents
 	DocumentsFilter struct {
-		UserID string
-		Search string
+		Search   string
+		Merchant string
+		Currency string
+		MinTotal *float64
+		MaxTotal *float64
+		DateFrom string
+		DateTo   string
+		Query    filterutil.Query
+	}
+
+	// SessionsFilter represents the filter type for listing and filtering sessions
+	SessionsFilter struct {
+		Query filterutil.Query
+	}
+
+	// ExpenseReportsFilter represents the filter type for listing and filtering expense reports
+	ExpenseReportsFilter struct {
+		ApproverID string
+		Query      filterutil.Query
+	}
+
+	// APIKeysFilter represents the filter type for listing and filtering API keys
+	APIKeysFilter struct {
+		Revoked *bool
+		Query   filterutil.Query
+	}
+
+	// ImpersonationsFilter represents the filter type for listing and filtering impersonations
+	ImpersonationsFilter struct {
+		Query filterutil.Query
+	}
+
+	// ActivityLogsFilter represents the filter type for listing and filtering activity logs
+	ActivityLogsFilter struct {
+		URL           string
+		Method        string
+		ResponseCode  int
+		APIMRequestID string
+		From          *time.Time
+		To            *time.Time
+		MinDurationMS int64
+		MaxDurationMS int64
+	}
+)
+
+// * whitelist of fields for the `filter` and `s` query params
+var (
+	// UserFilterSchema lists the filterable and sortable fields of users
+	UserFilterSchema = filterutil.Schema{
+		"id":         {Type: filterutil.TypeString, Sortable: true},
+		"first_name": {Type: filterutil.TypeString, Sortable: true},
+		"last_name":  {Type: filterutil.TypeString, Sortable: true},
+		"email":      {Type: filterutil.TypeString, Sortable: true},
+		"phone":      {Type: filterutil.TypeString},
+		"role":       {Type: filterutil.TypeString, Sortable: true},
+		"status":     {Type: filterutil.TypeString, Sortable: true},
+		"last_login": {Type: filterutil.TypeTime, Sortable: true},
+		"created_at": {Type: filterutil.TypeTime, Sortable: true},
+		"updated_at": {Type: filterutil.TypeTime, Sortable: true},
+	}
+
+	// DocumentFilterSchema lists the filterable and sortable fields of documents
+	DocumentFilterSchema = filterutil.Schema{
+		"id":               {Type: filterutil.TypeString, Sortable: true},
+		"file_name":        {Type: filterutil.TypeString, Sortable: true},
+		"merchant_name":    {Type: filterutil.TypeString, Sortable: true},
+		"merchant_address": {Type: filterutil.TypeString},
+		"transaction_date": {Type: filterutil.TypeDate, Sortable: true},
+		"currency":         {Type: filterutil.TypeString, Sortable: true},
+		"sub_total":        {Type: filterutil.TypeNumber, Sortable: true},
+		"total":            {Type: filterutil.TypeNumber, Sortable: true},
+		"total_tax":        {Type: filterutil.TypeNumber, Sortable: true},
+		"total_page":       {Type: filterutil.TypeNumber, Sortable: true},
+		"created_at":       {Type: filterutil.TypeTime, Sortable: true},
+		"updated_at":       {Type: filterutil.TypeTime, Sortable: true},
+		"search_rank":      {SortOnly: true},
+	}
+
+	// SessionFilterSchema lists the filterable and sortable fields of sessions
+	SessionFilterSchema = filterutil.Schema{
+		"id":         {Type: filterutil.TypeString, Sortable: true},
+		"user_id":    {Type: filterutil.TypeString, Sortable: true},
+		"is_blocked": {Type: filterutil.TypeBool, Sortable: true},
+		"ip_address": {Type: filterutil.TypeString, Sortable: true},
+		"user_agent": {Type: filterutil.TypeString},
+		"expires_at": {Type: filterutil.TypeTime, Sortable: true},
+		"created_at": {Type: filterutil.TypeTime, Sortable: true},
+		"updated_at": {Type: filterutil.TypeTime, Sortable: true},
+	}
+
+	// APIKeyFilterSchema lists the filterable and sortable fields of API keys
+	APIKeyFilterSchema = filterutil.Schema{
+		"id":              {Type: filterutil.TypeString, Sortable: true},
+		"user_id":         {Type: filterutil.TypeString, Sortable: true},
+		"organization_id": {Type: filterutil.TypeString},
+		"name":            {Type: filterutil.TypeString, Sortable: true},
+		"prefix":          {Type: filterutil.TypeString},
+		"expires_at":      {Type: filterutil.TypeTime, Sortable: true},
+		"revoked_at":      {Type: filterutil.TypeTime, Sortable: true},
+		"last_used_at":    {Type: filterutil.TypeTime, Sortable: true},
+		"created_at":      {Type: filterutil.TypeTime, Sortable: true},
+	}
+
+	// ImpersonationFilterSchema lists the filterable and sortable fields of impersonations
+	ImpersonationFilterSchema = filterutil.Schema{
+		"id":         {Type: filterutil.TypeString, Sortable: true},
+		"actor_id":   {Type: filterutil.TypeString, Sortable: true},
+		"user_id":    {Type: filterutil.TypeString, Sortable: true},
+		"reason":     {Type: filterutil.TypeString},
+		"expires_at": {Type: filterutil.TypeTime, Sortable: true},
+		"ended_at":   {Type: filterutil.TypeTime, Sortable: true},
+		"created_at": {Type: filterutil.TypeTime, Sortable: true},
+	}
+
+	// ActivityLogFilterSchema lists the sortable fields of activity logs, they are filtered by the dedicated query params
+	ActivityLogFilterSchema = filterutil.Schema{
+		"id":             {Type: filterutil.TypeString, Sortable: true},
+		"request_url":    {Type: filterutil.TypeString, Sortable: true},
+		"request_method": {Type: filterutil.TypeString, Sortable: true},
+		"response_code":  {Type: filterutil.TypeNumber, Sortable: true},
+		"duration_ms":    {Type: filterutil.TypeNumber, Sortable: true},
+		"created_at":     {Type: filterutil.TypeTime, Sortable: true},
+	}
+
+	// ExpenseReportFilterSchema lists the filterable and sortable fields of expense reports
+	ExpenseReportFilterSchema = filterutil.Schema{
+		"id":            {Type: filterutil.TypeString, Sortable: true},
+		"user_id":       {Type: filterutil.TypeString, Sortable: true},
+		"approver_id":   {Type: filterutil.TypeString, Sortable: true},
+		"title":         {Type: filterutil.TypeString, Sortable: true},
+		"status":        {Type: filterutil.TypeString, Sortable: true},
+		"total":         {Type: filterutil.TypeNumber, Sortable: true},
+		"currency":      {Type: filterutil.TypeString, Sortable: true},
+		"submitted_at":  {Type: filterutil.TypeTime, Sortable: true},
+		"decided_at":    {Type: filterutil.TypeTime, Sortable: true},
+		"reimbursed_at": {Type: filterutil.TypeTime, Sortable: true},
+		"created_at":    {Type: filterutil.TypeTime, Sortable: true},
+		"updated_at":    {Type: filterutil.TypeTime, Sortable: true},
 	}
 )
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"strings"
+	"time"
 
 	"tyr/internal/types"
 
@@ -26,6 +27,13 @@ func NewUser(gdb *gorm.DB) *User {
 func (r *User) List(ctx context.Context, output interface{}, count *int64, lc *requestutil.ListCondition[UsersFilter]) error {
 	conds := []string{}
 	vars := []any{}
+	if lc.Filter.Name != "" {
+		conds = append(conds, "CONCAT_WS(' ', first_name, last_name) ILIKE ?")
+		sVal := strings.ReplaceAll(lc.Filter.Name, "%", "")
+		sVal = strings.ReplaceAll(sVal, "?", "")
+		vars = append(vars, "%"+sVal+"%")
+	}
+
 	if lc.Filter.Search != "" {
 		conds = append(conds, "(first_name like ? OR last_name like ? OR email like ?)")
 		sVal := strings.ReplaceAll(lc.Filter.Search, "%", "")
@@ -34,6 +42,12 @@ func (r *User) List(ctx context.Context, output interface{}, count *int64, lc *r
 		vars = append(vars, sVal, sVal, sVal)
 	}
 
+	if len(lc.Filter.Query) > 0 {
+		qConds, qVars := lc.Filter.Query.SQL()
+		conds = append(conds, qConds)
+		vars = append(vars, qVars...)
+	}
+
 	return r.ReadAllByCondition(ctx, output, count, &requestutil.ListQueryCondition{
 		Page:    lc.Page,
 		PerPage: lc.PerPage,
@@ -54,6 +68,170 @@ func (r *User) FindByEmail(ctx context.Context, email string) (*types.User, erro
 	return rec, nil
 }
 
+// FindByPhone finds a user by the given phone
+func (r *User) FindByPhone(ctx context.Context, phone string) (*types.User, error) {
+	rec := &types.User{}
+	if err := r.GDB.WithContext(ctx).Where(`phone = ?`, phone).Take(rec).Error; err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// SetOTP stores the hash of a new one-time password of the user and resets the wrong attempts
+func (r *User) SetOTP(ctx context.Context, userID, otpHash string, sentAt time.Time) error {
+	return r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ?`, userID).Updates(map[string]interface{}{
+		"otp":          otpHash,
+		"otp_sent_at":  sentAt,
+		"otp_attempts": 0,
+	}).Error
+}
+
+// ClearOTP removes the one-time password of the user only if it is still the given one, returns false otherwise
+func (r *User) ClearOTP(ctx context.Context, userID, otpHash string) (bool, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ? AND otp = ?`, userID, otpHash).Updates(map[string]interface{}{
+		"otp":          nil,
+		"otp_attempts": 0,
+	})
+	return res.RowsAffected == 1, res.Error
+}
+
+// IncreaseOTPAttempts increases the wrong attempts of the current one-time password of the user and returns the new count
+func (r *User) IncreaseOTPAttempts(ctx context.Context, userID string) (int, error) {
+	var attempts int
+	err := r.GDB.WithContext(ctx).Raw(`UPDATE users SET otp_attempts = otp_attempts + 1 WHERE id = ? AND otp IS NOT NULL RETURNING otp_attempts`, userID).
+		Scan(&attempts).Error
+	return attempts, err
+}
+
+// SetMFASecret stores a new pending TOTP secret of the user, the two-factor authentication is disabled until it is activated
+func (r *User) SetMFASecret(ctx context.Context, userID, secret string) error {
+	return r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ?`, userID).Updates(map[string]interface{}{
+		"mfa_secret":          secret,
+		"mfa_enabled_at":      nil,
+		"mfa_last_step":       0,
+		"mfa_failed_attempts": 0,
+	}).Error
+}
+
+// EnableMFA activates the pending TOTP secret of the user, returns false if there is no pending secret
+func (r *User) EnableMFA(ctx context.Context, userID string, step int64) (bool, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.User{}).
+		Where(`id = ? AND mfa_secret IS NOT NULL AND mfa_enabled_at IS NULL`, userID).
+		Updates(map[string]interface{}{
+			"mfa_enabled_at": time.Now(),
+			"mfa_last_step":  step,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// DisableMFA removes the TOTP secret of the user
+func (r *User) DisableMFA(ctx context.Context, userID string) error {
+	return r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ?`, userID).Updates(map[string]interface{}{
+		"mfa_secret":          nil,
+		"mfa_enabled_at":      nil,
+		"mfa_last_step":       0,
+		"mfa_failed_attempts": 0,
+	}).Error
+}
+
+// UseMFAStep records the time step of an accepted TOTP code, returns false if the step or a later one has been used
+func (r *User) UseMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.User{}).
+		Where(`id = ? AND mfa_last_step < ?`, userID, step).
+		Update(`mfa_last_step`, step)
+	return res.RowsAffected == 1, res.Error
+}
+
+// IncreaseMFAFailedAttempts increases the wrong TOTP attempts of the user and returns the new count
+func (r *User) IncreaseMFAFailedAttempts(ctx context.Context, userID string) (int, error) {
+	var attempts int
+	err := r.GDB.WithContext(ctx).Raw(`UPDATE users SET mfa_failed_attempts = mfa_failed_attempts + 1 WHERE id = ? RETURNING mfa_failed_attempts`, userID).
+		Scan(&attempts).Error
+	return attempts, err
+}
+
+// ResetMFAFailedAttempts resets the wrong TOTP attempts of the user
+func (r *User) ResetMFAFailedAttempts(ctx context.Context, userID string) error {
+	return r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ?`, userID).Update(`mfa_failed_attempts`, 0).Error
+}
+
+// SetStatus transitions the user to the status, the deleted user is soft-deleted as well
+func (r *User) SetStatus(ctx context.Context, userID string, status types.UserStatus) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.User{}).Where(`id = ?`, userID).Update(`status`, status).Error; err != nil {
+			return err
+		}
+		if status != types.UserStatusDeleted {
+			return nil
+		}
+		return tx.Where(`id = ?`, userID).Delete(&types.User{}).Error
+	})
+}
+
+// ScheduleErasure sets the time to erase all data of the user, nil cancels the scheduled erasure
+func (r *User) ScheduleErasure(ctx context.Context, userID string, at *time.Time) error {
+	return r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ?`, userID).Update(`erasure_scheduled_at`, at).Error
+}
+
+// ListErasureDue lists the ids of the users whose scheduled erasure is due at the given time
+func (r *User) ListErasureDue(ctx context.Context, at time.Time, limit int) ([]string, error) {
+	ids := []string{}
+	err := r.GDB.WithContext(ctx).Model(&types.User{}).
+		Where(`erasure_scheduled_at <= ?`, at).
+		Order(`erasure_scheduled_at`).
+		Limit(limit).
+		Pluck(`id`, &ids).Error
+	return ids, err
+}
+
+// Erase permanently deletes all data of the user across the tables in a transaction.
+// The user record itself is kept for the audit trail, anonymized by the updates then soft-deleted,
+// the audit events of the user are kept without the IP addresses and user agents.
+// The login failures and lockouts of the account key, and the activity logs of the analyses of the documents are deleted as well.
+func (r *User) Erase(ctx context.Context, userID, accountKey string, updates map[string]interface{}) error {
+	return r.GDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		documentIDs := tx.Unscoped().Model(&types.Document{}).Select(`id`).Where(`user_id = ?`, userID)
+		reportIDs := tx.Unscoped().Model(&types.ExpenseReport{}).Select(`id`).Where(`user_id = ?`, userID)
+		// the activity logs of the analyses hold the raw results of the receipts
+		documentRequestIDs := tx.Unscoped().Model(&types.Document{}).Select(`apim_request_id`).Where(`user_id = ? AND apim_request_id <> ''`, userID)
+		analysisRequestIDs := tx.Unscoped().Model(&types.DocumentAnalysis{}).Select(`apim_request_id`).Where(`document_id IN (?)`, documentIDs)
+		steps := []*gorm.DB{
+			tx.Unscoped().Where(`apim_request_id IN (?) OR apim_request_id IN (?)`, documentRequestIDs, analysisRequestIDs).Delete(&types.ActivityLog{}),
+			tx.Exec(`DELETE FROM expense_report_documents WHERE document_id IN (?)`, documentIDs),
+			tx.Unscoped().Where(`expense_report_id IN (?)`, reportIDs).Delete(&types.ExpenseReportComment{}),
+			tx.Where(`expense_report_id IN (?)`, reportIDs).Delete(&types.ExpenseReportEvent{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.ExpenseReportComment{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.ExpenseReport{}),
+			tx.Unscoped().Where(`document_id IN (?)`, documentIDs).Delete(&types.DocumentItem{}),
+			tx.Unscoped().Where(`document_id IN (?)`, documentIDs).Delete(&types.DocumentAnalysis{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.Document{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.Session{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.Profile{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.UserToken{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.UserIdentity{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.MFARecoveryCode{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.PasswordHistory{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.DataExport{}),
+			tx.Unscoped().Where(`user_id = ?`, userID).Delete(&types.APIKey{}),
+			tx.Where(`user_id = ?`, userID).Delete(&types.OrganizationMember{}),
+			tx.Where(`key = ?`, accountKey).Delete(&types.LoginFailure{}),
+			tx.Where(`key = ?`, accountKey).Delete(&types.LoginLockout{}),
+			tx.Model(&types.AuditEvent{}).Where(`user_id = ?`, userID).
+				Updates(map[string]interface{}{"ip_address": "", "user_agent": ""}),
+			tx.Unscoped().Model(&types.User{}).Where(`id = ?`, userID).Updates(updates),
+			tx.Where(`id = ?`, userID).Delete(&types.User{}),
+		}
+		for _, step := range steps {
+			if step.Error != nil {
+				return step.Error
+			}
+		}
+
+		return nil
+	})
+}
+
 // UpdateRefreshToken updates the refresh token of the given user
 func (r *User) UpdateRefreshToken(ctx context.Context, userID, refreshToken string) error {
 	return r.GDB.WithContext(ctx).Model(&types.User{}).Where(`id = ?`, userID).Update(`refresh_token`, refreshToken).Error
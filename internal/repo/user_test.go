@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestUserErase checks the erasure deletes the rows of the user in every table holding its data and only them,
+// the audit trail is kept: the audit events without the IP addresses and user agents, the impersonations and the anonymized user
+func TestUserErase(t *testing.T) {
+	const (
+		userID     = "01HUSER00000000000000000000"
+		accountKey = "jo@tyr.io"
+	)
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConnPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []statement{}
+	record := func(db *gorm.DB) {
+		if sql := db.Statement.SQL.String(); !strings.HasPrefix(sql, "SAVEPOINT") {
+			statements = append(statements, statement{sql: sql, vars: db.Statement.Vars})
+		}
+	}
+	for _, err := range []error{
+		gdb.Callback().Delete().After("gorm:delete").Register("test:record", record),
+		gdb.Callback().Update().After("gorm:update").Register("test:record", record),
+		gdb.Callback().Raw().After("gorm:raw").Register("test:record", record),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updates := map[string]interface{}{"email": "erased-" + userID + "@erased.invalid", "first_name": "", "last_name": "", "status": "deleted"}
+	if err := NewUser(gdb).Erase(context.Background(), userID, accountKey, updates); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, updated := []string{}, []string{}
+	for _, s := range statements {
+		switch {
+		case strings.HasPrefix(s.sql, "DELETE FROM "):
+			deleted = append(deleted, strings.Trim(strings.Fields(s.sql)[2], `"`))
+		case strings.HasPrefix(s.sql, "UPDATE "):
+			updated = append(updated, strings.Trim(strings.Fields(s.sql)[1], `"`))
+		default:
+			t.Errorf("got statement %q", s.sql)
+		}
+
+		// the rows are selected by the user or its account key, the other users' rows are left alone
+		if !strings.Contains(s.sql, " WHERE ") || !containsVar(s.vars, userID) && !containsVar(s.vars, accountKey) {
+			t.Errorf("got statement %q with %v, want the rows of the user only", s.sql, s.vars)
+		}
+		for _, v := range s.vars {
+			if _, ok := v.(time.Time); ok || v == userID || v == accountKey || v == "" || containsValue(updates, v) {
+				continue
+			}
+			t.Errorf("got statement %q with the var %v", s.sql, v)
+		}
+	}
+
+	wantDeleted := []string{
+		"activity_logs", "expense_report_documents", "expense_report_comments", "expense_report_events",
+		"expense_report_comments", "expense_reports", "document_items", "document_analyses", "documents",
+		"sessions", "profiles", "user_tokens", "user_identities", "mfa_recovery_codes", "password_histories",
+		"data_exports", "api_keys", "organization_members", "login_failures", "login_lockouts",
+	}
+	if !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("got the tables %q erased, want %q", deleted, wantDeleted)
+	}
+	if want := []string{"audit_events", "users", "users"}; !reflect.DeepEqual(updated, want) {
+		t.Errorf("got the tables %q updated, want %q", updated, want)
+	}
+	for _, s := range statements {
+		if strings.Contains(s.sql, "impersonations") {
+			t.Errorf("got statement %q, want the impersonations kept for the audit trail", s.sql)
+		}
+	}
+
+	// the user is anonymized then soft-deleted
+	n := len(statements)
+	if n < 2 || !strings.Contains(statements[n-2].sql, "email") || !strings.Contains(statements[n-1].sql, "deleted_at") {
+		t.Errorf("got the last statements %+v, want the user anonymized then soft-deleted", statements[n-2:])
+	}
+	if audit := statements[n-3].sql; !strings.Contains(audit, "ip_address") || !strings.Contains(audit, "user_agent") {
+		t.Errorf("got %q, want the IP addresses and user agents of the audit events cleared", audit)
+	}
+}
+
+func containsValue(m map[string]interface{}, want interface{}) bool {
+	for _, v := range m {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"context"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity represents the client for user_identities table
+type UserIdentity struct {
+	*repoutil.Repo[types.UserIdentity]
+}
+
+// NewUserIdentity returns a new user identity database instance
+func NewUserIdentity(gdb *gorm.DB) *UserIdentity {
+	return &UserIdentity{repoutil.NewRepo[types.UserIdentity](gdb)}
+}
+
+// FindBySubject finds the identity of the provider by the subject, with its user even if soft-deleted,
+// so that the identity of a deleted user is not mistaken for a new one
+func (r *UserIdentity) FindBySubject(ctx context.Context, provider, subject string) (*types.UserIdentity, *types.User, error) {
+	rec := &types.UserIdentity{}
+	if err := r.GDB.WithContext(ctx).Where(`provider = ? AND subject = ?`, provider, subject).Take(rec).Error; err != nil {
+		return nil, nil, err
+	}
+
+	user := &types.User{}
+	if err := r.GDB.WithContext(ctx).Unscoped().Where(`id = ?`, rec.UserID).Take(user).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return rec, user, nil
+}
+
+// ListByUserID lists the identities of the user
+func (r *UserIdentity) ListByUserID(ctx context.Context, userID string) ([]*types.UserIdentity, error) {
+	recs := []*types.UserIdentity{}
+	if err := r.GDB.WithContext(ctx).Where(`user_id = ?`, userID).Order(`created_at`).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// Unlink deletes the identity of the user, returns false if there is no such identity
+func (r *UserIdentity) Unlink(ctx context.Context, userID, id string) (bool, error) {
+	res := r.GDB.WithContext(ctx).Unscoped().Where(`id = ? AND user_id = ?`, id, userID).Delete(&types.UserIdentity{})
+	return res.RowsAffected == 1, res.Error
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+
+	"gorm.io/gorm"
+)
+
+// UserToken represents the client for user_tokens table
+type UserToken struct {
+	*repoutil.Repo[types.UserToken]
+}
+
+// NewUserToken returns a new user token database instance
+func NewUserToken(gdb *gorm.DB) *UserToken {
+	return &UserToken{repoutil.NewRepo[types.UserToken](gdb)}
+}
+
+// MarkUsed marks the token as used only if it is still usable, returns false otherwise
+func (r *UserToken) MarkUsed(ctx context.Context, id string) (bool, error) {
+	res := r.GDB.WithContext(ctx).Model(&types.UserToken{}).
+		Where(`id = ? AND used_at IS NULL AND expires_at > ?`, id, time.Now()).
+		Update(`used_at`, time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// InvalidateByPurpose marks all usable tokens of the user for the given purpose as used
+func (r *UserToken) InvalidateByPurpose(ctx context.Context, userID, purpose string) error {
+	return r.GDB.WithContext(ctx).Model(&types.UserToken{}).
+		Where(`user_id = ? AND purpose = ? AND used_at IS NULL`, userID, purpose).
+		Update(`used_at`, time.Now()).Error
+}
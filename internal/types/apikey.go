@@ -0,0 +1,30 @@
+package types
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// APIKey represents a personal access token of a user for the integrations, restricted to its scopes.
+// The token is `tyr_<prefix>_<secret>`, only its hash is stored and the prefix identifies it.
+// swagger:model
+type APIKey struct {
+	Base
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// The organization the requests are made in, the one switched to when the key was created
+	OrganizationID *string `json:"organization_id,omitempty" gorm:"type:varchar(26)"`
+	// example: Accounting sync
+	Name string `json:"name" gorm:"type:varchar(100)"`
+	// The public part of the token to recognize the key
+	// example: tyr_3f9a0c1d7b2e
+	Prefix string `json:"prefix" gorm:"type:varchar(20);uniqueIndex"`
+	// SHA-256 hash of the whole token
+	TokenHash string `json:"-" gorm:"type:varchar(64)"`
+	// example: ["documents:read"]
+	Scopes     datatypes.JSONSlice[string] `json:"scopes"`
+	ExpiresAt  time.Time                   `json:"expires_at"`
+	RevokedAt  *time.Time                  `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time                  `json:"last_used_at,omitempty"`
+	LastUsedIP string                      `json:"last_used_ip,omitempty" gorm:"type:varchar(45)"`
+}
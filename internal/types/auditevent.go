@@ -0,0 +1,53 @@
+package types
+
+import "gorm.io/datatypes"
+
+// Audit event actions
+const (
+	AuditActionRefreshTokenReused   = "auth.refresh_token_reused"
+	AuditActionLoginFailed          = "auth.login_failed"
+	AuditActionAccountLocked        = "auth.account_locked"
+	AuditActionAccountUnlocked      = "auth.account_unlocked"
+	AuditActionUserActivated        = "user.activated"
+	AuditActionUserBlocked          = "user.blocked"
+	AuditActionUserDeleted          = "user.deleted"
+	AuditActionErasureScheduled     = "user.erasure_scheduled"
+	AuditActionErasureCancelled     = "user.erasure_cancelled"
+	AuditActionUserErased           = "user.erased"
+	AuditActionDataExported         = "user.data_exported"
+	AuditActionRoleCreated          = "rbac.role_created"
+	AuditActionRoleDeleted          = "rbac.role_deleted"
+	AuditActionPolicyCreated        = "rbac.policy_created"
+	AuditActionPolicyDeleted        = "rbac.policy_deleted"
+	AuditActionOrgCreated           = "organization.created"
+	AuditActionOrgUpdated           = "organization.updated"
+	AuditActionOrgDeleted           = "organization.deleted"
+	AuditActionOrgMemberUpdated     = "organization.member_updated"
+	AuditActionOrgMemberRemoved     = "organization.member_removed"
+	AuditActionOrgInvited           = "organization.invited"
+	AuditActionOrgInviteAccepted    = "organization.invitation_accepted"
+	AuditActionOrgInviteDeclined    = "organization.invitation_declined"
+	AuditActionOrgInviteRevoked     = "organization.invitation_revoked"
+	AuditActionAPIKeyCreated        = "api_key.created"
+	AuditActionAPIKeyRevoked        = "api_key.revoked"
+	AuditActionImpersonationStarted = "impersonation.started"
+	AuditActionImpersonationEnded   = "impersonation.ended"
+	AuditActionImpersonatedRequest  = "impersonation.request"
+)
+
+// AuditEvent represents a security relevant event
+// swagger:model
+type AuditEvent struct {
+	Base
+	// The user the event is about
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// The user who performed the action, empty if it is the user itself or the system
+	ActorID    string         `json:"actor_id,omitempty" gorm:"type:varchar(26)"`
+	Action     string         `json:"action" gorm:"type:varchar(100);index"`
+	ObjectType string         `json:"object_type,omitempty" gorm:"type:varchar(50)"`
+	ObjectID   string         `json:"object_id,omitempty" gorm:"type:varchar(26)"`
+	Reason     string         `json:"reason,omitempty"`
+	IPAddress  string         `json:"ip_address" gorm:"type:varchar(45)"`
+	UserAgent  string         `json:"user_agent"`
+	Metadata   datatypes.JSON `json:"metadata,omitempty"`
+}
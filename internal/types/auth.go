@@ -17,7 +17,18 @@ type Session struct {
 	IPAddress string    `json:"ip_address"`
 	UserAgent string    `json:"user_agent"`
 
-	RefreshToken sql.NullString `json:"-" gorm:"uniqueIndex:uix_users_refresh_token"`
+	// Device
+	DeviceName        string     `json:"device_name" gorm:"type:varchar(100)"`
+	Platform          string     `json:"platform" gorm:"type:varchar(20)"` // ios || android || web || other
+	LastSeenAt        *time.Time `json:"last_seen_at,omitempty"`
+	LastSeenIPAddress string     `json:"last_seen_ip_address" gorm:"type:varchar(45)"`
+	LastSeenUserAgent string     `json:"last_seen_user_agent"`
+
+	// The organization switched to, kept when refreshing the tokens of the session
+	ActiveOrganizationID *string `json:"active_organization_id,omitempty" gorm:"type:varchar(26)"`
+
+	// SHA-256 hash of the current refresh token of the session, the session is the family of all its rotated tokens
+	RefreshTokenHash sql.NullString `json:"-" gorm:"type:varchar(64);uniqueIndex:uix_sessions_refresh_token_hash"`
 
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
@@ -37,5 +48,15 @@ type AuthUser struct {
 	Name  string
 	Email string
 	Role  string
+	// Session of the access token
+	SessionID string
+	// Active organization of the session and the role of the user in it, empty out of organizations
+	OrganizationID string
+	OrgRole        string
+	// API key of the request and the scopes it is restricted to, empty for the access tokens
+	APIKeyID string
+	Scopes   []string
+	// The superadmin impersonating the user, empty unless impersonated
+	ActorID string
 	// add more if needed
 }
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// Data export statuses
+const (
+	DataExportPending   = "pending"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+)
+
+// DataExport represents an archive of all data of the user, built in the background
+// swagger:model
+type DataExport struct {
+	Base
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// pending || completed || failed
+	Status string `json:"status" gorm:"type:varchar(20)"`
+	// Blob key of the ZIP archive
+	BlobKey     string     `json:"-"`
+	Size        int64      `json:"size,omitempty"`
+	Error       string     `json:"-"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// The archive is deleted after this time
+	ExpiresAt time.Time `json:"expires_at"`
+}
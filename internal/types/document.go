@@ -21,6 +21,9 @@ type Document struct {
 	ModelID           string `json:"-" gorm:"type:varchar(20)"`
 	APIVersion        string `json:"-" gorm:"type:varchar(20)"`
 
+	// The organization the document is shared with, none for the personal documents
+	OrganizationID *string `json:"organization_id,omitempty" gorm:"type:varchar(26);index"`
+
 	// Merchant
 	MerchantName        string `json:"merchant_name"`
 	MerchantAddress     string `json:"merchant_address"`
@@ -40,6 +43,10 @@ type Document struct {
 
 	TotalPage int `json:"total_page"`
 
+	// Full-text search relevance and highlighted snippet, only filled when searching
+	SearchRank      float64 `json:"search_rank,omitempty" gorm:"->;-:migration"`
+	SearchHighlight string  `json:"search_highlight,omitempty" gorm:"->;-:migration"`
+
 	DocumentItem *DocumentItem `json:"document_item,omitempty"`
 }
 
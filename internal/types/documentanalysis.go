@@ -0,0 +1,27 @@
+package types
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DocumentAnalysis represents a single analysis run of a document, every re-analysis creates a new record
+// swagger:model
+type DocumentAnalysis struct {
+	Base
+	DocumentID    string    `json:"document_id" gorm:"index:idx_document_analyses_document_id"`
+	APIMRequestID string    `json:"apim_request_id" gorm:"column:apim_request_id;type:varchar(36);uniqueIndex:uix_document_analyses_apim_request_id"`
+	ModelID       string    `json:"model_id" gorm:"type:varchar(50)"`
+	APIVersion    string    `json:"api_version" gorm:"type:varchar(20)"`
+	Status        string    `json:"status" gorm:"type:varchar(20)"`
+	AnalyzedAt    time.Time `json:"analyzed_at"`
+	TotalPage     int       `json:"total_page"`
+
+	// Full OCR text of the document
+	Content string `json:"-" gorm:"type:text"`
+	// Page geometry with words, lines and their polygons
+	Pages datatypes.JSON `json:"-"`
+	// Raw analyze result returned by Azure
+	RawResult datatypes.JSON `json:"-"`
+}
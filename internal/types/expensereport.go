@@ -0,0 +1,100 @@
+package types
+
+import (
+	"time"
+
+	"github.com/M15t/gram/pkg/util/ulidutil"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Expense report statuses
+const (
+	// Editable by the submitter
+	ExpenseReportDraft = "draft"
+	// Waiting for the decision of the approver
+	ExpenseReportSubmitted = "submitted"
+	ExpenseReportApproved  = "approved"
+	// Editable by the submitter again to be resubmitted
+	ExpenseReportRejected   = "rejected"
+	ExpenseReportReimbursed = "reimbursed"
+)
+
+// Expense policy rules
+const (
+	// The receipt file of a document over the threshold is missing
+	ExpensePolicyMissingReceipt = "missing_receipt"
+	// The same spend is claimed twice, by the merchant, the date and the total
+	ExpensePolicyDuplicate = "duplicate"
+	// The spend happened on a weekend
+	ExpensePolicyWeekend = "weekend"
+)
+
+// ExpenseReport represents a reimbursement claim grouping documents of the submitter in an organization
+// swagger:model
+type ExpenseReport struct {
+	Base
+	// The submitter
+	UserID         string `json:"user_id" gorm:"type:varchar(26);index"`
+	OrganizationID string `json:"organization_id" gorm:"type:varchar(26);index"`
+	Title          string `json:"title" gorm:"type:varchar(100)"`
+	Description    string `json:"description"`
+	// draft || submitted || approved || rejected || reimbursed
+	Status string `json:"status" gorm:"type:varchar(20);index"`
+	// The sum of the totals of the documents when submitted
+	Total    float64 `json:"total"`
+	Currency string  `json:"currency" gorm:"type:varchar(3)"`
+	// The member assigned to decide on the report when submitted
+	ApproverID   *string    `json:"approver_id,omitempty" gorm:"type:varchar(26);index"`
+	SubmittedAt  *time.Time `json:"submitted_at,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+	ReimbursedAt *time.Time `json:"reimbursed_at,omitempty"`
+	// The policy violations flagged when submitted, list of ExpensePolicyViolation
+	PolicyViolations datatypes.JSON `json:"policy_violations,omitempty"`
+
+	Documents []*Document `json:"documents,omitempty" gorm:"many2many:expense_report_documents"`
+}
+
+// ExpensePolicyViolation represents a document of an expense report violating an expense policy
+// swagger:model
+type ExpensePolicyViolation struct {
+	// missing_receipt || duplicate || weekend
+	Rule       string `json:"rule"`
+	DocumentID string `json:"document_id"`
+	Message    string `json:"message"`
+}
+
+// ExpenseReportComment represents a comment of the submitter or a reviewer on an expense report
+// swagger:model
+type ExpenseReportComment struct {
+	Base
+	ExpenseReportID string `json:"expense_report_id" gorm:"type:varchar(26);index"`
+	UserID          string `json:"user_id" gorm:"type:varchar(26)"`
+	Body            string `json:"body"`
+
+	User *User `json:"user,omitempty"`
+}
+
+// ExpenseReportEvent represents a step of the workflow of an expense report, the trail is never changed
+// swagger:model
+type ExpenseReportEvent struct {
+	ID              string    `json:"id" gorm:"primaryKey"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExpenseReportID string    `json:"expense_report_id" gorm:"type:varchar(26);index"`
+	// The user who performed the step
+	ActorID    string `json:"actor_id" gorm:"type:varchar(26)"`
+	FromStatus string `json:"from_status,omitempty" gorm:"type:varchar(20)"`
+	ToStatus   string `json:"to_status" gorm:"type:varchar(20)"`
+	// The reason of the rejection or the note of the step
+	Note string `json:"note,omitempty"`
+
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// BeforeCreate hook executed by gorm
+func (e *ExpenseReportEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == "" {
+		e.ID = ulidutil.NewString()
+	}
+	return
+}
@@ -0,0 +1,21 @@
+package types
+
+import "time"
+
+// Impersonation represents a superadmin seeing the app as a user through a short-lived read-only access token,
+// every request made with the token is recorded in the audit events of the user
+// swagger:model
+type Impersonation struct {
+	Base
+	// The superadmin impersonating the user
+	ActorID string `json:"actor_id" gorm:"type:varchar(26);index"`
+	// The impersonated user
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// The organization of the user the requests are made in, if any
+	OrganizationID *string    `json:"organization_id,omitempty" gorm:"type:varchar(26)"`
+	Reason         string     `json:"reason"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+	IPAddress      string     `json:"ip_address" gorm:"type:varchar(45)"`
+	UserAgent      string     `json:"user_agent"`
+}
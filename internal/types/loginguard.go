@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// LoginFailure represents a failed login attempt of an account or an IP address
+type LoginFailure struct {
+	ID uint64 `json:"id" gorm:"primaryKey;autoIncrement"`
+	// Key of the attempt, eg: `account:<email>` or `ip:<address>`
+	Key       string    `json:"key" gorm:"type:varchar(320);index:idx_login_failures_key_created_at"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_login_failures_key_created_at"`
+}
+
+// LoginLockout represents a temporary lockout of an account or an IP address after too many failed login attempts
+// swagger:model
+type LoginLockout struct {
+	// Key of the lockout, eg: `account:<email>` or `ip:<address>`
+	// example: account:john.doe@tyr.io
+	Key string `json:"key" gorm:"primaryKey;type:varchar(320)"`
+	// Number of failed attempts which caused the lockout
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
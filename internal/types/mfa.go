@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// MFARecoveryCode represents a single-use recovery code of the TOTP two-factor authentication
+type MFARecoveryCode struct {
+	Base
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// SHA-256 hash of the recovery code
+	CodeHash string     `json:"-" gorm:"type:varchar(64)"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+}
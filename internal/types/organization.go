@@ -0,0 +1,80 @@
+package types
+
+import (
+	"time"
+
+	"github.com/M15t/gram/pkg/util/ulidutil"
+	"gorm.io/gorm"
+)
+
+// Organization roles of the members
+const (
+	// Manages the organization, its members and invitations, and all documents
+	OrgRoleOwner = "owner"
+	// Reviews the documents of all members
+	OrgRoleApprover = "approver"
+	// Shares the own documents with the organization
+	OrgRoleMember = "member"
+)
+
+// ValidOrgRoles for validation
+var ValidOrgRoles = []string{OrgRoleOwner, OrgRoleApprover, OrgRoleMember}
+
+// Organization invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+// Organization represents a workspace where the members track their expenses together
+// swagger:model
+type Organization struct {
+	Base
+	Name string `json:"name" gorm:"type:varchar(100)"`
+	// The user who created the organization
+	CreatedBy string `json:"created_by" gorm:"type:varchar(26)"`
+}
+
+// OrganizationMember represents the membership of a user in an organization, it is deleted when the user leaves
+// swagger:model
+type OrganizationMember struct {
+	ID             string    `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	OrganizationID string    `json:"organization_id" gorm:"type:varchar(26);uniqueIndex:uix_organization_members_organization_user"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(26);uniqueIndex:uix_organization_members_organization_user;index"`
+	// owner || approver || member
+	Role string `json:"role" gorm:"type:varchar(20)"`
+
+	Organization *Organization `json:"organization,omitempty"`
+	User         *User         `json:"user,omitempty"`
+}
+
+// BeforeCreate hook executed by gorm
+func (m *OrganizationMember) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == "" {
+		m.ID = ulidutil.NewString()
+	}
+	return
+}
+
+// OrganizationInvitation represents an invitation by email to join an organization,
+// the invited user accepts or declines it after logging in with the email address
+// swagger:model
+type OrganizationInvitation struct {
+	Base
+	OrganizationID string `json:"organization_id" gorm:"type:varchar(26);index"`
+	Email          string `json:"email"`
+	// The role of the member once accepted
+	Role string `json:"role" gorm:"type:varchar(20)"`
+	// The user who sent the invitation
+	InvitedBy string `json:"invited_by" gorm:"type:varchar(26)"`
+	// pending || accepted || declined || revoked
+	Status      string     `json:"status" gorm:"type:varchar(20)"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+
+	Organization *Organization `json:"organization,omitempty"`
+}
@@ -0,0 +1,9 @@
+package types
+
+// PasswordHistory represents a password the user has set, kept to prevent reusing the latest ones
+type PasswordHistory struct {
+	Base
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// Hash of the password, as stored in users.password
+	Hash string `json:"-" gorm:"not null"`
+}
@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+// RBACRole represents a role of the users, the system roles cannot be deleted
+// swagger:model
+type RBACRole struct {
+	// example: auditor
+	Name        string `json:"name" gorm:"primaryKey;type:varchar(50)"`
+	Description string `json:"description"`
+	System      bool   `json:"system" gorm:"not null;default:false"`
+	// The roles whose permissions are inherited, filled from the role parents
+	Parents   []string  `json:"parents" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RBACRoleParent represents the inheritance of a role from its parent role
+type RBACRoleParent struct {
+	Role   string `gorm:"primaryKey;type:varchar(50)"`
+	Parent string `gorm:"primaryKey;type:varchar(50);index"`
+}
+
+// RBACPolicy represents a permission of a role to perform an action on an object, `*` matches any
+// swagger:model
+type RBACPolicy struct {
+	Base
+	// example: auditor
+	Role string `json:"role" gorm:"type:varchar(50);uniqueIndex:uix_rbac_policies_role_object_action"`
+	// example: activity_log
+	Object string `json:"object" gorm:"type:varchar(50);uniqueIndex:uix_rbac_policies_role_object_action"`
+	// example: read_all
+	Action string `json:"action" gorm:"type:varchar(50);uniqueIndex:uix_rbac_policies_role_object_action"`
+}
+
+// RBACRevision is the single row counter increased on every change of the roles and the policies,
+// the instances reload their enforcer once it is changed
+type RBACRevision struct {
+	ID        int `gorm:"primaryKey"`
+	Revision  int64
+	UpdatedAt time.Time
+}
@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// RevokedToken represents a denylist entry of access tokens, either a single token (jti) or all tokens of a session (sid)
+type RevokedToken struct {
+	// Key of the entry, eg: `jti:<id>` or `sid:<id>`
+	Key       string    `json:"key" gorm:"primaryKey;type:varchar(64)"`
+	CreatedAt time.Time `json:"created_at"`
+	// The entry is useless after all the tokens it covers have expired
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
@@ -2,28 +2,21 @@ package types
 
 import "time"
 
-// cosnt
+// User statuses
 const (
-	UserStatusUnknown Status = iota
-	UserStatusActive
-	UserStatusBlocked
-	UserStatausDeleted
+	UserStatusActive  UserStatus = "active"
+	UserStatusBlocked UserStatus = "blocked"
+	UserStatusDeleted UserStatus = "deleted"
 )
 
-// Status represents the status of user
-type Status int
-
-func (s Status) String() string {
-	switch s {
-	case UserStatusActive:
-		return "active"
-	case UserStatusBlocked:
-		return "blocked"
-	case UserStatausDeleted:
-		return "deleted"
-	default:
-		return "unknown"
-	}
+// UserStatus represents the status of user, the user can only login while active
+type UserStatus string
+
+// ValidUserStatuses for validation
+var ValidUserStatuses = []UserStatus{UserStatusActive, UserStatusBlocked, UserStatusDeleted}
+
+func (s UserStatus) String() string {
+	return string(s)
 }
 
 // User represents the user model
@@ -34,17 +27,27 @@ type User struct {
 	LastName  string `json:"last_name"`
 	Role      string `json:"role"`
 
-	Password  string     `json:"-" gorm:"not null"`
-	LastLogin *time.Time `json:"last_login,omitempty"`
+	Password          string     `json:"-" gorm:"not null"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	LastLogin         *time.Time `json:"last_login,omitempty"`
 
 	Phone           string     `json:"phone" gorm:"uniqueIndex:uix_users_phone"`
 	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
-	OTP             *string    `json:"-" gorm:"type:varchar(10)"`
+	OTP             *string    `json:"-" gorm:"type:varchar(64)"` // HMAC-SHA256 hash of the one-time password
 	OTPSentAt       *time.Time `json:"-"`
+	OTPAttempts     int        `json:"-" gorm:"not null;default:0"` // wrong attempts of the current one-time password
 	Email           string     `json:"email" gorm:"uniqueIndex:uix_users_email"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 
-	Status string `json:"status" gorm:"type:varchar(20);default:active"` // active || blocked || deleted
+	Status UserStatus `json:"status" gorm:"type:varchar(20);not null;default:active"` // active || blocked || deleted
+	// All data of the user is erased after this time unless the user cancels it
+	ErasureScheduledAt *time.Time `json:"erasure_scheduled_at,omitempty"`
+
+	// TOTP two-factor authentication
+	MFASecret         *string    `json:"-"` // AES-GCM encrypted TOTP secret, pending until MFAEnabledAt is set
+	MFAEnabledAt      *time.Time `json:"mfa_enabled_at,omitempty"`
+	MFALastStep       int64      `json:"-" gorm:"not null;default:0"` // time step of the latest accepted TOTP code, against replay
+	MFAFailedAttempts int        `json:"-" gorm:"not null;default:0"`
 
 	Profile *Profile `json:"profile,omitempty" gorm:"foreignkey:UserID"`
 }
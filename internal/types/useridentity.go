@@ -0,0 +1,14 @@
+package types
+
+// UserIdentity represents an external identity of the user at an OpenID Connect provider, eg: Google, Apple
+// swagger:model
+type UserIdentity struct {
+	Base
+	UserID string `json:"user_id" gorm:"type:varchar(26);index"`
+	// example: google
+	Provider string `json:"provider" gorm:"type:varchar(50);uniqueIndex:uix_user_identities_provider_subject"`
+	// The `sub` claim of the ID tokens, unique per provider
+	Subject string `json:"subject" gorm:"type:varchar(255);uniqueIndex:uix_user_identities_provider_subject"`
+	// The email at the provider when the identity is linked
+	Email string `json:"email"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// User token purposes
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+	UserTokenMFAChallenge  = "mfa_challenge"
+	UserTokenUnlockAccount = "unlock_account"
+)
+
+// UserToken represents a single-use token sent to the user, eg: to verify email or to reset password
+type UserToken struct {
+	Base
+	UserID  string `json:"user_id" gorm:"type:varchar(26);index"`
+	Purpose string `json:"purpose" gorm:"type:varchar(30)"`
+	// SHA-256 hash of the secret part of the token
+	TokenHash string     `json:"-" gorm:"type:varchar(64)"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
@@ -0,0 +1,29 @@
+package usertoken
+
+import (
+	"context"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// New creates new user token service signing the tokens with the given secret
+func New(repo Repository, secret string) *UserToken {
+	return &UserToken{repo: repo, secret: []byte(secret), now: time.Now}
+}
+
+// UserToken represents signed single-use user token service
+type UserToken struct {
+	repo   Repository
+	secret []byte
+
+	now func() time.Time
+}
+
+// Repository represents the user token storage interface
+type Repository interface {
+	Create(ctx context.Context, rec *types.UserToken) error
+	ReadByID(ctx context.Context, output *types.UserToken, id string) error
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	InvalidateByPurpose(ctx context.Context, userID, purpose string) error
+}
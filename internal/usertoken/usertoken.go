@@ -0,0 +1,122 @@
+package usertoken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"tyr/internal/types"
+)
+
+// Custom errors
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenUsed    = errors.New("token already used")
+)
+
+// Issue creates a new token of the purpose for the user, the previous usable tokens of the same purpose are invalidated.
+// The token is `<id>.<expiry>.<secret>.<signature>`, the signature covers the purpose too so that a token
+// cannot be used for another purpose, and only the hash of the secret part is stored.
+func (s *UserToken) Issue(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	if err := s.repo.InvalidateByPurpose(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	rec := &types.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash(secret),
+		ExpiresAt: s.now().Add(ttl),
+	}
+	if err := s.repo.Create(ctx, rec); err != nil {
+		return "", err
+	}
+
+	payload := strings.Join([]string{rec.ID, strconv.FormatInt(rec.ExpiresAt.Unix(), 10), secret}, ".")
+	return payload + "." + s.sign(purpose, payload), nil
+}
+
+// Consume verifies the token of the purpose and marks it as used, returns the token record
+func (s *UserToken) Consume(ctx context.Context, token, purpose string) (*types.UserToken, error) {
+	rec, err := s.Verify(ctx, token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := s.repo.MarkUsed(ctx, rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrTokenUsed
+	}
+
+	return rec, nil
+}
+
+// Verify checks the token of the purpose without using it, returns the token record
+func (s *UserToken) Verify(ctx context.Context, token, purpose string) (*types.UserToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return nil, ErrInvalidToken
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.sign(purpose, payload))) {
+		return nil, ErrInvalidToken
+	}
+
+	// the expiry is signed, so expired tokens are rejected without touching the database
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if s.now().Unix() >= exp {
+		return nil, ErrTokenExpired
+	}
+
+	rec := &types.UserToken{}
+	if err := s.repo.ReadByID(ctx, rec, parts[0]); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if rec.Purpose != purpose || !hmac.Equal([]byte(rec.TokenHash), []byte(hash(parts[2]))) {
+		return nil, ErrInvalidToken
+	}
+	if rec.UsedAt != nil {
+		return nil, ErrTokenUsed
+	}
+	if !s.now().Before(rec.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return rec, nil
+}
+
+// Invalidate marks all usable tokens of the purpose of the user as used
+func (s *UserToken) Invalidate(ctx context.Context, userID, purpose string) error {
+	return s.repo.InvalidateByPurpose(ctx, userID, purpose)
+}
+
+func (s *UserToken) sign(purpose, payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
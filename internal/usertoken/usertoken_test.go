@@ -0,0 +1,280 @@
+package usertoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"tyr/internal/types"
+)
+
+const (
+	testUserID     = "01HUSER00000000000000000000"
+	purposeReset   = "reset_password"
+	purposeConfirm = "verify_email"
+)
+
+func TestIssueAndConsume(t *testing.T) {
+	s, repo, _ := newTestService()
+
+	token, err := s.Issue(context.Background(), testUserID, purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Split(token, "."); len(parts) != 4 {
+		t.Fatalf("got token %q, want 4 parts", token)
+	}
+
+	rec := repo.only(t)
+	if rec.UserID != testUserID || rec.Purpose != purposeReset {
+		t.Errorf("got token record %+v", rec)
+	}
+	// only the hash of the secret is stored
+	if secret := strings.Split(token, ".")[2]; rec.TokenHash == secret || rec.TokenHash != hash(secret) {
+		t.Error("the stored hash is not the hash of the secret")
+	}
+
+	if _, err := s.Verify(context.Background(), token, purposeReset); err != nil {
+		t.Fatalf("verifying got %v", err)
+	}
+	if rec.UsedAt != nil {
+		t.Fatal("the token is used by verifying it")
+	}
+
+	got, err := s.Consume(context.Background(), token, purposeReset)
+	if err != nil {
+		t.Fatalf("consuming got %v", err)
+	}
+	if got.ID != rec.ID || rec.UsedAt == nil {
+		t.Fatal("the token is not marked as used")
+	}
+
+	// single use
+	if _, err := s.Consume(context.Background(), token, purposeReset); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("consuming again got %v, want ErrTokenUsed", err)
+	}
+	if _, err := s.Verify(context.Background(), token, purposeReset); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("verifying the used token got %v, want ErrTokenUsed", err)
+	}
+}
+
+// TestConsumeConcurrently proves only one of the concurrent uses of a token succeeds,
+// the other one sees the token used by the conditional update
+func TestConsumeConcurrently(t *testing.T) {
+	s, repo, _ := newTestService()
+	token, err := s.Issue(context.Background(), testUserID, purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token is used between the verification and the update
+	repo.beforeMarkUsed = func() {
+		repo.beforeMarkUsed = nil
+		if _, err := s.Consume(context.Background(), token, purposeReset); err != nil {
+			t.Fatalf("the concurrent use got %v", err)
+		}
+	}
+	if _, err := s.Consume(context.Background(), token, purposeReset); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("got %v, want ErrTokenUsed", err)
+	}
+}
+
+func TestIssueInvalidatesPreviousTokens(t *testing.T) {
+	s, _, _ := newTestService()
+	ctx := context.Background()
+
+	first, err := s.Issue(ctx, testUserID, purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.Issue(ctx, testUserID, purposeConfirm, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherUser, err := s.Issue(ctx, "01HOTHER0000000000000000000", purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Issue(ctx, testUserID, purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Verify(ctx, first, purposeReset); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("the previous token got %v, want ErrTokenUsed", err)
+	}
+	for name, tc := range map[string]struct{ token, purpose string }{
+		"latest token":             {second, purposeReset},
+		"token of another purpose": {other, purposeConfirm},
+		"token of another user":    {otherUser, purposeReset},
+	} {
+		if _, err := s.Verify(ctx, tc.token, tc.purpose); err != nil {
+			t.Errorf("the %s got %v", name, err)
+		}
+	}
+
+	if err := s.Invalidate(ctx, testUserID, purposeReset); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(ctx, second, purposeReset); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("the invalidated token got %v, want ErrTokenUsed", err)
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	s, repo, clock := newTestService()
+	ctx := context.Background()
+
+	token, err := s.Issue(ctx, testUserID, purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.add(time.Hour - time.Second)
+	if _, err := s.Verify(ctx, token, purposeReset); err != nil {
+		t.Fatalf("got %v before the expiry", err)
+	}
+
+	clock.add(time.Second)
+	lookups := repo.lookups
+	if _, err := s.Consume(ctx, token, purposeReset); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("got %v, want ErrTokenExpired", err)
+	}
+	if repo.lookups != lookups {
+		t.Error("the expired token is looked up, the signed expiry is enough to reject it")
+	}
+	if repo.only(t).UsedAt != nil {
+		t.Error("the expired token is marked as used")
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	s, repo, _ := newTestService()
+	ctx := context.Background()
+
+	token, err := s.Issue(ctx, testUserID, purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	resign := func(parts ...string) string {
+		payload := strings.Join(parts, ".")
+		return payload + "." + s.sign(purposeReset, payload)
+	}
+
+	// the token of another user, issuing it for the same user would invalidate the token
+	foreign := New(repo, "another secret")
+	foreign.now = s.now
+	forged, err := foreign.Issue(ctx, "01HOTHER0000000000000000000", purposeReset, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		token   string
+		purpose string
+	}{
+		{name: "empty", token: "", purpose: purposeReset},
+		{name: "not enough parts", token: strings.Join(parts[:3], "."), purpose: purposeReset},
+		{name: "too many parts", token: token + ".x", purpose: purposeReset},
+		{name: "another purpose", token: token, purpose: purposeConfirm},
+		{name: "tampered id", token: strings.Join([]string{"01HOTHER0000000000000000000", parts[1], parts[2], parts[3]}, "."), purpose: purposeReset},
+		{name: "extended expiry", token: strings.Join([]string{parts[0], fmt.Sprint(s.now().Add(24 * time.Hour).Unix()), parts[2], parts[3]}, "."), purpose: purposeReset},
+		{name: "tampered signature", token: strings.Join([]string{parts[0], parts[1], parts[2], "x" + parts[3]}, "."), purpose: purposeReset},
+		{name: "signed by another secret", token: forged, purpose: purposeReset},
+		{name: "expiry not a number", token: resign(parts[0], "soon", parts[2]), purpose: purposeReset},
+		{name: "unknown id", token: resign("01HUNKNOWN00000000000000000", parts[1], parts[2]), purpose: purposeReset},
+		{name: "another secret part", token: resign(parts[0], parts[1], "guessed"), purpose: purposeReset},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.Consume(ctx, tc.token, tc.purpose); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	if _, err := s.Verify(ctx, token, purposeReset); err != nil {
+		t.Errorf("the token got %v after the invalid attempts", err)
+	}
+}
+
+func newTestService() (*UserToken, *fakeRepo, *clock) {
+	clock := &clock{now: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)}
+	repo := &fakeRepo{recs: map[string]*types.UserToken{}, clock: clock}
+	s := New(repo, "secret")
+	s.now = func() time.Time { return clock.now }
+	return s, repo, clock
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeRepo stores the tokens as the user_tokens table does
+type fakeRepo struct {
+	recs    map[string]*types.UserToken
+	clock   *clock
+	lookups int
+
+	beforeMarkUsed func()
+}
+
+func (r *fakeRepo) Create(_ context.Context, rec *types.UserToken) error {
+	rec.ID = fmt.Sprintf("01HTOKEN%019d", len(r.recs))
+	r.recs[rec.ID] = rec
+	return nil
+}
+
+func (r *fakeRepo) ReadByID(_ context.Context, output *types.UserToken, id string) error {
+	r.lookups++
+	rec, ok := r.recs[id]
+	if !ok {
+		return errors.New("record not found")
+	}
+	*output = *rec
+	return nil
+}
+
+func (r *fakeRepo) MarkUsed(_ context.Context, id string) (bool, error) {
+	if r.beforeMarkUsed != nil {
+		r.beforeMarkUsed()
+	}
+	rec, ok := r.recs[id]
+	if !ok || rec.UsedAt != nil || !rec.ExpiresAt.After(r.clock.now) {
+		return false, nil
+	}
+	now := r.clock.now
+	rec.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeRepo) InvalidateByPurpose(_ context.Context, userID, purpose string) error {
+	for _, rec := range r.recs {
+		if rec.UserID == userID && rec.Purpose == purpose && rec.UsedAt == nil {
+			now := r.clock.now
+			rec.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) only(t *testing.T) *types.UserToken {
+	t.Helper()
+	if len(r.recs) != 1 {
+		t.Fatalf("got %d token records, want 1", len(r.recs))
+	}
+	for _, rec := range r.recs {
+		return rec
+	}
+	return nil
+}
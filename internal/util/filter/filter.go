@@ -0,0 +1,234 @@
+package filterutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/M15t/gram/pkg/server"
+	repoutil "github.com/M15t/gram/pkg/util/repo"
+	"github.com/samber/lo"
+)
+
+// Error is returned when the filter or sort expression is invalid
+type Error struct {
+	Expr    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Expr == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Expr, e.Message)
+}
+
+// operators that are written right after the field name, longest first
+var symbolOps = []string{OpGte, OpLte, OpNotEq, OpNotContain, OpEq, OpGt, OpLt, OpContains}
+
+// Parse parses the filter expression into type-checked conditions against the schema.
+// Conditions are separated by commas and combined by AND, values can be double-quoted to contain commas, eg:
+//
+//	total>=10,merchant_name~"coffee, tea",transaction_date between 2026-01-01..2026-02-01,currency in USD|EUR
+func Parse(expr string, schema Schema) (Query, error) {
+	q := Query{}
+	for _, part := range splitConditions(expr) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		cond, err := parseCondition(part, schema)
+		if err != nil {
+			return nil, err
+		}
+		q = append(q, *cond)
+	}
+	return q, nil
+}
+
+// ParseList parses the `filter` and validates the `s` query params of a listing api against the schema.
+// Any error is returned as a validation error to be responded as is.
+func ParseList(filter, sort string, schema Schema) (Query, error) {
+	q, err := Parse(filter, schema)
+	if err != nil {
+		return nil, server.NewHTTPValidationError("Invalid filter, " + err.Error())
+	}
+	if err := ValidateSort(sort, schema); err != nil {
+		return nil, server.NewHTTPValidationError("Invalid sort, " + err.Error())
+	}
+	return q, nil
+}
+
+// SQL compiles the conditions into a parameterized SQL string and its vars.
+// Columns come from the schema only so the output is safe to be passed to GORM.
+func (q Query) SQL() (string, []any) {
+	conds := make([]string, 0, len(q))
+	vars := []any{}
+	for _, c := range q {
+		switch c.Op {
+		case OpContains, OpNotContain:
+			op := "ILIKE"
+			if c.Op == OpNotContain {
+				op = "NOT ILIKE"
+			}
+			conds = append(conds, c.Column+" "+op+" ?")
+			vars = append(vars, "%"+escapeLike(c.Values[0].(string))+"%")
+		case OpBetween:
+			conds = append(conds, c.Column+" BETWEEN ? AND ?")
+			vars = append(vars, c.Values[0], c.Values[1])
+		case OpIn:
+			conds = append(conds, c.Column+" IN ?")
+			vars = append(vars, c.Values)
+		case OpNotEq:
+			conds = append(conds, c.Column+" <> ?")
+			vars = append(vars, c.Values[0])
+		default:
+			conds = append(conds, c.Column+" "+c.Op+" ?")
+			vars = append(vars, c.Values[0])
+		}
+	}
+	return strings.Join(conds, " AND "), vars
+}
+
+// ValidateSort checks that every column of the sort param (eg: `+total,-created_at`) is sortable in the schema
+func ValidateSort(sort string, schema Schema) error {
+	for _, v := range repoutil.ParseSortParam(sort) {
+		f, ok := schema[v[0]]
+		if !ok || !(f.Sortable || f.SortOnly) {
+			return &Error{Expr: v[0], Message: "field is not sortable"}
+		}
+	}
+	return nil
+}
+
+func parseCondition(expr string, schema Schema) (*Condition, error) {
+	// field name
+	i := 0
+	for i < len(expr) && (expr[i] == '_' || expr[i] == '.' || (expr[i] >= 'a' && expr[i] <= 'z') || (expr[i] >= '0' && expr[i] <= '9')) {
+		i++
+	}
+	name := expr[:i]
+	field, ok := schema[name]
+	if name == "" || !ok || field.SortOnly {
+		return nil, &Error{Expr: expr, Message: "unknown field"}
+	}
+	if field.Column == "" {
+		field.Column = name
+	}
+
+	// operator
+	rest := strings.TrimLeft(expr[i:], " ")
+	op := ""
+	for _, o := range symbolOps {
+		if strings.HasPrefix(rest, o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		for _, o := range []string{OpBetween, OpIn} {
+			if strings.HasPrefix(strings.ToLower(rest), o+" ") {
+				op = o
+				break
+			}
+		}
+	}
+	if op == "" {
+		return nil, &Error{Expr: expr, Message: "missing or unknown operator"}
+	}
+	if !lo.Contains(typeOps[field.Type], op) {
+		return nil, &Error{Expr: expr, Message: fmt.Sprintf("operator %q is not supported for %s field", op, field.Type)}
+	}
+	raw := strings.TrimSpace(rest[len(op):])
+
+	// values
+	var rawValues []string
+	switch op {
+	case OpBetween:
+		rawValues = strings.SplitN(raw, "..", 2)
+		if len(rawValues) != 2 {
+			return nil, &Error{Expr: expr, Message: "between expects a range in form of `from..to`"}
+		}
+	case OpIn:
+		rawValues = strings.Split(raw, "|")
+	default:
+		rawValues = []string{raw}
+	}
+
+	cond := &Condition{Field: name, Column: field.Column, Op: op, Values: make([]any, 0, len(rawValues))}
+	for _, rv := range rawValues {
+		v, err := parseValue(unquote(strings.TrimSpace(rv)), field.Type)
+		if err != nil {
+			return nil, &Error{Expr: expr, Message: err.Error()}
+		}
+		cond.Values = append(cond.Values, v)
+	}
+
+	return cond, nil
+}
+
+func parseValue(raw, typ string) (any, error) {
+	switch typ {
+	case TypeString:
+		if raw == "" {
+			return nil, fmt.Errorf("value is required")
+		}
+		return raw, nil
+	case TypeNumber:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return v, nil
+	case TypeDate:
+		if _, err := time.Parse(time.DateOnly, raw); err != nil {
+			return nil, fmt.Errorf("invalid date %q, expecting YYYY-MM-DD", raw)
+		}
+		return raw, nil
+	case TypeTime:
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, expecting RFC3339 or YYYY-MM-DD", raw)
+		}
+		return t, nil
+	case TypeBool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", raw)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported field type %q", typ)
+}
+
+// splitConditions splits the expression by commas which are not inside double quotes
+func splitConditions(expr string) []string {
+	parts := []string{}
+	quoted := false
+	start := 0
+	for i, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			parts = append(parts, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
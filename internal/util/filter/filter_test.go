@@ -0,0 +1,189 @@
+package filterutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/M15t/gram/pkg/server"
+)
+
+var testSchema = Schema{
+	"id":               {Type: TypeString, Sortable: true},
+	"merchant_name":    {Type: TypeString, Sortable: true},
+	"name":             {Column: "full_name", Type: TypeString},
+	"total":            {Type: TypeNumber, Sortable: true},
+	"transaction_date": {Type: TypeDate, Sortable: true},
+	"created_at":       {Type: TypeTime, Sortable: true},
+	"is_blocked":       {Type: TypeBool},
+	"search_rank":      {SortOnly: true},
+}
+
+func TestParse(t *testing.T) {
+	day := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	instant := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		expr    string
+		want    Query
+		wantErr bool
+	}{
+		{name: "empty", expr: "", want: Query{}},
+		{name: "blank conditions are skipped", expr: " , ,", want: Query{}},
+		{name: "equal string", expr: "id=01H", want: Query{{Field: "id", Column: "id", Op: OpEq, Values: []any{"01H"}}}},
+		{name: "column of the schema", expr: "name~nido", want: Query{{Field: "name", Column: "full_name", Op: OpContains, Values: []any{"nido"}}}},
+		{name: "spaces around the operator", expr: "total >= 10", want: Query{{Field: "total", Column: "total", Op: OpGte, Values: []any{10.0}}}},
+		{name: "longest operator first", expr: "merchant_name!~tea", want: Query{{Field: "merchant_name", Column: "merchant_name", Op: OpNotContain, Values: []any{"tea"}}}},
+		{name: "not equal", expr: "total!=1.5", want: Query{{Field: "total", Column: "total", Op: OpNotEq, Values: []any{1.5}}}},
+		{name: "quoted value with comma", expr: `merchant_name~"coffee, tea",total<5`, want: Query{
+			{Field: "merchant_name", Column: "merchant_name", Op: OpContains, Values: []any{"coffee, tea"}},
+			{Field: "total", Column: "total", Op: OpLt, Values: []any{5.0}},
+		}},
+		{name: "between dates", expr: "transaction_date between 2026-01-01..2026-02-01", want: Query{
+			{Field: "transaction_date", Column: "transaction_date", Op: OpBetween, Values: []any{"2026-01-01", "2026-02-01"}},
+		}},
+		{name: "between is case insensitive", expr: "total BETWEEN 1..2", want: Query{{Field: "total", Column: "total", Op: OpBetween, Values: []any{1.0, 2.0}}}},
+		{name: "in", expr: "id in a|b|c", want: Query{{Field: "id", Column: "id", Op: OpIn, Values: []any{"a", "b", "c"}}}},
+		{name: "time as date", expr: "created_at>=2026-01-02", want: Query{{Field: "created_at", Column: "created_at", Op: OpGte, Values: []any{day}}}},
+		{name: "time as RFC3339", expr: "created_at<2026-01-02T03:04:05Z", want: Query{{Field: "created_at", Column: "created_at", Op: OpLt, Values: []any{instant}}}},
+		{name: "bool", expr: "is_blocked=true", want: Query{{Field: "is_blocked", Column: "is_blocked", Op: OpEq, Values: []any{true}}}},
+		{name: "unknown field", expr: "password=x", wantErr: true},
+		{name: "uppercase field", expr: "ID=x", wantErr: true},
+		{name: "sort only field", expr: "search_rank>1", wantErr: true},
+		{name: "missing operator", expr: "total", wantErr: true},
+		{name: "unsupported operator of type", expr: "is_blocked~t", wantErr: true},
+		{name: "contains on number", expr: "total~1", wantErr: true},
+		{name: "in on time", expr: "created_at in 2026-01-01|2026-01-02", wantErr: true},
+		{name: "between without range", expr: "total between 1", wantErr: true},
+		{name: "invalid number", expr: "total>ten", wantErr: true},
+		{name: "invalid date", expr: "transaction_date=01/02/2026", wantErr: true},
+		{name: "invalid time", expr: "created_at>yesterday", wantErr: true},
+		{name: "invalid bool", expr: "is_blocked=maybe", wantErr: true},
+		{name: "empty string", expr: "id=", wantErr: true},
+		{name: "injection in the field name", expr: "id;DROP TABLE users=1", wantErr: true},
+		{name: "injection through a column expression", expr: "(SELECT 1)=1", wantErr: true},
+		{name: "injection in the operator", expr: "id OR 1=1", wantErr: true},
+		{name: "injection in a number", expr: "total=1 OR 1=1", wantErr: true},
+		{name: "injection in a date", expr: "transaction_date='' OR ''=''", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.expr, testSchema)
+			if tc.wantErr {
+				var fe *Error
+				if !errors.As(err, &fe) {
+					t.Fatalf("got %v, want *Error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSQL(t *testing.T) {
+	cases := []struct {
+		name     string
+		expr     string
+		wantSQL  string
+		wantVars []any
+	}{
+		{name: "empty", expr: "", wantSQL: "", wantVars: []any{}},
+		{name: "compare", expr: "total>=10", wantSQL: "total >= ?", wantVars: []any{10.0}},
+		{name: "not equal", expr: "id!=a", wantSQL: "id <> ?", wantVars: []any{"a"}},
+		{name: "contains", expr: "merchant_name~tea", wantSQL: "merchant_name ILIKE ?", wantVars: []any{"%tea%"}},
+		{name: "not contains", expr: "merchant_name!~tea", wantSQL: "merchant_name NOT ILIKE ?", wantVars: []any{"%tea%"}},
+		{name: "contains escapes the wildcards", expr: `merchant_name~50%_off\`, wantSQL: "merchant_name ILIKE ?", wantVars: []any{`%50\%\_off\\%`}},
+		{name: "between", expr: "total between 1..2", wantSQL: "total BETWEEN ? AND ?", wantVars: []any{1.0, 2.0}},
+		{name: "in", expr: "id in a|b", wantSQL: "id IN ?", wantVars: []any{[]any{"a", "b"}}},
+		{name: "column of the schema", expr: "name=nido", wantSQL: "full_name = ?", wantVars: []any{"nido"}},
+		{name: "combined by AND", expr: "id=a,total<2", wantSQL: "id = ? AND total < ?", wantVars: []any{"a", 2.0}},
+		{name: "values are never inlined", expr: `merchant_name="x' OR '1'='1"`, wantSQL: "merchant_name = ?", wantVars: []any{"x' OR '1'='1"}},
+		{name: "quoted values are kept as is", expr: `id="a; DROP TABLE users; --"`, wantSQL: "id = ?", wantVars: []any{"a; DROP TABLE users; --"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := Parse(tc.expr, testSchema)
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			sql, vars := q.SQL()
+			if sql != tc.wantSQL {
+				t.Errorf("got sql %q, want %q", sql, tc.wantSQL)
+			}
+			if !reflect.DeepEqual(vars, tc.wantVars) {
+				t.Errorf("got vars %#v, want %#v", vars, tc.wantVars)
+			}
+		})
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	cases := []struct {
+		name    string
+		sort    string
+		wantErr bool
+	}{
+		{name: "empty", sort: ""},
+		{name: "single", sort: "total"},
+		{name: "directions", sort: "+total,-created_at"},
+		{name: "sort only field", sort: "-search_rank"},
+		{name: "not sortable", sort: "is_blocked", wantErr: true},
+		{name: "unknown", sort: "password", wantErr: true},
+		{name: "one of many unknown", sort: "total,-password", wantErr: true},
+		{name: "injection", sort: "total;DROP TABLE users", wantErr: true},
+		{name: "injection through an expression", sort: "(CASE WHEN 1=1 THEN total END)", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSort(tc.sort, testSchema)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	cases := []struct {
+		name    string
+		filter  string
+		sort    string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "empty", wantLen: 0},
+		{name: "filter and sort", filter: "total>1,id=a", sort: "-total", wantLen: 2},
+		{name: "invalid filter", filter: "password=x", wantErr: true},
+		{name: "invalid sort", filter: "total>1", sort: "password", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := ParseList(tc.filter, tc.sort, testSchema)
+			if tc.wantErr {
+				var he *server.HTTPError
+				if !errors.As(err, &he) || he.Code != 400 {
+					t.Fatalf("got %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v", err)
+			}
+			if len(q) != tc.wantLen {
+				t.Errorf("got %d conditions, want %d", len(q), tc.wantLen)
+			}
+		})
+	}
+}
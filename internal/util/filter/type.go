@@ -0,0 +1,62 @@
+package filterutil
+
+// Value types of a filterable field
+const (
+	TypeString = "string"
+	TypeNumber = "number"
+	// TypeDate is a date in YYYY-MM-DD format, compared as string
+	TypeDate = "date"
+	// TypeTime is a timestamp in RFC3339 or YYYY-MM-DD format
+	TypeTime = "time"
+	TypeBool = "bool"
+)
+
+// Operators of a condition
+const (
+	OpEq         = "="
+	OpNotEq      = "!="
+	OpGt         = ">"
+	OpGte        = ">="
+	OpLt         = "<"
+	OpLte        = "<="
+	OpContains   = "~"
+	OpNotContain = "!~"
+	OpBetween    = "between"
+	OpIn         = "in"
+)
+
+// allowed operators per value type
+var typeOps = map[string][]string{
+	TypeString: {OpEq, OpNotEq, OpContains, OpNotContain, OpIn},
+	TypeNumber: {OpEq, OpNotEq, OpGt, OpGte, OpLt, OpLte, OpBetween, OpIn},
+	TypeDate:   {OpEq, OpNotEq, OpGt, OpGte, OpLt, OpLte, OpBetween, OpIn},
+	TypeTime:   {OpEq, OpNotEq, OpGt, OpGte, OpLt, OpLte, OpBetween},
+	TypeBool:   {OpEq, OpNotEq},
+}
+
+// Field describes a filterable field of a resource
+type Field struct {
+	// Column name in database, default to the field name
+	Column string
+	// Value type, one of the Type* constants
+	Type string
+	// Whether the field can be used for sorting
+	Sortable bool
+	// Whether the field can only be used for sorting, eg: a computed column
+	SortOnly bool
+}
+
+// Schema is the whitelist of filterable fields of a resource, keyed by the field name used in the filter
+type Schema map[string]Field
+
+// Condition is a parsed and type-checked condition
+type Condition struct {
+	Field  string
+	Column string
+	Op     string
+	// Typed values, 2 values for between, 1 or more for in, 1 otherwise
+	Values []any
+}
+
+// Query is the list of conditions combined by AND
+type Query []Condition
@@ -42,3 +42,6 @@ gobuild() {
 }
 
 gobuild ./functions/migration migration
+gobuild ./functions/retention retention
+gobuild ./functions/reextract reextract
+gobuild ./functions/privacy privacy
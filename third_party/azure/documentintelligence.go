@@ -135,6 +135,7 @@ func (s *Service) GetAnalyzeDocument(c contextutil.Context, url string) (*Result
 
 	data := new(ResultAnalyzeResponse)
 	json.Unmarshal(resData, &data)
+	data.Raw = resData
 
 	if err := s.repo.ActivityLog.Create(c.GetContext(), &types.ActivityLog{
 		RequestURL:     url,
@@ -0,0 +1,99 @@
+package azure
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tyr/internal/types"
+
+	"github.com/iancoleman/strcase"
+)
+
+// StatusSucceeded is the status of a completed analyze operation
+const StatusSucceeded = "succeeded"
+
+// ErrEmptyDocument is returned when the analyze result contains no document
+var ErrEmptyDocument = errors.New("analyze result contains no document")
+
+// ToDocument maps the analyze result into document fields and its items.
+// Only the extracted fields are filled, the caller is responsible for the identity and ownership fields.
+func ToDocument(res *ResultAnalyzeResponse) (*types.Document, error) {
+	if res == nil || len(res.AnalyzeResult.Documents) == 0 {
+		return nil, ErrEmptyDocument
+	}
+
+	page := 0 // in case proccess multiple pages
+	fields := res.AnalyzeResult.Documents[page].Fields
+
+	doc := &types.Document{
+		TotalPage:           len(res.AnalyzeResult.Pages),
+		MerchantName:        fields.MerchantName.Content,
+		MerchantAddress:     fields.MerchantAddress.Content,
+		MerchantPhoneNumber: fields.MerchantPhoneNumber.Content,
+		TransactionDate:     parseStringToDate(fields.TransactionDate.Content),
+		TransactionTime:     fields.TransactionTime.Content,
+		TotalTax:            fields.TotalTax.ValueNumber,
+		Total:               fields.Total.ValueNumber,
+	}
+
+	if len(fields.TaxDetails.ValueArray) > 0 {
+		doc.Currency = fields.TaxDetails.ValueArray[0].ValueObject.Amount.ValueCurrency.CurrencyCode
+		doc.TaxDetails = fields.TaxDetails.ValueArray[0].Content
+	}
+
+	// map items, keep the content of every field only
+	mappedItems := make([]map[string]interface{}, 0)
+	for _, item := range fields.Items.ValueArray {
+		newItem := make(map[string]interface{})
+		for fieldName, fieldValue := range item.ValueObject {
+			if fieldValueMap, ok := fieldValue.(map[string]interface{}); ok {
+				if valueString, ok := fieldValueMap["content"].(string); ok {
+					newItem[strcase.ToSnake(fieldName)] = valueString
+				}
+			}
+		}
+		mappedItems = append(mappedItems, newItem)
+	}
+
+	jsonData, err := json.Marshal(mappedItems)
+	if err != nil {
+		return nil, err
+	}
+	doc.DocumentItem = &types.DocumentItem{Data: jsonData}
+
+	return doc, nil
+}
+
+// ToDocumentAnalysis maps the analyze result into an analysis run record, keeping the raw result, OCR content and page geometry.
+// The caller is responsible for linking it to the document.
+func ToDocumentAnalysis(res *ResultAnalyzeResponse) (*types.DocumentAnalysis, error) {
+	raw := res.Raw
+	if len(raw) == 0 {
+		var err error
+		if raw, err = json.Marshal(res); err != nil {
+			return nil, err
+		}
+	}
+
+	pages, err := json.Marshal(res.AnalyzeResult.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzedAt, err := time.Parse(time.RFC3339, res.LastUpdatedDateTime)
+	if err != nil {
+		analyzedAt = time.Now()
+	}
+
+	return &types.DocumentAnalysis{
+		ModelID:    res.AnalyzeResult.ModelID,
+		APIVersion: res.AnalyzeResult.APIVersion,
+		Status:     res.Status,
+		AnalyzedAt: analyzedAt,
+		TotalPage:  len(res.AnalyzeResult.Pages),
+		Content:    res.AnalyzeResult.Content,
+		Pages:      pages,
+		RawResult:  []byte(raw),
+	}, nil
+}
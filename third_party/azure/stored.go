@@ -0,0 +1,42 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"tyr/internal/repo"
+	"tyr/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// FindStoredResult looks up the stored analyze result of the given apim_request_id without calling Azure.
+// It prefers the dedicated document analyses, then falls back to the legacy activity logs in which case
+// the returned analysis is nil. Returns gorm.ErrRecordNotFound if nothing is stored.
+func FindStoredResult(ctx context.Context, repoSvc *repo.Service, apimReqID string) (*ResultAnalyzeResponse, *types.DocumentAnalysis, error) {
+	res := new(ResultAnalyzeResponse)
+
+	analysis, err := repoSvc.DocumentAnalysis.FindByAPIMRequestID(ctx, apimReqID)
+	if err == nil {
+		if err := json.Unmarshal(analysis.RawResult, res); err != nil {
+			return nil, nil, err
+		}
+		res.Raw = json.RawMessage(analysis.RawResult)
+		return res, analysis, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	activityLog, err := repoSvc.ActivityLog.FindAnalyzeResult(ctx, apimReqID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(activityLog.ResponseBody, res); err != nil {
+		return nil, nil, err
+	}
+	res.Raw = json.RawMessage(activityLog.ResponseBody)
+
+	return res, nil, nil
+}
@@ -1,5 +1,7 @@
 package azure
 
+import "encoding/json"
+
 // ResponseHeaders struct
 type ResponseHeaders struct {
 	ContentLength             []string `json:"Content-Length"`
@@ -198,44 +200,53 @@ type ResultAnalyzeResponse struct {
 				Offset float64 `json:"offset"`
 			} `json:"spans"`
 		} `json:"documents"`
-		ModelID string `json:"modelId"`
-		Pages   []struct {
-			Angle  float64 `json:"angle"`
-			Height float64 `json:"height"`
-			Lines  []struct {
-				Content string    `json:"content"`
-				Polygon []float64 `json:"polygon"`
-				Spans   []struct {
-					Length float64 `json:"length"`
-					Offset float64 `json:"offset"`
-				} `json:"spans"`
-			} `json:"lines"`
-			PageNumber float64 `json:"pageNumber"`
-			Spans      []struct {
-				Length float64 `json:"length"`
-				Offset float64 `json:"offset"`
-			} `json:"spans"`
-			Unit  string  `json:"unit"`
-			Width float64 `json:"width"`
-			Words []struct {
-				Confidence float64   `json:"confidence"`
-				Content    string    `json:"content"`
-				Polygon    []float64 `json:"polygon"`
-				Span       struct {
-					Length float64 `json:"length"`
-					Offset float64 `json:"offset"`
-				} `json:"span"`
-			} `json:"words"`
-		} `json:"pages"`
+		ModelID         string        `json:"modelId"`
+		Pages           []Page        `json:"pages"`
 		StringIndexType string        `json:"stringIndexType"`
 		Styles          []interface{} `json:"styles"`
 	} `json:"analyzeResult"`
 	CreatedDateTime     string `json:"createdDateTime"`
 	LastUpdatedDateTime string `json:"lastUpdatedDateTime"`
 	Status              string `json:"status"`
+
+	// Raw holds the original response body, including the fields that are not mapped
+	Raw json.RawMessage `json:"-"`
 }
 
 type (
+	// Page struct, holds the geometry and the OCR words, lines of a page
+	Page struct {
+		Angle      float64    `json:"angle"`
+		Height     float64    `json:"height"`
+		Lines      []PageLine `json:"lines"`
+		PageNumber float64    `json:"pageNumber"`
+		Spans      []Span     `json:"spans"`
+		Unit       string     `json:"unit"`
+		Width      float64    `json:"width"`
+		Words      []PageWord `json:"words"`
+	}
+
+	// PageLine struct
+	PageLine struct {
+		Content string    `json:"content"`
+		Polygon []float64 `json:"polygon"`
+		Spans   []Span    `json:"spans"`
+	}
+
+	// PageWord struct
+	PageWord struct {
+		Confidence float64   `json:"confidence"`
+		Content    string    `json:"content"`
+		Polygon    []float64 `json:"polygon"`
+		Span       Span      `json:"span"`
+	}
+
+	// Span struct
+	Span struct {
+		Length float64 `json:"length"`
+		Offset float64 `json:"offset"`
+	}
+
 	// DueDate struct
 	DueDate struct {
 		BoundingRegions []struct {
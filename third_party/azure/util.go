@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"path"
 
+	"github.com/araddon/dateparse"
 	"gorm.io/datatypes"
 )
 
@@ -60,3 +61,9 @@ func getAPIMRequestID(urlString string) string {
 	// Extract the desired path segment
 	return path.Base(parsedURL.Path)
 }
+
+func parseStringToDate(dateString string) string {
+	t, _ := dateparse.ParseAny(dateString)
+
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,35 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"tyr/config"
+)
+
+// consts for blob drivers
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+// Service represents blob storage service
+type Service interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// New returns blob storage service by the configured driver
+func New(cfg config.Blob) (Service, error) {
+	switch cfg.Driver {
+	case DriverS3:
+		return NewS3(cfg)
+	case DriverLocal, "":
+		return NewLocal(cfg.LocalPath), nil
+	default:
+		return nil, fmt.Errorf("unsupported blob driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/M15t/gram/pkg/util/ulidutil"
+)
+
+// File writes emails as .eml files into a local directory, for development only
+type File struct {
+	from string
+	dir  string
+}
+
+// NewFile returns file mailer writing into the given directory
+func NewFile(from, dir string) *File {
+	return &File{from: from, dir: dir}
+}
+
+// Send writes the message into a new file
+func (s *File) Send(_ context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	body, err := build(msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, ulidutil.NewString()+".eml"), body, 0o644)
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps sent emails in memory, for tests only
+type Memory struct {
+	from string
+
+	mu       sync.Mutex
+	messages []*Message
+}
+
+// NewMemory returns in-memory mailer
+func NewMemory(from string) *Memory {
+	return &Memory{from: from}
+}
+
+// Send keeps the message
+func (s *Memory) Send(_ context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns the sent messages
+func (s *Memory) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Message{}, s.messages...)
+}
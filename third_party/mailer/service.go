@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"tyr/config"
+)
+
+// consts for mailer drivers
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Message represents an email message
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Service represents mailer service
+type Service interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns mailer service by the configured driver
+func New(cfg config.Mailer) (Service, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTP(cfg), nil
+	case DriverFile, "":
+		return NewFile(cfg.From, cfg.FilePath), nil
+	case DriverMemory:
+		return NewMemory(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unsupported mailer driver: %s", cfg.Driver)
+	}
+}
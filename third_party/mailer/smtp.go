@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+
+	"tyr/config"
+)
+
+// SMTP sends emails through an SMTP server, using STARTTLS when the server supports it
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTP returns SMTP mailer
+func NewSMTP(cfg config.Mailer) *SMTP {
+	s := &SMTP{
+		addr: fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort),
+		from: cfg.From,
+	}
+	if cfg.SMTPUsername != "" {
+		s.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return s
+}
+
+// Send sends the message
+func (s *SMTP) Send(_ context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	body, err := build(msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, msg.To, body)
+}
@@ -0,0 +1,20 @@
+package sms
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Console logs text messages instead of sending them, for development only
+type Console struct{}
+
+// NewConsole returns console sms sender
+func NewConsole() *Console {
+	return &Console{}
+}
+
+// Send logs the message
+func (s *Console) Send(ctx context.Context, msg *Message) error {
+	slog.InfoContext(ctx, "sms sent", "to", msg.To, "text", msg.Text)
+	return nil
+}
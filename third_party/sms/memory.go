@@ -0,0 +1,32 @@
+package sms
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps sent text messages in memory, for tests only
+type Memory struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+// NewMemory returns in-memory sms sender
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send keeps the message
+func (s *Memory) Send(_ context.Context, msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns the sent messages
+func (s *Memory) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Message{}, s.messages...)
+}
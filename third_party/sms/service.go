@@ -0,0 +1,37 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"tyr/config"
+)
+
+// consts for sms drivers
+const (
+	DriverConsole = "console"
+	DriverMemory  = "memory"
+)
+
+// Message represents a text message
+type Message struct {
+	To   string
+	Text string
+}
+
+// Service represents sms sender service
+type Service interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns sms sender service by the configured driver
+func New(cfg config.SMS) (Service, error) {
+	switch cfg.Driver {
+	case DriverConsole, "":
+		return NewConsole(), nil
+	case DriverMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unsupported sms driver: %s", cfg.Driver)
+	}
+}